@@ -9,3 +9,7 @@ replace github.com/madpo/go-gost-crypto/pkg/wrapper => ./pkg/wrapper
 require github.com/madpo/go-gost-crypto/pkg/cryptography v1.0.0
 
 replace github.com/madpo/go-gost-crypto/pkg/cryptography => ./pkg/cryptography
+
+require github.com/madpo/go-gost-crypto/pkg/c14n v1.0.0 // indirect
+
+replace github.com/madpo/go-gost-crypto/pkg/c14n => ./pkg/c14n
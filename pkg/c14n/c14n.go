@@ -0,0 +1,394 @@
+package c14n
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+// идентификаторы алгоритмов канонизации и преобразований
+const (
+	Canonical             = "http://www.w3.org/TR/2001/REC-xml-c14n-20010315"
+	CanonicalWithComments = "http://www.w3.org/TR/2001/REC-xml-c14n-20010315#WithComments"
+	Exclusive             = "http://www.w3.org/2001/10/xml-exc-c14n#"
+	ExclusiveWithComments = "http://www.w3.org/2001/10/xml-exc-c14n#WithComments"
+	SMEVTransform         = "urn://smev-gov-ru/xmldsig/transform"
+)
+
+// пространство имен префикса xml
+const XMLNamespace = "http://www.w3.org/XML/1998/namespace"
+
+/*
+Объявление пространства имен
+*/
+type Namespace struct {
+	Prefix string
+	URI    string
+}
+
+/*
+Атрибут элемента XML
+*/
+type Attribute struct {
+	Prefix string
+	Local  string
+	Value  string
+}
+
+/*
+Узел дерева XML: *Element, Text, Comment или ProcInst
+*/
+type Node interface{}
+
+/*
+Элемент XML с префиксами в исходном виде
+*/
+type Element struct {
+	Prefix     string
+	Local      string
+	Namespaces []Namespace
+	Attributes []Attribute
+	Children   []Node
+	Parent     *Element
+}
+
+/*
+Текст
+*/
+type Text string
+
+/*
+Комментарий
+*/
+type Comment string
+
+/*
+Инструкция обработки
+*/
+type ProcInst struct {
+	Target string
+	Inst   string
+}
+
+/*
+Документ XML
+*/
+type Document struct {
+	Children []Node
+}
+
+// разобрать документ XML в дерево
+func Parse(reader io.Reader) (*Document, error) {
+	decoder := xml.NewDecoder(reader)
+	document := &Document{}
+	var current *Element
+
+	appendNode := func(node Node) {
+		if current == nil {
+			document.Children = append(document.Children, node)
+		} else {
+			current.Children = append(current.Children, node)
+		}
+	}
+
+	for {
+		token, exception := decoder.RawToken()
+
+		if exception == io.EOF {
+			break
+		}
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			element := &Element{Prefix: token.Name.Space, Local: token.Name.Local, Parent: current}
+
+			for _, attribute := range token.Attr {
+				switch {
+				case attribute.Name.Space == "xmlns":
+					element.Namespaces = append(element.Namespaces, Namespace{Prefix: attribute.Name.Local, URI: attribute.Value})
+				case attribute.Name.Space == "" && attribute.Name.Local == "xmlns":
+					element.Namespaces = append(element.Namespaces, Namespace{URI: attribute.Value})
+				default:
+					element.Attributes = append(element.Attributes, Attribute{Prefix: attribute.Name.Space, Local: attribute.Name.Local, Value: attribute.Value})
+				}
+			}
+
+			appendNode(element)
+			current = element
+		case xml.EndElement:
+			if current == nil || current.Prefix != token.Name.Space || current.Local != token.Name.Local {
+				return nil, errors.New("Некорректный XML: непарный закрывающий тег " + token.Name.Local)
+			}
+
+			current = current.Parent
+		case xml.CharData:
+			// текст вне корневого элемента не входит в модель документа
+			if current != nil {
+				appendNode(Text(token))
+			}
+		case xml.Comment:
+			appendNode(Comment(token))
+		case xml.ProcInst:
+			appendNode(ProcInst{Target: token.Target, Inst: string(token.Inst)})
+		}
+	}
+
+	if current != nil {
+		return nil, errors.New("Некорректный XML: не закрыт элемент " + current.Local)
+	}
+
+	if document.Root() == nil {
+		return nil, errors.New("Некорректный XML: нет корневого элемента")
+	}
+
+	return document, nil
+}
+
+// корневой элемент документа
+func (document *Document) Root() *Element {
+	for _, node := range document.Children {
+		if element, ok := node.(*Element); ok {
+			return element
+		}
+	}
+
+	return nil
+}
+
+// найти пространство имен префикса в области видимости элемента
+func (element *Element) LookupNamespace(prefix string) (string, bool) {
+	if prefix == "xml" {
+		return XMLNamespace, true
+	}
+
+	for current := element; current != nil; current = current.Parent {
+		for _, namespace := range current.Namespaces {
+			if namespace.Prefix == prefix {
+				return namespace.URI, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// пространство имен элемента
+func (element *Element) NamespaceURI() string {
+	uri, _ := element.LookupNamespace(element.Prefix)
+
+	return uri
+}
+
+// найти элемент по условию обходом в глубину
+func (element *Element) Find(match func(*Element) bool) *Element {
+	if match(element) {
+		return element
+	}
+
+	for _, child := range element.Children {
+		if child, ok := child.(*Element); ok {
+			if found := child.Find(match); found != nil {
+				return found
+			}
+		}
+	}
+
+	return nil
+}
+
+// найти элемент по значению атрибута Id
+func (element *Element) FindByID(id string) *Element {
+	return element.Find(func(current *Element) bool {
+		for _, attribute := range current.Attributes {
+			if attribute.Local == "Id" && attribute.Value == id {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// найти элемент по локальному имени
+func (element *Element) FindByLocalName(local string) *Element {
+	return element.Find(func(current *Element) bool {
+		return current.Local == local
+	})
+}
+
+// значение атрибута без префикса
+func (element *Element) Attribute(local string) (string, bool) {
+	for _, attribute := range element.Attributes {
+		if attribute.Prefix == "" && attribute.Local == local {
+			return attribute.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// текстовое содержимое элемента и его потомков
+func (element *Element) Text() string {
+	var builder strings.Builder
+
+	for _, child := range element.Children {
+		switch child := child.(type) {
+		case Text:
+			builder.WriteString(string(child))
+		case *Element:
+			builder.WriteString(child.Text())
+		}
+	}
+
+	return builder.String()
+}
+
+// полное имя элемента или атрибута
+func qualifiedName(prefix string, local string) string {
+	if prefix == "" {
+		return local
+	}
+
+	return prefix + ":" + local
+}
+
+// записать документ XML с исходными префиксами
+func (document *Document) Write(writer io.Writer) error {
+	buffer := bufio.NewWriter(writer)
+
+	for i, node := range document.Children {
+		if i > 0 {
+			buffer.WriteString("\n")
+		}
+
+		writeNode(buffer, node)
+	}
+
+	return buffer.Flush()
+}
+
+func writeNode(buffer *bufio.Writer, node Node) {
+	switch node := node.(type) {
+	case *Element:
+		name := qualifiedName(node.Prefix, node.Local)
+		buffer.WriteString("<" + name)
+
+		for _, namespace := range node.Namespaces {
+			writeNamespace(buffer, namespace)
+		}
+
+		for _, attribute := range node.Attributes {
+			writeAttribute(buffer, attribute)
+		}
+
+		if len(node.Children) == 0 {
+			buffer.WriteString("/>")
+			return
+		}
+
+		buffer.WriteString(">")
+
+		for _, child := range node.Children {
+			writeNode(buffer, child)
+		}
+
+		buffer.WriteString("</" + name + ">")
+	case Text:
+		buffer.WriteString(escapeText(string(node)))
+	case Comment:
+		buffer.WriteString("<!--" + string(node) + "-->")
+	case ProcInst:
+		writeProcInst(buffer, node)
+	}
+}
+
+func writeNamespace(buffer *bufio.Writer, namespace Namespace) {
+	name := "xmlns"
+
+	if namespace.Prefix != "" {
+		name += ":" + namespace.Prefix
+	}
+
+	buffer.WriteString(" " + name + "=\"" + escapeAttribute(namespace.URI) + "\"")
+}
+
+func writeAttribute(buffer *bufio.Writer, attribute Attribute) {
+	buffer.WriteString(" " + qualifiedName(attribute.Prefix, attribute.Local) + "=\"" + escapeAttribute(attribute.Value) + "\"")
+}
+
+func writeProcInst(buffer *bufio.Writer, node ProcInst) {
+	buffer.WriteString("<?" + node.Target)
+
+	if node.Inst != "" {
+		buffer.WriteString(" " + node.Inst)
+	}
+
+	buffer.WriteString("?>")
+}
+
+// экранирование текста по правилам канонизации
+func escapeText(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;").Replace(text)
+}
+
+// экранирование значения атрибута по правилам канонизации
+func escapeAttribute(value string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", "\"", "&quot;", "\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;").Replace(value)
+}
+
+// получить поток, в который в отдельной горутине пишет write
+// позволяет передать результат канонизации в метод хэширования без буферизации
+func NewReader(write func(io.Writer) error) io.Reader {
+	reader, writer := io.Pipe()
+
+	go func() {
+		writer.CloseWithError(write(writer))
+	}()
+
+	return reader
+}
+
+// получить метод канонизации или преобразования документа XML по идентификатору алгоритма
+// inclusivePrefixes - список InclusiveNamespaces PrefixList для исключающей канонизации
+func CreateCanonicalizationMethod(algorithm string, inclusivePrefixes []string) (release func(), canonicalize func(io.Reader) (io.Reader, error), exception error) {
+	var write func(writer io.Writer, document *Document) error
+
+	switch algorithm {
+	case Canonical, CanonicalWithComments:
+		write = func(writer io.Writer, document *Document) error {
+			return CanonicalizeDocument(writer, document, algorithm == CanonicalWithComments)
+		}
+	case Exclusive, ExclusiveWithComments:
+		write = func(writer io.Writer, document *Document) error {
+			return CanonicalizeExclusiveDocument(writer, document, inclusivePrefixes, algorithm == ExclusiveWithComments)
+		}
+	case SMEVTransform:
+		return func() {},
+			func(reader io.Reader) (io.Reader, error) {
+				return NewReader(func(writer io.Writer) error {
+					return TransformSMEV(writer, reader)
+				}), nil
+			}, nil
+	default:
+		return nil, nil, errors.New("Не поддерживается алгоритм канонизации " + algorithm)
+	}
+
+	return func() {},
+		func(reader io.Reader) (io.Reader, error) {
+			document, exception := Parse(reader)
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			return NewReader(func(writer io.Writer) error {
+				return write(writer, document)
+			}), nil
+		}, nil
+}
@@ -0,0 +1,266 @@
+package c14n
+
+import (
+	"bufio"
+	"io"
+	"sort"
+)
+
+/*
+Параметры канонизации
+*/
+type canonicalizer struct {
+	buffer       *bufio.Writer
+	exclusive    bool
+	withComments bool
+	// префиксы InclusiveNamespaces, пространство имен по умолчанию обозначается пустой строкой
+	inclusivePrefixes map[string]bool
+}
+
+// канонизация документа по Canonical XML 1.0
+// http://www.w3.org/TR/2001/REC-xml-c14n-20010315
+func CanonicalizeDocument(writer io.Writer, document *Document, withComments bool) error {
+	return newCanonicalizer(writer, false, nil, withComments).document(document)
+}
+
+// канонизация элемента с потомками по Canonical XML 1.0
+// элемент наследует пространства имен и атрибуты xml:* предков
+func Canonicalize(writer io.Writer, element *Element, withComments bool) error {
+	return newCanonicalizer(writer, false, nil, withComments).apex(element)
+}
+
+// исключающая канонизация документа
+// http://www.w3.org/2001/10/xml-exc-c14n#
+func CanonicalizeExclusiveDocument(writer io.Writer, document *Document, inclusivePrefixes []string, withComments bool) error {
+	return newCanonicalizer(writer, true, inclusivePrefixes, withComments).document(document)
+}
+
+// исключающая канонизация элемента с потомками
+// inclusivePrefixes - префиксы InclusiveNamespaces PrefixList, #default для пространства имен по умолчанию
+func CanonicalizeExclusive(writer io.Writer, element *Element, inclusivePrefixes []string, withComments bool) error {
+	return newCanonicalizer(writer, true, inclusivePrefixes, withComments).apex(element)
+}
+
+func newCanonicalizer(writer io.Writer, exclusive bool, inclusivePrefixes []string, withComments bool) *canonicalizer {
+	canonicalizer := &canonicalizer{
+		buffer:            bufio.NewWriter(writer),
+		exclusive:         exclusive,
+		withComments:      withComments,
+		inclusivePrefixes: map[string]bool{},
+	}
+
+	for _, prefix := range inclusivePrefixes {
+		if prefix == "#default" {
+			prefix = ""
+		}
+
+		canonicalizer.inclusivePrefixes[prefix] = true
+	}
+
+	return canonicalizer
+}
+
+// узлы до корневого элемента завершаются переводом строки, после него - начинаются с него
+func (canonicalizer *canonicalizer) document(document *Document) error {
+	afterRoot := false
+
+	for _, node := range document.Children {
+		switch node := node.(type) {
+		case *Element:
+			canonicalizer.element(node, map[string]string{}, nil)
+			afterRoot = true
+
+			continue
+		case Comment:
+			if !canonicalizer.withComments {
+				continue
+			}
+		case ProcInst:
+			if node.Target == "xml" {
+				continue
+			}
+		default:
+			continue
+		}
+
+		if afterRoot {
+			canonicalizer.buffer.WriteString("\n")
+		}
+
+		canonicalizer.node(node, nil)
+
+		if !afterRoot {
+			canonicalizer.buffer.WriteString("\n")
+		}
+	}
+
+	return canonicalizer.buffer.Flush()
+}
+
+func (canonicalizer *canonicalizer) apex(element *Element) error {
+	var inherited []Attribute
+
+	// в Canonical XML 1.0 элемент наследует атрибуты xml:* предков
+	if !canonicalizer.exclusive {
+		seen := map[string]bool{}
+
+		for _, attribute := range element.Attributes {
+			if attribute.Prefix == "xml" {
+				seen[attribute.Local] = true
+			}
+		}
+
+		for ancestor := element.Parent; ancestor != nil; ancestor = ancestor.Parent {
+			for _, attribute := range ancestor.Attributes {
+				if attribute.Prefix == "xml" && !seen[attribute.Local] {
+					seen[attribute.Local] = true
+					inherited = append(inherited, attribute)
+				}
+			}
+		}
+	}
+
+	canonicalizer.element(element, map[string]string{}, inherited)
+
+	return canonicalizer.buffer.Flush()
+}
+
+func (canonicalizer *canonicalizer) node(node Node, rendered map[string]string) {
+	switch node := node.(type) {
+	case *Element:
+		canonicalizer.element(node, rendered, nil)
+	case Text:
+		canonicalizer.buffer.WriteString(escapeText(string(node)))
+	case Comment:
+		if canonicalizer.withComments {
+			canonicalizer.buffer.WriteString("<!--" + string(node) + "-->")
+		}
+	case ProcInst:
+		writeProcInst(canonicalizer.buffer, node)
+	}
+}
+
+// пространства имен, которые должны быть выведены для элемента
+func (canonicalizer *canonicalizer) namespaces(element *Element, rendered map[string]string) []Namespace {
+	candidates := map[string]string{}
+
+	if canonicalizer.exclusive {
+		// видимо используемые пространства имен и префиксы InclusiveNamespaces
+		prefixes := map[string]bool{element.Prefix: true}
+
+		for _, attribute := range element.Attributes {
+			if attribute.Prefix != "" {
+				prefixes[attribute.Prefix] = true
+			}
+		}
+
+		for prefix := range canonicalizer.inclusivePrefixes {
+			prefixes[prefix] = true
+		}
+
+		for prefix := range prefixes {
+			uri, ok := element.LookupNamespace(prefix)
+
+			if ok || prefix == "" {
+				candidates[prefix] = uri
+			}
+		}
+	} else {
+		// все пространства имен в области видимости, ближайшее объявление имеет приоритет
+		for current := element; current != nil; current = current.Parent {
+			for _, namespace := range current.Namespaces {
+				if _, ok := candidates[namespace.Prefix]; !ok {
+					candidates[namespace.Prefix] = namespace.URI
+				}
+			}
+		}
+	}
+
+	var namespaces []Namespace
+
+	for prefix, uri := range candidates {
+		if prefix == "xml" {
+			continue
+		}
+
+		previous, ok := rendered[prefix]
+
+		// пустое пространство имен по умолчанию выводится, только если предок вывел непустое
+		if prefix == "" && uri == "" {
+			if !ok || previous == "" {
+				continue
+			}
+		} else if ok && previous == uri {
+			continue
+		}
+
+		namespaces = append(namespaces, Namespace{Prefix: prefix, URI: uri})
+	}
+
+	sort.Slice(namespaces, func(i, j int) bool {
+		return namespaces[i].Prefix < namespaces[j].Prefix
+	})
+
+	return namespaces
+}
+
+func (canonicalizer *canonicalizer) element(element *Element, rendered map[string]string, inherited []Attribute) {
+	namespaces := canonicalizer.namespaces(element, rendered)
+	scope := rendered
+
+	if len(namespaces) != 0 {
+		scope = make(map[string]string, len(rendered)+len(namespaces))
+
+		for prefix, uri := range rendered {
+			scope[prefix] = uri
+		}
+
+		for _, namespace := range namespaces {
+			scope[namespace.Prefix] = namespace.URI
+		}
+	}
+
+	type resolvedAttribute struct {
+		Attribute
+		uri string
+	}
+
+	attributes := make([]resolvedAttribute, 0, len(element.Attributes)+len(inherited))
+
+	for _, attribute := range append(append([]Attribute{}, element.Attributes...), inherited...) {
+		uri := ""
+
+		if attribute.Prefix != "" {
+			uri, _ = element.LookupNamespace(attribute.Prefix)
+		}
+
+		attributes = append(attributes, resolvedAttribute{attribute, uri})
+	}
+
+	sort.Slice(attributes, func(i, j int) bool {
+		if attributes[i].uri != attributes[j].uri {
+			return attributes[i].uri < attributes[j].uri
+		}
+
+		return attributes[i].Local < attributes[j].Local
+	})
+
+	name := qualifiedName(element.Prefix, element.Local)
+	canonicalizer.buffer.WriteString("<" + name)
+
+	for _, namespace := range namespaces {
+		writeNamespace(canonicalizer.buffer, namespace)
+	}
+
+	for _, attribute := range attributes {
+		writeAttribute(canonicalizer.buffer, attribute.Attribute)
+	}
+
+	canonicalizer.buffer.WriteString(">")
+
+	for _, child := range element.Children {
+		canonicalizer.node(child, scope)
+	}
+
+	canonicalizer.buffer.WriteString("</" + name + ">")
+}
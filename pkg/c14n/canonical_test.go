@@ -0,0 +1,218 @@
+package c14n
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+// канонизировать документ методом из CreateCanonicalizationMethod
+func canonicalizeString(t *testing.T, algorithm string, input string) string {
+	release, canonicalize, error := CreateCanonicalizationMethod(algorithm, nil)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer release()
+
+	canonical, error := canonicalize(strings.NewReader(input))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	data, error := io.ReadAll(canonical)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	return string(data)
+}
+
+// пример 3.1 Canonical XML 1.0: инструкции обработки и комментарии
+const testPIsAndComments = `<?xml version="1.0"?>
+
+<?xml-stylesheet   href="doc.xsl"
+   type="text/xsl"   ?>
+
+<doc>Hello, world!<!-- Comment 1 --></doc>
+
+<?pi-without-data     ?>
+
+<!-- Comment 2 -->
+
+<!-- Comment 3 -->`
+
+func Test_CanonicalPIsAndComments_Success(t *testing.T) {
+	want := "<?xml-stylesheet href=\"doc.xsl\"\n   type=\"text/xsl\"   ?>\n<doc>Hello, world!</doc>\n<?pi-without-data?>"
+
+	if result := canonicalizeString(t, Canonical, testPIsAndComments); result != want {
+		t.Errorf("Ожидался результат канонизации\n%s\nПолучен\n%s", want, result)
+	}
+
+	want = "<?xml-stylesheet href=\"doc.xsl\"\n   type=\"text/xsl\"   ?>\n<doc>Hello, world!<!-- Comment 1 --></doc>\n<?pi-without-data?>\n<!-- Comment 2 -->\n<!-- Comment 3 -->"
+
+	if result := canonicalizeString(t, CanonicalWithComments, testPIsAndComments); result != want {
+		t.Errorf("Ожидался результат канонизации с комментариями\n%s\nПолучен\n%s", want, result)
+	}
+}
+
+// пример 3.2 Canonical XML 1.0: пробельные символы сохраняются
+func Test_CanonicalWhitespace_Success(t *testing.T) {
+	input := `<doc>
+   <clean>   </clean>
+   <dirty>   A   B   </dirty>
+   <mixed>
+      A
+      <clean>   </clean>
+      B
+      <dirty>   A   B   </dirty>
+      C
+   </mixed>
+</doc>`
+
+	if result := canonicalizeString(t, Canonical, input); result != input {
+		t.Errorf("Ожидался результат канонизации\n%s\nПолучен\n%s", input, result)
+	}
+}
+
+// пример 3.3 Canonical XML 1.0 без DTD: теги, атрибуты и пространства имен
+func Test_CanonicalStartAndEndTags_Success(t *testing.T) {
+	input := `<doc>
+   <e1   />
+   <e2   ></e2>
+   <e3   name = "elem3"   id="elem3"   />
+   <e4   name="elem4"   id="elem4"   ></e4>
+   <e5 a:attr="out" b:attr="sorted" attr2="all" attr="I'm"
+      xmlns:b="http://www.ietf.org"
+      xmlns:a="http://www.w3.org"
+      xmlns="http://example.org"/>
+   <e6 xmlns="" xmlns:a="http://www.w3.org">
+      <e7 xmlns="http://www.ietf.org">
+         <e8 xmlns="" xmlns:a="http://www.w3.org">
+            <e9 xmlns="" xmlns:a="http://www.ietf.org" attr="default"/>
+         </e8>
+      </e7>
+   </e6>
+</doc>`
+
+	want := `<doc>
+   <e1></e1>
+   <e2></e2>
+   <e3 id="elem3" name="elem3"></e3>
+   <e4 id="elem4" name="elem4"></e4>
+   <e5 xmlns="http://example.org" xmlns:a="http://www.w3.org" xmlns:b="http://www.ietf.org" attr="I'm" attr2="all" b:attr="sorted" a:attr="out"></e5>
+   <e6 xmlns:a="http://www.w3.org">
+      <e7 xmlns="http://www.ietf.org">
+         <e8 xmlns="">
+            <e9 xmlns:a="http://www.ietf.org" attr="default"></e9>
+         </e8>
+      </e7>
+   </e6>
+</doc>`
+
+	if result := canonicalizeString(t, Canonical, input); result != want {
+		t.Errorf("Ожидался результат канонизации\n%s\nПолучен\n%s", want, result)
+	}
+}
+
+// пример 3.4 Canonical XML 1.0 без DTD: ссылки на символы, CDATA и экранирование
+func Test_CanonicalCharacterModifications_Success(t *testing.T) {
+	input := `<doc>
+   <text>First line&#x0d;&#10;Second line</text>
+   <value>&#x32;</value>
+   <compute><![CDATA[value>"0" && value<"10" ?"valid":"error"]]></compute>
+   <compute expr='value>"0" &amp;&amp; value&lt;"10" ?"valid":"error"'>valid</compute>
+   <norm attr=' &apos;   &#x20;&#13;&#xa;&#9;   &apos; '/>
+</doc>`
+
+	want := `<doc>
+   <text>First line&#xD;
+Second line</text>
+   <value>2</value>
+   <compute>value&gt;"0" &amp;&amp; value&lt;"10" ?"valid":"error"</compute>
+   <compute expr="value>&quot;0&quot; &amp;&amp; value&lt;&quot;10&quot; ?&quot;valid&quot;:&quot;error&quot;">valid</compute>
+   <norm attr=" '    &#xD;&#xA;&#x9;   ' "></norm>
+</doc>`
+
+	if result := canonicalizeString(t, Canonical, input); result != want {
+		t.Errorf("Ожидался результат канонизации\n%s\nПолучен\n%s", want, result)
+	}
+}
+
+// пример 3.6 Canonical XML 1.0: символы выводятся в UTF-8
+func Test_CanonicalUTF8_Success(t *testing.T) {
+	if result := canonicalizeString(t, Canonical, `<doc>&#169;</doc>`); result != "<doc>©</doc>" {
+		t.Errorf("Ожидался результат канонизации <doc>©</doc>. Получен %s", result)
+	}
+}
+
+// пример раздела 2.2 Exclusive XML Canonicalization
+const testExclusiveDocument = `<n0:local xmlns:n0="foo:bar" xmlns:n3="ftp://example.org">
+   <n1:elem2 xmlns:n1="http://example.net" xml:lang="en">
+       <n3:stuff xmlns:n3="ftp://example.org"/>
+   </n1:elem2>
+</n0:local>`
+
+func Test_CanonicalizeSubset_Success(t *testing.T) {
+	document, error := Parse(strings.NewReader(testExclusiveDocument))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	element := document.Root().FindByLocalName("elem2")
+	var inclusive, exclusive bytes.Buffer
+
+	if error := Canonicalize(&inclusive, element, false); error != nil {
+		t.Fatal(error)
+	}
+
+	want := "<n1:elem2 xmlns:n0=\"foo:bar\" xmlns:n1=\"http://example.net\" xmlns:n3=\"ftp://example.org\" xml:lang=\"en\">\n" +
+		"       <n3:stuff></n3:stuff>\n   </n1:elem2>"
+
+	if inclusive.String() != want {
+		t.Errorf("Ожидался результат канонизации\n%s\nПолучен\n%s", want, inclusive.String())
+	}
+
+	if error := CanonicalizeExclusive(&exclusive, element, nil, false); error != nil {
+		t.Fatal(error)
+	}
+
+	want = "<n1:elem2 xmlns:n1=\"http://example.net\" xml:lang=\"en\">\n" +
+		"       <n3:stuff xmlns:n3=\"ftp://example.org\"></n3:stuff>\n   </n1:elem2>"
+
+	if exclusive.String() != want {
+		t.Errorf("Ожидался результат исключающей канонизации\n%s\nПолучен\n%s", want, exclusive.String())
+	}
+}
+
+func Test_CanonicalizeExclusiveInclusiveNamespaces_Success(t *testing.T) {
+	document, error := Parse(strings.NewReader(`<root xmlns="urn:default" xmlns:a="urn:a" xmlns:b="urn:b" xml:lang="ru">` +
+		`<a:child b="1"><inner>text</inner></a:child></root>`))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	var buffer bytes.Buffer
+
+	if error := CanonicalizeExclusive(&buffer, document.Root().FindByLocalName("child"), []string{"b", "#default"}, false); error != nil {
+		t.Fatal(error)
+	}
+
+	want := `<a:child xmlns="urn:default" xmlns:a="urn:a" xmlns:b="urn:b" b="1"><inner>text</inner></a:child>`
+
+	if buffer.String() != want {
+		t.Errorf("Ожидался результат канонизации %s. Получен %s", want, buffer.String())
+	}
+}
+
+func Test_CanonicalizationMethod_Unsupported(t *testing.T) {
+	if _, _, error := CreateCanonicalizationMethod("http://www.w3.org/2006/12/xml-c14n11", nil); error == nil {
+		t.Error("Ожидалась ошибка для неподдерживаемого алгоритма")
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/madpo/go-gost-crypto/pkg/c14n
+
+go 1.20
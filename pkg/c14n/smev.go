@@ -0,0 +1,134 @@
+package c14n
+
+import (
+	"bufio"
+	"encoding/xml"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// преобразование СМЭВ 3 urn://smev-gov-ru/xmldsig/transform
+// удаляются объявление XML, инструкции обработки и пробельные текстовые узлы,
+// префиксы заменяются на ns1, ns2... и объявляются там, где используются,
+// атрибуты с пространством имен идут первыми и сортируются по пространству имен и имени
+func TransformSMEV(writer io.Writer, reader io.Reader) error {
+	decoder := xml.NewDecoder(reader)
+	buffer := bufio.NewWriter(writer)
+	// объявленные префиксы по уровням вложенности
+	var scopes []map[string]string
+	var names []string
+	// счетчик префиксов не сбрасывается при выходе из области видимости
+	counter := 0
+
+	findPrefix := func(uri string) (string, bool) {
+		for i := len(scopes) - 1; i >= 0; i-- {
+			if prefix, ok := scopes[i][uri]; ok {
+				return prefix, true
+			}
+		}
+
+		return "", false
+	}
+
+	for {
+		token, exception := decoder.Token()
+
+		if exception == io.EOF {
+			break
+		}
+
+		if exception != nil {
+			return exception
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			scope := map[string]string{}
+			scopes = append(scopes, scope)
+			var declarations []Namespace
+
+			declare := func(uri string) string {
+				prefix, ok := findPrefix(uri)
+
+				if !ok {
+					counter++
+					prefix = "ns" + strconv.Itoa(counter)
+					scope[uri] = prefix
+					declarations = append(declarations, Namespace{Prefix: prefix, URI: uri})
+				}
+
+				return prefix
+			}
+
+			name := token.Name.Local
+
+			if token.Name.Space != "" {
+				name = declare(token.Name.Space) + ":" + name
+			}
+
+			var attributes []xml.Attr
+
+			for _, attribute := range token.Attr {
+				if attribute.Name.Space == "xmlns" || (attribute.Name.Space == "" && attribute.Name.Local == "xmlns") {
+					continue
+				}
+
+				attributes = append(attributes, attribute)
+			}
+
+			sort.Slice(attributes, func(i, j int) bool {
+				x, y := attributes[i].Name, attributes[j].Name
+
+				if (x.Space == "") != (y.Space == "") {
+					return y.Space == ""
+				}
+
+				if x.Space != y.Space {
+					return x.Space < y.Space
+				}
+
+				return x.Local < y.Local
+			})
+
+			attributeNames := make([]string, len(attributes))
+
+			for i, attribute := range attributes {
+				attributeNames[i] = attribute.Name.Local
+
+				if attribute.Name.Space != "" {
+					attributeNames[i] = declare(attribute.Name.Space) + ":" + attribute.Name.Local
+				}
+			}
+
+			buffer.WriteString("<" + name)
+
+			for _, declaration := range declarations {
+				buffer.WriteString(" xmlns:" + declaration.Prefix + "=\"" + escapeSMEVAttribute(declaration.URI) + "\"")
+			}
+
+			for i, attribute := range attributes {
+				buffer.WriteString(" " + attributeNames[i] + "=\"" + escapeSMEVAttribute(attribute.Value) + "\"")
+			}
+
+			buffer.WriteString(">")
+			names = append(names, name)
+		case xml.EndElement:
+			buffer.WriteString("</" + names[len(names)-1] + ">")
+			names = names[:len(names)-1]
+			scopes = scopes[:len(scopes)-1]
+		case xml.CharData:
+			if len(names) != 0 && strings.TrimSpace(string(token)) != "" {
+				buffer.WriteString(strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(string(token)))
+			}
+		}
+	}
+
+	return buffer.Flush()
+}
+
+// экранирование значения атрибута при преобразовании СМЭВ
+func escapeSMEVAttribute(value string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\"", "&quot;").Replace(value)
+}
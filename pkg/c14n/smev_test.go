@@ -0,0 +1,142 @@
+package c14n
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_SMEVTransform_Success(t *testing.T) {
+	input := `<?xml version="1.0" encoding="UTF-8"?>
+<?xml-stylesheet type="text/xsl" href="style.xsl"?>
+
+<elementOne xmlns="http://test/1" xmlns:qwe="http://test/2" xmlns:asd="http://test/3">
+	<qwe:elementTwo>
+		<asd:elementThree>
+			<!-- комментарий -->
+			<elementFour> z x c </elementFour>
+			<![CDATA[ z x c ]]>
+		</asd:elementThree>
+	</qwe:elementTwo>
+</elementOne>`
+
+	want := `<ns1:elementOne xmlns:ns1="http://test/1"><ns2:elementTwo xmlns:ns2="http://test/2">` +
+		`<ns3:elementThree xmlns:ns3="http://test/3"><ns1:elementFour> z x c </ns1:elementFour> z x c </ns3:elementThree>` +
+		`</ns2:elementTwo></ns1:elementOne>`
+
+	if result := canonicalizeString(t, SMEVTransform, input); result != want {
+		t.Errorf("Ожидался результат преобразования %s. Получен %s", want, result)
+	}
+}
+
+func Test_SMEVTransformAttributes_Success(t *testing.T) {
+	input := `<root xmlns:b="urn:b" xmlns:a="urn:a" z="1" a:y="2" b:x="3" a:w="4">` +
+		`<a:first/><second xmlns="urn:c"/><third xmlns="urn:c">&lt;&amp;&gt;</third></root>`
+
+	// атрибуты без пространства имен идут последними, префиксы соседних элементов не переиспользуются
+	want := `<root xmlns:ns1="urn:a" xmlns:ns2="urn:b" ns1:w="4" ns1:y="2" ns2:x="3" z="1">` +
+		`<ns1:first></ns1:first><ns3:second xmlns:ns3="urn:c"></ns3:second><ns4:third xmlns:ns4="urn:c">&lt;&amp;&gt;</ns4:third></root>`
+
+	if result := canonicalizeString(t, SMEVTransform, input); result != want {
+		t.Errorf("Ожидался результат преобразования %s. Получен %s", want, result)
+	}
+}
+
+func Test_SMEVTransformHashTest_Success(t *testing.T) {
+	file, error := os.Open("../../test/HashTest.xml")
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer file.Close()
+
+	document, error := Parse(file)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	var canonical, transformed bytes.Buffer
+
+	if error := CanonicalizeExclusive(&canonical, document.Root().FindByID("SIGNED_BY_CONSUMER"), nil, false); error != nil {
+		t.Fatal(error)
+	}
+
+	if error := TransformSMEV(&transformed, &canonical); error != nil {
+		t.Fatal(error)
+	}
+
+	want := `<ns1:MessageTypeSelector xmlns:ns1="urn://x-artefacts-smev-gov-ru/services/message-exchange/types/basic/1.3" Id="SIGNED_BY_CONSUMER">` +
+		`<ns1:NamespaceURI>urn://x-artefacts-zags-rogdzp/root/112-23/4.0.1</ns1:NamespaceURI>` +
+		`<ns1:RootElementLocalName>Request</ns1:RootElementLocalName>` +
+		`<ns1:Timestamp>2020-08-19T11:31:55.7639468+03:00</ns1:Timestamp>` +
+		`</ns1:MessageTypeSelector>`
+
+	if transformed.String() != want {
+		t.Errorf("Ожидался результат преобразования %s. Получен %s", want, transformed.String())
+	}
+}
+
+// метод хэширования с той же сигнатурой, что и методы pkg/cryptography
+func calculateTestHash(reader io.Reader) (io.Reader, error) {
+	hash := sha256.New()
+
+	if _, error := io.Copy(hash, reader); error != nil {
+		return nil, error
+	}
+
+	return bytes.NewReader(hash.Sum(nil)), nil
+}
+
+func Test_CanonicalizationMethodHash_Success(t *testing.T) {
+	release, canonicalize, error := CreateCanonicalizationMethod(Exclusive, nil)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer release()
+
+	releaseTransform, transform, error := CreateCanonicalizationMethod(SMEVTransform, nil)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer releaseTransform()
+
+	canonical, error := canonicalize(strings.NewReader(`<a:root xmlns:a="urn:a" xmlns:unused="urn:unused">  <a:child/>  </a:root>`))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	transformed, error := transform(canonical)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	hash, error := calculateTestHash(transformed)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	data, error := io.ReadAll(hash)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	digest := sha256.Sum256([]byte(`<ns1:root xmlns:ns1="urn:a"><ns1:child></ns1:child></ns1:root>`))
+
+	if result, want := hex.EncodeToString(data), hex.EncodeToString(digest[:]); result != want {
+		t.Errorf("Ожидался хэш %s. Получен %s", want, result)
+	}
+}
@@ -0,0 +1,272 @@
+package cryptography
+
+import (
+	"crypto/cipher"
+	"errors"
+	"hash"
+)
+
+// преобразование ключа ACPKM и режимы с его использованием, Р 1323565.1.017-2018, RFC 8645
+// размеры секций задаются в байтах
+
+/*
+Конструктор блочного шифра по ключу, например NewKuznyechik или NewMagma
+*/
+type BlockConstructor func(key []byte) (cipher.Block, error)
+
+// вычислить следующий ключ секции ACPKM(K) = MSB_k(E_K(D_1) || ... || E_K(D_J)), D = 0x80..0x9f
+func acpkmKey(block cipher.Block) []byte {
+	blockSize := block.BlockSize()
+	key := make([]byte, KuznyechikKeySize)
+
+	for offset := 0; offset < len(key); offset += blockSize {
+		for i := 0; i < blockSize; i++ {
+			key[offset+i] = byte(0x80 + offset + i)
+		}
+
+		block.Encrypt(key[offset:offset+blockSize], key[offset:offset+blockSize])
+	}
+
+	return key
+}
+
+/*
+Режим гаммирования с преобразованием ключа после каждой секции
+*/
+type ctrACPKMMode struct {
+	newCipher   BlockConstructor
+	block       cipher.Block
+	counter     []byte
+	gamma       []byte
+	sectionSize int
+	// число байт, обработанных текущим ключом
+	processed int
+	offset    int
+}
+
+// получить режим CTR-ACPKM
+// sectionSize - размер секции N, кратный размеру блока; синхропосылка имеет длину половины блока
+func NewCTRACPKM(newCipher BlockConstructor, key []byte, iv []byte, sectionSize int) (cipher.Stream, error) {
+	block, exception := newCipher(key)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	blockSize := block.BlockSize()
+
+	if len(iv) != blockSize/2 {
+		return nil, errors.New("Длина синхропосылки должна быть равна половине размера блока")
+	}
+
+	if sectionSize <= 0 || sectionSize%blockSize != 0 {
+		return nil, errors.New("Размер секции должен быть кратен размеру блока")
+	}
+
+	counter := make([]byte, blockSize)
+	copy(counter, iv)
+
+	return &ctrACPKMMode{
+		newCipher:   newCipher,
+		block:       block,
+		counter:     counter,
+		gamma:       make([]byte, blockSize),
+		sectionSize: sectionSize,
+	}, nil
+}
+
+func (mode *ctrACPKMMode) XORKeyStream(dst, src []byte) {
+	checkStreamBuffers(dst, src)
+
+	for i := range src {
+		if mode.offset == 0 {
+			mode.nextGamma()
+		}
+
+		dst[i] = src[i] ^ mode.gamma[mode.offset]
+		mode.offset = (mode.offset + 1) % len(mode.gamma)
+	}
+}
+
+// выработать следующий блок гаммы, при исчерпании секции сменить ключ
+func (mode *ctrACPKMMode) nextGamma() {
+	if mode.processed == mode.sectionSize {
+		block, exception := mode.newCipher(acpkmKey(mode.block))
+
+		// конструктор уже принимал ключ той же длины
+		if exception != nil {
+			panic(exception)
+		}
+
+		mode.block = block
+		mode.processed = 0
+	}
+
+	mode.block.Encrypt(mode.gamma, mode.counter)
+	incrementCounter(mode.counter)
+	mode.processed += len(mode.gamma)
+}
+
+/*
+Ключ секции K^i и дополнительный ключ K^i_1
+*/
+type omacSection struct {
+	block      cipher.Block
+	additional []byte
+}
+
+/*
+Имитовставка OMAC-ACPKM
+ключи секций K^i и дополнительные ключи K^i_1 вырабатываются режимом CTR-ACPKM из нулевых данных
+*/
+type omacACPKM struct {
+	newCipher   BlockConstructor
+	key         []byte
+	blockSize   int
+	sectionSize int
+	masterSize  int
+	tagSize     int
+	// поток ключевого материала ACPKM^Master
+	master cipher.Stream
+	// ключи выработанных секций
+	sections []omacSection
+	// состояние: C_{i-1}, число обработанных блоков и необработанный последний блок
+	state  []byte
+	blocks int
+	buffer []byte
+}
+
+// получить метод вычисления имитовставки OMAC-ACPKM
+// sectionSize - размер секции N, masterSize - размер секции T* выработки ключей, tagSize - длина имитовставки, при 0 - размер блока
+func NewOMACACPKM(newCipher BlockConstructor, key []byte, sectionSize int, masterSize int, tagSize int) (hash.Hash, error) {
+	block, exception := newCipher(key)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	blockSize := block.BlockSize()
+
+	if sectionSize <= 0 || sectionSize%blockSize != 0 || masterSize <= 0 || masterSize%blockSize != 0 {
+		return nil, errors.New("Размер секции должен быть кратен размеру блока")
+	}
+
+	if tagSize == 0 {
+		tagSize = blockSize
+	}
+
+	if tagSize < 0 || tagSize > blockSize {
+		return nil, errors.New("Неверная длина имитовставки")
+	}
+
+	mac := &omacACPKM{
+		newCipher:   newCipher,
+		key:         append([]byte{}, key...),
+		blockSize:   blockSize,
+		sectionSize: sectionSize,
+		masterSize:  masterSize,
+		tagSize:     tagSize,
+	}
+
+	mac.Reset()
+
+	return mac, nil
+}
+
+func (mac *omacACPKM) Size() int {
+	return mac.tagSize
+}
+
+func (mac *omacACPKM) BlockSize() int {
+	return mac.blockSize
+}
+
+func (mac *omacACPKM) Reset() {
+	// ACPKM^Master(T*, K, d) - CTR-ACPKM с синхропосылкой 1^{n/2}
+	iv := make([]byte, mac.blockSize/2)
+
+	for i := range iv {
+		iv[i] = 0xff
+	}
+
+	mac.master, _ = NewCTRACPKM(mac.newCipher, mac.key, iv, mac.masterSize)
+	mac.sections = nil
+	mac.state = make([]byte, mac.blockSize)
+	mac.blocks = 0
+	mac.buffer = mac.buffer[:0]
+}
+
+func (mac *omacACPKM) Write(data []byte) (int, error) {
+	return writeMACBlocks(data, &mac.buffer, mac.blockSize, mac.processBlock), nil
+}
+
+func (mac *omacACPKM) Sum(in []byte) []byte {
+	// вычисление не изменяет состояние, запись может быть продолжена
+	last := make([]byte, mac.blockSize)
+	copy(last, mac.buffer)
+
+	block, additional := mac.sectionKeys(mac.blocks)
+
+	if len(mac.buffer) < mac.blockSize {
+		last[len(mac.buffer)] = 0x80
+		additional = shiftSubkey(additional)
+	}
+
+	xorBytes(last, last, mac.state)
+	xorBytes(last, last, additional)
+	block.Encrypt(last, last)
+
+	return append(in, last[:mac.tagSize]...)
+}
+
+// обработать блок, кроме последнего
+func (mac *omacACPKM) processBlock(data []byte) {
+	block, _ := mac.sectionKeys(mac.blocks)
+
+	xorBytes(mac.state, mac.state, data)
+	block.Encrypt(mac.state, mac.state)
+	mac.blocks++
+}
+
+// получить ключи секции, содержащей блок с номером index от нуля
+func (mac *omacACPKM) sectionKeys(index int) (cipher.Block, []byte) {
+	section := index * mac.blockSize / mac.sectionSize
+
+	for len(mac.sections) <= section {
+		material := make([]byte, KuznyechikKeySize+mac.blockSize)
+		mac.master.XORKeyStream(material, material)
+
+		block, exception := mac.newCipher(material[:KuznyechikKeySize])
+
+		// длина ключа совпадает с длиной ключа, принятого конструктором
+		if exception != nil {
+			panic(exception)
+		}
+
+		mac.sections = append(mac.sections, omacSection{block: block, additional: material[KuznyechikKeySize:]})
+	}
+
+	return mac.sections[section].block, mac.sections[section].additional
+}
+
+// вычислить K2 = K1 << 1 с приведением по модулю многочлена поля
+func shiftSubkey(key []byte) []byte {
+	result := make([]byte, len(key))
+	carry := key[0] >> 7
+
+	for i := 0; i < len(key)-1; i++ {
+		result[i] = key[i]<<1 | key[i+1]>>7
+	}
+
+	result[len(key)-1] = key[len(key)-1] << 1
+
+	if carry != 0 {
+		if len(key) == KuznyechikBlockSize {
+			result[len(key)-1] ^= 0x87
+		} else {
+			result[len(key)-1] ^= 0x1b
+		}
+	}
+
+	return result
+}
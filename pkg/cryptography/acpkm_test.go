@@ -0,0 +1,150 @@
+package cryptography
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+)
+
+// открытый текст примеров RFC 8645, приложение A
+var testACPKMPlaintext = []string{
+	"1122334455667700ffeeddccbbaa9988",
+	"00112233445566778899aabbcceeff0a",
+	"112233445566778899aabbcceeff0a00",
+	"2233445566778899aabbcceeff0a0011",
+	"33445566778899aabbcceeff0a001122",
+	"445566778899aabbcceeff0a00112233",
+	"5566778899aabbcceeff0a0011223344",
+}
+
+func Test_CTRACPKM_Success(t *testing.T) {
+	key, _ := hex.DecodeString(testKuznyechikKey)
+	iv, _ := hex.DecodeString("1234567890abcef0")
+	plaintext, _ := hex.DecodeString(strings.Join(testACPKMPlaintext, ""))
+
+	// размер секции N = 256 бит
+	stream, error := NewCTRACPKM(NewKuznyechik, key, iv, 32)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	ciphertext := make([]byte, len(plaintext))
+	stream.XORKeyStream(ciphertext, plaintext)
+
+	want := "f195d8bec10ed1dbd57b5fa240bda1b8" + "85eee733f6a13e5df33ce4b33c45dee4" +
+		"4bceeb8f646f4c55001706275e85e800" + "587c4df568d094393e4834afd0805046" +
+		"cf30f57686aeece11cfc6c316b8a896e" + "dffd07ec813636460c4f3b743423163e" +
+		"6409a9c282fac8d469d221e7fbd6de5d"
+
+	if result := hex.EncodeToString(ciphertext); result != want {
+		t.Errorf("Ожидался шифртекст %s. Получен %s", want, result)
+	}
+
+	// расшифрование потока через io.Copy
+	stream, _ = NewCTRACPKM(NewKuznyechik, key, iv, 32)
+
+	var decrypted bytes.Buffer
+
+	if _, error := io.Copy(&decrypted, cipher.StreamReader{S: stream, R: bytes.NewReader(ciphertext)}); error != nil {
+		t.Fatal(error)
+	}
+
+	if !bytes.Equal(decrypted.Bytes(), plaintext) {
+		t.Errorf("Ожидался открытый текст %x. Получен %x", plaintext, decrypted.Bytes())
+	}
+}
+
+func Test_CTRACPKM_Magma(t *testing.T) {
+	// пример RFC 8645 для Магмы: ключ примеров для Кузнечика, N = 128 бит
+	key, _ := hex.DecodeString(testKuznyechikKey)
+	iv, _ := hex.DecodeString("12345678")
+	plaintext, _ := hex.DecodeString(strings.Join(testACPKMPlaintext, ""))
+
+	stream, error := NewCTRACPKM(NewMagma, key, iv, 16)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	ciphertext := make([]byte, len(plaintext))
+	stream.XORKeyStream(ciphertext, plaintext)
+
+	want := "2ab81deeeb1e4cab" + "68e104c4bd6b94ea" + "c72c67af6c2e5b6b" + "0eafb61770f1b32e" +
+		"a1ae71149eed1382" + "abd467180672ec6f" + "84a2f15b3fca72c1" + "5559fbd38c4c7c5d" +
+		"a90d5adbbd3d22f9" + "2b2283b686439fb4" + "796fa8a3fe3b7ec3" + "9e48c896f90e1097" +
+		"a9351073a37a742c" + "0569c8d445faeac5"
+
+	if result := hex.EncodeToString(ciphertext); result != want {
+		t.Errorf("Ожидался шифртекст %s. Получен %s", want, result)
+	}
+
+	if _, error := NewCTRACPKM(NewMagma, key, iv, 12); error == nil {
+		t.Error("Ожидалась ошибка для размера секции, не кратного блоку")
+	}
+}
+
+func Test_OMACACPKM_Success(t *testing.T) {
+	key, _ := hex.DecodeString(testKuznyechikKey)
+	message, _ := hex.DecodeString(strings.Join(testACPKMPlaintext[:5], ""))
+
+	// N = 256 бит, T* = 768 бит
+	mac, error := NewOMACACPKM(NewKuznyechik, key, 32, 96, 0)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if _, error := io.Copy(mac, bytes.NewReader(message)); error != nil {
+		t.Fatal(error)
+	}
+
+	want := "fbb8dcee45bea67c35f58c5700898e5d"
+
+	if result := hex.EncodeToString(mac.Sum(nil)); result != want {
+		t.Errorf("Ожидалась имитовставка %s. Получена %s", want, result)
+	}
+
+	// Sum не изменяет состояние, запись частями дает тот же результат
+	mac.Reset()
+	mac.Write(message[:7])
+	first := mac.Sum(nil)
+	mac.Write(message[7:])
+
+	if result := hex.EncodeToString(mac.Sum(nil)); result != want {
+		t.Errorf("Ожидалась имитовставка %s. Получена %s", want, result)
+	}
+
+	if bytes.Equal(first, mac.Sum(nil)) {
+		t.Error("Ожидались разные имитовставки для разных данных")
+	}
+}
+
+func Test_OMACACPKM_Magma(t *testing.T) {
+	key, _ := hex.DecodeString(testKuznyechikKey)
+	message, _ := hex.DecodeString(strings.Join(testACPKMPlaintext[:5], ""))
+
+	// N = 128 бит, T* = 640 бит
+	for _, test := range []struct {
+		length int
+		want   string
+	}{
+		{8, "a9d85fe8266d67cf"},
+		{80, "3ddb7105300b6c6b"},
+	} {
+		mac, error := NewOMACACPKM(NewMagma, key, 16, 80, 0)
+
+		if error != nil {
+			t.Fatal(error)
+		}
+
+		mac.Write(message[:test.length])
+
+		if result := hex.EncodeToString(mac.Sum(nil)); result != test.want {
+			t.Errorf("%d байт: ожидалась имитовставка %s. Получена %s", test.length, test.want, result)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package cryptography
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"io"
+	"runtime"
+	"sync"
+
+	"github.com/madpo/go-gost-crypto/pkg/wrapper"
+)
+
+/*
+Параметры пакетной подписи
+*/
+type BatchOptions struct {
+	// число параллельных обработчиков, по умолчанию runtime.NumCPU()
+	Workers int
+	// алгоритм подписи, по умолчанию определяется по сертификату
+	Algorithm *SignatureAlgorithm
+	// формировать открепленную подпись CAdES-BES вместо значения подписи
+	CMS bool
+	// разрешить параллельные вызовы Sign, по умолчанию вызовы выполняются по одному
+	// ключ контейнера КриптоПро использует один дескриптор, поэтому для ContainerSigner флаг не устанавливается
+	ConcurrentSign bool
+}
+
+/*
+Элемент пакета: данные или готовый хэш
+*/
+type BatchItem struct {
+	// идентификатор элемента, возвращается в результате
+	ID string
+	// подписываемые данные
+	Content io.Reader
+	// хэш данных в порядке байт КриптоПро, используется, если Content не задан
+	Digest []byte
+}
+
+/*
+Результат подписи элемента пакета
+*/
+type BatchResult struct {
+	ID string
+	// порядковый номер элемента в пакете
+	Index int
+	// значение подписи s||r или CMS подпись в DER
+	Signature []byte
+	Exception error
+}
+
+// получить метод пакетной подписи ключом из контейнера КриптоПро
+// контейнер открывается и PIN вводится один раз на все элементы пакета
+func CreateContainerBatchSignMethod(cspType wrapper.CSPType, container string, pin string, options BatchOptions) (release func(), sign func(items []BatchItem) []BatchResult, signChannel func(ctx context.Context, items <-chan BatchItem) <-chan BatchResult, exception error) {
+	releaseSigner, signer, exception := CreateContainerSigner(cspType, container, pin)
+
+	if exception != nil {
+		return nil, nil, nil, exception
+	}
+
+	options.ConcurrentSign = false
+
+	releaseBatch, sign, signChannel, exception := CreateBatchSignMethod(signer, signer.Certificate(), options)
+
+	if exception != nil {
+		releaseSigner()
+		return nil, nil, nil, exception
+	}
+
+	return func() {
+		releaseBatch()
+		releaseSigner()
+	}, sign, signChannel, nil
+}
+
+// получить метод пакетной подписи
+// хэши вычисляются параллельно options.Workers обработчиками, ошибка элемента не прерывает обработку пакета
+// sign возвращает результаты в порядке элементов, signChannel - по мере готовности, канал результатов закрывается после закрытия канала элементов
+// при отмене ctx signChannel прекращает подпись и закрывает канал результатов, оставшиеся элементы вычитываются без подписи до закрытия канала элементов
+func CreateBatchSignMethod(signer crypto.Signer, certificate *x509.Certificate, options BatchOptions) (release func(), sign func(items []BatchItem) []BatchResult, signChannel func(ctx context.Context, items <-chan BatchItem) <-chan BatchResult, exception error) {
+	algorithm := options.Algorithm
+
+	if algorithm == nil {
+		if certificate == nil {
+			return nil, nil, nil, errors.New("Не задан сертификат или алгоритм подписи")
+		}
+
+		algorithm, exception = FindCertificateSignatureAlgorithm(certificate)
+
+		if exception != nil {
+			return nil, nil, nil, exception
+		}
+	}
+
+	if options.CMS && certificate == nil {
+		return nil, nil, nil, errors.New("Для CMS подписи требуется сертификат")
+	}
+
+	if exception := checkSignatureKey(signer.Public(), algorithm); exception != nil {
+		return nil, nil, nil, exception
+	}
+
+	workers := options.Workers
+
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	parameters := &signParameters{
+		signer:      lockedSigner(signer, options.ConcurrentSign),
+		certificate: certificate,
+		algorithm:   algorithm,
+		detached:    true,
+		attributes:  signingCertificateAttributes,
+	}
+
+	signChannel = func(ctx context.Context, items <-chan BatchItem) <-chan BatchResult {
+		results := make(chan BatchResult, workers)
+		indexed := make(chan batchTask)
+
+		go func() {
+			// отправитель элементов не должен блокироваться после отмены
+			defer func() {
+				for range items {
+				}
+			}()
+			defer close(indexed)
+
+			for index := 0; ; index++ {
+				var item BatchItem
+				var ok bool
+
+				select {
+				case item, ok = <-items:
+					if !ok {
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+
+				select {
+				case indexed <- batchTask{index: index, item: item}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+
+		var group sync.WaitGroup
+
+		for i := 0; i < workers; i++ {
+			group.Add(1)
+
+			go func() {
+				defer group.Done()
+
+				for task := range indexed {
+					result := signBatchItem(task, parameters, options.CMS)
+
+					select {
+					case results <- result:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+		}
+
+		go func() {
+			group.Wait()
+			close(results)
+		}()
+
+		return results
+	}
+
+	sign = func(items []BatchItem) []BatchResult {
+		channel := make(chan BatchItem)
+
+		go func() {
+			for _, item := range items {
+				channel <- item
+			}
+
+			close(channel)
+		}()
+
+		results := make([]BatchResult, len(items))
+
+		for result := range signChannel(context.Background(), channel) {
+			results[result.Index] = result
+		}
+
+		return results
+	}
+
+	return func() {}, sign, signChannel, nil
+}
+
+/*
+Элемент пакета с порядковым номером
+*/
+type batchTask struct {
+	index int
+	item  BatchItem
+}
+
+// подписать один элемент пакета
+func signBatchItem(task batchTask, parameters *signParameters, cms bool) BatchResult {
+	result := BatchResult{ID: task.item.ID, Index: task.index}
+	digest := task.item.Digest
+
+	if task.item.Content != nil {
+		digest, result.Exception = calculateDigest(parameters.algorithm.Hash, task.item.Content)
+
+		if result.Exception != nil {
+			return result
+		}
+	} else if len(digest) != parameters.algorithm.Hash.Size {
+		result.Exception = errors.New("Не заданы данные или хэш неверной длины")
+
+		return result
+	}
+
+	if !cms {
+		result.Signature, result.Exception = signDigest(parameters.signer, parameters.algorithm, digest)
+
+		return result
+	}
+
+	signedData, exception := signDigestContent(digest, parameters)
+
+	if exception != nil {
+		result.Exception = exception
+
+		return result
+	}
+
+	signature, exception := marshalSignedData(signedData)
+
+	if exception != nil {
+		result.Exception = exception
+
+		return result
+	}
+
+	result.Signature, result.Exception = io.ReadAll(signature)
+
+	return result
+}
+
+// сформировать открепленную SignedData по хэшу содержимого
+func signDigestContent(digest []byte, parameters *signParameters) (*SignedData, error) {
+	signerInfo, exception := createSignerInfo(digest, OIDData, parameters)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	return &SignedData{
+		Version:          1,
+		DigestAlgorithms: []AlgorithmIdentifier{digestAlgorithmIdentifier(parameters.algorithm.Hash)},
+		EncapContentInfo: EncapsulatedContentInfo{EContentType: OIDData},
+		Certificates:     marshalCertificates([]*x509.Certificate{parameters.certificate}),
+		SignerInfos:      []SignerInfo{*signerInfo},
+	}, nil
+}
+
+/*
+Подписант, выполняющий вызовы Sign по одному
+*/
+type mutexSigner struct {
+	crypto.Signer
+	mutex sync.Mutex
+}
+
+func (signer *mutexSigner) Sign(random io.Reader, digest []byte, options crypto.SignerOpts) ([]byte, error) {
+	signer.mutex.Lock()
+	defer signer.mutex.Unlock()
+
+	return signer.Signer.Sign(random, digest, options)
+}
+
+// обернуть подписанта блокировкой, если параллельная подпись не разрешена
+func lockedSigner(signer crypto.Signer, concurrent bool) crypto.Signer {
+	if concurrent {
+		return signer
+	}
+
+	return &mutexSigner{Signer: signer}
+}
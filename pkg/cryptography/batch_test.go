@@ -0,0 +1,204 @@
+package cryptography
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// сформировать тестовый пакет из данных и хэшей
+func createTestBatch(t *testing.T, algorithm *HashAlgorithm, count int) []BatchItem {
+	var items []BatchItem
+
+	for i := 0; i < count; i++ {
+		content := fmt.Sprintf("Запись реестра %d", i)
+		item := BatchItem{ID: fmt.Sprint(i)}
+
+		if i%2 == 0 {
+			item.Content = strings.NewReader(content)
+		} else {
+			digest, error := calculateBytesDigest(algorithm, []byte(content))
+
+			if error != nil {
+				t.Fatal(error)
+			}
+
+			item.Digest = digest
+		}
+
+		items = append(items, item)
+	}
+
+	return items
+}
+
+func Test_BatchSign_Success(t *testing.T) {
+	privateKey, certificate := createTestGOSTCertificate(t, CurveTC26_256A, "Batch")
+
+	release, sign, _, error := CreateBatchSignMethod(privateKey, certificate, BatchOptions{Workers: 4})
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer release()
+
+	items := createTestBatch(t, HashGOST3411_2012_256, 20)
+	items = append(items, BatchItem{ID: "broken", Digest: []byte{1, 2, 3}})
+
+	results := sign(items)
+
+	if len(results) != len(items) {
+		t.Fatalf("Ожидалось %d результатов. Получено %d", len(items), len(results))
+	}
+
+	for i, result := range results[:20] {
+		if result.ID != items[i].ID || result.Index != i {
+			t.Fatalf("Нарушен порядок результатов: элемент %d, получен %s", i, result.ID)
+		}
+
+		if result.Exception != nil {
+			t.Fatalf("Элемент %s: %v", result.ID, result.Exception)
+		}
+
+		digest, _ := calculateBytesDigest(HashGOST3411_2012_256, []byte(fmt.Sprintf("Запись реестра %d", i)))
+
+		if !privateKey.PublicKey.VerifyDigest(digest, result.Signature) {
+			t.Errorf("Ожидалась верная подпись элемента %s", result.ID)
+		}
+	}
+
+	if results[20].Exception == nil {
+		t.Error("Ожидалась ошибка для хэша неверной длины")
+	}
+}
+
+func Test_BatchSignCMS_Success(t *testing.T) {
+	privateKey, certificate := createTestGOSTCertificate(t, CurveTC26_512A, "Batch")
+
+	release, _, signChannel, error := CreateBatchSignMethod(privateKey, certificate, BatchOptions{Workers: 3, CMS: true, ConcurrentSign: true})
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer release()
+
+	batch := createTestBatch(t, HashGOST3411_2012_512, 6)
+	items := make(chan BatchItem)
+
+	go func() {
+		for _, item := range batch {
+			items <- item
+		}
+
+		close(items)
+	}()
+
+	count := 0
+
+	for result := range signChannel(context.Background(), items) {
+		count++
+
+		if result.Exception != nil {
+			t.Fatalf("Элемент %s: %v", result.ID, result.Exception)
+		}
+
+		report := verifyTestCMS(t, result.Signature, strings.NewReader(fmt.Sprintf("Запись реестра %d", result.Index)))
+
+		if !report.Valid() {
+			t.Errorf("Ожидалась верная CMS подпись элемента %s", result.ID)
+		}
+	}
+
+	if count != 6 {
+		t.Errorf("Ожидалось 6 результатов. Получено %d", count)
+	}
+}
+
+func Test_BatchSignChannel_Canceled(t *testing.T) {
+	privateKey, certificate := createTestGOSTCertificate(t, CurveTC26_256A, "Batch")
+
+	release, _, signChannel, error := CreateBatchSignMethod(privateKey, certificate, BatchOptions{Workers: 2, ConcurrentSign: true})
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer release()
+
+	batch := createTestBatch(t, HashGOST3411_2012_256, 200)
+	items := make(chan BatchItem)
+	sent := make(chan struct{})
+
+	go func() {
+		for _, item := range batch {
+			items <- item
+		}
+
+		close(items)
+		close(sent)
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	results := signChannel(ctx, items)
+
+	<-results
+	cancel()
+
+	// после отмены канал результатов закрывается, а отправитель не блокируется
+	count := 1
+	timeout := time.After(10 * time.Second)
+
+	for closed := false; !closed; {
+		select {
+		case _, ok := <-results:
+			if ok {
+				count++
+			} else {
+				closed = true
+			}
+		case <-timeout:
+			t.Fatal("Канал результатов не закрыт после отмены")
+		}
+	}
+
+	select {
+	case <-sent:
+	case <-timeout:
+		t.Fatal("Отправитель элементов заблокирован после отмены")
+	}
+
+	if count == len(batch) {
+		t.Errorf("Ожидалась остановка подписи после отмены. Получено %d результатов", count)
+	}
+}
+
+func Test_BatchSign_KeyMismatch(t *testing.T) {
+	privateKey, _ := createTestGOSTCertificate(t, CurveCryptoProA, "Batch")
+
+	if _, _, _, error := CreateBatchSignMethod(privateKey, nil, BatchOptions{Algorithm: SignatureGOST3410_2012_512}); error == nil {
+		t.Error("Ожидалась ошибка для ключа 256 бит и алгоритма ГОСТ Р 34.10-2012-512")
+	}
+
+	if _, _, _, error := CreateBatchSignMethod(privateKey, nil, BatchOptions{Algorithm: SignatureGOST3410_2012_256, CMS: true}); error == nil {
+		t.Error("Ожидалась ошибка для CMS подписи без сертификата")
+	}
+
+	release, sign, _, error := CreateBatchSignMethod(privateKey, nil, BatchOptions{Algorithm: SignatureGOST3410_2012_256})
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer release()
+
+	results := sign([]BatchItem{{ID: "raw", Content: bytes.NewReader([]byte("Hello world"))}})
+
+	if results[0].Exception != nil || len(results[0].Signature) != 64 {
+		t.Errorf("Ожидалась подпись длиной 64 байта. Получена ошибка %v", results[0].Exception)
+	}
+}
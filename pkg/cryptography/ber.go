@@ -0,0 +1,312 @@
+package cryptography
+
+import (
+	"bufio"
+	"encoding/asn1"
+	"errors"
+	"io"
+)
+
+// максимальный размер элемента, который читается в память целиком
+const berElementLimit = 64 * 1024 * 1024
+
+/*
+Потоковое чтение BER без загрузки всего сообщения в память
+*/
+type berReader struct {
+	reader *bufio.Reader
+}
+
+/*
+Заголовок BER элемента
+*/
+type berHeader struct {
+	// исходные байты идентификатора и длины
+	raw        []byte
+	identifier []byte
+	// длина содержимого, -1 для неопределенной длины
+	length int64
+}
+
+func newBERReader(reader io.Reader) *berReader {
+	return &berReader{reader: bufio.NewReader(reader)}
+}
+
+func (header *berHeader) constructed() bool {
+	return header.identifier[0]&0x20 != 0
+}
+
+// является ли элемент универсальным или контекстным с заданным номером тега
+func (header *berHeader) is(class byte, tag byte) bool {
+	return len(header.identifier) == 1 && header.identifier[0]&0xdf == class|tag
+}
+
+// прочитать заголовок элемента, io.EOF возвращается только в начале элемента
+func (reader *berReader) readHeader() (*berHeader, error) {
+	first, exception := reader.reader.ReadByte()
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	raw := []byte{first}
+
+	if first&0x1f == 0x1f {
+		for {
+			value, exception := reader.readByte()
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			raw = append(raw, value)
+
+			if value&0x80 == 0 {
+				break
+			}
+		}
+	}
+
+	header := &berHeader{identifier: raw}
+
+	lengthByte, exception := reader.readByte()
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	raw = append(raw, lengthByte)
+
+	switch {
+	case lengthByte == 0x80:
+		if !header.constructed() {
+			return nil, errors.New("Неопределенная длина у примитивного ASN.1 элемента")
+		}
+
+		header.length = -1
+	case lengthByte&0x80 == 0:
+		header.length = int64(lengthByte)
+	default:
+		count := int(lengthByte & 0x7f)
+
+		if count > 8 {
+			return nil, errors.New("Некорректная длина ASN.1 элемента")
+		}
+
+		for i := 0; i < count; i++ {
+			value, exception := reader.readByte()
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			raw = append(raw, value)
+			header.length = header.length<<8 | int64(value)
+		}
+
+		if header.length < 0 {
+			return nil, errors.New("Некорректная длина ASN.1 элемента")
+		}
+	}
+
+	header.identifier = raw[:len(header.identifier)]
+	header.raw = raw
+
+	return header, nil
+}
+
+// прочитать байт внутри элемента, конец потока означает обрезанную структуру
+func (reader *berReader) readByte() (byte, error) {
+	value, exception := reader.reader.ReadByte()
+
+	if exception == io.EOF {
+		return 0, errors.New("Обрезанная ASN.1 структура")
+	}
+
+	return value, exception
+}
+
+// проверить и пропустить маркер конца содержимого
+func (reader *berReader) readEndOfContents() (bool, error) {
+	marker, exception := reader.reader.Peek(2)
+
+	if exception != nil {
+		if exception == io.EOF {
+			return false, errors.New("Не найден конец ASN.1 элемента")
+		}
+
+		return false, exception
+	}
+
+	if marker[0] != 0 || marker[1] != 0 {
+		return false, nil
+	}
+
+	reader.reader.Discard(2)
+
+	return true, nil
+}
+
+// прочитать элемент целиком и вернуть его DER представление
+func (reader *berReader) readElement() ([]byte, error) {
+	header, exception := reader.readHeader()
+
+	if exception != nil {
+		if exception == io.EOF {
+			return nil, errors.New("Обрезанная ASN.1 структура")
+		}
+
+		return nil, exception
+	}
+
+	return reader.readContent(header)
+}
+
+// прочитать содержимое элемента после заголовка и вернуть DER представление элемента
+func (reader *berReader) readContent(header *berHeader) ([]byte, error) {
+	if header.length >= 0 {
+		if header.length > berElementLimit {
+			return nil, errors.New("Слишком большой ASN.1 элемент")
+		}
+
+		data := make([]byte, len(header.raw)+int(header.length))
+		copy(data, header.raw)
+
+		if _, exception := io.ReadFull(reader.reader, data[len(header.raw):]); exception != nil {
+			return nil, errors.New("Обрезанная ASN.1 структура")
+		}
+
+		return berToDER(data)
+	}
+
+	var children [][]byte
+
+	for {
+		end, exception := reader.readEndOfContents()
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		if end {
+			return buildDERElement(header.identifier, children), nil
+		}
+
+		child, exception := reader.readElement()
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		children = append(children, child)
+	}
+}
+
+// скопировать содержимое OCTET STRING, примитивной или составной, не загружая его в память
+func (reader *berReader) copyOctets(output io.Writer, header *berHeader) error {
+	if !header.constructed() {
+		if _, exception := io.CopyN(output, reader.reader, header.length); exception != nil {
+			if exception == io.EOF {
+				return errors.New("Обрезанная ASN.1 структура")
+			}
+
+			return exception
+		}
+
+		return nil
+	}
+
+	// составная строка определенной длины читается из ограниченного потока
+	inner := reader
+
+	if header.length >= 0 {
+		inner = newBERReader(io.LimitReader(reader.reader, header.length))
+	}
+
+	for {
+		if header.length < 0 {
+			end, exception := inner.readEndOfContents()
+
+			if exception != nil {
+				return exception
+			}
+
+			if end {
+				return nil
+			}
+		}
+
+		child, exception := inner.readHeader()
+
+		if exception == io.EOF && header.length >= 0 {
+			return nil
+		}
+
+		if exception != nil {
+			return exception
+		}
+
+		if !child.is(0, 0x04) {
+			return errors.New("Составная OCTET STRING содержит элемент другого типа")
+		}
+
+		if exception := inner.copyOctets(output, child); exception != nil {
+			return exception
+		}
+	}
+}
+
+// прочитать заголовок составного элемента с заданным тегом, содержимое читается дальше потоком
+func (reader *berReader) expectHeader(class byte, tag byte) (*berHeader, error) {
+	header, exception := reader.readHeader()
+
+	if exception == io.EOF {
+		return nil, errors.New("Обрезанная ASN.1 структура")
+	}
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	if !header.constructed() || !header.is(class, tag) {
+		return nil, errors.New("Неожиданный ASN.1 элемент")
+	}
+
+	return header, nil
+}
+
+// пропустить оставшиеся элементы составного элемента неопределенной длины и его маркер конца
+func (reader *berReader) closeElement(header *berHeader) error {
+	if header.length >= 0 {
+		return nil
+	}
+
+	for {
+		end, exception := reader.readEndOfContents()
+
+		if exception != nil {
+			return exception
+		}
+
+		if end {
+			return nil
+		}
+
+		if _, exception := reader.readElement(); exception != nil {
+			return exception
+		}
+	}
+}
+
+// прочитать элемент целиком и разобрать его в value
+func (reader *berReader) readValue(value interface{}) error {
+	element, exception := reader.readElement()
+
+	if exception != nil {
+		return exception
+	}
+
+	_, exception = asn1.Unmarshal(element, value)
+
+	return exception
+}
@@ -0,0 +1,211 @@
+package cryptography
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"io"
+	"math/big"
+)
+
+// идентификаторы атрибутов CAdES
+var (
+	OIDAttributeSigningCertificateV2    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 47}
+	OIDAttributeSignatureTimeStampToken = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 14}
+)
+
+/*
+Идентификатор сертификата ESSCertIDv2
+*/
+type essCertIDv2 struct {
+	HashAlgorithm AlgorithmIdentifier `asn1:"optional"`
+	CertHash      []byte
+	IssuerSerial  issuerSerial `asn1:"optional"`
+}
+
+/*
+Издатель и серийный номер сертификата в форме GeneralNames
+*/
+type issuerSerial struct {
+	Issuer       []asn1.RawValue
+	SerialNumber *big.Int
+}
+
+/*
+Атрибут signing-certificate-v2
+*/
+type signingCertificateV2 struct {
+	Certs []essCertIDv2
+}
+
+// получить метод формирования подписи CAdES-BES
+func CreateCAdESBESSignMethod(signer crypto.Signer, certificate *x509.Certificate, detached bool, options SignOptions) (release func(), sign func(io.Reader) (io.Reader, error), exception error) {
+	return createCAdESSignMethod(signer, certificate, detached, nil, options)
+}
+
+// получить метод формирования подписи CAdES-T
+// timestamp - метод получения штампа времени, например из CreateTimestampMethod
+func CreateCAdESTSignMethod(signer crypto.Signer, certificate *x509.Certificate, detached bool, timestamp func(io.Reader) (io.Reader, error), options SignOptions) (release func(), sign func(io.Reader) (io.Reader, error), exception error) {
+	if timestamp == nil {
+		return nil, nil, errors.New("Не задан метод получения штампа времени")
+	}
+
+	return createCAdESSignMethod(signer, certificate, detached, timestamp, options)
+}
+
+func createCAdESSignMethod(signer crypto.Signer, certificate *x509.Certificate, detached bool, timestamp func(io.Reader) (io.Reader, error), options SignOptions) (release func(), sign func(io.Reader) (io.Reader, error), exception error) {
+	algorithm, exception := findSignOptionsAlgorithm(certificate, options)
+
+	if exception != nil {
+		return nil, nil, exception
+	}
+
+	parameters := &signParameters{
+		signer:      signer,
+		certificate: certificate,
+		algorithm:   algorithm,
+		detached:    detached,
+		attributes:  signingCertificateAttributes,
+	}
+
+	return func() {},
+		func(content io.Reader) (io.Reader, error) {
+			signedData, exception := signContent(content, parameters)
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			if timestamp != nil {
+				if exception := addSignatureTimestamp(&signedData.SignerInfos[0], timestamp); exception != nil {
+					return nil, exception
+				}
+			}
+
+			return marshalSignedData(signedData)
+		}, nil
+}
+
+// сформировать атрибут signing-certificate-v2
+func signingCertificateAttributes(algorithm *SignatureAlgorithm, certificate *x509.Certificate) ([]Attribute, error) {
+	certificateHash, exception := calculateBytesDigest(algorithm.Hash, certificate.Raw)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	certID := essCertIDv2{
+		CertHash: certificateHash,
+		IssuerSerial: issuerSerial{
+			// directoryName [4] EXPLICIT Name
+			Issuer:       []asn1.RawValue{{Class: asn1.ClassContextSpecific, Tag: 4, IsCompound: true, Bytes: certificate.RawIssuer}},
+			SerialNumber: certificate.SerialNumber,
+		},
+	}
+
+	// SHA-256 является значением по умолчанию и не кодируется
+	if algorithm.Hash != HashSha256 {
+		certID.HashAlgorithm = digestAlgorithmIdentifier(algorithm.Hash)
+	}
+
+	attribute, exception := newAttribute(OIDAttributeSigningCertificateV2, signingCertificateV2{Certs: []essCertIDv2{certID}})
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	return []Attribute{attribute}, nil
+}
+
+// проверить атрибут signing-certificate-v2, если он есть
+func verifySigningCertificate(attributes []Attribute, certificate *x509.Certificate) error {
+	attribute, ok := findAttribute(attributes, OIDAttributeSigningCertificateV2)
+
+	if !ok {
+		return nil
+	}
+
+	var value signingCertificateV2
+
+	if len(attribute.Values) != 1 {
+		return errors.New("Некорректный атрибут signing-certificate-v2")
+	}
+
+	if _, exception := asn1.Unmarshal(attribute.Values[0].FullBytes, &value); exception != nil {
+		return exception
+	}
+
+	if len(value.Certs) == 0 {
+		return errors.New("Пустой атрибут signing-certificate-v2")
+	}
+
+	algorithm := HashSha256
+
+	if len(value.Certs[0].HashAlgorithm.Algorithm) > 0 {
+		found, exception := FindHashAlgorithm(value.Certs[0].HashAlgorithm.Algorithm)
+
+		if exception != nil {
+			return exception
+		}
+
+		algorithm = found
+	}
+
+	certificateHash, exception := calculateBytesDigest(algorithm, certificate.Raw)
+
+	if exception != nil {
+		return exception
+	}
+
+	if !bytes.Equal(certificateHash, value.Certs[0].CertHash) {
+		return errors.New("Хэш сертификата подписанта не совпадает с атрибутом signing-certificate-v2")
+	}
+
+	return nil
+}
+
+// добавить штамп времени на значение подписи в неподписанные атрибуты
+func addSignatureTimestamp(signerInfo *SignerInfo, timestamp func(io.Reader) (io.Reader, error)) error {
+	token, exception := timestamp(bytes.NewReader(signerInfo.Signature))
+
+	if exception != nil {
+		return exception
+	}
+
+	return addTimestampAttribute(signerInfo, OIDAttributeSignatureTimeStampToken, token)
+}
+
+// добавить штамп времени в неподписанные атрибуты
+func addTimestampAttribute(signerInfo *SignerInfo, oid asn1.ObjectIdentifier, token io.Reader) error {
+	encodedToken, exception := io.ReadAll(token)
+
+	if exception != nil {
+		return exception
+	}
+
+	return addUnsignedAttribute(signerInfo, Attribute{Type: oid, Values: []asn1.RawValue{{FullBytes: encodedToken}}})
+}
+
+// добавить неподписанный атрибут в SignerInfo, порядок существующих атрибутов сохраняется
+func addUnsignedAttribute(signerInfo *SignerInfo, attribute Attribute) error {
+	encoded, exception := asn1.Marshal(attribute)
+
+	if exception != nil {
+		return exception
+	}
+
+	var content []byte
+
+	if len(signerInfo.UnsignedAttributes.FullBytes) != 0 {
+		content = append(content, derContent(signerInfo.UnsignedAttributes.FullBytes)...)
+	}
+
+	content = append(content, encoded...)
+
+	// [1] IMPLICIT SET OF
+	signerInfo.UnsignedAttributes = asn1.RawValue{FullBytes: encodeDERElement([]byte{0xa1}, content)}
+
+	return nil
+}
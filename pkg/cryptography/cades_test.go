@@ -0,0 +1,390 @@
+package cryptography
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testValidity struct {
+	NotBefore time.Time
+	NotAfter  time.Time
+}
+
+type testTBSCertificate struct {
+	Version            int `asn1:"explicit,tag:0"`
+	SerialNumber       *big.Int
+	SignatureAlgorithm AlgorithmIdentifier
+	Issuer             asn1.RawValue
+	Validity           testValidity
+	Subject            asn1.RawValue
+	PublicKey          asn1.RawValue
+}
+
+type testCertificate struct {
+	TBSCertificate     asn1.RawValue
+	SignatureAlgorithm AlgorithmIdentifier
+	Signature          asn1.BitString
+}
+
+// сформировать самоподписанный сертификат ГОСТ Р 34.10-2012 для тестов
+func createTestGOSTCertificate(t *testing.T, curve *Curve, commonName string) (*PrivateKey, *x509.Certificate) {
+	privateKey, error := GeneratePrivateKey(curve, nil)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	publicKey, error := MarshalPublicKey(&privateKey.PublicKey)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	name, error := asn1.Marshal(pkix.Name{CommonName: commonName}.ToRDNSequence())
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	algorithm := SignatureGOST3410_2012_256
+
+	if curve.PointSize == 64 {
+		algorithm = SignatureGOST3410_2012_512
+	}
+
+	tbs, error := asn1.Marshal(testTBSCertificate{
+		Version:            2,
+		SerialNumber:       big.NewInt(time.Now().UnixNano()),
+		SignatureAlgorithm: AlgorithmIdentifier{Algorithm: algorithm.OID},
+		Issuer:             asn1.RawValue{FullBytes: name},
+		Validity:           testValidity{NotBefore: time.Now().Add(-time.Hour).UTC(), NotAfter: time.Now().Add(time.Hour).UTC()},
+		Subject:            asn1.RawValue{FullBytes: name},
+		PublicKey:          asn1.RawValue{FullBytes: publicKey},
+	})
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	digest, error := calculateBytesDigest(algorithm.Hash, tbs)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	signature, error := privateKey.SignDigest(digest, nil)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	raw, error := asn1.Marshal(testCertificate{
+		TBSCertificate:     asn1.RawValue{FullBytes: tbs},
+		SignatureAlgorithm: AlgorithmIdentifier{Algorithm: algorithm.OID},
+		Signature:          asn1.BitString{Bytes: signature, BitLength: 8 * len(signature)},
+	})
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	certificate, error := x509.ParseCertificate(raw)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	return privateKey, certificate
+}
+
+// сформировать самоподписанный сертификат RSA для тестов
+func createTestRSACertificate(t *testing.T, commonName string) (*rsa.PrivateKey, *x509.Certificate) {
+	privateKey, error := rsa.GenerateKey(rand.Reader, 2048)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	}
+
+	raw, error := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	certificate, error := x509.ParseCertificate(raw)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	return privateKey, certificate
+}
+
+// служба штампов времени для тестов
+func createTestTSA(t *testing.T, status int) *httptest.Server {
+	privateKey, certificate := createTestRSACertificate(t, "Test TSA")
+
+	return startTestTSA(t, status, privateKey, certificate)
+}
+
+// служба штампов времени для тестов с заданным ключом и сертификатом
+func startTestTSA(t *testing.T, status int, privateKey *rsa.PrivateKey, certificate *x509.Certificate) *httptest.Server {
+	serialNumber := int64(0)
+
+	return httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, error := io.ReadAll(request.Body)
+
+		if error != nil {
+			t.Error(error)
+			return
+		}
+
+		var timeStampRequest TimeStampRequest
+
+		if _, error := asn1.Unmarshal(body, &timeStampRequest); error != nil {
+			t.Error(error)
+			return
+		}
+
+		response := TimeStampResponse{Status: PKIStatusInfo{Status: status}}
+
+		if status == tspGranted {
+			serialNumber++
+
+			info, error := asn1.Marshal(TSTInfo{
+				Version:        1,
+				Policy:         asn1.ObjectIdentifier{1, 2, 3, 4},
+				MessageImprint: timeStampRequest.MessageImprint,
+				SerialNumber:   big.NewInt(serialNumber),
+				GenTime:        time.Now().UTC().Truncate(time.Second),
+				Nonce:          timeStampRequest.Nonce,
+			})
+
+			if error != nil {
+				t.Error(error)
+				return
+			}
+
+			signedData, error := signContent(bytes.NewReader(info), &signParameters{
+				signer:      privateKey,
+				certificate: certificate,
+				algorithm:   SignatureRSASha256,
+				contentType: OIDTSTInfo,
+				attributes:  signingCertificateAttributes,
+			})
+
+			if error != nil {
+				t.Error(error)
+				return
+			}
+
+			token, error := marshalSignedData(signedData)
+
+			if error != nil {
+				t.Error(error)
+				return
+			}
+
+			encodedToken, _ := io.ReadAll(token)
+			response.TimeStampToken = asn1.RawValue{FullBytes: encodedToken}
+		} else {
+			response.Status.StatusString = []string{"rejected"}
+		}
+
+		encoded, error := asn1.Marshal(response)
+
+		if error != nil {
+			t.Error(error)
+			return
+		}
+
+		writer.Header().Set("Content-Type", "application/timestamp-reply")
+		writer.Write(encoded)
+	}))
+}
+
+func Test_CAdESBES_Success(t *testing.T) {
+	privateKey, certificate := createTestGOSTCertificate(t, CurveCryptoProA, "CAdES-BES")
+
+	release, sign, error := CreateCAdESBESSignMethod(privateKey, certificate, true, SignOptions{})
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer release()
+
+	signature, error := sign(strings.NewReader("Hello world"))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	data, error := io.ReadAll(signature)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	signedData, error := parseSignedData(data)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	attributes, error := parseAttributes(signedData.SignerInfos[0].SignedAttributes)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if _, ok := findAttribute(attributes, OIDAttributeSigningCertificateV2); !ok {
+		t.Error("Ожидался атрибут signing-certificate-v2")
+	}
+
+	releaseVerify, verify, error := CreateCMSVerifyMethod()
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer releaseVerify()
+
+	report, error := verify(bytes.NewReader(data), strings.NewReader("Hello world"))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if !report.Valid() {
+		t.Fatalf("Ожидалась верная подпись. Получен статус %s: %v", report.Signers[0].Status, report.Signers[0].Exception)
+	}
+
+	if !report.Signers[0].SignatureAlgorithm.Equal(OIDGOST3410_2012_256) {
+		t.Errorf("Ожидался алгоритм подписи %s. Получен %s", OIDGOST3410_2012_256, report.Signers[0].SignatureAlgorithm)
+	}
+}
+
+func Test_CAdEST_Success(t *testing.T) {
+	tsa := createTestTSA(t, tspGranted)
+	defer tsa.Close()
+
+	releaseTimestamp, timestamp, error := CreateTimestampMethod(tsa.URL, HashGOST3411_2012_256)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer releaseTimestamp()
+
+	privateKey, certificate := createTestGOSTCertificate(t, CurveTC26_512A, "CAdES-T")
+
+	release, sign, error := CreateCAdESTSignMethod(privateKey, certificate, false, timestamp, SignOptions{})
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer release()
+
+	signature, error := sign(strings.NewReader("Hello world"))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	data, error := io.ReadAll(signature)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	signedData, error := parseSignedData(data)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	signerInfo := signedData.SignerInfos[0]
+	attributes, error := parseAttributes(signerInfo.UnsignedAttributes)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	attribute, ok := findAttribute(attributes, OIDAttributeSignatureTimeStampToken)
+
+	if !ok {
+		t.Fatal("Ожидался атрибут signature-time-stamp")
+	}
+
+	info, error := ParseTimestampToken(bytes.NewReader(attribute.Values[0].FullBytes))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	digest, error := calculateBytesDigest(HashGOST3411_2012_256, signerInfo.Signature)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if !bytes.Equal(info.MessageImprint.HashedMessage, digest) {
+		t.Error("Штамп времени выдан не на значение подписи")
+	}
+
+	releaseVerify, verify, error := CreateCMSVerifyMethod()
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer releaseVerify()
+
+	report, error := verify(bytes.NewReader(data), nil)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if !report.Valid() {
+		t.Fatalf("Ожидалась верная подпись. Получен статус %s: %v", report.Signers[0].Status, report.Signers[0].Exception)
+	}
+}
+
+func Test_Timestamp_Rejected(t *testing.T) {
+	tsa := createTestTSA(t, 2)
+	defer tsa.Close()
+
+	release, timestamp, error := CreateTimestampMethod(tsa.URL, HashGOST3411_2012_256)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer release()
+
+	if _, error := timestamp(strings.NewReader("Hello world")); error == nil {
+		t.Error("Ожидалась ошибка для отклоненного запроса")
+	}
+}
@@ -0,0 +1,144 @@
+package cryptography
+
+import (
+	"crypto"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+)
+
+/*
+Сертификат X.509 с разобранным открытым ключом и алгоритмом подписи ГОСТ
+
+crypto/x509 не знает алгоритмов ГОСТ: PublicKey равен nil, SignatureAlgorithm и PublicKeyAlgorithm - Unknown
+*/
+type Certificate struct {
+	*x509.Certificate
+	// открытый ключ ГОСТ Р 34.10, nil для ключей других алгоритмов
+	GOSTPublicKey *PublicKey
+	// параметры открытого ключа ГОСТ Р 34.10: набор параметров кривой и алгоритма хэширования
+	GOSTPublicKeyParameters PublicKeyParameters
+	// алгоритм подписи сертификата издателем, nil для алгоритмов, неизвестных библиотеке
+	IssuerSignatureAlgorithm *SignatureAlgorithm
+}
+
+// разобрать сертификат из DER или PEM
+func ParseCertificate(data []byte) (*Certificate, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		if block.Type != "CERTIFICATE" {
+			return nil, errors.New("Не поддерживается тип PEM " + block.Type)
+		}
+
+		data = block.Bytes
+	}
+
+	certificate, exception := x509.ParseCertificate(data)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	return NewCertificate(certificate)
+}
+
+// дополнить сертификат crypto/x509 открытым ключом и алгоритмом подписи ГОСТ
+func NewCertificate(certificate *x509.Certificate) (*Certificate, error) {
+	var raw rawCertificate
+
+	if _, exception := asn1.Unmarshal(certificate.Raw, &raw); exception != nil {
+		return nil, exception
+	}
+
+	result := &Certificate{Certificate: certificate}
+
+	// алгоритмы, неизвестные библиотеке (ECDSA, Ed25519, SHA-1 с RSA), проверяет crypto/x509
+	if algorithm, exception := findCertificateSignatureAlgorithm(&raw.SignatureAlgorithm); exception == nil {
+		result.IssuerSignatureAlgorithm = algorithm
+	}
+
+	var info subjectPublicKeyInfo
+
+	if _, exception := asn1.Unmarshal(certificate.RawSubjectPublicKeyInfo, &info); exception != nil {
+		return nil, exception
+	}
+
+	publicKeyAlgorithm := info.Algorithm.Algorithm
+
+	if !publicKeyAlgorithm.Equal(OIDGOST3410_2001) && !publicKeyAlgorithm.Equal(OIDGOST3410_2012_256) && !publicKeyAlgorithm.Equal(OIDGOST3410_2012_512) {
+		return result, nil
+	}
+
+	if _, exception := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &result.GOSTPublicKeyParameters); exception != nil {
+		return nil, exception
+	}
+
+	publicKey, exception := ParsePublicKey(certificate.RawSubjectPublicKeyInfo)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	result.GOSTPublicKey = publicKey
+
+	return result, nil
+}
+
+// найти алгоритм подписи сертификата, для RSASSA-PSS алгоритм хэширования задается в параметрах
+func findCertificateSignatureAlgorithm(identifier *AlgorithmIdentifier) (*SignatureAlgorithm, error) {
+	var hash *HashAlgorithm
+
+	if identifier.Algorithm.Equal(OIDRSAPSS) {
+		hashAlgorithm, exception := parseRSAPSSHash(identifier.Parameters.FullBytes)
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		hash = hashAlgorithm
+	}
+
+	return FindSignatureAlgorithm(identifier.Algorithm, hash)
+}
+
+// алгоритм хэширования, которым издатель подписал сертификат, nil для алгоритмов, неизвестных библиотеке
+func (certificate *Certificate) HashAlgorithm() *HashAlgorithm {
+	if certificate.IssuerSignatureAlgorithm == nil {
+		return nil
+	}
+
+	return certificate.IssuerSignatureAlgorithm.Hash
+}
+
+// открытый ключ сертификата: *PublicKey для ГОСТ Р 34.10, ключ стандартной библиотеки для остальных алгоритмов
+func (certificate *Certificate) Public() crypto.PublicKey {
+	if certificate.GOSTPublicKey != nil {
+		return certificate.GOSTPublicKey
+	}
+
+	return certificate.PublicKey
+}
+
+// идентификатор ключа по RFC 5280 п. 4.2.1.2 (1): SHA-1 от значения subjectPublicKey, так его вычисляет КриптоПро
+func (certificate *Certificate) KeyIdentifier() ([]byte, error) {
+	var info subjectPublicKeyInfo
+
+	if _, exception := asn1.Unmarshal(certificate.RawSubjectPublicKeyInfo, &info); exception != nil {
+		return nil, exception
+	}
+
+	identifier := sha1.Sum(info.PublicKey.RightAlign())
+
+	return identifier[:], nil
+}
+
+// проверить подпись сертификата ключом издателя, хэш ГОСТ вычисляется методами хэширования
+// как и в crypto/x509, издатель должен быть УЦ с правом подписи сертификатов, в том числе для подписей ГОСТ
+func (certificate *Certificate) CheckSignatureFrom(issuer *Certificate) error {
+	if exception := checkIssuerConstraints(issuer.Certificate, 0); exception != nil {
+		return exception
+	}
+
+	return checkCertificateSignature(certificate.Certificate, issuer.Certificate)
+}
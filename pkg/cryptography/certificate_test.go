@@ -0,0 +1,142 @@
+package cryptography
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func Test_ParseCertificate_Success(t *testing.T) {
+	issuerKey, issuer := createTestIssuedCertificate(t, CurveTC26_512A, "Издатель", nil, nil, nil)
+	privateKey, subject := createTestIssuedCertificate(t, CurveTC26_256A, "Владелец", issuerKey, issuer, nil)
+
+	for _, data := range [][]byte{subject.Raw, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: subject.Raw})} {
+		certificate, error := ParseCertificate(data)
+
+		if error != nil {
+			t.Fatal(error)
+		}
+
+		if certificate.GOSTPublicKey == nil || certificate.GOSTPublicKey.X.Cmp(privateKey.X) != 0 || certificate.GOSTPublicKey.Curve != CurveTC26_256A {
+			t.Error("Ожидался открытый ключ ГОСТ Р 34.10-2012-256 владельца")
+		}
+
+		if !certificate.GOSTPublicKeyParameters.PublicKeyParamSet.Equal(CurveTC26_256A.OID) || !certificate.GOSTPublicKeyParameters.DigestParamSet.Equal(OIDGOST3411_2012_256) {
+			t.Errorf("Ожидались параметры ключа %s. Получены %v", CurveTC26_256A.OID, certificate.GOSTPublicKeyParameters)
+		}
+
+		// сертификат подписан ключом 512 бит издателя
+		if certificate.IssuerSignatureAlgorithm != SignatureGOST3410_2012_512 || certificate.HashAlgorithm() != HashGOST3411_2012_512 {
+			t.Errorf("Ожидался алгоритм подписи %s. Получен %s", SignatureGOST3410_2012_512.Name, certificate.IssuerSignatureAlgorithm.Name)
+		}
+
+		if _, ok := certificate.Public().(*PublicKey); !ok {
+			t.Error("Ожидался открытый ключ *PublicKey")
+		}
+	}
+
+	var info subjectPublicKeyInfo
+	asn1.Unmarshal(subject.RawSubjectPublicKeyInfo, &info)
+	want := sha1.Sum(info.PublicKey.RightAlign())
+
+	certificate, _ := NewCertificate(subject)
+	identifier, error := certificate.KeyIdentifier()
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if !bytes.Equal(identifier, want[:]) {
+		t.Errorf("Ожидался идентификатор ключа %x. Получен %x", want, identifier)
+	}
+
+	_, rsa := createTestRSACertificate(t, "RSA")
+	rsaCertificate, error := NewCertificate(rsa)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if rsaCertificate.GOSTPublicKey != nil || rsaCertificate.HashAlgorithm() != HashSha256 {
+		t.Error("Ожидался сертификат RSA без ключа ГОСТ с алгоритмом хэширования SHA-256")
+	}
+
+	// алгоритм ECDSA библиотеке неизвестен, сертификат разбирается средствами crypto/x509
+	ecdsaKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "ECDSA"}, NotBefore: time.Now(), NotAfter: time.Now().Add(time.Hour), BasicConstraintsValid: true, IsCA: true}
+	raw, _ := x509.CreateCertificate(rand.Reader, template, template, &ecdsaKey.PublicKey, ecdsaKey)
+
+	ecdsaCertificate, error := ParseCertificate(raw)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if ecdsaCertificate.IssuerSignatureAlgorithm != nil || ecdsaCertificate.HashAlgorithm() != nil || ecdsaCertificate.GOSTPublicKey != nil {
+		t.Error("Ожидался сертификат ECDSA без алгоритма подписи и ключа ГОСТ")
+	}
+
+	if _, ok := ecdsaCertificate.Public().(*ecdsa.PublicKey); !ok || ecdsaCertificate.CheckSignatureFrom(ecdsaCertificate) != nil {
+		t.Error("Ожидался ключ ECDSA и верная подпись сертификата")
+	}
+}
+
+func Test_CertificateCheckSignature_Success(t *testing.T) {
+	authority := []pkix.Extension{
+		newTestExtension(t, asn1.ObjectIdentifier{2, 5, 29, 19}, struct{ IsCA bool }{true}),
+		// keyCertSign
+		newTestExtension(t, asn1.ObjectIdentifier{2, 5, 29, 15}, asn1.BitString{Bytes: []byte{0x04}, BitLength: 6}),
+	}
+
+	issuerKey, issuerCertificate := createTestIssuedCertificate(t, CurveTC26_512A, "Издатель", nil, nil, authority)
+	_, subjectCertificate := createTestIssuedCertificate(t, CurveTC26_256A, "Владелец", issuerKey, issuerCertificate, nil)
+	_, otherCertificate := createTestIssuedCertificate(t, CurveTC26_512A, "Другой издатель", nil, nil, authority)
+	leafKey, leafCertificate := createTestIssuedCertificate(t, CurveTC26_256A, "Не УЦ", nil, nil, nil)
+	_, leafSubjectCertificate := createTestIssuedCertificate(t, CurveTC26_256A, "Владелец", leafKey, leafCertificate, nil)
+
+	issuer, _ := NewCertificate(issuerCertificate)
+	subject, _ := NewCertificate(subjectCertificate)
+	other, _ := NewCertificate(otherCertificate)
+	leaf, _ := NewCertificate(leafCertificate)
+	leafSubject, _ := NewCertificate(leafSubjectCertificate)
+
+	if error := subject.CheckSignatureFrom(issuer); error != nil {
+		t.Errorf("Ожидалась верная подпись издателя. Получена ошибка %v", error)
+	}
+
+	if error := issuer.CheckSignatureFrom(issuer); error != nil {
+		t.Errorf("Ожидалась верная подпись самоподписанного сертификата. Получена ошибка %v", error)
+	}
+
+	if error := subject.CheckSignatureFrom(other); error == nil {
+		t.Error("Ожидалась ошибка для подписи другого издателя")
+	}
+
+	// подпись верна, но издатель не является УЦ
+	if error := leafSubject.CheckSignatureFrom(leaf); error == nil {
+		t.Error("Ожидалась ошибка для издателя без признака УЦ")
+	}
+
+	// изменение подписанной части сертификата
+	raw := append([]byte{}, subjectCertificate.Raw...)
+	raw[bytes.Index(raw, []byte("Владелец"))] ^= 1
+
+	changed, error := ParseCertificate(raw)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if error := changed.CheckSignatureFrom(issuer); error == nil {
+		t.Error("Ожидалась ошибка для измененного сертификата")
+	}
+}
@@ -0,0 +1,377 @@
+package cryptography
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"time"
+)
+
+// идентификаторы CMS
+var (
+	OIDData                      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	OIDSignedData                = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	OIDAttributeContentType      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	OIDAttributeMessageDigest    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	OIDAttributeSigningTime      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+	OIDAttributeCounterSignature = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 6}
+)
+
+/*
+Идентификатор алгоритма
+*/
+type AlgorithmIdentifier struct {
+	Algorithm  asn1.ObjectIdentifier
+	Parameters asn1.RawValue `asn1:"optional"`
+}
+
+/*
+Атрибут CMS
+*/
+type Attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values []asn1.RawValue `asn1:"set"`
+}
+
+/*
+Издатель и серийный номер сертификата
+*/
+type IssuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+/*
+Контейнер CMS
+*/
+type ContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+/*
+Инкапсулированное содержимое
+*/
+type EncapsulatedContentInfo struct {
+	EContentType asn1.ObjectIdentifier
+	EContent     []byte `asn1:"explicit,optional,tag:0"`
+}
+
+/*
+Подписанные данные
+*/
+type SignedData struct {
+	Version          int
+	DigestAlgorithms []AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo EncapsulatedContentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      []SignerInfo  `asn1:"set"`
+}
+
+/*
+Информация о подписанте
+*/
+type SignerInfo struct {
+	Version            int
+	SID                asn1.RawValue
+	DigestAlgorithm    AlgorithmIdentifier
+	SignedAttributes   asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm AlgorithmIdentifier
+	Signature          []byte
+	UnsignedAttributes asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+// прочитать CMS сообщение в DER, BER, PEM или base64
+func readCMS(reader io.Reader) ([]byte, error) {
+	data, exception := io.ReadAll(reader)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, errors.New("Пустое CMS сообщение")
+	}
+
+	// пробелы обрезаются только у текстовых форматов, DER может заканчиваться байтом пробела
+	if data[0] != 0x30 {
+		data = bytes.TrimSpace(data)
+
+		if block, _ := pem.Decode(data); block != nil {
+			data = block.Bytes
+		} else {
+			clean := bytes.Map(func(symbol rune) rune {
+				if symbol == '\r' || symbol == '\n' || symbol == ' ' || symbol == '\t' {
+					return -1
+				}
+
+				return symbol
+			}, data)
+
+			decoded, exception := base64.StdEncoding.DecodeString(string(clean))
+
+			if exception != nil {
+				return nil, errors.New("Неизвестный формат CMS сообщения")
+			}
+
+			data = decoded
+		}
+	}
+
+	return berToDER(data)
+}
+
+// разобрать CMS SignedData
+func parseSignedData(data []byte) (*SignedData, error) {
+	var contentInfo ContentInfo
+
+	rest, exception := asn1.Unmarshal(data, &contentInfo)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	if len(rest) != 0 {
+		return nil, errors.New("Лишние данные после CMS сообщения")
+	}
+
+	if !contentInfo.ContentType.Equal(OIDSignedData) {
+		return nil, errors.New("CMS сообщение не является SignedData")
+	}
+
+	var signedData SignedData
+
+	if _, exception := asn1.Unmarshal(contentInfo.Content.Bytes, &signedData); exception != nil {
+		return nil, exception
+	}
+
+	return &signedData, nil
+}
+
+// найти атрибут по идентификатору
+func findAttribute(attributes []Attribute, oid asn1.ObjectIdentifier) (*Attribute, bool) {
+	for i := range attributes {
+		if attributes[i].Type.Equal(oid) {
+			return &attributes[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// разобрать набор атрибутов [0] IMPLICIT SET OF Attribute
+func parseAttributes(raw asn1.RawValue) ([]Attribute, error) {
+	var attributes []Attribute
+
+	if len(raw.FullBytes) == 0 {
+		return attributes, nil
+	}
+
+	// атрибуты подписываются в кодировке SET OF, а не [0] IMPLICIT
+	encoded := append([]byte{0x31}, raw.FullBytes[1:]...)
+
+	if _, exception := asn1.UnmarshalWithParams(encoded, &attributes, "set"); exception != nil {
+		return nil, exception
+	}
+
+	return attributes, nil
+}
+
+// разобрать время подписи из значения атрибута
+func parseSigningTime(value asn1.RawValue) (time.Time, error) {
+	var signingTime time.Time
+
+	switch value.Tag {
+	case asn1.TagUTCTime:
+		_, exception := asn1.UnmarshalWithParams(value.FullBytes, &signingTime, "utc")
+
+		return signingTime, exception
+	case asn1.TagGeneralizedTime:
+		_, exception := asn1.UnmarshalWithParams(value.FullBytes, &signingTime, "generalized")
+
+		return signingTime, exception
+	}
+
+	return signingTime, errors.New("Некорректный формат времени подписи")
+}
+
+// преобразовать BER кодировку в DER: неопределенные длины и составные строки
+func berToDER(data []byte) ([]byte, error) {
+	result, rest, exception := berElementToDER(data)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	if len(rest) != 0 && !isEndOfContents(rest) {
+		return nil, errors.New("Лишние данные после ASN.1 структуры")
+	}
+
+	return result, nil
+}
+
+func isEndOfContents(data []byte) bool {
+	for _, value := range data {
+		if value != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// разобрать один BER элемент, вернуть его DER представление и остаток
+func berElementToDER(data []byte) (result []byte, rest []byte, exception error) {
+	if len(data) < 2 {
+		return nil, nil, errors.New("Обрезанная ASN.1 структура")
+	}
+
+	headerSize := 1
+
+	if data[0]&0x1f == 0x1f {
+		for headerSize < len(data) && data[headerSize]&0x80 != 0 {
+			headerSize++
+		}
+
+		headerSize++
+	}
+
+	if headerSize >= len(data) {
+		return nil, nil, errors.New("Обрезанная ASN.1 структура")
+	}
+
+	identifier := data[:headerSize]
+	constructed := data[0]&0x20 != 0
+	lengthByte := data[headerSize]
+	offset := headerSize + 1
+
+	var content []byte
+
+	if lengthByte == 0x80 {
+		if !constructed {
+			return nil, nil, errors.New("Неопределенная длина у примитивного ASN.1 элемента")
+		}
+
+		// неопределенная длина: элементы до маркера конца содержимого
+		var children [][]byte
+		rest = data[offset:]
+
+		for {
+			if len(rest) < 2 {
+				return nil, nil, errors.New("Не найден конец ASN.1 элемента")
+			}
+
+			if rest[0] == 0 && rest[1] == 0 {
+				rest = rest[2:]
+				break
+			}
+
+			child, next, exception := berElementToDER(rest)
+
+			if exception != nil {
+				return nil, nil, exception
+			}
+
+			children = append(children, child)
+			rest = next
+		}
+
+		return buildDERElement(identifier, children), rest, nil
+	}
+
+	length := 0
+
+	if lengthByte&0x80 == 0 {
+		length = int(lengthByte)
+	} else {
+		count := int(lengthByte & 0x7f)
+
+		if count > 4 || offset+count > len(data) {
+			return nil, nil, errors.New("Некорректная длина ASN.1 элемента")
+		}
+
+		for _, value := range data[offset : offset+count] {
+			length = length<<8 | int(value)
+		}
+
+		offset += count
+	}
+
+	if length < 0 || offset+length > len(data) {
+		return nil, nil, errors.New("Обрезанная ASN.1 структура")
+	}
+
+	content = data[offset : offset+length]
+	rest = data[offset+length:]
+
+	if !constructed {
+		return encodeDERElement(identifier, content), rest, nil
+	}
+
+	var children [][]byte
+
+	for len(content) > 0 {
+		child, next, exception := berElementToDER(content)
+
+		if exception != nil {
+			return nil, nil, exception
+		}
+
+		children = append(children, child)
+		content = next
+	}
+
+	return buildDERElement(identifier, children), rest, nil
+}
+
+// собрать составной элемент, составные OCTET STRING склеиваются в примитивный
+func buildDERElement(identifier []byte, children [][]byte) []byte {
+	if len(identifier) == 1 && identifier[0]&0xdf == asn1.TagOctetString {
+		var content []byte
+
+		for _, child := range children {
+			content = append(content, derContent(child)...)
+		}
+
+		return encodeDERElement([]byte{identifier[0] &^ 0x20}, content)
+	}
+
+	return encodeDERElement(identifier, bytes.Join(children, nil))
+}
+
+// получить содержимое DER элемента без заголовка
+func derContent(element []byte) []byte {
+	offset := 2
+
+	if element[1]&0x80 != 0 {
+		offset += int(element[1] & 0x7f)
+	}
+
+	return element[offset:]
+}
+
+// закодировать элемент с длиной в DER
+func encodeDERElement(identifier []byte, content []byte) []byte {
+	result := append([]byte{}, identifier...)
+	length := len(content)
+
+	if length < 0x80 {
+		result = append(result, byte(length))
+	} else {
+		var lengthBytes []byte
+
+		for value := length; value > 0; value >>= 8 {
+			lengthBytes = append([]byte{byte(value)}, lengthBytes...)
+		}
+
+		result = append(result, 0x80|byte(len(lengthBytes)))
+		result = append(result, lengthBytes...)
+	}
+
+	return append(result, content...)
+}
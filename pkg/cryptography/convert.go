@@ -0,0 +1,448 @@
+package cryptography
+
+import (
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+/*
+Представление подписи, хэша или открытого ключа ГОСТ
+*/
+type ValueFormat int
+
+const (
+	// определить представление автоматически, при форматировании - представление, принятое в библиотеке
+	FormatAuto ValueFormat = iota
+	// порядок байт КриптоПро: перевернутая подпись s||r, хэш в порядке CryptoGetHashParam, ключ X||Y little-endian
+	FormatCryptoPro
+	// big-endian: подпись s||r по RFC 4491 и XMLDSig, хэш как число, ключ X||Y
+	FormatRFC
+	// ASN.1 DER: подпись SEQUENCE { r, s }, хэш OCTET STRING, ключ SubjectPublicKeyInfo
+	FormatASN1
+)
+
+func (format ValueFormat) String() string {
+	switch format {
+	case FormatCryptoPro:
+		return "CryptoPro"
+	case FormatRFC:
+		return "RFC"
+	case FormatASN1:
+		return "ASN.1"
+	}
+
+	return "Auto"
+}
+
+/*
+Текстовое кодирование значения
+*/
+type ValueEncoding int
+
+const (
+	// определить кодирование автоматически
+	EncodingAuto ValueEncoding = iota
+	EncodingBinary
+	EncodingHex
+	EncodingBase64
+)
+
+func (encoding ValueEncoding) String() string {
+	switch encoding {
+	case EncodingBinary:
+		return "binary"
+	case EncodingHex:
+		return "hex"
+	case EncodingBase64:
+		return "base64"
+	}
+
+	return "auto"
+}
+
+/*
+Представление и кодирование значения
+*/
+type ValueInfo struct {
+	Format   ValueFormat
+	Encoding ValueEncoding
+}
+
+/*
+Подпись ГОСТ Р 34.10 в ASN.1
+*/
+type gostSignatureValue struct {
+	R *big.Int
+	S *big.Int
+}
+
+// декодировать значение из hex, base64 или двоичного вида
+// при EncodingAuto выбирается первое кодирование, результат которого проходит проверку valid
+func decodeValue(data []byte, encoding ValueEncoding, valid func([]byte) bool) ([]byte, ValueEncoding, error) {
+	decoders := []struct {
+		encoding ValueEncoding
+		decode   func([]byte) ([]byte, error)
+	}{
+		{EncodingHex, decodeHexValue},
+		{EncodingBase64, decodeBase64Value},
+		{EncodingBinary, func(data []byte) ([]byte, error) { return data, nil }},
+	}
+
+	for _, decoder := range decoders {
+		if encoding != EncodingAuto && encoding != decoder.encoding {
+			continue
+		}
+
+		value, exception := decoder.decode(data)
+
+		if exception == nil && valid(value) {
+			return value, decoder.encoding, nil
+		}
+	}
+
+	return nil, EncodingAuto, errors.New("Не удалось определить кодирование значения")
+}
+
+// декодировать hex с пробелами, двоеточиями и префиксом 0x
+func decodeHexValue(data []byte) ([]byte, error) {
+	text := strings.Join(strings.Fields(string(data)), "")
+	text = strings.ReplaceAll(text, ":", "")
+	text = strings.TrimPrefix(strings.TrimPrefix(text, "0x"), "0X")
+
+	return hex.DecodeString(text)
+}
+
+// декодировать base64 или base64url с переносами строк и без дополнения
+func decodeBase64Value(data []byte) ([]byte, error) {
+	text := strings.Join(strings.Fields(string(data)), "")
+
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if value, exception := encoding.DecodeString(text); exception == nil {
+			return value, nil
+		}
+	}
+
+	return nil, errors.New("Некорректное значение base64")
+}
+
+// закодировать значение: hex в нижнем регистре, base64 с дополнением
+func EncodeValue(value []byte, encoding ValueEncoding) []byte {
+	switch encoding {
+	case EncodingHex:
+		return []byte(hex.EncodeToString(value))
+	case EncodingBase64:
+		return []byte(base64.StdEncoding.EncodeToString(value))
+	}
+
+	return value
+}
+
+// значение является полным элементом DER с заданным тегом
+func isDERElement(data []byte, tag int) bool {
+	var raw asn1.RawValue
+	rest, exception := asn1.Unmarshal(data, &raw)
+
+	return exception == nil && len(rest) == 0 && raw.Class == asn1.ClassUniversal && raw.Tag == tag
+}
+
+// подпись s||r в допустимом диапазоне кривой, при curve = nil проверяется только размер
+func isSignatureInRange(signature []byte, curve *Curve) bool {
+	if curve == nil {
+		return len(signature) == 64 || len(signature) == 128
+	}
+
+	if len(signature) != 2*curve.PointSize {
+		return false
+	}
+
+	for _, half := range [][]byte{signature[:curve.PointSize], signature[curve.PointSize:]} {
+		value := new(big.Int).SetBytes(half)
+
+		if value.Sign() <= 0 || value.Cmp(curve.Q) >= 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// разобрать подпись ГОСТ Р 34.10 в любом представлении и кодировании, возвращается подпись s||r
+// если переданы открытый ключ и хэш в порядке КриптоПро, порядок байт определяется проверкой подписи,
+// иначе по диапазону r и s для кривой curve, если в диапазон попадает только один порядок, иначе возвращается ошибка
+func ParseSignatureValue(data []byte, curve *Curve, publicKey *PublicKey, digest []byte) ([]byte, ValueInfo, error) {
+	if curve == nil && publicKey != nil {
+		curve = publicKey.Curve
+	}
+
+	size := 0
+
+	if curve != nil {
+		size = curve.PointSize
+	}
+
+	value, encoding, exception := decodeValue(data, EncodingAuto, func(value []byte) bool {
+		if len(value) > 0 && value[0] == 0x30 && isDERElement(value, asn1.TagSequence) {
+			return true
+		}
+
+		return size == 0 && (len(value) == 64 || len(value) == 128) || size != 0 && len(value) == 2*size
+	})
+
+	if exception != nil {
+		return nil, ValueInfo{}, exception
+	}
+
+	info := ValueInfo{Encoding: encoding}
+
+	if isDERElement(value, asn1.TagSequence) && len(value) != 2*size {
+		var parsed gostSignatureValue
+
+		if _, exception := asn1.Unmarshal(value, &parsed); exception != nil {
+			return nil, ValueInfo{}, exception
+		}
+
+		if size == 0 {
+			size = 32
+
+			if parsed.R.BitLen() > 256 || parsed.S.BitLen() > 256 {
+				size = 64
+			}
+		}
+
+		if parsed.R.Sign() <= 0 || parsed.S.Sign() <= 0 || parsed.R.BitLen() > 8*size || parsed.S.BitLen() > 8*size {
+			return nil, ValueInfo{}, errors.New("Некорректная подпись ASN.1")
+		}
+
+		info.Format = FormatASN1
+
+		return append(parsed.S.FillBytes(make([]byte, size)), parsed.R.FillBytes(make([]byte, size))...), info, nil
+	}
+
+	candidates := []struct {
+		format    ValueFormat
+		signature []byte
+	}{
+		{FormatRFC, value},
+		{FormatCryptoPro, reverseBytes(value)},
+	}
+
+	if publicKey != nil && digest != nil {
+		for _, candidate := range candidates {
+			if publicKey.VerifyDigest(digest, candidate.signature) {
+				info.Format = candidate.format
+
+				return candidate.signature, info, nil
+			}
+		}
+
+		return nil, ValueInfo{}, errors.New("Не удалось определить порядок байт подписи")
+	}
+
+	var found []byte
+
+	for _, candidate := range candidates {
+		if !isSignatureInRange(candidate.signature, curve) {
+			continue
+		}
+
+		// оба порядка байт допустимы для кривой, выбор без проверки подписи был бы угадыванием
+		if found != nil {
+			return nil, ValueInfo{}, errors.New("Порядок байт подписи неоднозначен, для определения нужны открытый ключ и хэш")
+		}
+
+		found = candidate.signature
+		info.Format = candidate.format
+	}
+
+	if found == nil {
+		return nil, ValueInfo{}, errors.New("Не удалось определить порядок байт подписи")
+	}
+
+	return found, info, nil
+}
+
+// представить подпись s||r в заданном формате и кодировании
+func FormatSignatureValue(signature []byte, info ValueInfo) ([]byte, error) {
+	if len(signature) != 64 && len(signature) != 128 {
+		return nil, errors.New("Некорректный размер подписи ГОСТ Р 34.10")
+	}
+
+	var value []byte
+
+	switch info.Format {
+	case FormatCryptoPro:
+		value = reverseBytes(signature)
+	case FormatRFC, FormatAuto:
+		value = signature
+	case FormatASN1:
+		half := len(signature) / 2
+		encoded, exception := asn1.Marshal(gostSignatureValue{
+			R: new(big.Int).SetBytes(signature[half:]),
+			S: new(big.Int).SetBytes(signature[:half]),
+		})
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		value = encoded
+	}
+
+	return EncodeValue(value, info.Encoding), nil
+}
+
+// разобрать значение хэша в любом кодировании, возвращается хэш в порядке байт КриптоПро
+// порядок байт не определяется по значению: при FormatAuto распознается только ASN.1, иначе принимается порядок КриптоПро
+// для алгоритмов, не входящих в ГОСТ, порядок байт не меняется
+func ParseDigestValue(data []byte, algorithm *HashAlgorithm, format ValueFormat) ([]byte, ValueInfo, error) {
+	value, encoding, exception := decodeValue(data, EncodingAuto, func(value []byte) bool {
+		return len(value) == algorithm.Size || isDERElement(value, asn1.TagOctetString)
+	})
+
+	if exception != nil {
+		return nil, ValueInfo{}, exception
+	}
+
+	info := ValueInfo{Format: format, Encoding: encoding}
+
+	if format == FormatASN1 || format == FormatAuto && len(value) != algorithm.Size {
+		if _, exception := asn1.Unmarshal(value, &value); exception != nil {
+			return nil, ValueInfo{}, exception
+		}
+
+		info.Format = FormatASN1
+	} else if format == FormatAuto {
+		info.Format = FormatCryptoPro
+	}
+
+	if len(value) != algorithm.Size {
+		return nil, ValueInfo{}, errors.New("Некорректный размер хэша " + algorithm.Name)
+	}
+
+	if info.Format == FormatRFC && algorithm.HashType != 0 {
+		value = reverseBytes(value)
+	}
+
+	return value, info, nil
+}
+
+// представить хэш в порядке байт КриптоПро в заданном формате и кодировании
+func FormatDigestValue(digest []byte, algorithm *HashAlgorithm, info ValueInfo) ([]byte, error) {
+	if len(digest) != algorithm.Size {
+		return nil, errors.New("Некорректный размер хэша " + algorithm.Name)
+	}
+
+	value := digest
+
+	switch info.Format {
+	case FormatRFC:
+		if algorithm.HashType != 0 {
+			value = reverseBytes(digest)
+		}
+	case FormatASN1:
+		encoded, exception := asn1.Marshal(digest)
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		value = encoded
+	}
+
+	return EncodeValue(value, info.Encoding), nil
+}
+
+// разобрать открытый ключ ГОСТ Р 34.10 в любом представлении и кодировании
+// при curve = nil кривая определяется по SubjectPublicKeyInfo или подбором кривой, которой принадлежит точка
+func ParsePublicKeyValue(data []byte, curve *Curve) (*PublicKey, ValueInfo, error) {
+	candidates := curves
+
+	if curve != nil {
+		candidates = []*Curve{curve}
+	}
+
+	// точка в порядке КриптоПро или big-endian, в том числе несжатая точка 04||X||Y
+	parseRaw := func(value []byte) (*PublicKey, ValueFormat) {
+		if len(value)%2 == 1 && value[0] == 0x04 {
+			value = value[1:]
+		}
+
+		for _, candidate := range candidates {
+			if len(value) != 2*candidate.PointSize {
+				continue
+			}
+
+			if key, exception := UnmarshalPublicKey(candidate, value); exception == nil {
+				return key, FormatCryptoPro
+			}
+
+			x := new(big.Int).SetBytes(value[:candidate.PointSize])
+			y := new(big.Int).SetBytes(value[candidate.PointSize:])
+
+			if candidate.IsOnCurve(x, y) {
+				return &PublicKey{Curve: candidate, X: x, Y: y}, FormatRFC
+			}
+		}
+
+		return nil, FormatAuto
+	}
+
+	parse := func(value []byte) (*PublicKey, ValueFormat) {
+		if isDERElement(value, asn1.TagSequence) {
+			if key, exception := ParsePublicKey(value); exception == nil && (curve == nil || key.Curve == curve) {
+				return key, FormatASN1
+			}
+		}
+
+		if isDERElement(value, asn1.TagOctetString) {
+			var raw []byte
+
+			if _, exception := asn1.Unmarshal(value, &raw); exception == nil {
+				if key, format := parseRaw(raw); format == FormatCryptoPro {
+					return key, FormatASN1
+				}
+			}
+		}
+
+		return parseRaw(value)
+	}
+
+	var key *PublicKey
+	var format ValueFormat
+
+	_, encoding, exception := decodeValue(data, EncodingAuto, func(value []byte) bool {
+		key, format = parse(value)
+
+		return key != nil
+	})
+
+	if exception != nil {
+		return nil, ValueInfo{}, errors.New("Не удалось разобрать открытый ключ ГОСТ Р 34.10")
+	}
+
+	return key, ValueInfo{Format: format, Encoding: encoding}, nil
+}
+
+// представить открытый ключ в заданном формате и кодировании
+func FormatPublicKeyValue(key *PublicKey, info ValueInfo) ([]byte, error) {
+	value := key.Raw()
+
+	switch info.Format {
+	case FormatRFC:
+		value = append(key.X.FillBytes(make([]byte, key.Curve.PointSize)), key.Y.FillBytes(make([]byte, key.Curve.PointSize))...)
+	case FormatASN1:
+		encoded, exception := MarshalPublicKey(key)
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		value = encoded
+	}
+
+	return EncodeValue(value, info.Encoding), nil
+}
@@ -0,0 +1,226 @@
+package cryptography
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+var testValueFormats = []ValueFormat{FormatCryptoPro, FormatRFC, FormatASN1}
+
+var testValueEncodings = []ValueEncoding{EncodingBinary, EncodingHex, EncodingBase64}
+
+func Test_SignatureValue_Success(t *testing.T) {
+	for _, curve := range []*Curve{CurveCryptoProA, CurveTC26_512A} {
+		privateKey, error := GeneratePrivateKey(curve, nil)
+
+		if error != nil {
+			t.Fatal(error)
+		}
+
+		digest := bytes.Repeat([]byte{0x5a}, curve.PointSize)
+		signature, error := privateKey.SignDigest(digest, nil)
+
+		if error != nil {
+			t.Fatal(error)
+		}
+
+		for _, format := range testValueFormats {
+			for _, encoding := range testValueEncodings {
+				info := ValueInfo{Format: format, Encoding: encoding}
+				formatted, error := FormatSignatureValue(signature, info)
+
+				if error != nil {
+					t.Fatal(error)
+				}
+
+				parsed, detected, error := ParseSignatureValue(formatted, nil, &privateKey.PublicKey, digest)
+
+				if error != nil {
+					t.Fatalf("%s %s: %v", format, encoding, error)
+				}
+
+				if !bytes.Equal(parsed, signature) || detected != info {
+					t.Errorf("Ожидалась подпись в представлении %s %s. Определено %s %s", format, encoding, detected.Format, detected.Encoding)
+				}
+			}
+		}
+	}
+}
+
+func Test_SignatureValueWithoutKey_Success(t *testing.T) {
+	privateKey, error := GeneratePrivateKey(CurveTC26_256A, nil)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	// подпись, у которой в обратном порядке байт r или s выходит за порядок подгруппы q
+	var signature []byte
+
+	for signature == nil || isSignatureInRange(reverseBytes(signature), CurveTC26_256A) {
+		if signature, error = privateKey.SignDigest(bytes.Repeat([]byte{0x01}, 32), nil); error != nil {
+			t.Fatal(error)
+		}
+	}
+
+	parsed, info, error := ParseSignatureValue([]byte(strings.ToUpper(hex.EncodeToString(signature))), CurveTC26_256A, nil, nil)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if !bytes.Equal(parsed, signature) || info.Format != FormatRFC || info.Encoding != EncodingHex {
+		t.Errorf("Ожидалась подпись RFC в hex. Определено %s %s", info.Format, info.Encoding)
+	}
+
+	if parsed, info, error := ParseSignatureValue(reverseBytes(signature), CurveTC26_256A, nil, nil); error != nil || !bytes.Equal(parsed, signature) || info.Format != FormatCryptoPro {
+		t.Errorf("Ожидалась подпись в формате КриптоПро. Получена ошибка %v", error)
+	}
+
+	// оба порядка байт в диапазоне кривой
+	ambiguous := bytes.Repeat([]byte{0x01}, 64)
+
+	if _, _, error := ParseSignatureValue(ambiguous, CurveTC26_256A, nil, nil); error == nil {
+		t.Error("Ожидалась ошибка неоднозначного порядка байт")
+	}
+
+	if _, _, error := ParseSignatureValue(signature, nil, nil, nil); error == nil {
+		t.Error("Ожидалась ошибка неоднозначного порядка байт без кривой")
+	}
+}
+
+func Test_DigestValue_Success(t *testing.T) {
+	digest := make([]byte, 32)
+
+	for i := range digest {
+		digest[i] = byte(i)
+	}
+
+	rfc := reverseBytes(digest)
+
+	parsed, info, error := ParseDigestValue([]byte(hex.EncodeToString(rfc)), HashGOST3411_2012_256, FormatRFC)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if !bytes.Equal(parsed, digest) || info.Encoding != EncodingHex {
+		t.Errorf("Ожидался хэш в порядке КриптоПро %x. Получен %x", digest, parsed)
+	}
+
+	for _, format := range testValueFormats {
+		formatted, error := FormatDigestValue(digest, HashGOST3411_2012_256, ValueInfo{Format: format, Encoding: EncodingBase64})
+
+		if error != nil {
+			t.Fatal(error)
+		}
+
+		parsed, _, error := ParseDigestValue(formatted, HashGOST3411_2012_256, format)
+
+		if error != nil {
+			t.Fatal(error)
+		}
+
+		if !bytes.Equal(parsed, digest) {
+			t.Errorf("%s: ожидался хэш %x. Получен %x", format, digest, parsed)
+		}
+	}
+
+	// ASN.1 определяется автоматически, порядок байт SHA не меняется
+	encoded, _ := FormatDigestValue(digest, HashSha256, ValueInfo{Format: FormatASN1})
+	parsed, info, error = ParseDigestValue(encoded, HashSha256, FormatAuto)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if !bytes.Equal(parsed, digest) || info.Format != FormatASN1 || info.Encoding != EncodingBinary {
+		t.Errorf("Ожидался хэш ASN.1. Определено %s %s", info.Format, info.Encoding)
+	}
+
+	if formatted, _ := FormatDigestValue(digest, HashSha256, ValueInfo{Format: FormatRFC}); !bytes.Equal(formatted, digest) {
+		t.Error("Порядок байт хэша SHA-256 не должен меняться")
+	}
+}
+
+func Test_PublicKeyValue_Success(t *testing.T) {
+	for _, curve := range []*Curve{CurveCryptoProA, CurveTC26_256A, CurveTC26_512B} {
+		privateKey, error := GeneratePrivateKey(curve, nil)
+
+		if error != nil {
+			t.Fatal(error)
+		}
+
+		for _, format := range testValueFormats {
+			for _, encoding := range testValueEncodings {
+				info := ValueInfo{Format: format, Encoding: encoding}
+				formatted, error := FormatPublicKeyValue(&privateKey.PublicKey, info)
+
+				if error != nil {
+					t.Fatal(error)
+				}
+
+				key, detected, error := ParsePublicKeyValue(formatted, nil)
+
+				if error != nil {
+					t.Fatalf("%s %s %s: %v", curve.Name, format, encoding, error)
+				}
+
+				if key.X.Cmp(privateKey.X) != 0 || key.Y.Cmp(privateKey.Y) != 0 || detected != info {
+					t.Errorf("%s: ожидался ключ в представлении %s %s. Определено %s %s", curve.Name, format, encoding, detected.Format, detected.Encoding)
+				}
+			}
+		}
+	}
+}
+
+func Test_PublicKeyValue_Encodings(t *testing.T) {
+	privateKey, error := GeneratePrivateKey(CurveCryptoProA, nil)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	raw := privateKey.PublicKey.Raw()
+	point := append([]byte{0x04}, reverseBytes(raw[:32])...)
+	point = append(point, reverseBytes(raw[32:])...)
+
+	inputs := map[string][]byte{
+		// base64 с переносами строк
+		"base64": []byte(base64.StdEncoding.EncodeToString(raw)[:40] + "\n" + base64.StdEncoding.EncodeToString(raw)[40:]),
+		// hex с двоеточиями в верхнем регистре
+		"hex": []byte(strings.ToUpper(strings.Join(splitHex(hex.EncodeToString(raw)), ":"))),
+		// несжатая точка big-endian
+		"point": point,
+	}
+
+	for name, input := range inputs {
+		key, _, error := ParsePublicKeyValue(input, CurveCryptoProA)
+
+		if error != nil {
+			t.Fatalf("%s: %v", name, error)
+		}
+
+		if key.X.Cmp(privateKey.X) != 0 {
+			t.Errorf("%s: ожидался исходный ключ", name)
+		}
+	}
+
+	if _, _, error := ParsePublicKeyValue([]byte("not a key"), nil); error == nil {
+		t.Error("Ожидалась ошибка для некорректного ключа")
+	}
+}
+
+// разбить hex на байты
+func splitHex(value string) []string {
+	var result []string
+
+	for i := 0; i < len(value); i += 2 {
+		result = append(result, value[i:i+2])
+	}
+
+	return result
+}
@@ -0,0 +1,211 @@
+package cryptography
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"io"
+)
+
+// получить метод добавления параллельной подписи в существующую CMS подпись
+// новый SignerInfo с атрибутом signing-certificate-v2 подписывает то же содержимое, существующие подписи не изменяются
+// для открепленной подписи content - подписанные данные, для присоединенной - nil
+func CreateCMSCosignMethod(signer crypto.Signer, certificate *x509.Certificate, options SignOptions) (release func(), cosign func(signature io.Reader, content io.Reader) (io.Reader, error), exception error) {
+	algorithm, exception := findSignOptionsAlgorithm(certificate, options)
+
+	if exception != nil {
+		return nil, nil, exception
+	}
+
+	parameters := &signParameters{
+		signer:      signer,
+		certificate: certificate,
+		algorithm:   algorithm,
+		attributes:  signingCertificateAttributes,
+	}
+
+	return func() {},
+		func(signature io.Reader, content io.Reader) (io.Reader, error) {
+			signedData, exception := readSignedData(signature)
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			if signedData.EncapContentInfo.EContent != nil {
+				content = bytes.NewReader(signedData.EncapContentInfo.EContent)
+			} else if content == nil {
+				return nil, errors.New("Для открепленной подписи не переданы подписанные данные")
+			}
+
+			digest, exception := calculateDigest(algorithm.Hash, content)
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			if exception := checkSignedDataDigest(signedData, algorithm.Hash, digest); exception != nil {
+				return nil, exception
+			}
+
+			signerInfo, exception := createSignerInfo(digest, signedData.EncapContentInfo.EContentType, parameters)
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			signedData.SignerInfos = append(signedData.SignerInfos, *signerInfo)
+
+			addDigestAlgorithm(signedData, algorithm.Hash)
+
+			if exception := addSignedDataCertificate(signedData, certificate); exception != nil {
+				return nil, exception
+			}
+
+			return marshalSignedData(signedData)
+		}, nil
+}
+
+// получить метод добавления заверяющей подписи countersignature
+// подписывается значение подписи SignerInfo, сертификат которого равен target, при target = nil заверяются все подписанты
+// заверяющая подпись помещается в неподписанные атрибуты, поэтому значения существующих подписей не изменяются
+func CreateCMSCountersignMethod(signer crypto.Signer, certificate *x509.Certificate, options SignOptions) (release func(), countersign func(signature io.Reader, target *x509.Certificate) (io.Reader, error), exception error) {
+	algorithm, exception := findSignOptionsAlgorithm(certificate, options)
+
+	if exception != nil {
+		return nil, nil, exception
+	}
+
+	parameters := &signParameters{
+		signer:             signer,
+		certificate:        certificate,
+		algorithm:          algorithm,
+		attributes:         signingCertificateAttributes,
+		withoutContentType: true,
+	}
+
+	return func() {},
+		func(signature io.Reader, target *x509.Certificate) (io.Reader, error) {
+			signedData, exception := readSignedData(signature)
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			found := false
+
+			for i := range signedData.SignerInfos {
+				signerInfo := &signedData.SignerInfos[i]
+
+				if target != nil && findSignerCertificate(signerInfo.SID, []*x509.Certificate{target}) == nil {
+					continue
+				}
+
+				if exception := addCountersignature(signerInfo, parameters); exception != nil {
+					return nil, exception
+				}
+
+				found = true
+			}
+
+			if !found {
+				return nil, errors.New("В подписи не найден подписант с указанным сертификатом")
+			}
+
+			if exception := addSignedDataCertificate(signedData, certificate); exception != nil {
+				return nil, exception
+			}
+
+			return marshalSignedData(signedData)
+		}, nil
+}
+
+// подписать значение подписи SignerInfo и добавить атрибут countersignature
+func addCountersignature(signerInfo *SignerInfo, parameters *signParameters) error {
+	digest, exception := calculateBytesDigest(parameters.algorithm.Hash, signerInfo.Signature)
+
+	if exception != nil {
+		return exception
+	}
+
+	countersignature, exception := createSignerInfo(digest, nil, parameters)
+
+	if exception != nil {
+		return exception
+	}
+
+	attribute, exception := newAttribute(OIDAttributeCounterSignature, *countersignature)
+
+	if exception != nil {
+		return exception
+	}
+
+	return addUnsignedAttribute(signerInfo, attribute)
+}
+
+// проверить, что хэш содержимого совпадает с messageDigest подписантов с тем же алгоритмом хэширования
+func checkSignedDataDigest(signedData *SignedData, algorithm *HashAlgorithm, digest []byte) error {
+	for _, signerInfo := range signedData.SignerInfos {
+		if !signerInfo.DigestAlgorithm.Algorithm.Equal(algorithm.OID) {
+			continue
+		}
+
+		attributes, exception := parseAttributes(signerInfo.SignedAttributes)
+
+		if exception != nil {
+			return exception
+		}
+
+		attribute, ok := findAttribute(attributes, OIDAttributeMessageDigest)
+
+		if !ok || len(attribute.Values) != 1 {
+			continue
+		}
+
+		var messageDigest []byte
+
+		if _, exception := asn1.Unmarshal(attribute.Values[0].FullBytes, &messageDigest); exception != nil {
+			return exception
+		}
+
+		if !bytes.Equal(messageDigest, digest) {
+			return errors.New("Данные не совпадают с подписанными существующими подписантами")
+		}
+	}
+
+	return nil
+}
+
+// добавить алгоритм хэширования в digestAlgorithms, если его там нет
+func addDigestAlgorithm(signedData *SignedData, algorithm *HashAlgorithm) {
+	for _, identifier := range signedData.DigestAlgorithms {
+		if identifier.Algorithm.Equal(algorithm.OID) {
+			return
+		}
+	}
+
+	signedData.DigestAlgorithms = append(signedData.DigestAlgorithms, digestAlgorithmIdentifier(algorithm))
+}
+
+// добавить сертификат в набор сертификатов SignedData, если его там нет
+func addSignedDataCertificate(signedData *SignedData, certificate *x509.Certificate) error {
+	certificates, exception := parseCertificates(signedData.Certificates)
+
+	if exception != nil {
+		return exception
+	}
+
+	for _, value := range certificates {
+		if bytes.Equal(value.Raw, certificate.Raw) {
+			return nil
+		}
+	}
+
+	// остальные элементы набора, включая сертификаты других форматов, сохраняются
+	content := append(append([]byte{}, signedData.Certificates.Bytes...), certificate.Raw...)
+	signedData.Certificates = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: content}
+
+	return nil
+}
@@ -0,0 +1,266 @@
+package cryptography
+
+import (
+	"bytes"
+	"crypto/x509"
+	"io"
+	"strings"
+	"testing"
+)
+
+// сформировать тестовую CMS подпись
+func signTestCMS(t *testing.T, privateKey *PrivateKey, certificate *x509.Certificate, detached bool) []byte {
+	release, sign, error := CreateCAdESBESSignMethod(privateKey, certificate, detached, SignOptions{})
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer release()
+
+	signature, error := sign(strings.NewReader("Hello world"))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	data, error := io.ReadAll(signature)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	return data
+}
+
+// проверить тестовую CMS подпись
+func verifyTestCMS(t *testing.T, data []byte, content io.Reader) *VerifyReport {
+	release, verify, error := CreateCMSVerifyMethod()
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer release()
+
+	report, error := verify(bytes.NewReader(data), content)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	return report
+}
+
+// найти результат проверки подписанта по сертификату
+func findTestSigner(reports []SignerReport, certificate *x509.Certificate) *SignerReport {
+	for i := range reports {
+		if reports[i].Certificate != nil && reports[i].Certificate.Equal(certificate) {
+			return &reports[i]
+		}
+	}
+
+	return nil
+}
+
+func Test_CMSCosign_Success(t *testing.T) {
+	firstKey, firstCertificate := createTestGOSTCertificate(t, CurveCryptoProA, "First")
+	secondKey, secondCertificate := createTestGOSTCertificate(t, CurveTC26_512A, "Second")
+
+	for _, detached := range []bool{true, false} {
+		data := signTestCMS(t, firstKey, firstCertificate, detached)
+
+		release, cosign, error := CreateCMSCosignMethod(secondKey, secondCertificate, SignOptions{})
+
+		if error != nil {
+			t.Fatal(error)
+		}
+
+		defer release()
+
+		var content io.Reader
+
+		if detached {
+			content = strings.NewReader("Hello world")
+		}
+
+		signature, error := cosign(bytes.NewReader(data), content)
+
+		if error != nil {
+			t.Fatal(error)
+		}
+
+		cosigned, error := io.ReadAll(signature)
+
+		if error != nil {
+			t.Fatal(error)
+		}
+
+		if detached {
+			content = strings.NewReader("Hello world")
+		}
+
+		report := verifyTestCMS(t, cosigned, content)
+
+		if len(report.Signers) != 2 || !report.Valid() {
+			t.Fatalf("Ожидались две верные подписи. Получено %d", len(report.Signers))
+		}
+
+		if findTestSigner(report.Signers, firstCertificate) == nil || findTestSigner(report.Signers, secondCertificate) == nil {
+			t.Error("Ожидались подписанты First и Second")
+		}
+
+		signedData, error := parseSignedData(cosigned)
+
+		if error != nil {
+			t.Fatal(error)
+		}
+
+		if len(signedData.DigestAlgorithms) != 2 {
+			t.Errorf("Ожидалось два алгоритма хэширования. Получено %d", len(signedData.DigestAlgorithms))
+		}
+	}
+}
+
+func Test_CMSCosign_ContentMismatch(t *testing.T) {
+	firstKey, firstCertificate := createTestGOSTCertificate(t, CurveCryptoProA, "First")
+	secondKey, secondCertificate := createTestGOSTCertificate(t, CurveCryptoProA, "Second")
+
+	data := signTestCMS(t, firstKey, firstCertificate, true)
+
+	_, cosign, error := CreateCMSCosignMethod(secondKey, secondCertificate, SignOptions{})
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if _, error := cosign(bytes.NewReader(data), strings.NewReader("Hello WORLD")); error == nil {
+		t.Error("Ожидалась ошибка для данных, отличных от подписанных")
+	}
+
+	if _, error := cosign(bytes.NewReader(data), nil); error == nil {
+		t.Error("Ожидалась ошибка для открепленной подписи без данных")
+	}
+}
+
+func Test_CMSCountersign_Success(t *testing.T) {
+	firstKey, firstCertificate := createTestGOSTCertificate(t, CurveCryptoProA, "First")
+	secondKey, secondCertificate := createTestGOSTCertificate(t, CurveTC26_512A, "Second")
+	thirdKey, thirdCertificate := createTestRSACertificate(t, "Third")
+
+	data := signTestCMS(t, firstKey, firstCertificate, true)
+
+	_, countersign, error := CreateCMSCountersignMethod(secondKey, secondCertificate, SignOptions{})
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	signature, error := countersign(bytes.NewReader(data), firstCertificate)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	countersigned, error := io.ReadAll(signature)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	original, _ := parseSignedData(data)
+	updated, _ := parseSignedData(countersigned)
+
+	if !bytes.Equal(original.SignerInfos[0].Signature, updated.SignerInfos[0].Signature) {
+		t.Error("Значение заверяемой подписи не должно изменяться")
+	}
+
+	// заверяющая подпись RSA над подписью ГОСТ
+	_, countersignRSA, error := CreateCMSCountersignMethod(thirdKey, thirdCertificate, SignOptions{})
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	signature, error = countersignRSA(bytes.NewReader(countersigned), nil)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	countersigned, error = io.ReadAll(signature)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	report := verifyTestCMS(t, countersigned, strings.NewReader("Hello world"))
+
+	if !report.Valid() {
+		t.Fatalf("Ожидалась верная подпись. Получен статус %s: %v", report.Signers[0].Status, report.Signers[0].Exception)
+	}
+
+	countersignatures := report.Signers[0].Countersignatures
+
+	if len(countersignatures) != 2 {
+		t.Fatalf("Ожидалось две заверяющие подписи. Получено %d", len(countersignatures))
+	}
+
+	for _, certificate := range []*x509.Certificate{secondCertificate, thirdCertificate} {
+		countersignature := findTestSigner(countersignatures, certificate)
+
+		if countersignature == nil || countersignature.Status != SignerValid || countersignature.SigningTime.IsZero() {
+			t.Errorf("Ожидалась верная заверяющая подпись %s", certificate.Subject.CommonName)
+		}
+	}
+
+	if _, error := countersign(bytes.NewReader(data), thirdCertificate); error == nil {
+		t.Error("Ожидалась ошибка для сертификата, которого нет среди подписантов")
+	}
+}
+
+func Test_CMSCountersign_Modified(t *testing.T) {
+	firstKey, firstCertificate := createTestGOSTCertificate(t, CurveCryptoProA, "First")
+	secondKey, secondCertificate := createTestGOSTCertificate(t, CurveCryptoProA, "Second")
+
+	data := signTestCMS(t, firstKey, firstCertificate, true)
+
+	_, countersign, error := CreateCMSCountersignMethod(secondKey, secondCertificate, SignOptions{})
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	signature, error := countersign(bytes.NewReader(data), nil)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	countersigned, error := io.ReadAll(signature)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	// подмена значения заверяемой подписи делает неверными обе подписи
+	signedData, _ := parseSignedData(countersigned)
+	signedData.SignerInfos[0].Signature[0] ^= 0xff
+
+	modified, error := marshalSignedData(signedData)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	encoded, _ := io.ReadAll(modified)
+	report := verifyTestCMS(t, encoded, strings.NewReader("Hello world"))
+
+	if report.Valid() {
+		t.Fatal("Ожидалась неверная подпись")
+	}
+
+	if countersignatures := report.Signers[0].Countersignatures; len(countersignatures) != 1 || countersignatures[0].Status != SignerDigestMismatch {
+		t.Error("Ожидалось несовпадение хэша заверяющей подписи")
+	}
+}
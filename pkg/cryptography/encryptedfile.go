@@ -0,0 +1,558 @@
+package cryptography
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// формат потокового аутентифицированного шифрования файлов
+//
+// заголовок: сигнатура GOSTFILE | версия | шифр | способ выработки ключа | размер фрагмента | соль 32 байта |
+// для пароля число итераций PBKDF2, для открытого ключа набор параметров кривой и эфемерный открытый ключ
+//
+// далее фрагменты одинакового размера, последний может быть короче или пустым, каждый зашифрован в режиме MGM
+// одноразовое значение фрагмента содержит его номер и признак последнего фрагмента, дополнительные данные - заголовок,
+// поэтому перестановка, удаление и обрезка фрагментов, а также изменение заголовка обнаруживаются при расшифровании
+
+const (
+	// размер фрагмента по умолчанию
+	FileChunkSize = 64 * 1024
+	// максимальный размер фрагмента
+	FileMaxChunkSize = 16 * 1024 * 1024
+	// число итераций PBKDF2 по умолчанию
+	FileIterations = 2000
+
+	fileVersion  = 1
+	fileSaltSize = 32
+	// длина неизменяемой части заголовка: сигнатура, версия, шифр, способ, размер фрагмента, соль
+	fileHeaderSize = 8 + 3 + 4 + fileSaltSize
+)
+
+// способ выработки ключа файла
+const (
+	filePassword  = 1
+	filePublicKey = 2
+)
+
+var fileSignature = []byte("GOSTFILE")
+
+// шифры файла в порядке номеров в заголовке
+var fileCiphers = []*ContentCipher{
+	ContentCipherKuznyechik,
+	ContentCipherMagma,
+}
+
+/*
+Параметры шифрования файла
+*/
+type FileOptions struct {
+	// шифр, по умолчанию ContentCipherKuznyechik
+	Cipher *ContentCipher
+	// размер фрагмента открытого текста, по умолчанию FileChunkSize
+	ChunkSize int
+	// число итераций PBKDF2 для пароля, по умолчанию FileIterations
+	Iterations int
+	// источник случайных чисел, по умолчанию crypto/rand
+	Random io.Reader
+}
+
+/*
+Запись зашифрованного файла, Close записывает последний фрагмент
+*/
+type FileEncrypter struct {
+	output    io.Writer
+	aead      cipher.AEAD
+	header    []byte
+	chunkSize int
+	buffer    []byte
+	index     uint64
+	closed    bool
+}
+
+/*
+Чтение зашифрованного файла с проверкой каждого фрагмента
+*/
+type FileDecrypter struct {
+	input     io.Reader
+	reader    *bufio.Reader
+	aead      cipher.AEAD
+	header    []byte
+	chunkSize int
+	chunk     []byte
+	plaintext []byte
+	index     uint64
+	final     bool
+	exception error
+}
+
+// начать запись файла, зашифрованного на ключе из пароля PBKDF2-HMAC-ГОСТ Р 34.11-2012 (512)
+func NewPasswordFileEncrypter(output io.Writer, password []byte, options FileOptions) (*FileEncrypter, error) {
+	if exception := setFileDefaults(&options); exception != nil {
+		return nil, exception
+	}
+
+	header, salt, exception := createFileHeader(filePassword, options)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	header = binary.BigEndian.AppendUint32(header, uint32(options.Iterations))
+
+	master, exception := PBKDF2(password, salt, options.Iterations, KuznyechikKeySize, HashGOST3411_2012_512)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	return newFileEncrypter(output, options, header, master)
+}
+
+// начать запись файла для получателя с открытым ключом ГОСТ Р 34.10-2012
+// ключ файла вырабатывается VKO_GOSTR3410_2012_256 с эфемерным ключом, который сохраняется в заголовке
+func NewPublicKeyFileEncrypter(output io.Writer, recipient *PublicKey, options FileOptions) (*FileEncrypter, error) {
+	if exception := setFileDefaults(&options); exception != nil {
+		return nil, exception
+	}
+
+	if exception := recipient.Validate(); exception != nil {
+		return nil, exception
+	}
+
+	header, salt, exception := createFileHeader(filePublicKey, options)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	ephemeral, exception := GeneratePrivateKey(recipient.Curve, options.Random)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	curve, exception := asn1.Marshal(recipient.Curve.OID)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	key := ephemeral.Raw()
+
+	header = append(header, byte(len(curve)))
+	header = append(header, curve...)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(key)))
+	header = append(header, key...)
+
+	master, exception := ephemeral.VKO(recipient, salt[:16], HashGOST3411_2012_256)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	return newFileEncrypter(output, options, header, master)
+}
+
+// начать чтение файла, зашифрованного на пароле
+func NewPasswordFileDecrypter(input io.Reader, password []byte) (*FileDecrypter, error) {
+	decrypter, mode, salt, exception := readFileHeader(input)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	if mode != filePassword {
+		return nil, errors.New("Файл зашифрован не на пароле")
+	}
+
+	var iterations [4]byte
+
+	if exception := decrypter.readHeaderField(iterations[:]); exception != nil {
+		return nil, exception
+	}
+
+	count := binary.BigEndian.Uint32(iterations[:])
+
+	if count == 0 || count > pbkdf2MaxIterations {
+		return nil, errors.New("Неверное число итераций PBKDF2")
+	}
+
+	master, exception := PBKDF2(password, salt, int(count), KuznyechikKeySize, HashGOST3411_2012_512)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	if exception := decrypter.setKey(master, salt); exception != nil {
+		return nil, exception
+	}
+
+	return decrypter, nil
+}
+
+// начать чтение файла, зашифрованного для открытого ключа данного закрытого ключа
+func NewPrivateKeyFileDecrypter(input io.Reader, privateKey *PrivateKey) (*FileDecrypter, error) {
+	decrypter, mode, salt, exception := readFileHeader(input)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	if mode != filePublicKey {
+		return nil, errors.New("Файл зашифрован не на открытом ключе")
+	}
+
+	var size [2]byte
+
+	if exception := decrypter.readHeaderField(size[:1]); exception != nil {
+		return nil, exception
+	}
+
+	encodedCurve := make([]byte, size[0])
+
+	if exception := decrypter.readHeaderField(encodedCurve); exception != nil {
+		return nil, exception
+	}
+
+	var curve asn1.ObjectIdentifier
+
+	if _, exception := asn1.Unmarshal(encodedCurve, &curve); exception != nil {
+		return nil, exception
+	}
+
+	if !curve.Equal(privateKey.Curve.OID) {
+		return nil, errors.New("Файл зашифрован для ключа с другими параметрами кривой")
+	}
+
+	if exception := decrypter.readHeaderField(size[:]); exception != nil {
+		return nil, exception
+	}
+
+	key := make([]byte, binary.BigEndian.Uint16(size[:]))
+
+	if exception := decrypter.readHeaderField(key); exception != nil {
+		return nil, exception
+	}
+
+	ephemeral, exception := UnmarshalPublicKey(privateKey.Curve, key)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	master, exception := privateKey.VKO(ephemeral, salt[:16], HashGOST3411_2012_256)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	if exception := decrypter.setKey(master, salt); exception != nil {
+		return nil, exception
+	}
+
+	return decrypter, nil
+}
+
+func setFileDefaults(options *FileOptions) error {
+	if options.Cipher == nil {
+		options.Cipher = ContentCipherKuznyechik
+	}
+
+	if options.ChunkSize == 0 {
+		options.ChunkSize = FileChunkSize
+	}
+
+	if options.Iterations == 0 {
+		options.Iterations = FileIterations
+	}
+
+	if options.Random == nil {
+		options.Random = rand.Reader
+	}
+
+	if options.ChunkSize < 0 || options.ChunkSize > FileMaxChunkSize {
+		return errors.New("Неверный размер фрагмента файла")
+	}
+
+	if options.Iterations < 0 || options.Iterations > pbkdf2MaxIterations {
+		return errors.New("Неверное число итераций PBKDF2")
+	}
+
+	return nil
+}
+
+// сформировать неизменяемую часть заголовка со случайной солью
+func createFileHeader(mode byte, options FileOptions) (header []byte, salt []byte, exception error) {
+	cipherIndex := -1
+
+	for i, fileCipher := range fileCiphers {
+		if fileCipher == options.Cipher {
+			cipherIndex = i
+		}
+	}
+
+	if cipherIndex < 0 {
+		return nil, nil, errors.New("Неподдерживаемый шифр файла " + options.Cipher.Name)
+	}
+
+	header = append(append([]byte{}, fileSignature...), fileVersion, byte(cipherIndex+1), mode)
+	header = binary.BigEndian.AppendUint32(header, uint32(options.ChunkSize))
+	salt = make([]byte, fileSaltSize)
+
+	if _, exception := io.ReadFull(options.Random, salt); exception != nil {
+		return nil, nil, exception
+	}
+
+	return append(header, salt...), salt, nil
+}
+
+// выработать ключ файла из общего ключа, соль входит в порождающее значение
+func createFileAEAD(contentCipher *ContentCipher, master []byte, salt []byte) (cipher.AEAD, error) {
+	key, exception := KDFTree(master, []byte("gost file"), salt, KuznyechikKeySize)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	block, exception := contentCipher.NewCipher(key)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	return NewMGM(block, 0)
+}
+
+func newFileEncrypter(output io.Writer, options FileOptions, header []byte, master []byte) (*FileEncrypter, error) {
+	aead, exception := createFileAEAD(options.Cipher, master, header[fileHeaderSize-fileSaltSize:fileHeaderSize])
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	if _, exception := output.Write(header); exception != nil {
+		return nil, exception
+	}
+
+	return &FileEncrypter{
+		output:    output,
+		aead:      aead,
+		header:    header,
+		chunkSize: options.ChunkSize,
+		buffer:    make([]byte, 0, options.ChunkSize+aead.Overhead()),
+	}, nil
+}
+
+// одноразовое значение фрагмента: 7 байт номера фрагмента и признак последнего фрагмента, старший бит равен 0
+func fileChunkNonce(size int, index uint64, final bool) []byte {
+	nonce := make([]byte, size)
+	counter := binary.BigEndian.AppendUint64(nil, index)
+	copy(nonce[size-8:size-1], counter[1:])
+
+	if final {
+		nonce[size-1] = 1
+	}
+
+	return nonce
+}
+
+// зашифровать данные, полные фрагменты записываются, когда известно, что они не последние
+func (encrypter *FileEncrypter) Write(data []byte) (int, error) {
+	if encrypter.closed {
+		return 0, errors.New("Запись в закрытый зашифрованный файл")
+	}
+
+	written := 0
+
+	for len(data) > 0 {
+		if len(encrypter.buffer) == encrypter.chunkSize {
+			if exception := encrypter.flush(false); exception != nil {
+				return written, exception
+			}
+		}
+
+		count := copy(encrypter.buffer[len(encrypter.buffer):encrypter.chunkSize], data)
+		encrypter.buffer = encrypter.buffer[:len(encrypter.buffer)+count]
+		data = data[count:]
+		written += count
+	}
+
+	return written, nil
+}
+
+// записать последний фрагмент, без него файл считается обрезанным
+func (encrypter *FileEncrypter) Close() error {
+	if encrypter.closed {
+		return nil
+	}
+
+	encrypter.closed = true
+
+	return encrypter.flush(true)
+}
+
+func (encrypter *FileEncrypter) flush(final bool) error {
+	if encrypter.index>>55 != 0 {
+		return errors.New("Превышено число фрагментов файла")
+	}
+
+	nonce := fileChunkNonce(encrypter.aead.NonceSize(), encrypter.index, final)
+	chunk := encrypter.aead.Seal(encrypter.buffer[:0], nonce, encrypter.buffer, encrypter.header)
+
+	if _, exception := encrypter.output.Write(chunk); exception != nil {
+		return exception
+	}
+
+	encrypter.buffer = encrypter.buffer[:0]
+	encrypter.index++
+
+	return nil
+}
+
+// прочитать неизменяемую часть заголовка
+func readFileHeader(input io.Reader) (decrypter *FileDecrypter, mode byte, salt []byte, exception error) {
+	decrypter = &FileDecrypter{input: input, reader: bufio.NewReader(input)}
+	header := make([]byte, fileHeaderSize)
+
+	if exception := decrypter.readHeaderField(header); exception != nil {
+		return nil, 0, nil, exception
+	}
+
+	if !bytes.Equal(header[:len(fileSignature)], fileSignature) {
+		return nil, 0, nil, errors.New("Файл не является зашифрованным файлом ГОСТ")
+	}
+
+	if header[8] != fileVersion {
+		return nil, 0, nil, errors.New("Неподдерживаемая версия зашифрованного файла")
+	}
+
+	if header[9] == 0 || int(header[9]) > len(fileCiphers) {
+		return nil, 0, nil, errors.New("Неподдерживаемый шифр файла")
+	}
+
+	decrypter.chunkSize = int(binary.BigEndian.Uint32(header[11:15]))
+
+	if decrypter.chunkSize <= 0 || decrypter.chunkSize > FileMaxChunkSize {
+		return nil, 0, nil, errors.New("Неверный размер фрагмента файла")
+	}
+
+	return decrypter, header[10], header[fileHeaderSize-fileSaltSize:], nil
+}
+
+// прочитать поле заголовка, заголовок целиком входит в дополнительные данные фрагментов
+func (decrypter *FileDecrypter) readHeaderField(field []byte) error {
+	if _, exception := io.ReadFull(decrypter.reader, field); exception != nil {
+		return errors.New("Обрезанный заголовок зашифрованного файла")
+	}
+
+	decrypter.header = append(decrypter.header, field...)
+
+	return nil
+}
+
+func (decrypter *FileDecrypter) setKey(master []byte, salt []byte) error {
+	aead, exception := createFileAEAD(fileCiphers[decrypter.header[9]-1], master, salt)
+
+	if exception != nil {
+		return exception
+	}
+
+	decrypter.aead = aead
+	decrypter.chunk = make([]byte, decrypter.chunkSize+aead.Overhead())
+
+	return nil
+}
+
+// прочитать расшифрованные данные, ошибка возвращается на первом поврежденном фрагменте
+func (decrypter *FileDecrypter) Read(data []byte) (int, error) {
+	for len(decrypter.plaintext) == 0 {
+		if decrypter.exception != nil {
+			return 0, decrypter.exception
+		}
+
+		if decrypter.final {
+			return 0, io.EOF
+		}
+
+		decrypter.exception = decrypter.next()
+	}
+
+	count := copy(data, decrypter.plaintext)
+	decrypter.plaintext = decrypter.plaintext[count:]
+
+	return count, nil
+}
+
+// перейти к фрагменту с заданным номером для продолжения чтения, вход должен поддерживать io.Seeker
+func (decrypter *FileDecrypter) SeekChunk(index uint64) error {
+	seeker, ok := decrypter.input.(io.Seeker)
+
+	if !ok {
+		return errors.New("Источник зашифрованного файла не поддерживает позиционирование")
+	}
+
+	offset := int64(len(decrypter.header)) + int64(index)*int64(len(decrypter.chunk))
+
+	if _, exception := seeker.Seek(offset, io.SeekStart); exception != nil {
+		return exception
+	}
+
+	decrypter.reader.Reset(decrypter.input)
+	decrypter.index = index
+	decrypter.plaintext = nil
+	decrypter.final = false
+	decrypter.exception = nil
+
+	return nil
+}
+
+// размер фрагмента открытого текста, фрагмент с номером i начинается в открытом тексте с i * ChunkSize
+func (decrypter *FileDecrypter) ChunkSize() int {
+	return decrypter.chunkSize
+}
+
+// расшифровать следующий фрагмент, последним считается фрагмент, за которым нет данных
+func (decrypter *FileDecrypter) next() error {
+	count, exception := io.ReadFull(decrypter.reader, decrypter.chunk)
+
+	if exception == io.EOF || count < decrypter.aead.Overhead() {
+		return errors.New("Зашифрованный файл обрезан")
+	}
+
+	if exception != nil && exception != io.ErrUnexpectedEOF {
+		return exception
+	}
+
+	final := exception == io.ErrUnexpectedEOF
+
+	if !final {
+		if _, exception := decrypter.reader.Peek(1); exception == io.EOF {
+			final = true
+		} else if exception != nil {
+			return exception
+		}
+	}
+
+	nonce := fileChunkNonce(decrypter.aead.NonceSize(), decrypter.index, final)
+	plaintext, exception := decrypter.aead.Open(decrypter.chunk[:0], nonce, decrypter.chunk[:count], decrypter.header)
+
+	if exception != nil {
+		if decrypter.index == 0 {
+			return errors.New("Неверный ключ или поврежден фрагмент 0 зашифрованного файла")
+		}
+
+		return errors.New("Поврежден или обрезан фрагмент зашифрованного файла")
+	}
+
+	decrypter.plaintext = plaintext
+	decrypter.final = final
+	decrypter.index++
+
+	return nil
+}
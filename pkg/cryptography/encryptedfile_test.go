@@ -0,0 +1,232 @@
+package cryptography
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+var testFilePassword = []byte("пароль")
+
+// зашифровать содержимое на пароле, записывая его частями
+func encryptTestFile(t *testing.T, content []byte, options FileOptions) []byte {
+	var output bytes.Buffer
+
+	encrypter, error := NewPasswordFileEncrypter(&output, testFilePassword, options)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	for _, part := range [][]byte{content[:len(content)/3], content[len(content)/3:]} {
+		if _, error := encrypter.Write(part); error != nil {
+			t.Fatal(error)
+		}
+	}
+
+	if error := encrypter.Close(); error != nil {
+		t.Fatal(error)
+	}
+
+	return output.Bytes()
+}
+
+func decryptTestFile(data []byte, password []byte) ([]byte, error) {
+	decrypter, error := NewPasswordFileDecrypter(iotest.HalfReader(bytes.NewReader(data)), password)
+
+	if error != nil {
+		return nil, error
+	}
+
+	return io.ReadAll(decrypter)
+}
+
+func Test_PasswordFile_Success(t *testing.T) {
+	for _, example := range []struct {
+		cipher *ContentCipher
+		size   int
+	}{
+		{ContentCipherKuznyechik, 0},
+		{ContentCipherKuznyechik, 3000},
+		{ContentCipherMagma, 1000},
+		// размер, кратный фрагменту: последним помечается полный фрагмент
+		{ContentCipherMagma, 4000},
+	} {
+		content := make([]byte, example.size)
+		rand.Read(content)
+
+		data := encryptTestFile(t, content, FileOptions{Cipher: example.cipher, ChunkSize: 1000, Iterations: 2})
+		chunks := (example.size + 999) / 1000
+
+		if chunks == 0 {
+			chunks = 1
+		}
+
+		if overhead := len(data) - example.size; overhead != fileHeaderSize+4+chunks*example.cipher.BlockSize {
+			t.Errorf("%s: ожидалось %d фрагментов. Получен файл длиной %d байт", example.cipher.Name, chunks, len(data))
+		}
+
+		decrypted, error := decryptTestFile(data, testFilePassword)
+
+		if error != nil {
+			t.Fatal(error)
+		}
+
+		if !bytes.Equal(decrypted, content) {
+			t.Errorf("%s: ожидалось исходное содержимое длиной %d байт. Получено %d байт", example.cipher.Name, len(content), len(decrypted))
+		}
+	}
+}
+
+func Test_PasswordFile_Rejected(t *testing.T) {
+	content := make([]byte, 2500)
+	rand.Read(content)
+
+	options := FileOptions{ChunkSize: 1000, Iterations: 2}
+	data := encryptTestFile(t, content, options)
+	header := fileHeaderSize + 4
+	chunk := 1000 + KuznyechikBlockSize
+
+	if _, error := decryptTestFile(data, []byte("другой пароль")); error == nil {
+		t.Error("Ожидалась ошибка для неверного пароля")
+	}
+
+	// перестановка первых двух фрагментов
+	swapped := append([]byte{}, data[:header]...)
+	swapped = append(swapped, data[header+chunk:header+2*chunk]...)
+	swapped = append(swapped, data[header:header+chunk]...)
+	swapped = append(swapped, data[header+2*chunk:]...)
+
+	for name, changed := range map[string][]byte{
+		"переставленных фрагментов": swapped,
+		"обрезанного файла":         data[:header+2*chunk],
+		"обрезанного фрагмента":     data[:len(data)-1],
+		"удаленного фрагмента":      append(append([]byte{}, data[:header]...), data[header+chunk:]...),
+		"измененного размера фрагмента": func() []byte {
+			changed := append([]byte{}, data...)
+			changed[13] ^= 1
+
+			return changed
+		}(),
+		"измененного байта": func() []byte {
+			changed := append([]byte{}, data...)
+			changed[header+chunk+10] ^= 1
+
+			return changed
+		}(),
+	} {
+		if _, error := decryptTestFile(changed, testFilePassword); error == nil {
+			t.Errorf("Ожидалась ошибка для %s", name)
+		}
+	}
+
+	// данные до поврежденного фрагмента расшифровываются
+	changed := append([]byte{}, data...)
+	changed[header+chunk+10] ^= 1
+
+	decrypter, _ := NewPasswordFileDecrypter(bytes.NewReader(changed), testFilePassword)
+	decrypted, error := io.ReadAll(decrypter)
+
+	if error == nil || !bytes.Equal(decrypted, content[:1000]) {
+		t.Errorf("Ожидался первый фрагмент и ошибка. Получено %d байт", len(decrypted))
+	}
+
+	if _, error := NewPrivateKeyFileDecrypter(bytes.NewReader(data), &PrivateKey{}); error == nil {
+		t.Error("Ожидалась ошибка для файла, зашифрованного на пароле")
+	}
+}
+
+func Test_PublicKeyFile_Success(t *testing.T) {
+	privateKey, _ := GeneratePrivateKey(CurveTC26_512A, nil)
+	otherKey, _ := GeneratePrivateKey(CurveTC26_512A, nil)
+	content := bytes.Repeat([]byte("Hello world "), 1000)
+
+	var output bytes.Buffer
+
+	encrypter, error := NewPublicKeyFileEncrypter(&output, &privateKey.PublicKey, FileOptions{ChunkSize: 4096})
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if _, error := io.Copy(encrypter, bytes.NewReader(content)); error != nil {
+		t.Fatal(error)
+	}
+
+	if error := encrypter.Close(); error != nil {
+		t.Fatal(error)
+	}
+
+	data := output.Bytes()
+	decrypter, error := NewPrivateKeyFileDecrypter(bytes.NewReader(data), privateKey)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	decrypted, error := io.ReadAll(decrypter)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if !bytes.Equal(decrypted, content) {
+		t.Errorf("Ожидалось исходное содержимое длиной %d байт. Получено %d байт", len(content), len(decrypted))
+	}
+
+	// продолжение чтения с третьего фрагмента
+	if error := decrypter.SeekChunk(2); error != nil {
+		t.Fatal(error)
+	}
+
+	rest, error := io.ReadAll(decrypter)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if !bytes.Equal(rest, content[2*decrypter.ChunkSize():]) {
+		t.Errorf("Ожидалось содержимое с фрагмента 2 длиной %d байт. Получено %d байт", len(content)-2*decrypter.ChunkSize(), len(rest))
+	}
+
+	decrypter, error = NewPrivateKeyFileDecrypter(bytes.NewReader(data), otherKey)
+
+	if error == nil {
+		_, error = io.ReadAll(decrypter)
+	}
+
+	if error == nil {
+		t.Error("Ожидалась ошибка для другого закрытого ключа")
+	}
+
+	if _, error := NewPasswordFileDecrypter(bytes.NewReader(data), testFilePassword); error == nil {
+		t.Error("Ожидалась ошибка для файла, зашифрованного на открытом ключе")
+	}
+}
+
+func Test_PBKDF2_Success(t *testing.T) {
+	salt := []byte("salt")
+	value, error := PBKDF2([]byte("password"), salt, 2, 100, HashGOST3411_2012_512)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	// T_1 = U_1 ^ U_2, U_1 = HMAC(P, S || INT(1)), U_2 = HMAC(P, U_1)
+	first, _ := calculateHMAC(HashGOST3411_2012_512, []byte("password"), append(append([]byte{}, salt...), 0, 0, 0, 1))
+	second, _ := calculateHMAC(HashGOST3411_2012_512, []byte("password"), first)
+
+	for i := range first {
+		first[i] ^= second[i]
+	}
+
+	if len(value) != 100 || !bytes.Equal(value[:64], first) {
+		t.Errorf("Ожидался первый блок %x. Получен %x", first, value[:64])
+	}
+
+	if _, error := PBKDF2([]byte("password"), salt, 1, 32, HashSha256); error == nil {
+		t.Error("Ожидалась ошибка для алгоритма хэширования не ГОСТ")
+	}
+}
@@ -0,0 +1,667 @@
+package cryptography
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+)
+
+// идентификаторы атрибутов CAdES-C, CAdES-X и CAdES-A
+var (
+	OIDAttributeCertificateRefs    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 21}
+	OIDAttributeRevocationRefs     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 22}
+	OIDAttributeCertificateValues  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 23}
+	OIDAttributeRevocationValues   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 24}
+	OIDAttributeEscTimeStamp       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 25}
+	OIDAttributeArchiveTimeStampV2 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 48}
+	OIDOCSPBasic                   = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 1}
+
+	// SHA-1 в CertID ответов OCSP, для подписи и ссылок не используется
+	oidSha1 = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+)
+
+/*
+Данные для усовершенствования подписи
+*/
+type EnhanceOptions struct {
+	// сертификаты цепочки подписанта: промежуточные и корневые сертификаты, сертификаты служб
+	Certificates []*x509.Certificate
+	// списки отзыва сертификатов в DER
+	CRLs [][]byte
+	// ответы OCSP в DER, OCSPResponse или BasicOCSPResponse
+	OCSPResponses [][]byte
+	// метод получения штампа времени, например из CreateTimestampMethod
+	Timestamp func(io.Reader) (io.Reader, error)
+	// алгоритм хэширования для ссылок, по умолчанию алгоритм хэширования подписанта
+	Hash *HashAlgorithm
+}
+
+/*
+Хэш с алгоритмом OtherHashAlgAndValue
+*/
+type otherHash struct {
+	HashAlgorithm AlgorithmIdentifier
+	HashValue     []byte
+}
+
+/*
+Ссылка на сертификат OtherCertID
+*/
+type otherCertID struct {
+	OtherCertHash otherHash
+	IssuerSerial  issuerSerial `asn1:"optional"`
+}
+
+/*
+Идентификатор списка отзыва
+*/
+type crlIdentifier struct {
+	CRLIssuer     asn1.RawValue
+	CRLIssuedTime time.Time `asn1:"utc"`
+	CRLNumber     *big.Int  `asn1:"optional"`
+}
+
+/*
+Ссылка на список отзыва
+*/
+type crlValidatedID struct {
+	CRLHash       otherHash
+	CRLIdentifier crlIdentifier `asn1:"optional"`
+}
+
+type crlListID struct {
+	CRLs []crlValidatedID
+}
+
+/*
+Идентификатор ответа OCSP
+*/
+type ocspIdentifier struct {
+	OCSPResponderID asn1.RawValue
+	ProducedAt      time.Time `asn1:"generalized"`
+}
+
+/*
+Ссылка на ответ OCSP
+*/
+type ocspResponsesID struct {
+	OCSPIdentifier ocspIdentifier
+	OCSPRepHash    otherHash `asn1:"optional"`
+}
+
+type ocspListID struct {
+	OCSPResponses []ocspResponsesID
+}
+
+/*
+Ссылки на данные о статусе сертификатов CrlOcspRef
+*/
+type crlOcspRef struct {
+	CRLIDs  crlListID  `asn1:"optional,explicit,tag:0"`
+	OCSPIDs ocspListID `asn1:"optional,explicit,tag:1"`
+}
+
+/*
+Значения данных о статусе сертификатов RevocationValues
+*/
+type revocationValues struct {
+	CRLVals  []asn1.RawValue `asn1:"optional,explicit,tag:0"`
+	OCSPVals []asn1.RawValue `asn1:"optional,explicit,tag:1"`
+}
+
+/*
+Ответ OCSP
+*/
+type ocspResponse struct {
+	ResponseStatus asn1.Enumerated
+	ResponseBytes  ocspResponseBytes `asn1:"optional,explicit,tag:0"`
+}
+
+type ocspResponseBytes struct {
+	ResponseType asn1.ObjectIdentifier
+	Response     []byte
+}
+
+/*
+Подписанный ответ OCSP BasicOCSPResponse, разбирается только заголовок
+*/
+type basicOCSPResponse struct {
+	TBSResponseData    ocspResponseData
+	SignatureAlgorithm AlgorithmIdentifier
+	Signature          asn1.BitString
+	Certificates       asn1.RawValue `asn1:"optional,explicit,tag:0"`
+}
+
+type ocspResponseData struct {
+	Version     int `asn1:"optional,explicit,default:0,tag:0"`
+	ResponderID asn1.RawValue
+	ProducedAt  time.Time `asn1:"generalized"`
+	Responses   asn1.RawValue
+	Extensions  asn1.RawValue `asn1:"optional,explicit,tag:1"`
+}
+
+/*
+Ответ OCSP о статусе одного сертификата
+*/
+type ocspSingleResponse struct {
+	CertID     ocspCertID
+	CertStatus asn1.RawValue
+	ThisUpdate time.Time     `asn1:"generalized"`
+	NextUpdate time.Time     `asn1:"optional,explicit,tag:0,generalized"`
+	Extensions asn1.RawValue `asn1:"optional,explicit,tag:1"`
+}
+
+/*
+Идентификатор сертификата в ответе OCSP
+*/
+type ocspCertID struct {
+	HashAlgorithm  AlgorithmIdentifier
+	IssuerNameHash []byte
+	IssuerKeyHash  []byte
+	SerialNumber   *big.Int
+}
+
+// атрибуты CAdES-X Long Type 1, которые добавляются к подписи
+var xlt1Attributes = []asn1.ObjectIdentifier{
+	OIDAttributeCertificateRefs,
+	OIDAttributeRevocationRefs,
+	OIDAttributeCertificateValues,
+	OIDAttributeRevocationValues,
+	OIDAttributeEscTimeStamp,
+}
+
+// получить метод усовершенствования подписи до CAdES-X Long Type 1
+// для открепленной подписи content - подписанные данные, для присоединенной - nil
+// усовершенствуется только верная подпись, подпись без штампа времени предварительно дополняется до CAdES-T
+func CreateCAdESXLT1EnhanceMethod(options EnhanceOptions) (release func(), enhance func(signature io.Reader, content io.Reader) (io.Reader, error), exception error) {
+	if options.Timestamp == nil {
+		return nil, nil, errors.New("Не задан метод получения штампа времени")
+	}
+
+	return func() {},
+		func(signature io.Reader, content io.Reader) (io.Reader, error) {
+			signedData, exception := readSignedData(signature)
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			report, exception := verifySignedData(signedData, content)
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			for i := range signedData.SignerInfos {
+				signer := &report.Signers[i]
+
+				if signer.Status != SignerValid {
+					return nil, fmt.Errorf("Подпись не верна: %v", signer.Exception)
+				}
+
+				if exception := enhanceSignerInfoXLT1(&signedData.SignerInfos[i], signer.Certificate, &options); exception != nil {
+					return nil, exception
+				}
+			}
+
+			return marshalSignedData(signedData)
+		}, nil
+}
+
+// получить метод усовершенствования подписи до CAdES-A архивным штампом времени
+// для открепленной подписи content - подписанные данные, для присоединенной - nil
+// алгоритм хэширования архивных данных задается методом получения штампа времени
+func CreateCAdESAEnhanceMethod(timestamp func(io.Reader) (io.Reader, error)) (release func(), enhance func(signature io.Reader, content io.Reader) (io.Reader, error), exception error) {
+	if timestamp == nil {
+		return nil, nil, errors.New("Не задан метод получения штампа времени")
+	}
+
+	return func() {},
+		func(signature io.Reader, content io.Reader) (io.Reader, error) {
+			signedData, exception := readSignedData(signature)
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			var external []byte
+
+			if signedData.EncapContentInfo.EContent == nil {
+				if content == nil {
+					return nil, errors.New("Для открепленной подписи не переданы подписанные данные")
+				}
+
+				// содержимое хэшируется для каждого подписанта, поэтому читается один раз
+				external, exception = io.ReadAll(content)
+
+				if exception != nil {
+					return nil, exception
+				}
+			}
+
+			for i := range signedData.SignerInfos {
+				signerInfo := &signedData.SignerInfos[i]
+				data, exception := archiveTimestampData(signedData, signerInfo, external)
+
+				if exception != nil {
+					return nil, exception
+				}
+
+				token, exception := timestamp(bytes.NewReader(data))
+
+				if exception != nil {
+					return nil, exception
+				}
+
+				if exception := addTimestampAttribute(signerInfo, OIDAttributeArchiveTimeStampV2, token); exception != nil {
+					return nil, exception
+				}
+			}
+
+			return marshalSignedData(signedData)
+		}, nil
+}
+
+// прочитать и разобрать CMS SignedData
+func readSignedData(signature io.Reader) (*SignedData, error) {
+	data, exception := readCMS(signature)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	return parseSignedData(data)
+}
+
+// дополнить SignerInfo атрибутами CAdES-C, CAdES-X Long и штампом CAdES-C
+func enhanceSignerInfoXLT1(signerInfo *SignerInfo, signerCertificate *x509.Certificate, options *EnhanceOptions) error {
+	algorithm := options.Hash
+
+	if algorithm == nil {
+		found, exception := FindHashAlgorithm(signerInfo.DigestAlgorithm.Algorithm)
+
+		if exception != nil {
+			return exception
+		}
+
+		algorithm = found
+	}
+
+	attributes, exception := parseAttributes(signerInfo.UnsignedAttributes)
+
+	if exception != nil {
+		return exception
+	}
+
+	// повторное усовершенствование дублировало бы ссылки, значения и штамп CAdES-C
+	for _, oid := range xlt1Attributes {
+		if _, ok := findAttribute(attributes, oid); ok {
+			return errors.New("Подпись уже содержит атрибут CAdES-X Long Type 1 " + oid.String())
+		}
+	}
+
+	info, _, exception := signatureTimestamp(signerInfo)
+
+	if exception != nil {
+		return fmt.Errorf("Штамп времени на подпись не верен: %v", exception)
+	}
+
+	if info == nil {
+		if exception := addSignatureTimestamp(signerInfo, options.Timestamp); exception != nil {
+			return exception
+		}
+	}
+
+	// ссылки и значения не содержат сертификат подписанта, он указан в signing-certificate-v2
+	var chain []*x509.Certificate
+
+	for _, certificate := range options.Certificates {
+		if bytes.Equal(certificate.Raw, signerCertificate.Raw) {
+			continue
+		}
+
+		chain = append(chain, certificate)
+	}
+
+	certificateRefs, exception := completeCertificateRefs(chain, algorithm)
+
+	if exception != nil {
+		return exception
+	}
+
+	// данные о статусе перечисляются для подписанта и далее в порядке complete-certificate-references
+	revocationRefs, ocspValues, exception := completeRevocationRefs(append([]*x509.Certificate{signerCertificate}, chain...), options.CRLs, options.OCSPResponses, algorithm)
+
+	if exception != nil {
+		return exception
+	}
+
+	var certificateValues []asn1.RawValue
+
+	for _, certificate := range chain {
+		certificateValues = append(certificateValues, asn1.RawValue{FullBytes: certificate.Raw})
+	}
+
+	revocation := revocationValues{OCSPVals: ocspValues}
+
+	for _, crl := range options.CRLs {
+		revocation.CRLVals = append(revocation.CRLVals, asn1.RawValue{FullBytes: crl})
+	}
+
+	for _, value := range []struct {
+		oid   asn1.ObjectIdentifier
+		value interface{}
+	}{
+		{OIDAttributeCertificateRefs, certificateRefs},
+		{OIDAttributeRevocationRefs, revocationRefs},
+		{OIDAttributeCertificateValues, certificateValues},
+		{OIDAttributeRevocationValues, revocation},
+	} {
+		attribute, exception := newAttribute(value.oid, value.value)
+
+		if exception != nil {
+			return exception
+		}
+
+		if exception := addUnsignedAttribute(signerInfo, attribute); exception != nil {
+			return exception
+		}
+	}
+
+	data, exception := escTimestampData(signerInfo)
+
+	if exception != nil {
+		return exception
+	}
+
+	token, exception := options.Timestamp(bytes.NewReader(data))
+
+	if exception != nil {
+		return exception
+	}
+
+	return addTimestampAttribute(signerInfo, OIDAttributeEscTimeStamp, token)
+}
+
+// хэш объекта в форме OtherHashAlgAndValue
+func newOtherHash(algorithm *HashAlgorithm, data []byte) (otherHash, error) {
+	digest, exception := calculateBytesDigest(algorithm, data)
+
+	if exception != nil {
+		return otherHash{}, exception
+	}
+
+	return otherHash{HashAlgorithm: digestAlgorithmIdentifier(algorithm), HashValue: digest}, nil
+}
+
+// сформировать значение complete-certificate-references
+func completeCertificateRefs(certificates []*x509.Certificate, algorithm *HashAlgorithm) ([]otherCertID, error) {
+	refs := []otherCertID{}
+
+	for _, certificate := range certificates {
+		hash, exception := newOtherHash(algorithm, certificate.Raw)
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		refs = append(refs, otherCertID{
+			OtherCertHash: hash,
+			IssuerSerial: issuerSerial{
+				Issuer:       []asn1.RawValue{{Class: asn1.ClassContextSpecific, Tag: 4, IsCompound: true, Bytes: certificate.RawIssuer}},
+				SerialNumber: certificate.SerialNumber,
+			},
+		})
+	}
+
+	return refs, nil
+}
+
+// сформировать значение complete-revocation-references и значения ответов OCSP
+// для каждого сертификата формируется CrlOcspRef со списками отзыва его издателя и ответами OCSP с его CertID
+func completeRevocationRefs(certificates []*x509.Certificate, crls [][]byte, ocspResponses [][]byte, algorithm *HashAlgorithm) ([]crlOcspRef, []asn1.RawValue, error) {
+	refs := make([]crlOcspRef, len(certificates))
+	var ocspValues []asn1.RawValue
+
+	for _, crl := range crls {
+		revocationList, exception := x509.ParseRevocationList(crl)
+
+		if exception != nil {
+			return nil, nil, exception
+		}
+
+		hash, exception := newOtherHash(algorithm, crl)
+
+		if exception != nil {
+			return nil, nil, exception
+		}
+
+		id := crlValidatedID{
+			CRLHash: hash,
+			CRLIdentifier: crlIdentifier{
+				CRLIssuer:     asn1.RawValue{FullBytes: revocationList.RawIssuer},
+				CRLIssuedTime: revocationList.ThisUpdate.UTC(),
+				CRLNumber:     revocationList.Number,
+			},
+		}
+
+		matched := false
+
+		for i, certificate := range certificates {
+			if bytes.Equal(certificate.RawIssuer, revocationList.RawIssuer) {
+				refs[i].CRLIDs.CRLs = append(refs[i].CRLIDs.CRLs, id)
+				matched = true
+			}
+		}
+
+		if !matched {
+			return nil, nil, errors.New("Список отзыва не относится к сертификатам подписи")
+		}
+	}
+
+	for _, response := range ocspResponses {
+		basic, exception := parseBasicOCSPResponse(response)
+
+		if exception != nil {
+			return nil, nil, exception
+		}
+
+		var parsed basicOCSPResponse
+
+		if _, exception := asn1.Unmarshal(basic, &parsed); exception != nil {
+			return nil, nil, exception
+		}
+
+		var responses []ocspSingleResponse
+
+		if _, exception := asn1.Unmarshal(parsed.TBSResponseData.Responses.FullBytes, &responses); exception != nil {
+			return nil, nil, errors.New("Некорректный ответ OCSP")
+		}
+
+		hash, exception := newOtherHash(algorithm, basic)
+
+		if exception != nil {
+			return nil, nil, exception
+		}
+
+		id := ocspResponsesID{
+			OCSPIdentifier: ocspIdentifier{
+				OCSPResponderID: parsed.TBSResponseData.ResponderID,
+				ProducedAt:      parsed.TBSResponseData.ProducedAt.UTC(),
+			},
+			OCSPRepHash: hash,
+		}
+
+		matched := false
+
+		for i, certificate := range certificates {
+			for _, single := range responses {
+				ok, exception := matchOCSPCertID(&single.CertID, certificate, certificates)
+
+				if exception != nil {
+					return nil, nil, exception
+				}
+
+				if ok {
+					refs[i].OCSPIDs.OCSPResponses = append(refs[i].OCSPIDs.OCSPResponses, id)
+					matched = true
+
+					break
+				}
+			}
+		}
+
+		if !matched {
+			return nil, nil, errors.New("Ответ OCSP не относится к сертификатам подписи")
+		}
+
+		ocspValues = append(ocspValues, asn1.RawValue{FullBytes: basic})
+	}
+
+	return refs, ocspValues, nil
+}
+
+// относится ли CertID ответа OCSP к сертификату: совпадают серийный номер и хэш имени издателя,
+// хэш ключа издателя сверяется, если сертификат издателя есть среди сертификатов подписи
+func matchOCSPCertID(id *ocspCertID, certificate *x509.Certificate, certificates []*x509.Certificate) (bool, error) {
+	if id.SerialNumber == nil || id.SerialNumber.Cmp(certificate.SerialNumber) != 0 {
+		return false, nil
+	}
+
+	nameHash, exception := ocspCertIDDigest(id.HashAlgorithm.Algorithm, certificate.RawIssuer)
+
+	if exception != nil {
+		return false, exception
+	}
+
+	if !bytes.Equal(nameHash, id.IssuerNameHash) {
+		return false, nil
+	}
+
+	issuerFound := false
+
+	for _, issuer := range certificates {
+		if !bytes.Equal(issuer.RawSubject, certificate.RawIssuer) {
+			continue
+		}
+
+		var info subjectPublicKeyInfo
+
+		if _, exception := asn1.Unmarshal(issuer.RawSubjectPublicKeyInfo, &info); exception != nil {
+			return false, exception
+		}
+
+		keyHash, exception := ocspCertIDDigest(id.HashAlgorithm.Algorithm, info.PublicKey.RightAlign())
+
+		if exception != nil {
+			return false, exception
+		}
+
+		if bytes.Equal(keyHash, id.IssuerKeyHash) {
+			return true, nil
+		}
+
+		issuerFound = true
+	}
+
+	return !issuerFound, nil
+}
+
+// хэш для CertID, ответы OCSP чаще всего используют SHA-1
+func ocspCertIDDigest(oid asn1.ObjectIdentifier, data []byte) ([]byte, error) {
+	if oid.Equal(oidSha1) {
+		digest := sha1.Sum(data)
+
+		return digest[:], nil
+	}
+
+	algorithm, exception := FindHashAlgorithm(oid)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	return calculateBytesDigest(algorithm, data)
+}
+
+// получить BasicOCSPResponse из OCSPResponse или BasicOCSPResponse
+func parseBasicOCSPResponse(data []byte) ([]byte, error) {
+	var response ocspResponse
+
+	if _, exception := asn1.Unmarshal(data, &response); exception == nil {
+		if response.ResponseStatus != 0 {
+			return nil, errors.New("Ответ OCSP не является успешным")
+		}
+
+		if !response.ResponseBytes.ResponseType.Equal(OIDOCSPBasic) {
+			return nil, errors.New("Не поддерживается тип ответа OCSP " + response.ResponseBytes.ResponseType.String())
+		}
+
+		return response.ResponseBytes.Response, nil
+	}
+
+	var basic basicOCSPResponse
+
+	if _, exception := asn1.Unmarshal(data, &basic); exception != nil {
+		return nil, errors.New("Некорректный ответ OCSP")
+	}
+
+	return data, nil
+}
+
+// данные для штампа CAdES-C: значение подписи, штамп на подпись и полные ссылки
+func escTimestampData(signerInfo *SignerInfo) ([]byte, error) {
+	attributes, exception := parseAttributes(signerInfo.UnsignedAttributes)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	data := append([]byte{}, signerInfo.Signature...)
+
+	for _, oid := range []asn1.ObjectIdentifier{OIDAttributeSignatureTimeStampToken, OIDAttributeCertificateRefs, OIDAttributeRevocationRefs} {
+		attribute, ok := findAttribute(attributes, oid)
+
+		if !ok {
+			return nil, errors.New("Не найден атрибут " + oid.String())
+		}
+
+		encoded, exception := asn1.Marshal(*attribute)
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		data = append(data, encoded...)
+	}
+
+	return data, nil
+}
+
+// данные для архивного штампа v2: encapContentInfo, внешнее содержимое, сертификаты, списки отзыва и все поля SignerInfo
+func archiveTimestampData(signedData *SignedData, signerInfo *SignerInfo, external []byte) ([]byte, error) {
+	encapContentInfo, exception := asn1.Marshal(signedData.EncapContentInfo)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	data := append([]byte{}, encapContentInfo...)
+	data = append(data, external...)
+	data = append(data, signedData.Certificates.FullBytes...)
+	data = append(data, signedData.CRLs.FullBytes...)
+
+	encodedSignerInfo, exception := asn1.Marshal(*signerInfo)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	return append(data, derContent(encodedSignerInfo)...), nil
+}
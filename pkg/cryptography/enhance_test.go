@@ -0,0 +1,465 @@
+package cryptography
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"io"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+// сформировать удостоверяющий центр, его список отзыва и ответ OCSP о статусе сертификата subject издателя issuer
+func createTestRevocationData(t *testing.T, subject *x509.Certificate, issuer *x509.Certificate) (*x509.Certificate, []byte, []byte) {
+	privateKey, error := rsa.GenerateKey(rand.Reader, 2048)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	raw, error := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	certificate, error := x509.ParseCertificate(raw)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	crl, error := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Now().Add(-time.Minute),
+		NextUpdate: time.Now().Add(time.Hour),
+	}, certificate, privateKey)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	var info subjectPublicKeyInfo
+
+	if _, error := asn1.Unmarshal(issuer.RawSubjectPublicKeyInfo, &info); error != nil {
+		t.Fatal(error)
+	}
+
+	nameHash := sha1.Sum(subject.RawIssuer)
+	keyHash := sha1.Sum(info.PublicKey.RightAlign())
+
+	responses, error := asn1.Marshal([]ocspSingleResponse{{
+		CertID: ocspCertID{
+			HashAlgorithm:  AlgorithmIdentifier{Algorithm: oidSha1, Parameters: asn1Null},
+			IssuerNameHash: nameHash[:],
+			IssuerKeyHash:  keyHash[:],
+			SerialNumber:   subject.SerialNumber,
+		},
+		// good [0] IMPLICIT NULL
+		CertStatus: asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0},
+		ThisUpdate: time.Now().UTC().Truncate(time.Second),
+	}})
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	basic, error := asn1.Marshal(basicOCSPResponse{
+		TBSResponseData: ocspResponseData{
+			// byName [1] EXPLICIT Name
+			ResponderID: asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 1, IsCompound: true, Bytes: certificate.RawSubject},
+			ProducedAt:  time.Now().UTC().Truncate(time.Second),
+			Responses:   asn1.RawValue{FullBytes: responses},
+		},
+		SignatureAlgorithm: AlgorithmIdentifier{Algorithm: OIDRSASha256, Parameters: asn1Null},
+		Signature:          asn1.BitString{Bytes: []byte{0}, BitLength: 8},
+	})
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	response, error := asn1.Marshal(ocspResponse{ResponseBytes: ocspResponseBytes{ResponseType: OIDOCSPBasic, Response: basic}})
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	return certificate, crl, response
+}
+
+// найти значение неподписанного атрибута первого подписанта
+func findUnsignedAttribute(t *testing.T, data []byte, oid asn1.ObjectIdentifier) (*SignerInfo, []byte) {
+	signedData, error := parseSignedData(data)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	signerInfo := &signedData.SignerInfos[0]
+	attributes, error := parseAttributes(signerInfo.UnsignedAttributes)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	attribute, ok := findAttribute(attributes, oid)
+
+	if !ok {
+		t.Fatalf("Ожидался атрибут %s", oid)
+	}
+
+	return signerInfo, attribute.Values[0].FullBytes
+}
+
+func Test_CAdESXLT1Enhance_Success(t *testing.T) {
+	tsa := createTestTSA(t, tspGranted)
+	defer tsa.Close()
+
+	releaseTimestamp, timestamp, error := CreateTimestampMethod(tsa.URL, HashGOST3411_2012_256)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer releaseTimestamp()
+
+	privateKey, certificate := createTestGOSTCertificate(t, CurveTC26_256A, "CAdES-X Long Type 1")
+
+	releaseSign, sign, error := CreateCAdESBESSignMethod(privateKey, certificate, true, SignOptions{})
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer releaseSign()
+
+	signature, error := sign(strings.NewReader("Hello world"))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	authority, crl, ocsp := createTestRevocationData(t, certificate, certificate)
+
+	release, enhance, error := CreateCAdESXLT1EnhanceMethod(EnhanceOptions{
+		Certificates:  []*x509.Certificate{certificate, authority},
+		CRLs:          [][]byte{crl},
+		OCSPResponses: [][]byte{ocsp},
+		Timestamp:     timestamp,
+	})
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer release()
+
+	enhanced, error := enhance(signature, strings.NewReader("Hello world"))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	data, error := io.ReadAll(enhanced)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	for _, oid := range []asn1.ObjectIdentifier{
+		OIDAttributeSignatureTimeStampToken,
+		OIDAttributeCertificateRefs,
+		OIDAttributeRevocationRefs,
+		OIDAttributeCertificateValues,
+		OIDAttributeRevocationValues,
+	} {
+		findUnsignedAttribute(t, data, oid)
+	}
+
+	_, encodedValues := findUnsignedAttribute(t, data, OIDAttributeCertificateValues)
+	var values []asn1.RawValue
+
+	if _, error := asn1.Unmarshal(encodedValues, &values); error != nil {
+		t.Fatal(error)
+	}
+
+	if len(values) != 1 || !bytes.Equal(values[0].FullBytes, authority.Raw) {
+		t.Errorf("Ожидался один сертификат удостоверяющего центра. Получено %d", len(values))
+	}
+
+	// ответ OCSP относится к сертификату подписанта, список отзыва - к сертификату удостоверяющего центра
+	_, encodedRefs := findUnsignedAttribute(t, data, OIDAttributeRevocationRefs)
+	var refs []crlOcspRef
+
+	if _, error := asn1.Unmarshal(encodedRefs, &refs); error != nil {
+		t.Fatal(error)
+	}
+
+	if len(refs) != 2 || len(refs[0].OCSPIDs.OCSPResponses) != 1 || len(refs[0].CRLIDs.CRLs) != 0 ||
+		len(refs[1].OCSPIDs.OCSPResponses) != 0 || len(refs[1].CRLIDs.CRLs) != 1 {
+		t.Errorf("Ожидались ссылки на ответ OCSP для подписанта и список отзыва для удостоверяющего центра. Получено %+v", refs)
+	}
+
+	signerInfo, token := findUnsignedAttribute(t, data, OIDAttributeEscTimeStamp)
+
+	info, error := ParseTimestampToken(bytes.NewReader(token))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	escData, error := escTimestampData(signerInfo)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	digest, error := calculateBytesDigest(HashGOST3411_2012_256, escData)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if !bytes.Equal(info.MessageImprint.HashedMessage, digest) {
+		t.Error("Штамп CAdES-C выдан не на подпись и ссылки")
+	}
+
+	releaseVerify, verify, error := CreateCMSVerifyMethod()
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer releaseVerify()
+
+	report, error := verify(bytes.NewReader(data), strings.NewReader("Hello world"))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if !report.Valid() {
+		t.Fatalf("Ожидалась верная подпись. Получен статус %s: %v", report.Signers[0].Status, report.Signers[0].Exception)
+	}
+}
+
+func Test_CAdESAEnhance_Success(t *testing.T) {
+	tsa := createTestTSA(t, tspGranted)
+	defer tsa.Close()
+
+	releaseTimestamp, timestamp, error := CreateTimestampMethod(tsa.URL, HashGOST3411_2012_512)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer releaseTimestamp()
+
+	privateKey, certificate := createTestGOSTCertificate(t, CurveTC26_512A, "CAdES-A")
+
+	releaseSign, sign, error := CreateCAdESTSignMethod(privateKey, certificate, true, timestamp, SignOptions{})
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer releaseSign()
+
+	signature, error := sign(strings.NewReader("Hello world"))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	release, enhance, error := CreateCAdESAEnhanceMethod(timestamp)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer release()
+
+	if _, error := enhance(signature, nil); error == nil {
+		t.Fatal("Ожидалась ошибка для открепленной подписи без данных")
+	}
+
+	signature, error = sign(strings.NewReader("Hello world"))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	original, error := io.ReadAll(signature)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	enhanced, error := enhance(bytes.NewReader(original), strings.NewReader("Hello world"))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	data, error := io.ReadAll(enhanced)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	_, token := findUnsignedAttribute(t, data, OIDAttributeArchiveTimeStampV2)
+
+	info, error := ParseTimestampToken(bytes.NewReader(token))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	signedData, error := parseSignedData(original)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	archiveData, error := archiveTimestampData(signedData, &signedData.SignerInfos[0], []byte("Hello world"))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	digest, error := calculateBytesDigest(HashGOST3411_2012_512, archiveData)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if !bytes.Equal(info.MessageImprint.HashedMessage, digest) {
+		t.Error("Архивный штамп выдан не на подпись и данные")
+	}
+}
+
+func Test_CAdESXLT1Enhance_Rejected(t *testing.T) {
+	tsa := createTestTSA(t, tspGranted)
+	defer tsa.Close()
+
+	releaseTimestamp, timestamp, error := CreateTimestampMethod(tsa.URL, HashGOST3411_2012_256)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer releaseTimestamp()
+
+	privateKey, certificate := createTestGOSTCertificate(t, CurveTC26_256A, "CAdES-X Long Type 1")
+	_, other := createTestGOSTCertificate(t, CurveTC26_256A, "Другой сертификат")
+
+	releaseSign, sign, error := CreateCAdESBESSignMethod(privateKey, certificate, true, SignOptions{})
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer releaseSign()
+
+	signature, error := sign(strings.NewReader("Hello world"))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	original, error := io.ReadAll(signature)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	authority, crl, ocsp := createTestRevocationData(t, certificate, certificate)
+	_, _, otherOCSP := createTestRevocationData(t, other, other)
+
+	options := func(ocsp []byte) EnhanceOptions {
+		return EnhanceOptions{
+			Certificates:  []*x509.Certificate{authority},
+			CRLs:          [][]byte{crl},
+			OCSPResponses: [][]byte{ocsp},
+			Timestamp:     timestamp,
+		}
+	}
+
+	release, enhance, error := CreateCAdESXLT1EnhanceMethod(options(ocsp))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer release()
+
+	if _, error := enhance(bytes.NewReader(original), strings.NewReader("Другие данные")); error == nil {
+		t.Error("Ожидалась ошибка для подписи, не совпадающей с данными")
+	}
+
+	releaseOther, enhanceOther, error := CreateCAdESXLT1EnhanceMethod(options(otherOCSP))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer releaseOther()
+
+	if _, error := enhanceOther(bytes.NewReader(original), strings.NewReader("Hello world")); error == nil {
+		t.Error("Ожидалась ошибка для ответа OCSP о другом сертификате")
+	}
+
+	enhanced, error := enhance(bytes.NewReader(original), strings.NewReader("Hello world"))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if _, error := enhance(enhanced, strings.NewReader("Hello world")); error == nil {
+		t.Error("Ожидалась ошибка для повторного усовершенствования")
+	}
+
+	// штамп времени выдан не на значение подписи
+	signedData, error := parseSignedData(original)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	token, error := timestamp(strings.NewReader("Другие данные"))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if error := addTimestampAttribute(&signedData.SignerInfos[0], OIDAttributeSignatureTimeStampToken, token); error != nil {
+		t.Fatal(error)
+	}
+
+	stamped, error := marshalSignedData(signedData)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if _, error := enhance(stamped, strings.NewReader("Hello world")); error == nil {
+		t.Error("Ожидалась ошибка для неверного штампа времени на подпись")
+	}
+}
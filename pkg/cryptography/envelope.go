@@ -0,0 +1,1001 @@
+package cryptography
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"io"
+
+	"github.com/madpo/go-gost-crypto/pkg/wrapper"
+)
+
+// шифрование CMS EnvelopedData для сертификатов ГОСТ Р 34.10-2012, Р 1323565.1.024-2019 и RFC 4490
+
+// идентификаторы EnvelopedData и алгоритмов шифрования
+var (
+	OIDEnvelopedData              = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 3}
+	OIDMagmaCTRACPKM              = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 5, 1, 1}
+	OIDKuznyechikCTRACPKM         = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 5, 2, 1}
+	OIDMagmaKExp15                = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 7, 1, 1}
+	OIDKuznyechikKExp15           = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 7, 2, 1}
+	OIDAgreementGOST3410_2012_256 = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 6, 1}
+	OIDAgreementGOST3410_2012_512 = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 6, 2}
+	OIDGOST28147                  = asn1.ObjectIdentifier{1, 2, 643, 2, 2, 21}
+)
+
+const (
+	// длина UKM транспорта ключа: 16 байт для VKO, 8 байт для KDF_TREE и синхропосылка KExp15
+	transportUKMSize = 32
+	// размер фрагмента зашифрованного содержимого
+	envelopeChunkSize = 64 * 1024
+)
+
+/*
+Алгоритм шифрования содержимого CMS в режиме CTR-ACPKM
+*/
+type ContentCipher struct {
+	Name string
+	OID  asn1.ObjectIdentifier
+	// алгоритм экспорта ключа KExp15 этим же шифром
+	WrapOID asn1.ObjectIdentifier
+	// конструктор блочного шифра
+	NewCipher BlockConstructor
+	// размер блока в байтах
+	BlockSize int
+	// размер секции CTR-ACPKM в байтах
+	SectionSize int
+}
+
+var (
+	ContentCipherKuznyechik = &ContentCipher{Name: "Кузнечик CTR-ACPKM", OID: OIDKuznyechikCTRACPKM, WrapOID: OIDKuznyechikKExp15, NewCipher: NewKuznyechik, BlockSize: KuznyechikBlockSize, SectionSize: 4096}
+	ContentCipherMagma      = &ContentCipher{Name: "Магма CTR-ACPKM", OID: OIDMagmaCTRACPKM, WrapOID: OIDMagmaKExp15, NewCipher: NewMagma, BlockSize: MagmaBlockSize, SectionSize: 1024}
+)
+
+var contentCiphers = []*ContentCipher{
+	ContentCipherKuznyechik,
+	ContentCipherMagma,
+}
+
+/*
+Параметры шифрования CMS
+*/
+type EnvelopeOptions struct {
+	// шифр содержимого, по умолчанию ContentCipherKuznyechik
+	Cipher *ContentCipher
+	// передавать ключ согласованием (KeyAgreeRecipientInfo), по умолчанию транспортом ключа (KeyTransRecipientInfo)
+	KeyAgreement bool
+	// источник случайных чисел, по умолчанию crypto/rand
+	Random io.Reader
+}
+
+/*
+Получатель зашифрованного сообщения
+*/
+type envelopeRecipient struct {
+	certificate *x509.Certificate
+	publicKey   *PublicKey
+	algorithm   AlgorithmIdentifier
+}
+
+type keyTransRecipientInfo struct {
+	Version                int
+	RID                    asn1.RawValue
+	KeyEncryptionAlgorithm AlgorithmIdentifier
+	EncryptedKey           []byte
+}
+
+type keyAgreeRecipientInfo struct {
+	Version int
+	// [0] EXPLICIT OriginatorIdentifierOrKey, тег разбирается вручную
+	Originator             asn1.RawValue
+	UKM                    []byte `asn1:"explicit,optional,tag:1"`
+	KeyEncryptionAlgorithm AlgorithmIdentifier
+	RecipientEncryptedKeys []recipientEncryptedKey
+}
+
+type recipientEncryptedKey struct {
+	RID          asn1.RawValue
+	EncryptedKey []byte
+}
+
+// параметры шифров ГОСТ Р 34.12-2015 в CMS: синхропосылка и 8 байт порождающего значения
+type gost3412Parameters struct {
+	UKM []byte
+}
+
+// транспорт ключа KExp15
+type gostKeyTransport struct {
+	EncryptedKey       []byte
+	EphemeralPublicKey asn1.RawValue `asn1:"optional,tag:0"`
+	UKM                []byte
+}
+
+// транспорт ключа ГОСТ 28147-89 КриптоПро, RFC 4490 п. 4.2
+type gost28147KeyTransport struct {
+	SessionEncryptedKey gost28147EncryptedKey
+	TransportParameters gostTransportParameters `asn1:"optional,tag:0"`
+}
+
+type gost28147EncryptedKey struct {
+	EncryptedKey []byte
+	MaskKey      []byte `asn1:"optional,tag:0"`
+	MACKey       []byte
+}
+
+type gostTransportParameters struct {
+	EncryptionParamSet asn1.ObjectIdentifier
+	EphemeralPublicKey asn1.RawValue `asn1:"optional,tag:0"`
+	UKM                []byte
+}
+
+// параметры шифрования ГОСТ 28147-89, RFC 4357 п. 10.1
+type gost28147Parameters struct {
+	IV                 []byte
+	EncryptionParamSet asn1.ObjectIdentifier
+}
+
+// найти алгоритм шифрования содержимого по идентификатору
+func FindContentCipher(oid asn1.ObjectIdentifier) (*ContentCipher, error) {
+	for _, contentCipher := range contentCiphers {
+		if contentCipher.OID.Equal(oid) {
+			return contentCipher, nil
+		}
+	}
+
+	return nil, errors.New("Неподдерживаемый алгоритм шифрования содержимого " + oid.String())
+}
+
+// найти алгоритм шифрования содержимого по алгоритму экспорта ключа
+func findWrapContentCipher(oid asn1.ObjectIdentifier) (*ContentCipher, error) {
+	for _, contentCipher := range contentCiphers {
+		if contentCipher.WrapOID.Equal(oid) {
+			return contentCipher, nil
+		}
+	}
+
+	return nil, errors.New("Неподдерживаемый алгоритм экспорта ключа " + oid.String())
+}
+
+// получить метод шифрования CMS EnvelopedData для сертификатов получателей ГОСТ Р 34.10-2012
+// содержимое шифруется потоком и записывается в BER с неопределенными длинами
+func CreateCMSEncryptMethod(certificates []*x509.Certificate, options EnvelopeOptions) (release func(), encrypt func(content io.Reader, output io.Writer) error, exception error) {
+	if len(certificates) == 0 {
+		return nil, nil, errors.New("Не заданы сертификаты получателей")
+	}
+
+	if options.Cipher == nil {
+		options.Cipher = ContentCipherKuznyechik
+	}
+
+	if options.Random == nil {
+		options.Random = rand.Reader
+	}
+
+	recipients := make([]*envelopeRecipient, len(certificates))
+
+	for i, certificate := range certificates {
+		var info subjectPublicKeyInfo
+
+		if _, exception := asn1.Unmarshal(certificate.RawSubjectPublicKeyInfo, &info); exception != nil {
+			return nil, nil, exception
+		}
+
+		algorithm := info.Algorithm.Algorithm
+
+		if !algorithm.Equal(OIDGOST3410_2012_256) && !algorithm.Equal(OIDGOST3410_2012_512) {
+			return nil, nil, errors.New("Сертификат получателя не содержит ключ ГОСТ Р 34.10-2012")
+		}
+
+		publicKey, exception := ParsePublicKey(certificate.RawSubjectPublicKeyInfo)
+
+		if exception != nil {
+			return nil, nil, exception
+		}
+
+		recipients[i] = &envelopeRecipient{certificate: certificate, publicKey: publicKey, algorithm: info.Algorithm}
+	}
+
+	return func() {},
+		func(content io.Reader, output io.Writer) error {
+			return encryptEnvelopedData(content, output, recipients, options)
+		}, nil
+}
+
+// получить метод расшифрования CMS EnvelopedData закрытым ключом получателя
+// поддерживаются сообщения Кузнечик и Магма CTR-ACPKM и сообщения ГОСТ 28147-89 КриптоПро
+func CreateCMSDecryptMethod(privateKey *PrivateKey, certificate *x509.Certificate) (release func(), decrypt func(message io.Reader, output io.Writer) error, exception error) {
+	publicKey, exception := ParsePublicKey(certificate.RawSubjectPublicKeyInfo)
+
+	if exception != nil {
+		return nil, nil, exception
+	}
+
+	if publicKey.Curve != privateKey.Curve || publicKey.X.Cmp(privateKey.X) != 0 || publicKey.Y.Cmp(privateKey.Y) != 0 {
+		return nil, nil, errors.New("Закрытый ключ не соответствует сертификату")
+	}
+
+	// ключ содержимого импортируется в Go, содержимое расшифровывается потоком шифра
+	open := func(recipientInfos []asn1.RawValue, algorithm AlgorithmIdentifier, output io.Writer) (io.Writer, func() error, error) {
+		key, exception := decryptContentKey(recipientInfos, algorithm, privateKey, certificate)
+
+		if exception != nil {
+			return nil, nil, exception
+		}
+
+		stream, exception := createContentStream(algorithm, key, true)
+
+		if exception != nil {
+			return nil, nil, exception
+		}
+
+		return cipher.StreamWriter{S: stream, W: output}, func() error { return nil }, nil
+	}
+
+	return func() {},
+		func(message io.Reader, output io.Writer) error {
+			return decryptEnvelopedData(message, output, open)
+		}, nil
+}
+
+// получить метод расшифрования CMS EnvelopedData ключом обмена контейнера КриптоПро
+// поддерживаются сообщения ГОСТ 28147-89 с транспортом ключа RFC 4490, которые по умолчанию формирует КриптоПро:
+// ключ содержимого импортируется на ключе согласования и расшифровывает содержимое, не покидая провайдер
+func CreateCMSContainerDecryptMethod(signer *ContainerSigner) (release func(), decrypt func(message io.Reader, output io.Writer) error, exception error) {
+	if signer.keySpec != wrapper.KeyExchange {
+		return nil, nil, errors.New("Ключ контейнера не предназначен для обмена ключами")
+	}
+
+	open := func(recipientInfos []asn1.RawValue, algorithm AlgorithmIdentifier, output io.Writer) (io.Writer, func() error, error) {
+		key, exception := importContainerContentKey(signer, recipientInfos, algorithm)
+
+		if exception != nil {
+			return nil, nil, exception
+		}
+
+		writer := &cspDecryptWriter{key: key, output: output}
+
+		return writer, writer.finish, nil
+	}
+
+	return func() {},
+		func(message io.Reader, output io.Writer) error {
+			return decryptEnvelopedData(message, output, open)
+		}, nil
+}
+
+// импортировать ключ содержимого ГОСТ 28147-89 на ключе согласования контейнера и установить режим CFB
+func importContainerContentKey(signer *ContainerSigner, recipientInfos []asn1.RawValue, algorithm AlgorithmIdentifier) (*wrapper.CryptoKey, error) {
+	if !algorithm.Algorithm.Equal(OIDGOST28147) {
+		return nil, errors.New("Ключом контейнера расшифровываются только сообщения ГОСТ 28147-89")
+	}
+
+	var parameters gost28147Parameters
+
+	if _, exception := asn1.Unmarshal(algorithm.Parameters.FullBytes, &parameters); exception != nil {
+		return nil, exception
+	}
+
+	certificates := []*x509.Certificate{signer.certificate}
+
+	for _, recipientInfo := range recipientInfos {
+		if recipientInfo.Class != asn1.ClassUniversal || recipientInfo.Tag != asn1.TagSequence {
+			continue
+		}
+
+		var keyTransport keyTransRecipientInfo
+
+		if _, exception := asn1.Unmarshal(recipientInfo.FullBytes, &keyTransport); exception != nil {
+			return nil, exception
+		}
+
+		if findSignerCertificate(keyTransport.RID, certificates) == nil {
+			continue
+		}
+
+		var transport gost28147KeyTransport
+
+		if _, exception := asn1.Unmarshal(keyTransport.EncryptedKey, &transport); exception != nil {
+			return nil, errors.New("Ключом контейнера расшифровываются только сообщения с транспортом ключа ГОСТ 28147-89 КриптоПро")
+		}
+
+		transportParameters := transport.TransportParameters
+
+		// параметры шифрования задаются ключу при импорте и должны совпадать с параметрами содержимого
+		if !transportParameters.EncryptionParamSet.Equal(parameters.EncryptionParamSet) {
+			return nil, errors.New("Параметры шифрования ключа и содержимого различаются")
+		}
+
+		ephemeral, exception := parseEphemeralKey(transportParameters.EphemeralPublicKey.FullBytes, signer.publicKey.Curve)
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		releaseAgreement, agreementKey, exception := signer.AgreeKey(ephemeral, transportParameters.UKM)
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		defer releaseAgreement()
+
+		sessionKey := transport.SessionEncryptedKey
+		wrapped := append(append(append([]byte{}, transportParameters.UKM...), sessionKey.EncryptedKey...), sessionKey.MACKey...)
+		encodedParameters, exception := asn1.Marshal(transportParameters.EncryptionParamSet)
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		key, exception := wrapper.ImportWrappedKey(signer.provider, agreementKey, &wrapped, &encodedParameters)
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		if exception := wrapper.SetCipherMode(key, wrapper.CipherModeCFB); exception != nil {
+			wrapper.ReleaseKey(key)
+			return nil, exception
+		}
+
+		iv := append([]byte{}, parameters.IV...)
+
+		if exception := wrapper.SetCipherIV(key, &iv); exception != nil {
+			wrapper.ReleaseKey(key)
+			return nil, exception
+		}
+
+		return key, nil
+	}
+
+	return nil, errors.New("Сообщение зашифровано не для данного сертификата")
+}
+
+/*
+Расшифрование содержимого ключом провайдера
+провайдер расшифровывает данные блоками, остаток передается последним вызовом
+*/
+type cspDecryptWriter struct {
+	key     *wrapper.CryptoKey
+	output  io.Writer
+	pending []byte
+}
+
+func (writer *cspDecryptWriter) Write(data []byte) (int, error) {
+	writer.pending = append(writer.pending, data...)
+	size := len(writer.pending) / GOST28147BlockSize * GOST28147BlockSize
+
+	if size == 0 {
+		return len(data), nil
+	}
+
+	block := append([]byte{}, writer.pending[:size]...)
+	writer.pending = writer.pending[size:]
+
+	if exception := wrapper.DecryptData(writer.key, &block); exception != nil {
+		return 0, exception
+	}
+
+	if _, exception := writer.output.Write(block); exception != nil {
+		return 0, exception
+	}
+
+	return len(data), nil
+}
+
+// расшифровать остаток и освободить ключ
+func (writer *cspDecryptWriter) finish() error {
+	defer wrapper.ReleaseKey(writer.key)
+
+	block := append([]byte{}, writer.pending...)
+	writer.pending = nil
+
+	if exception := wrapper.DecryptFinalData(writer.key, &block); exception != nil {
+		return exception
+	}
+
+	_, exception := writer.output.Write(block)
+
+	return exception
+}
+
+// зашифровать содержимое и записать EnvelopedData
+func encryptEnvelopedData(content io.Reader, output io.Writer, recipients []*envelopeRecipient, options EnvelopeOptions) error {
+	contentCipher := options.Cipher
+	ivSize := contentCipher.BlockSize / 2
+
+	key := make([]byte, KuznyechikKeySize)
+	ukm := make([]byte, ivSize+8)
+
+	if _, exception := io.ReadFull(options.Random, key); exception != nil {
+		return exception
+	}
+
+	if _, exception := io.ReadFull(options.Random, ukm); exception != nil {
+		return exception
+	}
+
+	var recipientInfos []byte
+	version := 0
+
+	for _, recipient := range recipients {
+		recipientInfo, exception := createRecipientInfo(recipient, key, options)
+
+		if exception != nil {
+			return exception
+		}
+
+		recipientInfos = append(recipientInfos, recipientInfo...)
+	}
+
+	// KeyAgreeRecipientInfo имеет версию 3, поэтому EnvelopedData - версию 2
+	if options.KeyAgreement {
+		version = 2
+	}
+
+	parameters, exception := asn1.Marshal(gost3412Parameters{UKM: ukm})
+
+	if exception != nil {
+		return exception
+	}
+
+	algorithm, exception := asn1.Marshal(AlgorithmIdentifier{Algorithm: contentCipher.OID, Parameters: asn1.RawValue{FullBytes: parameters}})
+
+	if exception != nil {
+		return exception
+	}
+
+	envelopedDataType, _ := asn1.Marshal(OIDEnvelopedData)
+	dataType, _ := asn1.Marshal(OIDData)
+	encodedVersion, _ := asn1.Marshal(version)
+
+	// ContentInfo, [0] EXPLICIT, EnvelopedData, EncryptedContentInfo и [0] IMPLICIT OCTET STRING - неопределенной длины
+	var header bytes.Buffer
+
+	header.Write([]byte{0x30, 0x80})
+	header.Write(envelopedDataType)
+	header.Write([]byte{0xa0, 0x80, 0x30, 0x80})
+	header.Write(encodedVersion)
+	header.Write(encodeDERElement([]byte{0x31}, recipientInfos))
+	header.Write([]byte{0x30, 0x80})
+	header.Write(dataType)
+	header.Write(algorithm)
+	header.Write([]byte{0xa0, 0x80})
+
+	if _, exception := output.Write(header.Bytes()); exception != nil {
+		return exception
+	}
+
+	stream, exception := NewCTRACPKM(contentCipher.NewCipher, key, ukm[:ivSize], contentCipher.SectionSize)
+
+	if exception != nil {
+		return exception
+	}
+
+	buffer := make([]byte, envelopeChunkSize)
+
+	for {
+		count, exception := io.ReadFull(content, buffer)
+
+		if count > 0 {
+			stream.XORKeyStream(buffer[:count], buffer[:count])
+
+			if _, exception := output.Write(encodeDERElement([]byte{asn1.TagOctetString}, buffer[:count])); exception != nil {
+				return exception
+			}
+		}
+
+		if exception == io.EOF || exception == io.ErrUnexpectedEOF {
+			break
+		}
+
+		if exception != nil {
+			return exception
+		}
+	}
+
+	// маркеры конца пяти элементов неопределенной длины
+	_, exception = output.Write(make([]byte, 10))
+
+	return exception
+}
+
+// сформировать информацию о получателе с ключом, экспортированным KExp15
+func createRecipientInfo(recipient *envelopeRecipient, key []byte, options EnvelopeOptions) ([]byte, error) {
+	ephemeral, exception := GeneratePrivateKey(recipient.publicKey.Curve, options.Random)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	ukm := make([]byte, transportUKMSize)
+
+	if _, exception := io.ReadFull(options.Random, ukm); exception != nil {
+		return nil, exception
+	}
+
+	encryptedKey, exception := wrapTransportKey(options.Cipher, ephemeral, recipient.publicKey, ukm, key)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	rid, exception := marshalIssuerAndSerialNumber(recipient.certificate)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	ephemeralKey, exception := MarshalPublicKey(&ephemeral.PublicKey)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	if !options.KeyAgreement {
+		// [0] IMPLICIT SubjectPublicKeyInfo
+		ephemeralKey[0] = 0xa0
+
+		transport, exception := asn1.Marshal(gostKeyTransport{
+			EncryptedKey:       encryptedKey,
+			EphemeralPublicKey: asn1.RawValue{FullBytes: ephemeralKey},
+			UKM:                ukm,
+		})
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		return asn1.Marshal(keyTransRecipientInfo{
+			RID:                    asn1.RawValue{FullBytes: rid},
+			KeyEncryptionAlgorithm: recipient.algorithm,
+			EncryptedKey:           transport,
+		})
+	}
+
+	agreement := OIDAgreementGOST3410_2012_256
+
+	if recipient.publicKey.Curve.PointSize == 64 {
+		agreement = OIDAgreementGOST3410_2012_512
+	}
+
+	wrapAlgorithm, exception := asn1.Marshal(AlgorithmIdentifier{Algorithm: options.Cipher.WrapOID})
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	// originatorKey [1] IMPLICIT OriginatorPublicKey внутри [0] EXPLICIT
+	ephemeralKey[0] = 0xa1
+
+	recipientInfo, exception := asn1.Marshal(keyAgreeRecipientInfo{
+		Version:                3,
+		Originator:             asn1.RawValue{FullBytes: encodeDERElement([]byte{0xa0}, ephemeralKey)},
+		UKM:                    ukm,
+		KeyEncryptionAlgorithm: AlgorithmIdentifier{Algorithm: agreement, Parameters: asn1.RawValue{FullBytes: wrapAlgorithm}},
+		RecipientEncryptedKeys: []recipientEncryptedKey{{RID: asn1.RawValue{FullBytes: rid}, EncryptedKey: encryptedKey}},
+	})
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	// [1] IMPLICIT KeyAgreeRecipientInfo
+	recipientInfo[0] = 0xa1
+
+	return recipientInfo, nil
+}
+
+// выработать ключи экспорта KExp15: KEK = VKO_GOSTR3410_2012_256(UKM[0:16]), K_MAC || K_Exp = KDF_TREE(KEK, UKM[16:24])
+func transportKeys(contentCipher *ContentCipher, privateKey *PrivateKey, publicKey *PublicKey, ukm []byte) (encryption cipher.Block, mac cipher.Block, exception error) {
+	if len(ukm) != transportUKMSize {
+		return nil, nil, errors.New("Неверная длина UKM транспорта ключа")
+	}
+
+	kek, exception := privateKey.VKO(publicKey, ukm[:16], HashGOST3411_2012_256)
+
+	if exception != nil {
+		return nil, nil, exception
+	}
+
+	keys, exception := KDFTree(kek, []byte("kdf tree"), ukm[16:24], 2*KuznyechikKeySize)
+
+	if exception != nil {
+		return nil, nil, exception
+	}
+
+	mac, exception = contentCipher.NewCipher(keys[:KuznyechikKeySize])
+
+	if exception != nil {
+		return nil, nil, exception
+	}
+
+	encryption, exception = contentCipher.NewCipher(keys[KuznyechikKeySize:])
+
+	if exception != nil {
+		return nil, nil, exception
+	}
+
+	return encryption, mac, nil
+}
+
+// экспортировать ключ содержимого, синхропосылка KExp15 берется из UKM[24:]
+func wrapTransportKey(contentCipher *ContentCipher, privateKey *PrivateKey, publicKey *PublicKey, ukm []byte, key []byte) ([]byte, error) {
+	encryption, mac, exception := transportKeys(contentCipher, privateKey, publicKey, ukm)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	return KExp15(encryption, mac, key, ukm[24:24+contentCipher.BlockSize/2])
+}
+
+// импортировать ключ содержимого
+func unwrapTransportKey(contentCipher *ContentCipher, privateKey *PrivateKey, publicKey *PublicKey, ukm []byte, wrapped []byte) ([]byte, error) {
+	encryption, mac, exception := transportKeys(contentCipher, privateKey, publicKey, ukm)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	return KImp15(encryption, mac, wrapped, ukm[24:24+contentCipher.BlockSize/2])
+}
+
+// прочитать EnvelopedData и записать расшифрованное содержимое
+// open по информации о получателях и алгоритму шифрования возвращает запись расшифровки и ее завершение
+func decryptEnvelopedData(message io.Reader, output io.Writer, open func(recipientInfos []asn1.RawValue, algorithm AlgorithmIdentifier, output io.Writer) (io.Writer, func() error, error)) error {
+	reader, exception := openCMSStream(message)
+
+	if exception != nil {
+		return exception
+	}
+
+	// заголовки ContentInfo, [0], EnvelopedData и EncryptedContentInfo закрываются после содержимого
+	enclosing := make([]*berHeader, 0, 4)
+
+	// ContentInfo
+	header, exception := reader.expectHeader(0, asn1.TagSequence)
+
+	if exception != nil {
+		return exception
+	}
+
+	enclosing = append(enclosing, header)
+
+	var contentType asn1.ObjectIdentifier
+
+	if exception := reader.readValue(&contentType); exception != nil {
+		return exception
+	}
+
+	if !contentType.Equal(OIDEnvelopedData) {
+		return errors.New("CMS сообщение не является EnvelopedData")
+	}
+
+	if header, exception = reader.expectHeader(asn1.ClassContextSpecific<<6, 0); exception != nil {
+		return exception
+	}
+
+	enclosing = append(enclosing, header)
+
+	// EnvelopedData
+	if header, exception = reader.expectHeader(0, asn1.TagSequence); exception != nil {
+		return exception
+	}
+
+	enclosing = append(enclosing, header)
+
+	var version int
+
+	if exception := reader.readValue(&version); exception != nil {
+		return exception
+	}
+
+	if header, exception = reader.readHeader(); exception != nil {
+		return exception
+	}
+
+	// originatorInfo не используется
+	if header.is(asn1.ClassContextSpecific<<6, 0) {
+		if _, exception := reader.readContent(header); exception != nil {
+			return exception
+		}
+
+		if header, exception = reader.readHeader(); exception != nil {
+			return exception
+		}
+	}
+
+	if !header.is(0, asn1.TagSet) {
+		return errors.New("Не найдена информация о получателях")
+	}
+
+	encodedRecipientInfos, exception := reader.readContent(header)
+
+	if exception != nil {
+		return exception
+	}
+
+	var recipientInfos []asn1.RawValue
+
+	if _, exception := asn1.UnmarshalWithParams(encodedRecipientInfos, &recipientInfos, "set"); exception != nil {
+		return exception
+	}
+
+	// EncryptedContentInfo
+	if header, exception = reader.expectHeader(0, asn1.TagSequence); exception != nil {
+		return exception
+	}
+
+	enclosing = append(enclosing, header)
+
+	if exception := reader.readValue(&contentType); exception != nil {
+		return exception
+	}
+
+	var algorithm AlgorithmIdentifier
+
+	if exception := reader.readValue(&algorithm); exception != nil {
+		return exception
+	}
+
+	header, exception = reader.readHeader()
+
+	if exception == io.EOF || exception == nil && !header.is(asn1.ClassContextSpecific<<6, 0) {
+		return errors.New("Зашифрованное содержимое отсутствует")
+	}
+
+	if exception != nil {
+		return exception
+	}
+
+	writer, finish, exception := open(recipientInfos, algorithm, output)
+
+	if exception != nil {
+		return exception
+	}
+
+	if exception := reader.copyOctets(writer, header); exception != nil {
+		finish()
+		return exception
+	}
+
+	if exception := finish(); exception != nil {
+		return exception
+	}
+
+	// unprotectedAttrs пропускаются, маркеры конца обязательны для обнаружения обрезанного сообщения
+	for i := len(enclosing) - 1; i >= 0; i-- {
+		if exception := reader.closeElement(enclosing[i]); exception != nil {
+			return exception
+		}
+	}
+
+	return nil
+}
+
+// открыть CMS сообщение: DER и BER читаются потоком, PEM и base64 - целиком
+func openCMSStream(message io.Reader) (*berReader, error) {
+	reader := bufio.NewReader(message)
+	first, exception := reader.Peek(1)
+
+	if exception != nil {
+		return nil, errors.New("Пустое CMS сообщение")
+	}
+
+	if first[0] == 0x30 {
+		return &berReader{reader: reader}, nil
+	}
+
+	data, exception := readCMS(reader)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	return newBERReader(bytes.NewReader(data)), nil
+}
+
+// найти получателя с данным сертификатом и импортировать ключ содержимого
+func decryptContentKey(recipientInfos []asn1.RawValue, algorithm AlgorithmIdentifier, privateKey *PrivateKey, certificate *x509.Certificate) ([]byte, error) {
+	certificates := []*x509.Certificate{certificate}
+
+	for _, recipientInfo := range recipientInfos {
+		switch {
+		case recipientInfo.Class == asn1.ClassUniversal && recipientInfo.Tag == asn1.TagSequence:
+			var keyTransport keyTransRecipientInfo
+
+			if _, exception := asn1.Unmarshal(recipientInfo.FullBytes, &keyTransport); exception != nil {
+				return nil, exception
+			}
+
+			// идентификатор получателя совпадает по форме с идентификатором подписанта
+			if findSignerCertificate(keyTransport.RID, certificates) != nil {
+				return decryptKeyTransport(keyTransport.EncryptedKey, algorithm, privateKey, certificate)
+			}
+		case recipientInfo.Class == asn1.ClassContextSpecific && recipientInfo.Tag == 1:
+			var keyAgreement keyAgreeRecipientInfo
+
+			if _, exception := asn1.Unmarshal(append([]byte{0x30}, recipientInfo.FullBytes[1:]...), &keyAgreement); exception != nil {
+				return nil, exception
+			}
+
+			for _, recipientKey := range keyAgreement.RecipientEncryptedKeys {
+				if findSignerCertificate(recipientKey.RID, certificates) != nil {
+					return decryptKeyAgreement(&keyAgreement, recipientKey.EncryptedKey, privateKey)
+				}
+			}
+		}
+	}
+
+	return nil, errors.New("Сообщение зашифровано не для данного сертификата")
+}
+
+// импортировать ключ из KeyTransRecipientInfo: KExp15 или ГОСТ 28147-89 КриптоПро
+func decryptKeyTransport(encryptedKey []byte, algorithm AlgorithmIdentifier, privateKey *PrivateKey, certificate *x509.Certificate) ([]byte, error) {
+	var transport gostKeyTransport
+
+	if _, exception := asn1.Unmarshal(encryptedKey, &transport); exception == nil {
+		// шифр экспорта ключа совпадает с шифром содержимого
+		contentCipher, exception := FindContentCipher(algorithm.Algorithm)
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		ephemeral, exception := parseEphemeralKey(transport.EphemeralPublicKey.FullBytes, privateKey.Curve)
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		return unwrapTransportKey(contentCipher, privateKey, ephemeral, transport.UKM, transport.EncryptedKey)
+	}
+
+	var legacy gost28147KeyTransport
+
+	if _, exception := asn1.Unmarshal(encryptedKey, &legacy); exception != nil {
+		return nil, errors.New("Неподдерживаемый формат транспорта ключа")
+	}
+
+	parameters := legacy.TransportParameters
+	sbox, exception := FindSBox(parameters.EncryptionParamSet)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	ephemeral, exception := parseEphemeralKey(parameters.EphemeralPublicKey.FullBytes, privateKey.Curve)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	// для ключей ГОСТ Р 34.10-2001 используется VKO_GOSTR3410_2001, для ключей 2012 - VKO_GOSTR3410_2012_256
+	hash := HashGOST3411_2012_256
+	var info subjectPublicKeyInfo
+
+	if _, exception := asn1.Unmarshal(certificate.RawSubjectPublicKeyInfo, &info); exception != nil {
+		return nil, exception
+	}
+
+	if info.Algorithm.Algorithm.Equal(OIDGOST3410_2001) {
+		hash = HashGOST3411
+	}
+
+	kek, exception := privateKey.VKO(ephemeral, parameters.UKM, hash)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	sessionKey := legacy.SessionEncryptedKey
+	wrapped := append(append(append([]byte{}, parameters.UKM...), sessionKey.EncryptedKey...), sessionKey.MACKey...)
+
+	return UnwrapCryptoPro(kek, wrapped, sbox)
+}
+
+// импортировать ключ из KeyAgreeRecipientInfo
+func decryptKeyAgreement(keyAgreement *keyAgreeRecipientInfo, encryptedKey []byte, privateKey *PrivateKey) ([]byte, error) {
+	agreement := keyAgreement.KeyEncryptionAlgorithm
+
+	if !agreement.Algorithm.Equal(OIDAgreementGOST3410_2012_256) && !agreement.Algorithm.Equal(OIDAgreementGOST3410_2012_512) {
+		return nil, errors.New("Неподдерживаемый алгоритм согласования ключа " + agreement.Algorithm.String())
+	}
+
+	var wrapAlgorithm AlgorithmIdentifier
+
+	if _, exception := asn1.Unmarshal(agreement.Parameters.FullBytes, &wrapAlgorithm); exception != nil {
+		return nil, exception
+	}
+
+	contentCipher, exception := findWrapContentCipher(wrapAlgorithm.Algorithm)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	// поддерживается только открытый ключ отправителя originatorKey [1]
+	originator := derContent(keyAgreement.Originator.FullBytes)
+
+	if len(originator) == 0 || originator[0] != 0xa1 {
+		return nil, errors.New("Не найден открытый ключ отправителя")
+	}
+
+	ephemeral, exception := parseEphemeralKey(originator, privateKey.Curve)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	return unwrapTransportKey(contentCipher, privateKey, ephemeral, keyAgreement.UKM, encryptedKey)
+}
+
+// разобрать эфемерный ключ с неявным тегом SubjectPublicKeyInfo, ключ должен лежать на кривой получателя
+func parseEphemeralKey(raw []byte, curve *Curve) (*PublicKey, error) {
+	if len(raw) == 0 {
+		return nil, errors.New("Не найден эфемерный открытый ключ")
+	}
+
+	var info subjectPublicKeyInfo
+
+	if _, exception := asn1.Unmarshal(append([]byte{0x30}, raw[1:]...), &info); exception != nil {
+		return nil, exception
+	}
+
+	var rawKey []byte
+
+	if _, exception := asn1.Unmarshal(info.PublicKey.RightAlign(), &rawKey); exception != nil {
+		return nil, exception
+	}
+
+	return UnmarshalPublicKey(curve, rawKey)
+}
+
+// получить поток шифрования или расшифрования содержимого
+func createContentStream(algorithm AlgorithmIdentifier, key []byte, decrypt bool) (cipher.Stream, error) {
+	if algorithm.Algorithm.Equal(OIDGOST28147) {
+		var parameters gost28147Parameters
+
+		if _, exception := asn1.Unmarshal(algorithm.Parameters.FullBytes, &parameters); exception != nil {
+			return nil, exception
+		}
+
+		sbox, exception := FindSBox(parameters.EncryptionParamSet)
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		// КриптоПро шифрует содержимое в режиме CFB с преобразованием ключа
+		if decrypt {
+			return NewGOST28147CFBDecrypter(key, sbox, parameters.IV, true)
+		}
+
+		return NewGOST28147CFBEncrypter(key, sbox, parameters.IV, true)
+	}
+
+	contentCipher, exception := FindContentCipher(algorithm.Algorithm)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	var parameters gost3412Parameters
+
+	if _, exception := asn1.Unmarshal(algorithm.Parameters.FullBytes, &parameters); exception != nil {
+		return nil, exception
+	}
+
+	ivSize := contentCipher.BlockSize / 2
+
+	if len(parameters.UKM) != ivSize+8 {
+		return nil, errors.New("Неверная длина UKM шифрования содержимого")
+	}
+
+	return NewCTRACPKM(contentCipher.NewCipher, key, parameters.UKM[:ivSize], contentCipher.SectionSize)
+}
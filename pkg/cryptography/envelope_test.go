@@ -0,0 +1,336 @@
+package cryptography
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"io/fs"
+	"os"
+	"testing"
+	"testing/iotest"
+
+	"github.com/madpo/go-gost-crypto/pkg/wrapper"
+)
+
+// зашифровать содержимое для сертификатов получателей
+func encryptTestEnvelope(t *testing.T, certificates []*x509.Certificate, options EnvelopeOptions, content []byte) []byte {
+	release, encrypt, error := CreateCMSEncryptMethod(certificates, options)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer release()
+
+	var output bytes.Buffer
+
+	if error := encrypt(bytes.NewReader(content), &output); error != nil {
+		t.Fatal(error)
+	}
+
+	return output.Bytes()
+}
+
+// расшифровать сообщение закрытым ключом получателя
+func decryptTestEnvelope(privateKey *PrivateKey, certificate *x509.Certificate, message []byte) ([]byte, error) {
+	release, decrypt, error := CreateCMSDecryptMethod(privateKey, certificate)
+
+	if error != nil {
+		return nil, error
+	}
+
+	defer release()
+
+	var output bytes.Buffer
+
+	if error := decrypt(iotest.HalfReader(bytes.NewReader(message)), &output); error != nil {
+		return nil, error
+	}
+
+	return output.Bytes(), nil
+}
+
+func Test_CMSEncryptKeyTransport_Success(t *testing.T) {
+	firstKey, first := createTestGOSTCertificate(t, CurveTC26_256A, "Получатель 256")
+	secondKey, second := createTestGOSTCertificate(t, CurveTC26_512A, "Получатель 512")
+
+	// содержимое больше нескольких фрагментов и секций CTR-ACPKM
+	content := make([]byte, 200*1024+17)
+	rand.Read(content)
+
+	message := encryptTestEnvelope(t, []*x509.Certificate{first, second}, EnvelopeOptions{}, content)
+
+	if bytes.Contains(message, content[:1024]) {
+		t.Error("Ожидалось зашифрованное содержимое")
+	}
+
+	for _, recipient := range []struct {
+		key         *PrivateKey
+		certificate *x509.Certificate
+	}{
+		{firstKey, first},
+		{secondKey, second},
+	} {
+		decrypted, error := decryptTestEnvelope(recipient.key, recipient.certificate, message)
+
+		if error != nil {
+			t.Fatal(error)
+		}
+
+		if !bytes.Equal(decrypted, content) {
+			t.Errorf("%s: ожидалось исходное содержимое длиной %d байт. Получено %d байт", recipient.certificate.Subject.CommonName, len(content), len(decrypted))
+		}
+	}
+
+	// сообщение с определенными длинами расшифровывается так же
+	der, error := berToDER(message)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	decrypted, error := decryptTestEnvelope(firstKey, first, der)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if !bytes.Equal(decrypted, content) {
+		t.Error("Ожидалось исходное содержимое сообщения в DER")
+	}
+}
+
+func Test_CMSEncryptKeyAgreement_Success(t *testing.T) {
+	privateKey, certificate := createTestGOSTCertificate(t, CurveTC26_512C, "Согласование")
+	content := []byte("Hello world")
+
+	message := encryptTestEnvelope(t, []*x509.Certificate{certificate}, EnvelopeOptions{Cipher: ContentCipherMagma, KeyAgreement: true}, content)
+
+	// сообщение в PEM и base64 читается целиком
+	encoded := pem.EncodeToMemory(&pem.Block{Type: "CMS", Bytes: message})
+
+	for _, input := range [][]byte{message, encoded, []byte(base64.StdEncoding.EncodeToString(message))} {
+		decrypted, error := decryptTestEnvelope(privateKey, certificate, input)
+
+		if error != nil {
+			t.Fatal(error)
+		}
+
+		if !bytes.Equal(decrypted, content) {
+			t.Errorf("Ожидалось содержимое %q. Получено %q", content, decrypted)
+		}
+	}
+
+	empty := encryptTestEnvelope(t, []*x509.Certificate{certificate}, EnvelopeOptions{KeyAgreement: true}, nil)
+	decrypted, error := decryptTestEnvelope(privateKey, certificate, empty)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if len(decrypted) != 0 {
+		t.Errorf("Ожидалось пустое содержимое. Получено %d байт", len(decrypted))
+	}
+}
+
+func Test_CMSDecrypt_Rejected(t *testing.T) {
+	privateKey, certificate := createTestGOSTCertificate(t, CurveTC26_256A, "Получатель")
+	otherKey, other := createTestGOSTCertificate(t, CurveTC26_256A, "Другой получатель")
+
+	if _, _, error := CreateCMSDecryptMethod(otherKey, certificate); error == nil {
+		t.Error("Ожидалась ошибка для ключа, не соответствующего сертификату")
+	}
+
+	message := encryptTestEnvelope(t, []*x509.Certificate{certificate}, EnvelopeOptions{}, []byte("Hello world"))
+
+	if _, error := decryptTestEnvelope(otherKey, other, message); error == nil {
+		t.Error("Ожидалась ошибка для сообщения другому получателю")
+	}
+
+	// изменение экспортированного ключа обнаруживается имитовставкой KExp15
+	var envelope struct {
+		ContentType asn1.ObjectIdentifier
+		Content     struct {
+			Version        int
+			RecipientInfos []keyTransRecipientInfo `asn1:"set"`
+			Encrypted      asn1.RawValue
+		} `asn1:"explicit,tag:0"`
+	}
+
+	der, _ := berToDER(message)
+
+	if _, error := asn1.Unmarshal(der, &envelope); error != nil {
+		t.Fatal(error)
+	}
+
+	var transport gostKeyTransport
+
+	if _, error := asn1.Unmarshal(envelope.Content.RecipientInfos[0].EncryptedKey, &transport); error != nil {
+		t.Fatal(error)
+	}
+
+	// изменяется байт самого экспортированного ключа, а не кодирования ASN.1
+	changed := append([]byte{}, der...)
+	changed[bytes.Index(der, transport.EncryptedKey)+len(transport.EncryptedKey)/2] ^= 1
+
+	if _, error := decryptTestEnvelope(privateKey, certificate, changed); error == nil || error.Error() != "Неверная имитовставка экспортированного ключа" {
+		t.Errorf("Ожидалась ошибка имитовставки для измененного экспортированного ключа. Получена %v", error)
+	}
+
+	if _, error := decryptTestEnvelope(privateKey, certificate, message[:len(message)-4]); error == nil {
+		t.Error("Ожидалась ошибка для обрезанного сообщения")
+	}
+
+	_, rsa := createTestRSACertificate(t, "RSA")
+
+	if _, _, error := CreateCMSEncryptMethod([]*x509.Certificate{rsa}, EnvelopeOptions{}); error == nil {
+		t.Error("Ожидалась ошибка для сертификата без ключа ГОСТ Р 34.10-2012")
+	}
+}
+
+func Test_CMSDecryptGOST28147_Success(t *testing.T) {
+	privateKey, certificate := createTestGOSTCertificate(t, CurveCryptoProA, "КриптоПро")
+	ephemeral, _ := GeneratePrivateKey(CurveCryptoProA, nil)
+
+	key := make([]byte, GOST28147KeySize)
+	ukm := make([]byte, KeyWrapUKMSize)
+	iv := make([]byte, 8)
+	rand.Read(key)
+	rand.Read(ukm)
+	rand.Read(iv)
+
+	// сообщение собирается тестом по структуре КриптоПро: транспорт ключа RFC 4490 и содержимое ГОСТ 28147-89 CFB с преобразованием ключа
+	// совместимость с КриптоПро проверяет Test_CMSDecryptCryptcp_Success, тест проверяет разбор этой структуры
+	kek, error := ephemeral.VKO(&privateKey.PublicKey, ukm, HashGOST3411_2012_256)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	wrapped, error := WrapCryptoPro(kek, ukm, key, SBoxCryptoProA)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	ephemeralKey, _ := MarshalPublicKey(&ephemeral.PublicKey)
+	ephemeralKey[0] = 0xa0
+
+	transport, error := asn1.Marshal(gost28147KeyTransport{
+		SessionEncryptedKey: gost28147EncryptedKey{EncryptedKey: wrapped[KeyWrapUKMSize : KeyWrapUKMSize+GOST28147KeySize], MACKey: wrapped[KeyWrapUKMSize+GOST28147KeySize:]},
+		TransportParameters: gostTransportParameters{
+			EncryptionParamSet: OIDGOST28147CryptoProA,
+			EphemeralPublicKey: asn1.RawValue{FullBytes: ephemeralKey},
+			UKM:                ukm,
+		},
+	})
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	rid, _ := marshalIssuerAndSerialNumber(certificate)
+	recipientInfo, _ := asn1.Marshal(keyTransRecipientInfo{
+		RID:                    asn1.RawValue{FullBytes: rid},
+		KeyEncryptionAlgorithm: AlgorithmIdentifier{Algorithm: OIDGOST3410_2012_256},
+		EncryptedKey:           transport,
+	})
+
+	content := bytes.Repeat([]byte("Hello world "), 200)
+	encrypted := make([]byte, len(content))
+	stream, _ := NewGOST28147CFBEncrypter(key, SBoxCryptoProA, iv, true)
+	stream.XORKeyStream(encrypted, content)
+
+	parameters, _ := asn1.Marshal(gost28147Parameters{IV: iv, EncryptionParamSet: OIDGOST28147CryptoProA})
+	algorithm, _ := asn1.Marshal(AlgorithmIdentifier{Algorithm: OIDGOST28147, Parameters: asn1.RawValue{FullBytes: parameters}})
+	envelopedDataType, _ := asn1.Marshal(OIDEnvelopedData)
+	dataType, _ := asn1.Marshal(OIDData)
+	version, _ := asn1.Marshal(0)
+
+	encryptedContentInfo := buildDERElement([]byte{0x30}, [][]byte{dataType, algorithm, encodeDERElement([]byte{0x80}, encrypted)})
+	envelopedData := buildDERElement([]byte{0x30}, [][]byte{version, encodeDERElement([]byte{0x31}, recipientInfo), encryptedContentInfo})
+	message := buildDERElement([]byte{0x30}, [][]byte{envelopedDataType, encodeDERElement([]byte{0xa0}, envelopedData)})
+
+	decrypted, error := decryptTestEnvelope(privateKey, certificate, message)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if !bytes.Equal(decrypted, content) {
+		t.Errorf("Ожидалось содержимое %q. Получено %q", content, decrypted)
+	}
+}
+
+// сообщения cryptcp для сертификата test/envelope/recipient.cer (ключ test/envelope/recipient.key, КриптоПро A):
+//
+//	cryptcp -encr -f recipient.cer -der content.txt gost28147.p7m
+//
+// kuznyechik.p7m формируется той же командой в КриптоПро CSP 5.0 с алгоритмом шифрования ГОСТ Р 34.12-2015 Кузнечик
+func Test_CMSDecryptCryptcp_Success(t *testing.T) {
+	keyData, error := os.ReadFile("../../test/envelope/recipient.key")
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	privateKey, error := ParseGOSTPrivateKey(bytes.NewReader(keyData), nil)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	certificateData, error := os.ReadFile("../../test/envelope/recipient.cer")
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	block, _ := pem.Decode(certificateData)
+	certificate, error := x509.ParseCertificate(block.Bytes)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	content, error := os.ReadFile("../../test/envelope/content.txt")
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	for _, name := range []string{"gost28147.p7m", "kuznyechik.p7m"} {
+		t.Run(name, func(t *testing.T) {
+			message, error := os.ReadFile("../../test/envelope/" + name)
+
+			if errors.Is(error, fs.ErrNotExist) {
+				t.Skipf("Нет сообщения %s, сформированного cryptcp", name)
+			}
+
+			if error != nil {
+				t.Fatal(error)
+			}
+
+			decrypted, error := decryptTestEnvelope(privateKey, certificate, message)
+
+			if error != nil {
+				t.Fatal(error)
+			}
+
+			if !bytes.Equal(decrypted, content) {
+				t.Errorf("Ожидалось содержимое %q. Получено %q", content, decrypted)
+			}
+		})
+	}
+}
+
+func Test_CMSContainerDecrypt_SignatureKey(t *testing.T) {
+	// ключ подписи не вырабатывает ключ согласования, проверка выполняется до обращения к провайдеру
+	if _, _, error := CreateCMSContainerDecryptMethod(&ContainerSigner{keySpec: wrapper.Signature}); error == nil {
+		t.Error("Ожидалась ошибка для ключа подписи контейнера")
+	}
+}
@@ -5,3 +5,7 @@ go 1.20
 require github.com/madpo/go-gost-crypto/pkg/wrapper v1.0.0
 
 replace github.com/madpo/go-gost-crypto/pkg/wrapper => ../wrapper
+
+require github.com/madpo/go-gost-crypto/pkg/c14n v1.0.0
+
+replace github.com/madpo/go-gost-crypto/pkg/c14n => ../c14n
@@ -0,0 +1,269 @@
+package cryptography
+
+import (
+	"crypto/cipher"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	// размер блока ГОСТ 28147-89 в байтах
+	GOST28147BlockSize = 8
+	// размер ключа ГОСТ 28147-89 в байтах
+	GOST28147KeySize = 32
+)
+
+// идентификаторы наборов параметров шифрования ГОСТ 28147-89
+var (
+	OIDGOST28147CryptoProA = asn1.ObjectIdentifier{1, 2, 643, 2, 2, 31, 1}
+	OIDGOST28147CryptoProB = asn1.ObjectIdentifier{1, 2, 643, 2, 2, 31, 2}
+	OIDGOST28147CryptoProC = asn1.ObjectIdentifier{1, 2, 643, 2, 2, 31, 3}
+	OIDGOST28147CryptoProD = asn1.ObjectIdentifier{1, 2, 643, 2, 2, 31, 4}
+	OIDGOST28147TC26Z      = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 2, 5, 1, 1}
+)
+
+// таблицы замен наборов параметров шифрования
+var sboxes = map[string]*SBox{
+	OIDGOST28147CryptoProA.String(): SBoxCryptoProA,
+	OIDGOST28147CryptoProB.String(): SBoxCryptoProB,
+	OIDGOST28147CryptoProC.String(): SBoxCryptoProC,
+	OIDGOST28147CryptoProD.String(): SBoxCryptoProD,
+	OIDGOST28147TC26Z.String():      SBoxTC26Z,
+}
+
+// найти таблицу замен по идентификатору набора параметров шифрования
+func FindSBox(oid asn1.ObjectIdentifier) (*SBox, error) {
+	if sbox, ok := sboxes[oid.String()]; ok {
+		return sbox, nil
+	}
+
+	return nil, errors.New("Не найден набор параметров шифрования ГОСТ 28147-89 " + oid.String())
+}
+
+// объем данных, после обработки которого выполняется преобразование ключа КриптоПро, RFC 4357 п. 2.3.2
+const gost28147MeshingSize = 1024
+
+// константа преобразования ключа КриптоПро
+var gost28147MeshingKey = []byte{
+	0x69, 0x00, 0x72, 0x22, 0x64, 0xc9, 0x04, 0x23, 0x8d, 0x3a, 0xdb, 0x96, 0x46, 0xe9, 0x2a, 0xc4,
+	0x18, 0xfe, 0xac, 0x94, 0x00, 0xed, 0x07, 0x12, 0xc0, 0x86, 0xdc, 0xc2, 0xef, 0x4c, 0xa9, 0x2b,
+}
+
+/*
+Блочный шифр ГОСТ 28147-89
+совпадает с Магмой, но ключ и блоки записываются в порядке little-endian, как в КриптоПро
+*/
+type gost28147Cipher struct {
+	core *magmaCipher
+}
+
+// создать блочный шифр ГОСТ 28147-89 в режиме простой замены
+// при sbox = nil используется таблица замен КриптоПро A
+func NewGOST28147(key []byte, sbox *SBox) (cipher.Block, error) {
+	if sbox == nil {
+		sbox = SBoxCryptoProA
+	}
+
+	return newGOST28147(key, sbox)
+}
+
+// создать шифр ГОСТ 28147-89 с заданной таблицей замен
+func newGOST28147(key []byte, sbox *SBox) (*gost28147Cipher, error) {
+	if len(key) != GOST28147KeySize {
+		return nil, errors.New("Неверная длина ключа ГОСТ 28147-89")
+	}
+
+	table, exception := createMagmaTable(sbox)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	core := &magmaCipher{table: table}
+
+	for i := range core.keys {
+		core.keys[i] = binary.LittleEndian.Uint32(key[4*i:])
+	}
+
+	return &gost28147Cipher{core: core}, nil
+}
+
+func (block *gost28147Cipher) BlockSize() int {
+	return GOST28147BlockSize
+}
+
+// накопители N1 и N2 - младшая и старшая половины блока
+func (block *gost28147Cipher) Encrypt(dst, src []byte) {
+	checkBlockBuffers(dst, src, GOST28147BlockSize)
+
+	n2, n1 := block.core.encrypt(binary.LittleEndian.Uint32(src[4:]), binary.LittleEndian.Uint32(src))
+
+	binary.LittleEndian.PutUint32(dst, n1)
+	binary.LittleEndian.PutUint32(dst[4:], n2)
+}
+
+func (block *gost28147Cipher) Decrypt(dst, src []byte) {
+	checkBlockBuffers(dst, src, GOST28147BlockSize)
+
+	n2, n1 := block.core.decrypt(binary.LittleEndian.Uint32(src[4:]), binary.LittleEndian.Uint32(src))
+
+	binary.LittleEndian.PutUint32(dst, n1)
+	binary.LittleEndian.PutUint32(dst[4:], n2)
+}
+
+// выполнить цикл 16-З режима выработки имитовставки
+func (block *gost28147Cipher) encryptMAC(dst, src []byte) {
+	n1 := binary.LittleEndian.Uint32(src)
+	n2 := binary.LittleEndian.Uint32(src[4:])
+
+	for i := 0; i < 16; i++ {
+		n1, n2 = n2^block.core.round(n1, block.core.keys[i%8]), n1
+	}
+
+	// в отличие от цикла 32-З все 16 шагов переставляют накопители, результат - N1 || N2
+	binary.LittleEndian.PutUint32(dst, n1)
+	binary.LittleEndian.PutUint32(dst[4:], n2)
+}
+
+// преобразовать ключ и синхропосылку: K' = D_K(C), IV' = E_K'(IV)
+func (block *gost28147Cipher) mesh(register []byte) *gost28147Cipher {
+	key := make([]byte, GOST28147KeySize)
+
+	for i := 0; i < len(key); i += GOST28147BlockSize {
+		block.Decrypt(key[i:], gost28147MeshingKey[i:])
+	}
+
+	meshed := &gost28147Cipher{core: &magmaCipher{table: block.core.table}}
+
+	for i := range meshed.core.keys {
+		meshed.core.keys[i] = binary.LittleEndian.Uint32(key[4*i:])
+	}
+
+	meshed.Encrypt(register, register)
+
+	return meshed
+}
+
+/*
+Поток ГОСТ 28147-89 в режимах гаммирования и гаммирования с обратной связью
+*/
+type gost28147Stream struct {
+	block *gost28147Cipher
+	// синхропосылка CFB или накопители N3, N4 режима гаммирования
+	register []byte
+	gamma    []byte
+	// шифртекст текущего блока для обратной связи CFB
+	ciphertext []byte
+	offset     int
+	// число байт, обработанных текущим ключом, -1 до первого блока
+	processed int
+	meshing   bool
+	feedback  bool
+	decrypt   bool
+}
+
+// получить режим гаммирования с обратной связью ГОСТ 28147-89 для зашифрования
+// meshing включает преобразование ключа КриптоПро каждые 1024 байта
+func NewGOST28147CFBEncrypter(key []byte, sbox *SBox, iv []byte, meshing bool) (cipher.Stream, error) {
+	return newGOST28147Stream(key, sbox, iv, meshing, true, false)
+}
+
+// получить режим гаммирования с обратной связью ГОСТ 28147-89 для расшифрования
+func NewGOST28147CFBDecrypter(key []byte, sbox *SBox, iv []byte, meshing bool) (cipher.Stream, error) {
+	return newGOST28147Stream(key, sbox, iv, meshing, true, true)
+}
+
+// получить режим гаммирования ГОСТ 28147-89
+func NewGOST28147CNT(key []byte, sbox *SBox, iv []byte, meshing bool) (cipher.Stream, error) {
+	return newGOST28147Stream(key, sbox, iv, meshing, false, false)
+}
+
+func newGOST28147Stream(key []byte, sbox *SBox, iv []byte, meshing bool, feedback bool, decrypt bool) (cipher.Stream, error) {
+	if sbox == nil {
+		sbox = SBoxCryptoProA
+	}
+
+	block, exception := newGOST28147(key, sbox)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	if len(iv) != GOST28147BlockSize {
+		return nil, errors.New("Неверная длина синхропосылки")
+	}
+
+	return &gost28147Stream{
+		block:      block,
+		register:   append([]byte{}, iv...),
+		gamma:      make([]byte, GOST28147BlockSize),
+		ciphertext: make([]byte, GOST28147BlockSize),
+		processed:  -1,
+		meshing:    meshing,
+		feedback:   feedback,
+		decrypt:    decrypt,
+	}, nil
+}
+
+func (stream *gost28147Stream) XORKeyStream(dst, src []byte) {
+	checkStreamBuffers(dst, src)
+
+	for i := range src {
+		if stream.offset == 0 {
+			stream.nextGamma()
+		}
+
+		value := src[i]
+		dst[i] = value ^ stream.gamma[stream.offset]
+
+		if !stream.decrypt {
+			value = dst[i]
+		}
+
+		stream.ciphertext[stream.offset] = value
+		stream.offset++
+
+		if stream.offset == GOST28147BlockSize {
+			if stream.feedback {
+				copy(stream.register, stream.ciphertext)
+			}
+
+			stream.offset = 0
+		}
+	}
+}
+
+// выработать следующий блок гаммы
+func (stream *gost28147Stream) nextGamma() {
+	if stream.meshing && stream.processed == gost28147MeshingSize {
+		stream.block = stream.block.mesh(stream.register)
+		stream.processed = 0
+	}
+
+	if stream.processed < 0 {
+		stream.processed = 0
+
+		// накопители режима гаммирования заполняются зашифрованной синхропосылкой
+		if !stream.feedback {
+			stream.block.Encrypt(stream.register, stream.register)
+		}
+	}
+
+	if !stream.feedback {
+		// N3 = N3 + C2 mod 2^32, N4 = N4 + C1 mod (2^32 - 1)
+		n3 := binary.LittleEndian.Uint32(stream.register) + 0x01010101
+		n4 := binary.LittleEndian.Uint32(stream.register[4:])
+		sum := n4 + 0x01010104
+
+		if sum < n4 {
+			sum++
+		}
+
+		binary.LittleEndian.PutUint32(stream.register, n3)
+		binary.LittleEndian.PutUint32(stream.register[4:], sum)
+	}
+
+	stream.block.Encrypt(stream.gamma, stream.register)
+	stream.processed += GOST28147BlockSize
+}
@@ -0,0 +1,212 @@
+package cryptography
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+// развернуть порядок байт
+func reverseTestBytes(data []byte) []byte {
+	result := make([]byte, len(data))
+
+	for i := range data {
+		result[len(data)-1-i] = data[i]
+	}
+
+	return result
+}
+
+func Test_GOST28147_Success(t *testing.T) {
+	// ГОСТ 28147-89 с таблицей param-Z совпадает с Магмой при обратном порядке байт слов ключа и блока
+	magmaKey, _ := hex.DecodeString(testMagmaKey)
+	key := make([]byte, GOST28147KeySize)
+
+	for i := 0; i < len(key); i += 4 {
+		copy(key[i:i+4], reverseTestBytes(magmaKey[i:i+4]))
+	}
+
+	block, error := NewGOST28147(key, SBoxTC26Z)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	plaintext, _ := hex.DecodeString(testMagmaPlaintext)
+	ciphertext := make([]byte, GOST28147BlockSize)
+
+	block.Encrypt(ciphertext, reverseTestBytes(plaintext))
+
+	if result := hex.EncodeToString(reverseTestBytes(ciphertext)); result != testMagmaCiphertext {
+		t.Errorf("Ожидался шифртекст %s. Получен %s", testMagmaCiphertext, result)
+	}
+
+	block.Decrypt(ciphertext, ciphertext)
+
+	if !bytes.Equal(reverseTestBytes(ciphertext), plaintext) {
+		t.Errorf("Ожидался открытый текст %s. Получен %x", testMagmaPlaintext, reverseTestBytes(ciphertext))
+	}
+
+	for _, sbox := range []*SBox{nil, SBoxCryptoProA, SBoxCryptoProB, SBoxCryptoProC, SBoxCryptoProD} {
+		if _, error := NewGOST28147(key, sbox); error != nil {
+			t.Error(error)
+		}
+	}
+}
+
+func Test_GOST28147Meshing_Success(t *testing.T) {
+	key, _ := hex.DecodeString(testMagmaKey)
+	iv, _ := hex.DecodeString("0102030405060708")
+	data := bytes.Repeat([]byte("Архив ГОСТ 28147-89 "), 150)
+
+	for _, example := range []struct {
+		name    string
+		encrypt func(meshing bool) (cipher.Stream, error)
+		decrypt func(meshing bool) (cipher.Stream, error)
+	}{
+		{
+			"CFB",
+			func(meshing bool) (cipher.Stream, error) { return NewGOST28147CFBEncrypter(key, nil, iv, meshing) },
+			func(meshing bool) (cipher.Stream, error) { return NewGOST28147CFBDecrypter(key, nil, iv, meshing) },
+		},
+		{
+			"CNT",
+			func(meshing bool) (cipher.Stream, error) { return NewGOST28147CNT(key, nil, iv, meshing) },
+			func(meshing bool) (cipher.Stream, error) { return NewGOST28147CNT(key, nil, iv, meshing) },
+		},
+	} {
+		meshed, error := example.encrypt(true)
+
+		if error != nil {
+			t.Fatal(error)
+		}
+
+		plain, _ := example.encrypt(false)
+
+		withMeshing := make([]byte, len(data))
+		withoutMeshing := make([]byte, len(data))
+
+		meshed.XORKeyStream(withMeshing, data)
+		plain.XORKeyStream(withoutMeshing, data)
+
+		// ключ преобразуется только после первых 1024 байт
+		if !bytes.Equal(withMeshing[:1024], withoutMeshing[:1024]) || bytes.Equal(withMeshing[1024:1032], withoutMeshing[1024:1032]) {
+			t.Errorf("%s: ожидалось преобразование ключа после 1024 байт", example.name)
+		}
+
+		decrypter, _ := example.decrypt(true)
+		result := make([]byte, len(data))
+
+		for offset := 0; offset < len(data); offset += 100 {
+			end := offset + 100
+
+			if end > len(data) {
+				end = len(data)
+			}
+
+			decrypter.XORKeyStream(result[offset:end], withMeshing[offset:end])
+		}
+
+		if !bytes.Equal(result, data) {
+			t.Errorf("%s: ожидалось совпадение расшифрованных данных", example.name)
+		}
+	}
+
+	if _, error := NewGOST28147CNT(key, nil, iv[:4], true); error == nil {
+		t.Error("Ожидалась ошибка для синхропосылки неверной длины")
+	}
+}
+
+func Test_GOST28147MeshingVectors_Success(t *testing.T) {
+	key, _ := hex.DecodeString(testMagmaKey)
+	iv, _ := hex.DecodeString("0102030405060708")
+
+	// три секции по 1024 байта, последняя неполная: преобразование ключа выполняется дважды
+	data := make([]byte, 2*gost28147MeshingSize+37)
+
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	// значения вычислены независимой реализацией CFB и CNT с преобразованием ключа КриптоПро в соглашениях gost-engine,
+	// таблица замен КриптоПро A; проверяются блоки на границах секций и SHA-256 всего шифртекста
+	for _, example := range []struct {
+		name    string
+		meshing bool
+		create  func(meshing bool) (cipher.Stream, error)
+		first   string
+		second  string
+		digest  string
+	}{
+		{
+			"CFB", false, func(meshing bool) (cipher.Stream, error) { return NewGOST28147CFBEncrypter(key, nil, iv, meshing) },
+			"b9554a477d4a54a3a1f12d6e505f69fd", "1aaa43ef47426ba0bf95e2f985444696",
+			"41c3684b282eea1f4c7ff47b8cc76ed3675485a376ed1affeb9678d257b50c20",
+		},
+		{
+			"CFB", true, func(meshing bool) (cipher.Stream, error) { return NewGOST28147CFBEncrypter(key, nil, iv, meshing) },
+			"b9554a477d4a54a3a79bf85ec319dc46", "c1e1949f9d7d8502750d21389f6757cb",
+			"471be71e1ec23f32341aea4f371c413bbde089fd1493ac308809adf8071ce0ca",
+		},
+		{
+			"CNT", false, func(meshing bool) (cipher.Stream, error) { return NewGOST28147CNT(key, nil, iv, meshing) },
+			"615f7fd783e5af646ce2de852fb9e08e", "d87e961a6882393237146192d482255a",
+			"d4734345b9b1058f01a3ca49fdfcc8ea05472509f1e6f5d936a7f6a8dbe1c490",
+		},
+		{
+			"CNT", true, func(meshing bool) (cipher.Stream, error) { return NewGOST28147CNT(key, nil, iv, meshing) },
+			"615f7fd783e5af64148d2ed9794b8797", "0c0cfc1e1e07f8182f42bd505a05d7f9",
+			"ab22784a403e12262c3042bbe3dc1e507cb0f0a11b85a05b24593a8e83680ff0",
+		},
+	} {
+		stream, error := example.create(example.meshing)
+
+		if error != nil {
+			t.Fatal(error)
+		}
+
+		result := make([]byte, len(data))
+		stream.XORKeyStream(result, data)
+
+		for _, window := range []struct {
+			offset int
+			want   string
+		}{
+			{gost28147MeshingSize - 8, example.first},
+			{2*gost28147MeshingSize - 8, example.second},
+		} {
+			if value := hex.EncodeToString(result[window.offset : window.offset+16]); value != window.want {
+				t.Errorf("%s, преобразование ключа %v: ожидался шифртекст %s со смещения %d. Получен %s", example.name, example.meshing, window.want, window.offset, value)
+			}
+		}
+
+		if digest := sha256.Sum256(result); hex.EncodeToString(digest[:]) != example.digest {
+			t.Errorf("%s, преобразование ключа %v: ожидался SHA-256 шифртекста %s. Получен %x", example.name, example.meshing, example.digest, digest)
+		}
+	}
+}
+
+func Test_GOST28147ECB_Success(t *testing.T) {
+	key, _ := hex.DecodeString(testMagmaKey)
+	block, _ := NewGOST28147(key, SBoxCryptoProA)
+	data := PadProcedure1([]byte("Ключ КриптоПро"), GOST28147BlockSize)
+
+	encrypted := make([]byte, len(data))
+	NewECBEncrypter(block).CryptBlocks(encrypted, data)
+
+	// блоки простой замены шифруются независимо
+	single := make([]byte, GOST28147BlockSize)
+	block.Encrypt(single, data[GOST28147BlockSize:])
+
+	if !bytes.Equal(single, encrypted[GOST28147BlockSize:2*GOST28147BlockSize]) {
+		t.Error("Ожидалось независимое шифрование блоков")
+	}
+
+	NewECBDecrypter(block).CryptBlocks(encrypted, encrypted)
+
+	if !bytes.Equal(encrypted, data) {
+		t.Error("Ожидалось совпадение расшифрованных данных")
+	}
+}
@@ -0,0 +1,764 @@
+package cryptography
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/asn1"
+	"errors"
+	"io"
+	"math/big"
+)
+
+/*
+Эллиптическая кривая ГОСТ Р 34.10 в форме Вейерштрасса
+*/
+type Curve struct {
+	Name string
+	OID  asn1.ObjectIdentifier
+
+	// модуль поля
+	P *big.Int
+	// порядок подгруппы
+	Q *big.Int
+	// коэффициенты уравнения y^2 = x^3 + ax + b
+	A *big.Int
+	B *big.Int
+	// базовая точка
+	X *big.Int
+	Y *big.Int
+	// кофактор m/q, отношение порядка группы точек к порядку подгруппы
+	Cofactor *big.Int
+
+	// размер координаты в байтах
+	PointSize int
+}
+
+/*
+Открытый ключ ГОСТ Р 34.10
+*/
+type PublicKey struct {
+	Curve *Curve
+	X     *big.Int
+	Y     *big.Int
+}
+
+/*
+Закрытый ключ ГОСТ Р 34.10
+*/
+type PrivateKey struct {
+	PublicKey
+	D *big.Int
+}
+
+/*
+Параметры подписания, алгоритм хэширования ГОСТ не входит в crypto.Hash
+*/
+type SignerOptions struct {
+	Hash *HashAlgorithm
+}
+
+func (options *SignerOptions) HashFunc() crypto.Hash {
+	return 0
+}
+
+func hexToBig(value string) *big.Int {
+	result, ok := new(big.Int).SetString(value, 16)
+
+	if !ok {
+		panic("Некорректный параметр кривой " + value)
+	}
+
+	return result
+}
+
+var (
+	// id-GostR3410-2001-TestParamSet, используется в контрольных примерах стандарта
+	CurveGOST3410_2001_Test = &Curve{
+		Name:      "id-GostR3410-2001-TestParamSet",
+		OID:       asn1.ObjectIdentifier{1, 2, 643, 2, 2, 35, 0},
+		P:         hexToBig("8000000000000000000000000000000000000000000000000000000000000431"),
+		Q:         hexToBig("8000000000000000000000000000000150FE8A1892976154C59CFC193ACCF5B3"),
+		A:         hexToBig("07"),
+		B:         hexToBig("5FBFF498AA938CE739B8E022FBAFEF40563F6E6A3472FC2A514C0CE9DAE23B7E"),
+		X:         hexToBig("02"),
+		Y:         hexToBig("08E2A8A0E65147D4BD6316030E16D19C85C97F0A9CA267122B96ABBCEA7E8FC8"),
+		Cofactor:  hexToBig("01"),
+		PointSize: 32,
+	}
+
+	// id-GostR3410-2001-CryptoPro-A-ParamSet
+	CurveCryptoProA = &Curve{
+		Name:      "id-GostR3410-2001-CryptoPro-A-ParamSet",
+		OID:       asn1.ObjectIdentifier{1, 2, 643, 2, 2, 35, 1},
+		P:         hexToBig("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFD97"),
+		Q:         hexToBig("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF6C611070995AD10045841B09B761B893"),
+		A:         hexToBig("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFD94"),
+		B:         hexToBig("A6"),
+		X:         hexToBig("01"),
+		Y:         hexToBig("8D91E471E0989CDA27DF505A453F2B7635294F2DDF23E3B122ACC99C9E9F1E14"),
+		Cofactor:  hexToBig("01"),
+		PointSize: 32,
+	}
+
+	// id-GostR3410-2001-CryptoPro-B-ParamSet
+	CurveCryptoProB = &Curve{
+		Name:      "id-GostR3410-2001-CryptoPro-B-ParamSet",
+		OID:       asn1.ObjectIdentifier{1, 2, 643, 2, 2, 35, 2},
+		P:         hexToBig("8000000000000000000000000000000000000000000000000000000000000C99"),
+		Q:         hexToBig("800000000000000000000000000000015F700CFFF1A624E5E497161BCC8A198F"),
+		A:         hexToBig("8000000000000000000000000000000000000000000000000000000000000C96"),
+		B:         hexToBig("3E1AF419A269A5F866A7D3C25C3DF80AE979259373FF2B182F49D4CE7E1BBC8B"),
+		X:         hexToBig("01"),
+		Y:         hexToBig("3FA8124359F96680B83D1C3EB2C070E5C545C9858D03ECFB744BF8D717717EFC"),
+		Cofactor:  hexToBig("01"),
+		PointSize: 32,
+	}
+
+	// id-GostR3410-2001-CryptoPro-C-ParamSet
+	CurveCryptoProC = &Curve{
+		Name:      "id-GostR3410-2001-CryptoPro-C-ParamSet",
+		OID:       asn1.ObjectIdentifier{1, 2, 643, 2, 2, 35, 3},
+		P:         hexToBig("9B9F605F5A858107AB1EC85E6B41C8AACF846E86789051D37998F7B9022D759B"),
+		Q:         hexToBig("9B9F605F5A858107AB1EC85E6B41C8AA582CA3511EDDFB74F02F3A6598980BB9"),
+		A:         hexToBig("9B9F605F5A858107AB1EC85E6B41C8AACF846E86789051D37998F7B9022D7598"),
+		B:         hexToBig("805A"),
+		X:         hexToBig("00"),
+		Y:         hexToBig("41ECE55743711A8C3CBF3783CD08C0EE4D4DC440D4641A8F366E550DFDB3BB67"),
+		Cofactor:  hexToBig("01"),
+		PointSize: 32,
+	}
+
+	// id-tc26-gost-3410-2012-256-paramSetA
+	CurveTC26_256A = &Curve{
+		Name:      "id-tc26-gost-3410-2012-256-paramSetA",
+		OID:       asn1.ObjectIdentifier{1, 2, 643, 7, 1, 2, 1, 1, 1},
+		P:         hexToBig("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFD97"),
+		Q:         hexToBig("400000000000000000000000000000000FD8CDDFC87B6635C115AF556C360C67"),
+		A:         hexToBig("C2173F1513981673AF4892C23035A27CE25E2013BF95AA33B22C656F277E7335"),
+		B:         hexToBig("295F9BAE7428ED9CCC20E7C359A9D41A22FCCD9108E17BF7BA9337A6F8AE9513"),
+		X:         hexToBig("91E38443A5E82C0D880923425712B2BB658B9196932E02C78B2582FE742DAA28"),
+		Y:         hexToBig("32879423AB1A0375895786C4BB46E9565FDE0B5344766740AF268ADB32322E5C"),
+		Cofactor:  hexToBig("04"),
+		PointSize: 32,
+	}
+
+	// id-tc26-gost-3410-12-512-paramSetA
+	CurveTC26_512A = &Curve{
+		Name:      "id-tc26-gost-3410-12-512-paramSetA",
+		OID:       asn1.ObjectIdentifier{1, 2, 643, 7, 1, 2, 1, 2, 1},
+		P:         hexToBig("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFDC7"),
+		Q:         hexToBig("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF27E69532F48D89116FF22B8D4E0560609B4B38ABFAD2B85DCACDB1411F10B275"),
+		A:         hexToBig("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFDC4"),
+		B:         hexToBig("E8C2505DEDFC86DDC1BD0B2B6667F1DA34B82574761CB0E879BD081CFD0B6265EE3CB090F30D27614CB4574010DA90DD862EF9D4EBEE4761503190785A71C760"),
+		X:         hexToBig("03"),
+		Y:         hexToBig("7503CFE87A836AE3A61B8816E25450E6CE5E1C93ACF1ABC1778064FDCBEFA921DF1626BE4FD036E93D75E6A50E3A41E98028FE5FC235F5B889A589CB5215F2A4"),
+		Cofactor:  hexToBig("01"),
+		PointSize: 64,
+	}
+
+	// id-tc26-gost-3410-12-512-paramSetB
+	CurveTC26_512B = &Curve{
+		Name:      "id-tc26-gost-3410-12-512-paramSetB",
+		OID:       asn1.ObjectIdentifier{1, 2, 643, 7, 1, 2, 1, 2, 2},
+		P:         hexToBig("8000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000006F"),
+		Q:         hexToBig("800000000000000000000000000000000000000000000000000000000000000149A1EC142565A545ACFDB77BD9D40CFA8B996712101BEA0EC6346C54374F25BD"),
+		A:         hexToBig("8000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000006C"),
+		B:         hexToBig("687D1B459DC841457E3E06CF6F5E2517B97C7D614AF138BCBF85DC806C4B289F3E965D2DB1416D217F8B276FAD1AB69C50F78BEE1FA3106EFB8CCBC7C5140116"),
+		X:         hexToBig("02"),
+		Y:         hexToBig("1A8F7EDA389B094C2C071E3647A8940F3C123B697578C213BE6DD9E6C8EC7335DCB228FD1EDF4A39152CBCAAF8C0398828041055F94CEEEC7E21340780FE41BD"),
+		Cofactor:  hexToBig("01"),
+		PointSize: 64,
+	}
+
+	// id-tc26-gost-3410-2012-512-paramSetC
+	CurveTC26_512C = &Curve{
+		Name:      "id-tc26-gost-3410-2012-512-paramSetC",
+		OID:       asn1.ObjectIdentifier{1, 2, 643, 7, 1, 2, 1, 2, 3},
+		P:         hexToBig("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFDC7"),
+		Q:         hexToBig("3FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFC98CDBA46506AB004C33A9FF5147502CC8EDA9E7A769A12694623CEF47F023ED"),
+		A:         hexToBig("DC9203E514A721875485A529D2C722FB187BC8980EB866644DE41C68E143064546E861C0E2C9EDD92ADE71F46FCF50FF2AD97F951FDA9F2A2EB6546F39689BD3"),
+		B:         hexToBig("B4C4EE28CEBC6C2C8AC12952CF37F16AC7EFB6A9F69F4B57FFDA2E4F0DE5ADE038CBC2FFF719D2C18DE0284B8BFEF3B52B8CC7A5F5BF0A3C8D2319A5312557E1"),
+		X:         hexToBig("E2E31EDFC23DE7BDEBE241CE593EF5DE2295B7A9CBAEF021D385F7074CEA043AA27272A7AE602BF2A7B9033DB9ED3610C6FB85487EAE97AAC5BC7928C1950148"),
+		Y:         hexToBig("F5CE40D95B5EB899ABBCCFF5911CB8577939804D6527378B8C108C3D2090FF9BE18E2D33E3021ED2EF32D85822423B6304F726AA854BAE07D0396E9A9ADDC40F"),
+		Cofactor:  hexToBig("04"),
+		PointSize: 64,
+	}
+)
+
+// синонимы наборов параметров
+var curveAliases = map[string]*Curve{
+	// id-GostR3410-2001-CryptoPro-XchA-ParamSet
+	"1.2.643.2.2.36.0": CurveCryptoProA,
+	// id-GostR3410-2001-CryptoPro-XchB-ParamSet
+	"1.2.643.2.2.36.1": CurveCryptoProC,
+	// id-tc26-gost-3410-2012-256-paramSetB
+	"1.2.643.7.1.2.1.1.2": CurveCryptoProA,
+	// id-tc26-gost-3410-2012-256-paramSetC
+	"1.2.643.7.1.2.1.1.3": CurveCryptoProB,
+	// id-tc26-gost-3410-2012-256-paramSetD
+	"1.2.643.7.1.2.1.1.4": CurveCryptoProC,
+}
+
+var curves = []*Curve{
+	CurveGOST3410_2001_Test,
+	CurveCryptoProA,
+	CurveCryptoProB,
+	CurveCryptoProC,
+	CurveTC26_256A,
+	CurveTC26_512A,
+	CurveTC26_512B,
+	CurveTC26_512C,
+}
+
+// найти кривую по идентификатору набора параметров
+func FindCurve(oid asn1.ObjectIdentifier) (*Curve, error) {
+	for _, curve := range curves {
+		if curve.OID.Equal(oid) {
+			return curve, nil
+		}
+	}
+
+	if curve, ok := curveAliases[oid.String()]; ok {
+		return curve, nil
+	}
+
+	return nil, errors.New("Не найден набор параметров эллиптической кривой " + oid.String())
+}
+
+// принадлежит ли точка кривой
+func (curve *Curve) IsOnCurve(x, y *big.Int) bool {
+	if x.Sign() < 0 || x.Cmp(curve.P) >= 0 || y.Sign() < 0 || y.Cmp(curve.P) >= 0 {
+		return false
+	}
+
+	left := new(big.Int).Mul(y, y)
+	left.Mod(left, curve.P)
+
+	right := new(big.Int).Mul(x, x)
+	right.Add(right, curve.A)
+	right.Mul(right, x)
+	right.Add(right, curve.B)
+	right.Mod(right, curve.P)
+
+	return left.Cmp(right) == 0
+}
+
+// сложение точек, бесконечно удаленная точка представлена nil
+func (curve *Curve) add(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	if x1 == nil {
+		return x2, y2
+	}
+
+	if x2 == nil {
+		return x1, y1
+	}
+
+	lambda := new(big.Int)
+
+	if x1.Cmp(x2) == 0 {
+		sum := new(big.Int).Add(y1, y2)
+
+		if sum.Mod(sum, curve.P).Sign() == 0 {
+			return nil, nil
+		}
+
+		// удвоение: (3x^2 + a) / 2y
+		lambda.Mul(x1, x1)
+		lambda.Mul(lambda, big.NewInt(3))
+		lambda.Add(lambda, curve.A)
+
+		denominator := new(big.Int).Lsh(y1, 1)
+		denominator.ModInverse(denominator.Mod(denominator, curve.P), curve.P)
+		lambda.Mul(lambda, denominator)
+	} else {
+		// сложение: (y2 - y1) / (x2 - x1)
+		lambda.Sub(y2, y1)
+
+		denominator := new(big.Int).Sub(x2, x1)
+		denominator.ModInverse(denominator.Mod(denominator, curve.P), curve.P)
+		lambda.Mul(lambda, denominator)
+	}
+
+	lambda.Mod(lambda, curve.P)
+
+	x3 := new(big.Int).Mul(lambda, lambda)
+	x3.Sub(x3, x1)
+	x3.Sub(x3, x2)
+	x3.Mod(x3, curve.P)
+
+	y3 := new(big.Int).Sub(x1, x3)
+	y3.Mul(y3, lambda)
+	y3.Sub(y3, y1)
+	y3.Mod(y3, curve.P)
+
+	return x3, y3
+}
+
+/*
+Точка кривой в координатах Якоби: x = X/Z^2, y = Y/Z^3, бесконечно удаленная точка имеет Z = 0
+*/
+type jacobianPoint struct {
+	x, y, z *big.Int
+}
+
+// удвоение точки в координатах Якоби
+func (curve *Curve) doubleJacobian(point *jacobianPoint) *jacobianPoint {
+	if point.z.Sign() == 0 || point.y.Sign() == 0 {
+		return &jacobianPoint{big.NewInt(1), big.NewInt(1), new(big.Int)}
+	}
+
+	xx := new(big.Int).Mul(point.x, point.x)
+	yy := new(big.Int).Mul(point.y, point.y)
+	yy.Mod(yy, curve.P)
+	zz := new(big.Int).Mul(point.z, point.z)
+	zz.Mod(zz, curve.P)
+
+	// S = 4 X Y^2, M = 3 X^2 + a Z^4
+	s := new(big.Int).Mul(point.x, yy)
+	s.Lsh(s, 2)
+	s.Mod(s, curve.P)
+
+	m := new(big.Int).Mul(zz, zz)
+	m.Mul(m, curve.A)
+	m.Add(m, xx.Mul(xx, big.NewInt(3)))
+	m.Mod(m, curve.P)
+
+	x := new(big.Int).Mul(m, m)
+	x.Sub(x, new(big.Int).Lsh(s, 1))
+	x.Mod(x, curve.P)
+
+	y := new(big.Int).Sub(s, x)
+	y.Mul(y, m)
+	y.Sub(y, yy.Lsh(yy.Mul(yy, yy), 3))
+	y.Mod(y, curve.P)
+
+	z := new(big.Int).Mul(point.y, point.z)
+	z.Lsh(z, 1)
+	z.Mod(z, curve.P)
+
+	return &jacobianPoint{x, y, z}
+}
+
+// сложение точек в координатах Якоби
+// совпадающие и противоположные точки при умножении на скаляр встречаются только для вырожденных значений
+func (curve *Curve) addJacobian(first, second *jacobianPoint) *jacobianPoint {
+	if first.z.Sign() == 0 {
+		return second
+	}
+
+	if second.z.Sign() == 0 {
+		return first
+	}
+
+	z1z1 := new(big.Int).Mul(first.z, first.z)
+	z1z1.Mod(z1z1, curve.P)
+	z2z2 := new(big.Int).Mul(second.z, second.z)
+	z2z2.Mod(z2z2, curve.P)
+
+	u1 := new(big.Int).Mul(first.x, z2z2)
+	u1.Mod(u1, curve.P)
+	u2 := new(big.Int).Mul(second.x, z1z1)
+	u2.Mod(u2, curve.P)
+
+	s1 := new(big.Int).Mul(first.y, second.z)
+	s1.Mul(s1, z2z2)
+	s1.Mod(s1, curve.P)
+	s2 := new(big.Int).Mul(second.y, first.z)
+	s2.Mul(s2, z1z1)
+	s2.Mod(s2, curve.P)
+
+	h := new(big.Int).Sub(u2, u1)
+	h.Mod(h, curve.P)
+	r := new(big.Int).Sub(s2, s1)
+	r.Mod(r, curve.P)
+
+	if h.Sign() == 0 {
+		if r.Sign() == 0 {
+			return curve.doubleJacobian(first)
+		}
+
+		return &jacobianPoint{big.NewInt(1), big.NewInt(1), new(big.Int)}
+	}
+
+	hh := new(big.Int).Mul(h, h)
+	hh.Mod(hh, curve.P)
+	hhh := new(big.Int).Mul(h, hh)
+	hhh.Mod(hhh, curve.P)
+	v := new(big.Int).Mul(u1, hh)
+	v.Mod(v, curve.P)
+
+	x := new(big.Int).Mul(r, r)
+	x.Sub(x, hhh)
+	x.Sub(x, new(big.Int).Lsh(v, 1))
+	x.Mod(x, curve.P)
+
+	y := new(big.Int).Sub(v, x)
+	y.Mul(y, r)
+	y.Sub(y, s1.Mul(s1, hhh))
+	y.Mod(y, curve.P)
+
+	z := new(big.Int).Mul(first.z, second.z)
+	z.Mul(z, h)
+	z.Mod(z, curve.P)
+
+	return &jacobianPoint{x, y, z}
+}
+
+// поменять местами точки при swap = 1, выбор выполняется маской по байтам координат фиксированного размера
+func (curve *Curve) conditionalSwap(swap byte, first, second *jacobianPoint, buffer []byte) {
+	size := curve.PointSize
+	mask := -swap
+
+	for _, pair := range [3][2]*big.Int{{first.x, second.x}, {first.y, second.y}, {first.z, second.z}} {
+		a, b := pair[0].FillBytes(buffer[:size]), pair[1].FillBytes(buffer[size:2*size])
+
+		for i := range a {
+			value := mask & (a[i] ^ b[i])
+			a[i] ^= value
+			b[i] ^= value
+		}
+
+		pair[0].SetBytes(a)
+		pair[1].SetBytes(b)
+	}
+}
+
+/*
+Умножение точки на скаляр лестницей Монтгомери
+
+к скаляру добавляется порядок группы точек m = Cofactor * Q или 2m, чтобы длина скаляра не зависела от его значения,
+число итераций и последовательность операций одинаковы для всех скаляров, точки выбираются маской без ветвлений
+*/
+func (curve *Curve) ScalarMult(x, y, k *big.Int) (*big.Int, *big.Int) {
+	order := new(big.Int).Mul(curve.Cofactor, curve.Q)
+	bits := order.BitLen() + 1
+
+	scalar := new(big.Int).Mod(k, order)
+	scalar.Add(scalar, order)
+
+	// k + m или k + 2m длиной bits, выбор по старшему биту k + m
+	padded := new(big.Int).Add(scalar, order)
+	buffer := make([]byte, 2*(bits/8+1))
+	first, second := scalar.FillBytes(buffer[:len(buffer)/2]), padded.FillBytes(buffer[len(buffer)/2:])
+	subtle.ConstantTimeCopy(1-int(scalar.Bit(bits-1)), first, second)
+	scalar.SetBytes(first)
+
+	// R0 = P, R1 = 2P, старший бит скаляра равен 1
+	r0 := &jacobianPoint{new(big.Int).Set(x), new(big.Int).Set(y), big.NewInt(1)}
+	r1 := curve.doubleJacobian(r0)
+	swapBuffer := make([]byte, 2*curve.PointSize)
+	var swap byte
+
+	for i := bits - 2; i >= 0; i-- {
+		bit := byte(scalar.Bit(i))
+		curve.conditionalSwap(swap^bit, r0, r1, swapBuffer)
+		swap = bit
+
+		r1 = curve.addJacobian(r0, r1)
+		r0 = curve.doubleJacobian(r0)
+	}
+
+	curve.conditionalSwap(swap, r0, r1, swapBuffer)
+
+	if r0.z.Sign() == 0 {
+		return nil, nil
+	}
+
+	zInverse := new(big.Int).ModInverse(r0.z, curve.P)
+	zz := new(big.Int).Mul(zInverse, zInverse)
+	zz.Mod(zz, curve.P)
+
+	resultX := new(big.Int).Mul(r0.x, zz)
+	resultX.Mod(resultX, curve.P)
+
+	resultY := new(big.Int).Mul(r0.y, zz.Mul(zz, zInverse))
+	resultY.Mod(resultY, curve.P)
+
+	return resultX, resultY
+}
+
+// умножение базовой точки на скаляр лестницей Монтгомери
+func (curve *Curve) ScalarBaseMult(k *big.Int) (*big.Int, *big.Int) {
+	return curve.ScalarMult(curve.X, curve.Y, k)
+}
+
+// умножение точки на открытый скаляр, время выполнения зависит от скаляра
+// используется только при проверке подписи и открытого ключа
+func (curve *Curve) scalarMultVartime(x, y, k *big.Int) (*big.Int, *big.Int) {
+	var resultX, resultY *big.Int
+
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		resultX, resultY = curve.add(resultX, resultY, resultX, resultY)
+
+		if k.Bit(i) == 1 {
+			resultX, resultY = curve.add(resultX, resultY, x, y)
+		}
+	}
+
+	return resultX, resultY
+}
+
+// преобразовать little-endian последовательность байт в число
+func littleEndianToBig(data []byte) *big.Int {
+	return new(big.Int).SetBytes(reverseBytes(data))
+}
+
+// преобразовать число в little-endian последовательность байт заданного размера
+func bigToLittleEndian(value *big.Int, size int) []byte {
+	return reverseBytes(value.FillBytes(make([]byte, size)))
+}
+
+// перевернуть порядок байт, исходный массив не изменяется
+func reverseBytes(data []byte) []byte {
+	result := make([]byte, len(data))
+
+	for i, value := range data {
+		result[len(data)-1-i] = value
+	}
+
+	return result
+}
+
+// получить открытый ключ из little-endian представления X||Y
+func UnmarshalPublicKey(curve *Curve, raw []byte) (*PublicKey, error) {
+	if len(raw) != 2*curve.PointSize {
+		return nil, errors.New("Некорректный размер открытого ключа")
+	}
+
+	key := &PublicKey{
+		Curve: curve,
+		X:     littleEndianToBig(raw[:curve.PointSize]),
+		Y:     littleEndianToBig(raw[curve.PointSize:]),
+	}
+
+	if !curve.IsOnCurve(key.X, key.Y) {
+		return nil, errors.New("Точка открытого ключа не принадлежит кривой")
+	}
+
+	return key, nil
+}
+
+// получить little-endian представление X||Y открытого ключа
+func (key *PublicKey) Raw() []byte {
+	return append(bigToLittleEndian(key.X, key.Curve.PointSize), bigToLittleEndian(key.Y, key.Curve.PointSize)...)
+}
+
+// проверить подпись s||r (big-endian) над хэшем, хэш передается в порядке байт КриптоПро
+func (key *PublicKey) VerifyDigest(digest []byte, signature []byte) bool {
+	curve := key.Curve
+
+	if len(signature) != 2*curve.PointSize {
+		return false
+	}
+
+	s := new(big.Int).SetBytes(signature[:curve.PointSize])
+	r := new(big.Int).SetBytes(signature[curve.PointSize:])
+
+	if r.Sign() <= 0 || r.Cmp(curve.Q) >= 0 || s.Sign() <= 0 || s.Cmp(curve.Q) >= 0 {
+		return false
+	}
+
+	e := littleEndianToBig(digest)
+	e.Mod(e, curve.Q)
+
+	if e.Sign() == 0 {
+		e.SetInt64(1)
+	}
+
+	v := new(big.Int).ModInverse(e, curve.Q)
+
+	z1 := new(big.Int).Mul(s, v)
+	z1.Mod(z1, curve.Q)
+
+	z2 := new(big.Int).Mul(r, v)
+	z2.Neg(z2)
+	z2.Mod(z2, curve.Q)
+
+	x1, y1 := curve.scalarMultVartime(curve.X, curve.Y, z1)
+	x2, y2 := curve.scalarMultVartime(key.X, key.Y, z2)
+	x, _ := curve.add(x1, y1, x2, y2)
+
+	if x == nil {
+		return false
+	}
+
+	return new(big.Int).Mod(x, curve.Q).Cmp(r) == 0
+}
+
+/*
+Параметры открытого ключа ГОСТ Р 34.10
+*/
+type PublicKeyParameters struct {
+	PublicKeyParamSet  asn1.ObjectIdentifier
+	DigestParamSet     asn1.ObjectIdentifier `asn1:"optional"`
+	EncryptionParamSet asn1.ObjectIdentifier `asn1:"optional"`
+}
+
+type subjectPublicKeyInfo struct {
+	Algorithm AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// разобрать открытый ключ ГОСТ Р 34.10 из SubjectPublicKeyInfo
+func ParsePublicKey(raw []byte) (*PublicKey, error) {
+	var info subjectPublicKeyInfo
+
+	if _, exception := asn1.Unmarshal(raw, &info); exception != nil {
+		return nil, exception
+	}
+
+	algorithm := info.Algorithm.Algorithm
+
+	if !algorithm.Equal(OIDGOST3410_2001) && !algorithm.Equal(OIDGOST3410_2012_256) && !algorithm.Equal(OIDGOST3410_2012_512) {
+		return nil, errors.New("Открытый ключ не является ключом ГОСТ Р 34.10: " + algorithm.String())
+	}
+
+	var parameters PublicKeyParameters
+
+	if _, exception := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &parameters); exception != nil {
+		return nil, exception
+	}
+
+	curve, exception := FindCurve(parameters.PublicKeyParamSet)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	var rawKey []byte
+
+	if _, exception := asn1.Unmarshal(info.PublicKey.RightAlign(), &rawKey); exception != nil {
+		return nil, exception
+	}
+
+	return UnmarshalPublicKey(curve, rawKey)
+}
+
+// алгоритм и параметры открытого ключа ГОСТ Р 34.10-2012 на кривой
+func publicKeyParameters(curve *Curve) (asn1.ObjectIdentifier, PublicKeyParameters) {
+	if curve.PointSize == 64 {
+		return OIDGOST3410_2012_512, PublicKeyParameters{PublicKeyParamSet: curve.OID}
+	}
+
+	return OIDGOST3410_2012_256, PublicKeyParameters{PublicKeyParamSet: curve.OID, DigestParamSet: OIDGOST3411_2012_256}
+}
+
+// сформировать открытый ключ в SubjectPublicKeyInfo
+func MarshalPublicKey(key *PublicKey) ([]byte, error) {
+	algorithm, parameters := publicKeyParameters(key.Curve)
+
+	encodedParameters, exception := asn1.Marshal(parameters)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	encodedKey, exception := asn1.Marshal(key.Raw())
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	return asn1.Marshal(subjectPublicKeyInfo{
+		Algorithm: AlgorithmIdentifier{Algorithm: algorithm, Parameters: asn1.RawValue{FullBytes: encodedParameters}},
+		PublicKey: asn1.BitString{Bytes: encodedKey, BitLength: 8 * len(encodedKey)},
+	})
+}
+
+// сгенерировать закрытый ключ
+func GeneratePrivateKey(curve *Curve, random io.Reader) (*PrivateKey, error) {
+	if random == nil {
+		random = rand.Reader
+	}
+
+	d, exception := randomScalar(curve, random)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	return NewPrivateKey(curve, d)
+}
+
+// получить закрытый ключ по значению d
+func NewPrivateKey(curve *Curve, d *big.Int) (*PrivateKey, error) {
+	if d.Sign() <= 0 || d.Cmp(curve.Q) >= 0 {
+		return nil, errors.New("Некорректное значение закрытого ключа")
+	}
+
+	x, y := curve.ScalarBaseMult(d)
+
+	return &PrivateKey{PublicKey: PublicKey{Curve: curve, X: x, Y: y}, D: d}, nil
+}
+
+// случайное число из интервала (0, q)
+func randomScalar(curve *Curve, random io.Reader) (*big.Int, error) {
+	max := new(big.Int).Sub(curve.Q, big.NewInt(1))
+
+	value, exception := rand.Int(random, max)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	return value.Add(value, big.NewInt(1)), nil
+}
+
+// открытый ключ, реализация crypto.Signer
+func (key *PrivateKey) Public() crypto.PublicKey {
+	return &key.PublicKey
+}
+
+// подписать хэш, реализация crypto.Signer
+func (key *PrivateKey) Sign(random io.Reader, digest []byte, options crypto.SignerOpts) ([]byte, error) {
+	return key.SignDigest(digest, random)
+}
+
+// подписать хэш в порядке байт КриптоПро, подпись возвращается как s||r (big-endian)
+func (key *PrivateKey) SignDigest(digest []byte, random io.Reader) ([]byte, error) {
+	if random == nil {
+		random = rand.Reader
+	}
+
+	for {
+		k, exception := randomScalar(key.Curve, random)
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		if signature := key.signDigest(digest, k); signature != nil {
+			return signature, nil
+		}
+	}
+}
+
+// подписать хэш с заданным k, nil если k не подходит
+func (key *PrivateKey) signDigest(digest []byte, k *big.Int) []byte {
+	curve := key.Curve
+
+	e := littleEndianToBig(digest)
+	e.Mod(e, curve.Q)
+
+	if e.Sign() == 0 {
+		e.SetInt64(1)
+	}
+
+	x, _ := curve.ScalarBaseMult(k)
+	r := new(big.Int).Mod(x, curve.Q)
+
+	if r.Sign() == 0 {
+		return nil
+	}
+
+	s := new(big.Int).Mul(r, key.D)
+	s.Add(s, new(big.Int).Mul(k, e))
+	s.Mod(s, curve.Q)
+
+	if s.Sign() == 0 {
+		return nil
+	}
+
+	return append(s.FillBytes(make([]byte, curve.PointSize)), r.FillBytes(make([]byte, curve.PointSize))...)
+}
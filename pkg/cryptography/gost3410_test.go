@@ -0,0 +1,128 @@
+package cryptography
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+func Test_Curves_Success(t *testing.T) {
+	for _, curve := range curves {
+		if !curve.IsOnCurve(curve.X, curve.Y) {
+			t.Errorf("Базовая точка не принадлежит кривой %s", curve.Name)
+		}
+
+		if x, _ := curve.ScalarBaseMult(curve.Q); x != nil {
+			t.Errorf("Порядок базовой точки кривой %s не равен q", curve.Name)
+		}
+	}
+}
+
+func Test_ScalarMult_Success(t *testing.T) {
+	for _, curve := range curves {
+		order := new(big.Int).Mul(curve.Cofactor, curve.Q)
+		random, _ := rand.Int(rand.Reader, curve.Q)
+
+		for _, k := range []*big.Int{big.NewInt(0), big.NewInt(1), big.NewInt(2), random, new(big.Int).Sub(curve.Q, big.NewInt(1)), curve.Q, order, new(big.Int).Add(order, big.NewInt(5))} {
+			x, y := curve.ScalarBaseMult(k)
+			wantX, wantY := curve.scalarMultVartime(curve.X, curve.Y, k)
+
+			if (x == nil) != (wantX == nil) || x != nil && (x.Cmp(wantX) != 0 || y.Cmp(wantY) != 0) {
+				t.Errorf("%s: ожидалась точка %v * G, совпадающая с умножением сложением и удвоением", curve.Name, k)
+			}
+		}
+	}
+}
+
+// контрольный пример ГОСТ Р 34.10-2012, приложение А.1
+func Test_GOST3410VerifyDigest_Success(t *testing.T) {
+	curve := CurveGOST3410_2001_Test
+
+	publicKey := &PublicKey{
+		Curve: curve,
+		X:     hexToBig("7F2B49E270DB6D90D8595BEC458B50C58585BA1D4E9B788F6689DBD8E56FD80B"),
+		Y:     hexToBig("26F1B489D6701DD185C8413A977B3CBBAF64D1C593D26627DFFB101A87FF77DA"),
+	}
+
+	e := hexToBig("2DFBC1B372D89A1188C09C52E0EEC61FCE52032AB1022E8E67ECE6672B043EE5")
+	r := hexToBig("41AA28D2F1AB148280CD9ED56FEDA41974053554A42767B83AD043FD39DC0493")
+	s := hexToBig("01456C64BA4642A1653C235A98A60249BCD6D3F746B631DF928014F6C5BF9C40")
+
+	digest := bigToLittleEndian(e, curve.PointSize)
+	signature := append(s.FillBytes(make([]byte, curve.PointSize)), r.FillBytes(make([]byte, curve.PointSize))...)
+
+	if !publicKey.VerifyDigest(digest, signature) {
+		t.Error("Ожидалась верная подпись контрольного примера")
+	}
+
+	digest[0] ^= 1
+
+	if publicKey.VerifyDigest(digest, signature) {
+		t.Error("Ожидалась неверная подпись для измененного хэша")
+	}
+}
+
+func Test_UnmarshalPublicKey_Success(t *testing.T) {
+	curve := CurveGOST3410_2001_Test
+
+	publicKey := &PublicKey{
+		Curve: curve,
+		X:     hexToBig("7F2B49E270DB6D90D8595BEC458B50C58585BA1D4E9B788F6689DBD8E56FD80B"),
+		Y:     hexToBig("26F1B489D6701DD185C8413A977B3CBBAF64D1C593D26627DFFB101A87FF77DA"),
+	}
+
+	result, error := UnmarshalPublicKey(curve, publicKey.Raw())
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if result.X.Cmp(publicKey.X) != 0 || result.Y.Cmp(publicKey.Y) != 0 {
+		t.Error("Открытый ключ не совпадает после преобразования")
+	}
+
+	raw := publicKey.Raw()
+	raw[0] ^= 1
+
+	if _, error := UnmarshalPublicKey(curve, raw); error == nil {
+		t.Error("Ожидалась ошибка для точки вне кривой")
+	}
+}
+
+// контрольный пример ГОСТ Р 34.10-2012, приложение А.1
+func Test_GOST3410SignDigest_Success(t *testing.T) {
+	curve := CurveGOST3410_2001_Test
+
+	privateKey, error := NewPrivateKey(curve, hexToBig("7A929ADE789BB9BE10ED359DD39A72C11B60961F49397EEE1D19CE9891EC3B28"))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	wantX := hexToBig("7F2B49E270DB6D90D8595BEC458B50C58585BA1D4E9B788F6689DBD8E56FD80B")
+
+	if privateKey.X.Cmp(wantX) != 0 {
+		t.Errorf("Ожидалась координата открытого ключа %X. Получена %X", wantX, privateKey.X)
+	}
+
+	digest := bigToLittleEndian(hexToBig("2DFBC1B372D89A1188C09C52E0EEC61FCE52032AB1022E8E67ECE6672B043EE5"), curve.PointSize)
+	signature := privateKey.signDigest(digest, hexToBig("77105C9B20BCD3122823C8CF6FCC7B956DE33814E95B7FE64FED924594DCEAB3"))
+
+	result := hex.EncodeToString(signature)
+	want := "01456c64ba4642a1653c235a98a60249bcd6d3f746b631df928014f6c5bf9c40" + "41aa28d2f1ab148280cd9ed56feda41974053554a42767b83ad043fd39dc0493"
+
+	if result != want {
+		t.Errorf("Ожидалась подпись %s. Получена %s", want, result)
+	}
+
+	signature, error = privateKey.SignDigest(digest, nil)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if !privateKey.PublicKey.VerifyDigest(digest, signature) {
+		t.Error("Ожидалась верная подпись")
+	}
+}
@@ -0,0 +1,114 @@
+package cryptography
+
+import (
+	"crypto/hmac"
+	"errors"
+	"hash"
+)
+
+// размер блока функции сжатия ГОСТ Р 34.11-2012 в байтах
+const gost3411BlockSize = 64
+
+// максимальное число итераций PBKDF2 при разборе и создании ключей и файлов
+const pbkdf2MaxIterations = 1 << 20
+
+// создать HMAC (RFC 2104) с алгоритмом хэширования ГОСТ Р 34.11-2012, Р 50.1.113-2016 п. 4.1.1
+// хэш вычисляется в Go без криптопровайдера: ключ HMAC не передается в CSP
+func newHMAC(algorithm *HashAlgorithm, key []byte) (hash.Hash, error) {
+	if algorithm != HashGOST3411_2012_256 && algorithm != HashGOST3411_2012_512 {
+		return nil, errors.New("HMAC вычисляется только с хэшем ГОСТ Р 34.11-2012")
+	}
+
+	return hmac.New(func() hash.Hash { return newStreebog(algorithm.Size) }, key), nil
+}
+
+// вычислить HMAC с алгоритмом хэширования ГОСТ Р 34.11-2012
+func calculateHMAC(algorithm *HashAlgorithm, key []byte, data []byte) ([]byte, error) {
+	mac, exception := newHMAC(algorithm, key)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	mac.Write(data)
+
+	return mac.Sum(nil), nil
+}
+
+// выработать ключевой материал KDF_TREE_GOSTR3411_2012_256 длиной length байт, Р 50.1.113-2016 п. 4.5
+// K(i) = HMAC_256(key, [i]_1 || label || 0x00 || seed || [L]), длина L задается в битах
+func KDFTree(key []byte, label []byte, seed []byte, length int) ([]byte, error) {
+	size := HashGOST3411_2012_256.Size
+
+	if length <= 0 || length > 255*size {
+		return nil, errors.New("Неверная длина ключевого материала KDF_TREE")
+	}
+
+	var bits []byte
+
+	for value := 8 * length; value > 0; value >>= 8 {
+		bits = append([]byte{byte(value)}, bits...)
+	}
+
+	result := make([]byte, 0, length+size)
+
+	for i := 1; len(result) < length; i++ {
+		data := append([]byte{byte(i)}, label...)
+		data = append(data, 0x00)
+		data = append(data, seed...)
+		data = append(data, bits...)
+
+		value, exception := calculateHMAC(HashGOST3411_2012_256, key, data)
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		result = append(result, value...)
+	}
+
+	return result[:length], nil
+}
+
+// выработать ключ из пароля PBKDF2 (RFC 8018) с HMAC ГОСТ Р 34.11-2012, Р 50.1.111-2016
+func PBKDF2(password []byte, salt []byte, iterations int, length int, algorithm *HashAlgorithm) ([]byte, error) {
+	if algorithm != HashGOST3411_2012_256 && algorithm != HashGOST3411_2012_512 {
+		return nil, errors.New("PBKDF2 вычисляется только с HMAC ГОСТ Р 34.11-2012")
+	}
+
+	if iterations <= 0 || iterations > pbkdf2MaxIterations || length <= 0 {
+		return nil, errors.New("Неверные параметры PBKDF2")
+	}
+
+	mac, exception := newHMAC(algorithm, password)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	result := make([]byte, 0, length+algorithm.Size)
+	value := make([]byte, 0, algorithm.Size)
+
+	for i := uint32(1); len(result) < length; i++ {
+		mac.Reset()
+		mac.Write(salt)
+		mac.Write([]byte{byte(i >> 24), byte(i >> 16), byte(i >> 8), byte(i)})
+		value = mac.Sum(value[:0])
+
+		block := append([]byte{}, value...)
+
+		for j := 1; j < iterations; j++ {
+			mac.Reset()
+			mac.Write(value)
+			value = mac.Sum(value[:0])
+
+			for k := range block {
+				block[k] ^= value[k]
+			}
+		}
+
+		result = append(result, block...)
+	}
+
+	return result[:length], nil
+}
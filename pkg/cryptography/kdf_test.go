@@ -0,0 +1,104 @@
+package cryptography
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// ключ, метка и порождающее значение примеров RFC 7836, приложение A.1
+var (
+	testKDFKey, _   = hex.DecodeString("000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f")
+	testKDFLabel, _ = hex.DecodeString("26bdb878")
+	testKDFSeed, _  = hex.DecodeString("af21434145656378")
+)
+
+func Test_HMACGOST3411_Success(t *testing.T) {
+	data, _ := hex.DecodeString("0126bdb87800af214341456563780100")
+
+	for _, example := range []struct {
+		hash *HashAlgorithm
+		want string
+	}{
+		{HashGOST3411_2012_256, "a1aa5f7de402d7b3d323f2991c8d4534013137010a83754fd0af6d7cd4922ed9"},
+		{HashGOST3411_2012_512, "a59bab22ecae19c65fbde6e5f4e9f5d8549d31f037f9df9b905500e171923a773d5f1530f2ed7e964cb2eedc29e9ad2f3afe93b2814f79f5000ffc0366c251e6"},
+	} {
+		value, error := calculateHMAC(example.hash, testKDFKey, data)
+
+		if error != nil {
+			t.Fatal(error)
+		}
+
+		if result := hex.EncodeToString(value); result != example.want {
+			t.Errorf("%s: ожидался HMAC %s. Получен %s", example.hash.Name, example.want, result)
+		}
+	}
+
+	// KDF_256 совпадает с HMAC_256 от того же блока данных
+	value, error := KDFTree(testKDFKey, testKDFLabel, testKDFSeed, 32)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if result, want := hex.EncodeToString(value), "a1aa5f7de402d7b3d323f2991c8d4534013137010a83754fd0af6d7cd4922ed9"; result != want {
+		t.Errorf("Ожидался ключ %s. Получен %s", want, result)
+	}
+}
+
+func Test_KDFTree_Success(t *testing.T) {
+	value, error := KDFTree(testKDFKey, testKDFLabel, testKDFSeed, 64)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	// длина L входит в данные каждого блока: 512 бит = 0x0200
+	for i, offset := range []int{0, 32} {
+		data := append([]byte{byte(i + 1)}, testKDFLabel...)
+		data = append(data, 0x00)
+		data = append(data, testKDFSeed...)
+		data = append(data, 0x02, 0x00)
+
+		block, _ := calculateHMAC(HashGOST3411_2012_256, testKDFKey, data)
+
+		if !bytes.Equal(value[offset:offset+32], block) {
+			t.Errorf("Ожидался блок %d ключевого материала %x. Получен %x", i+1, block, value[offset:offset+32])
+		}
+	}
+
+	short, _ := KDFTree(testKDFKey, testKDFLabel, testKDFSeed, 32)
+
+	if bytes.Equal(short, value[:32]) {
+		t.Error("Ожидался другой ключевой материал для другой длины")
+	}
+
+	if _, error := KDFTree(testKDFKey, testKDFLabel, testKDFSeed, 0); error == nil {
+		t.Error("Ожидалась ошибка для нулевой длины")
+	}
+}
+
+func Test_PBKDF2Vectors_Success(t *testing.T) {
+	// Р 50.1.111-2016, приложение: PBKDF2 с HMAC ГОСТ Р 34.11-2012 (512)
+	for _, example := range []struct {
+		password   string
+		salt       string
+		iterations int
+		want       string
+	}{
+		{"password", "salt", 1, "64770af7f748c3b1c9ac831dbcfd85c26111b30a8a657ddc3056b80ca73e040d2854fd36811f6d825cc4ab66ec0a68a490a9e5cf5156b3a2b7eecddbf9a16b47"},
+		{"password", "salt", 2, "5a585bafdfbb6e8830d6d68aa3b43ac00d2e4aebce01c9b31c2caed56f0236d4d34b2b8fbd2c4e89d54d46f50e47d45bbac301571743119e8d3c42ba66d348de"},
+		{"password", "salt", 4096, "e52deb9a2d2aaff4e2ac9d47a41f34c20376591c67807f0477e32549dc341bc7867c09841b6d58e29d0347c996301d55df0d34e47cf68f4e3c2cdaf1d9ab86c3"},
+		{"passwordPASSWORDpassword", "saltSALTsaltSALTsaltSALTsaltSALTsalt", 4096, "b2d8f1245fc4d29274802057e4b54e0a0753aa22fc53760b301cf008679e58fe4bee9addcae99ba2b0b20f431a9c5e50f395c89387d0945aedeca6eb4015dfc2bd2421ee9bb71183ba882ceebfef259f33f9e27dc6178cb89dc37428cf9cc52a2baa2d3a"},
+	} {
+		value, error := PBKDF2([]byte(example.password), []byte(example.salt), example.iterations, len(example.want)/2, HashGOST3411_2012_512)
+
+		if error != nil {
+			t.Fatal(error)
+		}
+
+		if result := hex.EncodeToString(value); result != example.want {
+			t.Errorf("%d итераций: ожидался ключ %s. Получен %s", example.iterations, example.want, result)
+		}
+	}
+}
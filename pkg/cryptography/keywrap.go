@@ -0,0 +1,231 @@
+package cryptography
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+)
+
+// экспорт и импорт ключей: KExp15/KImp15 (Р 1323565.1.017-2018), ГОСТ 28147-89 и КриптоПро (RFC 4357 п. 6)
+
+const (
+	// длина UKM экспорта ключей ГОСТ 28147-89 и КриптоПро
+	KeyWrapUKMSize = 8
+	// длина экспортированного ключа ГОСТ 28147-89 и КриптоПро: UKM, зашифрованный ключ и имитовставка
+	KeyWrapSize = KeyWrapUKMSize + GOST28147KeySize + 4
+)
+
+// экспортировать ключ KExp15: CTR_{K_Exp}(IV, K || OMAC_{K_MAC}(IV || K))
+// encryption и mac - шифры на ключах K_Exp и K_MAC, синхропосылка имеет длину половины блока
+func KExp15(encryption cipher.Block, mac cipher.Block, key []byte, iv []byte) ([]byte, error) {
+	if encryption.BlockSize() != mac.BlockSize() {
+		return nil, errors.New("Размеры блоков шифров экспорта ключа не совпадают")
+	}
+
+	omac, exception := NewOMAC(mac, 0)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	stream, exception := NewCTR(encryption, iv, encryption.BlockSize())
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	omac.Write(iv)
+	omac.Write(key)
+
+	result := omac.Sum(append([]byte{}, key...))
+	stream.XORKeyStream(result, result)
+
+	return result, nil
+}
+
+// импортировать ключ KImp15, имитовставка проверяется до возврата ключа
+func KImp15(encryption cipher.Block, mac cipher.Block, wrapped []byte, iv []byte) ([]byte, error) {
+	if encryption.BlockSize() != mac.BlockSize() {
+		return nil, errors.New("Размеры блоков шифров экспорта ключа не совпадают")
+	}
+
+	blockSize := encryption.BlockSize()
+
+	if len(wrapped) <= blockSize {
+		return nil, errors.New("Длина экспортированного ключа меньше длины имитовставки")
+	}
+
+	omac, exception := NewOMAC(mac, 0)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	stream, exception := NewCTR(encryption, iv, blockSize)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	result := make([]byte, len(wrapped))
+	stream.XORKeyStream(result, wrapped)
+
+	key, tag := result[:len(result)-blockSize], result[len(result)-blockSize:]
+
+	omac.Write(iv)
+	omac.Write(key)
+
+	if subtle.ConstantTimeCompare(tag, omac.Sum(nil)) != 1 {
+		return nil, errors.New("Неверная имитовставка экспортированного ключа")
+	}
+
+	return key, nil
+}
+
+// экспортировать ключ ГОСТ 28147-89, RFC 4357 п. 6.1
+// результат - UKM || ECB_KEK(CEK) || MAC_KEK(UKM, CEK); при sbox = nil используется таблица замен КриптоПро A
+func WrapGOST28147(kek []byte, ukm []byte, cek []byte, sbox *SBox) ([]byte, error) {
+	if sbox == nil {
+		sbox = SBoxCryptoProA
+	}
+
+	if len(ukm) != KeyWrapUKMSize {
+		return nil, errors.New("Неверная длина UKM")
+	}
+
+	if len(cek) != GOST28147KeySize {
+		return nil, errors.New("Неверная длина экспортируемого ключа")
+	}
+
+	block, exception := newGOST28147(kek, sbox)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	mac, exception := NewGOST28147MAC(kek, sbox, ukm)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	result := make([]byte, KeyWrapUKMSize+GOST28147KeySize, KeyWrapSize)
+	copy(result, ukm)
+	NewECBEncrypter(block).CryptBlocks(result[KeyWrapUKMSize:], cek)
+
+	mac.Write(cek)
+
+	return mac.Sum(result), nil
+}
+
+// импортировать ключ ГОСТ 28147-89, RFC 4357 п. 6.2
+func UnwrapGOST28147(kek []byte, wrapped []byte, sbox *SBox) ([]byte, error) {
+	if sbox == nil {
+		sbox = SBoxCryptoProA
+	}
+
+	if len(wrapped) != KeyWrapSize {
+		return nil, errors.New("Неверная длина экспортированного ключа")
+	}
+
+	block, exception := newGOST28147(kek, sbox)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	ukm := wrapped[:KeyWrapUKMSize]
+	mac, exception := NewGOST28147MAC(kek, sbox, ukm)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	cek := make([]byte, GOST28147KeySize)
+	NewECBDecrypter(block).CryptBlocks(cek, wrapped[KeyWrapUKMSize:KeyWrapUKMSize+GOST28147KeySize])
+
+	mac.Write(cek)
+
+	if subtle.ConstantTimeCompare(wrapped[KeyWrapUKMSize+GOST28147KeySize:], mac.Sum(nil)) != 1 {
+		return nil, errors.New("Неверная имитовставка экспортированного ключа")
+	}
+
+	return cek, nil
+}
+
+// экспортировать ключ КриптоПро, RFC 4357 п. 6.3: экспорт ГОСТ 28147-89 на диверсифицированном KEK
+func WrapCryptoPro(kek []byte, ukm []byte, cek []byte, sbox *SBox) ([]byte, error) {
+	if sbox == nil {
+		sbox = SBoxCryptoProA
+	}
+
+	diversified, exception := diversifyCryptoPro(kek, ukm, sbox)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	return WrapGOST28147(diversified, ukm, cek, sbox)
+}
+
+// импортировать ключ КриптоПро, RFC 4357 п. 6.4
+func UnwrapCryptoPro(kek []byte, wrapped []byte, sbox *SBox) ([]byte, error) {
+	if sbox == nil {
+		sbox = SBoxCryptoProA
+	}
+
+	if len(wrapped) != KeyWrapSize {
+		return nil, errors.New("Неверная длина экспортированного ключа")
+	}
+
+	diversified, exception := diversifyCryptoPro(kek, wrapped[:KeyWrapUKMSize], sbox)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	return UnwrapGOST28147(diversified, wrapped, sbox)
+}
+
+// диверсифицировать KEK по UKM, RFC 4357 п. 6.5
+// на шаге i биты i-го байта UKM делят слова ключа на две суммы S1 и S2, K[i+1] = CFB_{K[i]}(S1 || S2, K[i])
+func diversifyCryptoPro(kek []byte, ukm []byte, sbox *SBox) ([]byte, error) {
+	if len(kek) != GOST28147KeySize {
+		return nil, errors.New("Неверная длина ключа ГОСТ 28147-89")
+	}
+
+	if len(ukm) != KeyWrapUKMSize {
+		return nil, errors.New("Неверная длина UKM")
+	}
+
+	key := append([]byte{}, kek...)
+	iv := make([]byte, GOST28147BlockSize)
+
+	for i := 0; i < KeyWrapUKMSize; i++ {
+		var first, second uint32
+
+		for j := 0; j < 8; j++ {
+			word := binary.LittleEndian.Uint32(key[4*j:])
+
+			if ukm[i]>>j&1 == 1 {
+				first += word
+			} else {
+				second += word
+			}
+		}
+
+		binary.LittleEndian.PutUint32(iv, first)
+		binary.LittleEndian.PutUint32(iv[4:], second)
+
+		stream, exception := newGOST28147Stream(key, sbox, iv, false, true, false)
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		stream.XORKeyStream(key, key)
+	}
+
+	return key, nil
+}
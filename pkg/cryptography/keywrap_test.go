@@ -0,0 +1,170 @@
+package cryptography
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/hex"
+	"testing"
+)
+
+func Test_KExp15_Success(t *testing.T) {
+	key, _ := hex.DecodeString(testKuznyechikKey)
+	encryptionKey, _ := hex.DecodeString("202122232425262728292a2b2c2d2e2f38393a3b3c3d3e3f3031323334353637")
+	macKey, _ := hex.DecodeString("08090a0b0c0d0e0f0001020304050607101112131415161718191a1b1c1d1e1f")
+
+	kuznyechikEncryption, _ := NewKuznyechik(encryptionKey)
+	kuznyechikMAC, _ := NewKuznyechik(macKey)
+	magmaEncryption, _ := NewMagma(encryptionKey)
+	magmaMAC, _ := NewMagma(macKey)
+
+	// Р 1323565.1.017-2018, приложение А
+	for _, example := range []struct {
+		name       string
+		encryption cipher.Block
+		mac        cipher.Block
+		iv         string
+		want       string
+	}{
+		{
+			"Магма", magmaEncryption, magmaMAC, "67bed654",
+			"cfd5a12d5b81b6e1e99c916d07900c6ac12703fb3abded55567bf3742c899c755dafe7b42e3a8bd9",
+		},
+		{
+			"Кузнечик", kuznyechikEncryption, kuznyechikMAC, "0909472dd9f26be8",
+			"e36184e84e8d736ff36cc2e5ae065dc656b23c20f549b02fdff88e1f3f30d8c29a53f3ca554dbad80de152b9a4625b32",
+		},
+	} {
+		iv, _ := hex.DecodeString(example.iv)
+		wrapped, error := KExp15(example.encryption, example.mac, key, iv)
+
+		if error != nil {
+			t.Fatal(error)
+		}
+
+		if result := hex.EncodeToString(wrapped); result != example.want {
+			t.Errorf("%s: ожидался экспортированный ключ %s. Получен %s", example.name, example.want, result)
+		}
+
+		unwrapped, error := KImp15(example.encryption, example.mac, wrapped, iv)
+
+		if error != nil {
+			t.Fatal(error)
+		}
+
+		if !bytes.Equal(unwrapped, key) {
+			t.Errorf("%s: ожидался ключ %x. Получен %x", example.name, key, unwrapped)
+		}
+
+		wrapped[0] ^= 1
+
+		if _, error := KImp15(example.encryption, example.mac, wrapped, iv); error == nil {
+			t.Errorf("%s: ожидалась ошибка для измененного экспортированного ключа", example.name)
+		}
+	}
+
+	if _, error := KExp15(kuznyechikEncryption, magmaMAC, key, make([]byte, 8)); error == nil {
+		t.Error("Ожидалась ошибка для шифров с разными размерами блоков")
+	}
+}
+
+func Test_KeyWrapGOST28147_Success(t *testing.T) {
+	kek, _ := hex.DecodeString(testMagmaKey)
+	cek, _ := hex.DecodeString(testKuznyechikKey)
+	ukm, _ := hex.DecodeString("0102030405060708")
+
+	// значения вычислены независимой реализацией RFC 4357 в соглашениях gost-engine (keyWrapGost, keyWrapCryptoPro),
+	// таблица замен КриптоПро A
+	for _, example := range []struct {
+		name   string
+		wrap   func(kek, ukm, cek []byte, sbox *SBox) ([]byte, error)
+		unwrap func(kek, wrapped []byte, sbox *SBox) ([]byte, error)
+		want   string
+	}{
+		{
+			"ГОСТ 28147-89", WrapGOST28147, UnwrapGOST28147,
+			"0102030405060708a81e34323588fd4502b71aa381fecdecacb6976aef4116abb05b3b0282ccad2f1704e203",
+		},
+		{
+			"КриптоПро", WrapCryptoPro, UnwrapCryptoPro,
+			"0102030405060708da6a676456fc160fda5fd81ab1acf7939e329c72aa3f2952a3ea76c45b2d4163c3dbf5c2",
+		},
+	} {
+		wrapped, error := example.wrap(kek, ukm, cek, nil)
+
+		if error != nil {
+			t.Fatal(error)
+		}
+
+		if result := hex.EncodeToString(wrapped); result != example.want {
+			t.Errorf("%s: ожидался экспортированный ключ %s. Получен %s", example.name, example.want, result)
+		}
+
+		unwrapped, error := example.unwrap(kek, wrapped, nil)
+
+		if error != nil {
+			t.Fatal(error)
+		}
+
+		if !bytes.Equal(unwrapped, cek) {
+			t.Errorf("%s: ожидался ключ %x. Получен %x", example.name, cek, unwrapped)
+		}
+
+		for _, position := range []int{0, KeyWrapUKMSize, KeyWrapSize - 1} {
+			changed := append([]byte{}, wrapped...)
+			changed[position] ^= 1
+
+			if _, error := example.unwrap(kek, changed, nil); error == nil {
+				t.Errorf("%s: ожидалась ошибка для измененного байта %d", example.name, position)
+			}
+		}
+
+		if _, error := example.unwrap(kek, wrapped, SBoxCryptoProB); error == nil {
+			t.Errorf("%s: ожидалась ошибка для другой таблицы замен", example.name)
+		}
+	}
+
+	// экспорт ГОСТ 28147-89 шифрует ключ в режиме простой замены на исходном KEK, КриптоПро - на диверсифицированном
+	plain, _ := WrapGOST28147(kek, ukm, cek, nil)
+	cryptoPro, _ := WrapCryptoPro(kek, ukm, cek, nil)
+
+	block, _ := NewGOST28147(kek, nil)
+	encrypted := make([]byte, GOST28147KeySize)
+	NewECBEncrypter(block).CryptBlocks(encrypted, cek)
+
+	if !bytes.Equal(plain[KeyWrapUKMSize:KeyWrapUKMSize+GOST28147KeySize], encrypted) {
+		t.Error("Ожидалось шифрование ключа в режиме простой замены")
+	}
+
+	if bytes.Equal(plain, cryptoPro) {
+		t.Error("Ожидались разные результаты экспорта ГОСТ 28147-89 и КриптоПро")
+	}
+}
+
+func Test_DiversifyCryptoPro_Success(t *testing.T) {
+	kek, _ := hex.DecodeString(testMagmaKey)
+
+	first, error := diversifyCryptoPro(kek, []byte{1, 2, 3, 4, 5, 6, 7, 8}, SBoxCryptoProA)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	second, _ := diversifyCryptoPro(kek, []byte{1, 2, 3, 4, 5, 6, 7, 9}, SBoxCryptoProA)
+
+	// значения вычислены той же независимой реализацией RFC 4357 п. 6.5, что и в Test_KeyWrapGOST28147_Success
+	for _, example := range []struct {
+		value []byte
+		want  string
+	}{
+		{first, "c8ac12ab9100874102fdf6b486ae7c51c17540ccc35ec78a8d584fe3ba189e04"},
+		{second, "d8138f041488e617a93ec3683ad522ec6c831108e83ba05ea0e626d5429c830b"},
+	} {
+		if result := hex.EncodeToString(example.value); result != example.want {
+			t.Errorf("Ожидался диверсифицированный ключ %s. Получен %s", example.want, result)
+		}
+	}
+
+	if _, error := diversifyCryptoPro(kek, []byte{1}, SBoxCryptoProA); error == nil {
+		t.Error("Ожидалась ошибка для UKM неверной длины")
+	}
+}
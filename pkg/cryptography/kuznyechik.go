@@ -0,0 +1,361 @@
+package cryptography
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"sync"
+
+	"github.com/madpo/go-gost-crypto/pkg/wrapper"
+)
+
+const (
+	// размер блока Кузнечика в байтах
+	KuznyechikBlockSize = 16
+	// размер ключа Кузнечика в байтах
+	KuznyechikKeySize = 32
+)
+
+// нелинейное биективное преобразование pi, ГОСТ Р 34.12-2015 п. 4.1.1
+var kuznyechikPi = [256]byte{
+	252, 238, 221, 17, 207, 110, 49, 22, 251, 196, 250, 218, 35, 197, 4, 77,
+	233, 119, 240, 219, 147, 46, 153, 186, 23, 54, 241, 187, 20, 205, 95, 193,
+	249, 24, 101, 90, 226, 92, 239, 33, 129, 28, 60, 66, 139, 1, 142, 79,
+	5, 132, 2, 174, 227, 106, 143, 160, 6, 11, 237, 152, 127, 212, 211, 31,
+	235, 52, 44, 81, 234, 200, 72, 171, 242, 42, 104, 162, 253, 58, 206, 204,
+	181, 112, 14, 86, 8, 12, 118, 18, 191, 114, 19, 71, 156, 183, 93, 135,
+	21, 161, 150, 41, 16, 123, 154, 199, 243, 145, 120, 111, 157, 158, 178, 177,
+	50, 117, 25, 61, 255, 53, 138, 126, 109, 84, 198, 128, 195, 189, 13, 87,
+	223, 245, 36, 169, 62, 168, 67, 201, 215, 121, 214, 246, 124, 34, 185, 3,
+	224, 15, 236, 222, 122, 148, 176, 188, 220, 232, 40, 80, 78, 51, 10, 74,
+	167, 151, 96, 115, 30, 0, 98, 68, 26, 184, 56, 130, 100, 159, 38, 65,
+	173, 69, 70, 146, 39, 94, 85, 47, 140, 163, 165, 125, 105, 213, 149, 59,
+	7, 88, 179, 64, 134, 172, 29, 247, 48, 55, 107, 228, 136, 217, 231, 137,
+	225, 27, 131, 73, 76, 63, 248, 254, 141, 83, 170, 144, 202, 216, 133, 97,
+	32, 113, 103, 164, 45, 43, 9, 91, 203, 155, 37, 208, 190, 229, 108, 82,
+	89, 166, 116, 210, 230, 244, 180, 192, 209, 102, 175, 194, 57, 75, 99, 182,
+}
+
+// коэффициенты линейного преобразования l, ГОСТ Р 34.12-2015 п. 4.1.2
+var kuznyechikL = [KuznyechikBlockSize]byte{148, 32, 133, 16, 194, 192, 1, 251, 1, 192, 194, 16, 133, 32, 148, 1}
+
+/*
+Таблицы преобразований Кузнечика
+для каждой позиции байта хранится результат преобразования блока, содержащего только этот байт
+*/
+type kuznyechikTables struct {
+	// обратная подстановка pi
+	piInverse [256]byte
+	// LS - подстановка и линейное преобразование
+	encrypt [KuznyechikBlockSize][256][2]uint64
+	// L^-1 S^-1 - обратные преобразования
+	decrypt [KuznyechikBlockSize][256][2]uint64
+	// L^-1 - обратное линейное преобразование
+	linearInverse [KuznyechikBlockSize][256][2]uint64
+	// итерационные константы развертывания ключа
+	constants [32][KuznyechikBlockSize]byte
+}
+
+var (
+	kuznyechikOnce  sync.Once
+	kuznyechikTable *kuznyechikTables
+)
+
+/*
+Блочный шифр Кузнечик, ГОСТ Р 34.12-2015
+*/
+type kuznyechikCipher struct {
+	// итерационные ключи K1..K10
+	keys [10][2]uint64
+	// итерационные ключи K2..K9 после L^-1 для расшифрования
+	inverseKeys [10][2]uint64
+}
+
+// создать блочный шифр Кузнечик
+func NewKuznyechik(key []byte) (cipher.Block, error) {
+	if len(key) != KuznyechikKeySize {
+		return nil, errors.New("Неверная длина ключа Кузнечика")
+	}
+
+	tables := getKuznyechikTables()
+	block := &kuznyechikCipher{}
+
+	var a1, a0 [KuznyechikBlockSize]byte
+
+	copy(a1[:], key[:KuznyechikBlockSize])
+	copy(a0[:], key[KuznyechikBlockSize:])
+
+	block.keys[0] = loadKuznyechikBlock(a1[:])
+	block.keys[1] = loadKuznyechikBlock(a0[:])
+
+	// ячейка Фейстеля F[C](a1, a0) = (LSX[C](a1) xor a0, a1), по 8 итераций на пару ключей
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 8; j++ {
+			var state [KuznyechikBlockSize]byte
+
+			for k := range state {
+				state[k] = kuznyechikPi[a1[k]^tables.constants[8*i+j][k]]
+			}
+
+			kuznyechikLinear(&state)
+
+			for k := range state {
+				state[k] ^= a0[k]
+			}
+
+			a0 = a1
+			a1 = state
+		}
+
+		block.keys[2*i+2] = loadKuznyechikBlock(a1[:])
+		block.keys[2*i+3] = loadKuznyechikBlock(a0[:])
+	}
+
+	for i := 1; i < 9; i++ {
+		block.inverseKeys[i][0], block.inverseKeys[i][1] = kuznyechikTransform(&tables.linearInverse, block.keys[i][0], block.keys[i][1])
+	}
+
+	return block, nil
+}
+
+func (block *kuznyechikCipher) BlockSize() int {
+	return KuznyechikBlockSize
+}
+
+func (block *kuznyechikCipher) Encrypt(dst, src []byte) {
+	checkBlockBuffers(dst, src, KuznyechikBlockSize)
+
+	table := &kuznyechikTable.encrypt
+	high, low := binary.BigEndian.Uint64(src), binary.BigEndian.Uint64(src[8:])
+
+	for i := 0; i < 9; i++ {
+		high, low = kuznyechikTransform(table, high^block.keys[i][0], low^block.keys[i][1])
+	}
+
+	binary.BigEndian.PutUint64(dst, high^block.keys[9][0])
+	binary.BigEndian.PutUint64(dst[8:], low^block.keys[9][1])
+}
+
+func (block *kuznyechikCipher) Decrypt(dst, src []byte) {
+	checkBlockBuffers(dst, src, KuznyechikBlockSize)
+
+	tables := kuznyechikTable
+	high, low := binary.BigEndian.Uint64(src), binary.BigEndian.Uint64(src[8:])
+	high, low = kuznyechikTransform(&tables.linearInverse, high^block.keys[9][0], low^block.keys[9][1])
+
+	// L^-1 S^-1 (x) xor L^-1 (K) = L^-1 (S^-1 (x) xor K), поэтому S^-1 переносится в следующий раунд
+	for i := 8; i > 0; i-- {
+		high, low = kuznyechikTransform(&tables.decrypt, high, low)
+		high ^= block.inverseKeys[i][0]
+		low ^= block.inverseKeys[i][1]
+	}
+
+	var result [KuznyechikBlockSize]byte
+
+	binary.BigEndian.PutUint64(result[:], high)
+	binary.BigEndian.PutUint64(result[8:], low)
+
+	for i := range result {
+		result[i] = tables.piInverse[result[i]]
+	}
+
+	binary.BigEndian.PutUint64(dst, binary.BigEndian.Uint64(result[:])^block.keys[0][0])
+	binary.BigEndian.PutUint64(dst[8:], binary.BigEndian.Uint64(result[8:])^block.keys[0][1])
+}
+
+// применить табличное преобразование к блоку, половины блока обрабатываются без промежуточных массивов
+func kuznyechikTransform(table *[KuznyechikBlockSize][256][2]uint64, high, low uint64) (uint64, uint64) {
+	t0, t1, t2, t3 := &table[0][byte(high>>56)], &table[1][byte(high>>48)], &table[2][byte(high>>40)], &table[3][byte(high>>32)]
+	t4, t5, t6, t7 := &table[4][byte(high>>24)], &table[5][byte(high>>16)], &table[6][byte(high>>8)], &table[7][byte(high)]
+	t8, t9, t10, t11 := &table[8][byte(low>>56)], &table[9][byte(low>>48)], &table[10][byte(low>>40)], &table[11][byte(low>>32)]
+	t12, t13, t14, t15 := &table[12][byte(low>>24)], &table[13][byte(low>>16)], &table[14][byte(low>>8)], &table[15][byte(low)]
+
+	high = t0[0] ^ t1[0] ^ t2[0] ^ t3[0] ^ t4[0] ^ t5[0] ^ t6[0] ^ t7[0] ^ t8[0] ^ t9[0] ^ t10[0] ^ t11[0] ^ t12[0] ^ t13[0] ^ t14[0] ^ t15[0]
+	low = t0[1] ^ t1[1] ^ t2[1] ^ t3[1] ^ t4[1] ^ t5[1] ^ t6[1] ^ t7[1] ^ t8[1] ^ t9[1] ^ t10[1] ^ t11[1] ^ t12[1] ^ t13[1] ^ t14[1] ^ t15[1]
+
+	return high, low
+}
+
+// получить таблицы преобразований, таблицы вычисляются один раз
+func getKuznyechikTables() *kuznyechikTables {
+	kuznyechikOnce.Do(func() {
+		tables := &kuznyechikTables{}
+
+		for i, value := range kuznyechikPi {
+			tables.piInverse[value] = byte(i)
+		}
+
+		for position := 0; position < KuznyechikBlockSize; position++ {
+			for value := 0; value < 256; value++ {
+				var state [KuznyechikBlockSize]byte
+
+				state[position] = kuznyechikPi[value]
+				kuznyechikLinear(&state)
+				tables.encrypt[position][value] = loadKuznyechikBlock(state[:])
+
+				state = [KuznyechikBlockSize]byte{}
+				state[position] = tables.piInverse[value]
+				kuznyechikLinearInverse(&state)
+				tables.decrypt[position][value] = loadKuznyechikBlock(state[:])
+
+				state = [KuznyechikBlockSize]byte{}
+				state[position] = byte(value)
+				kuznyechikLinearInverse(&state)
+				tables.linearInverse[position][value] = loadKuznyechikBlock(state[:])
+			}
+		}
+
+		// C_i = L(Vec128(i))
+		for i := range tables.constants {
+			tables.constants[i][KuznyechikBlockSize-1] = byte(i + 1)
+			kuznyechikLinear(&tables.constants[i])
+		}
+
+		kuznyechikTable = tables
+	})
+
+	return kuznyechikTable
+}
+
+// умножение в поле GF(2^8) по модулю x^8 + x^7 + x^6 + x + 1
+func kuznyechikMultiply(a, b byte) byte {
+	var result byte
+
+	for b != 0 {
+		if b&1 != 0 {
+			result ^= a
+		}
+
+		if a&0x80 != 0 {
+			a = a<<1 ^ 0xc3
+		} else {
+			a <<= 1
+		}
+
+		b >>= 1
+	}
+
+	return result
+}
+
+// линейное преобразование L = R^16
+func kuznyechikLinear(state *[KuznyechikBlockSize]byte) {
+	for round := 0; round < KuznyechikBlockSize; round++ {
+		value := state[KuznyechikBlockSize-1]
+
+		for i := KuznyechikBlockSize - 2; i >= 0; i-- {
+			state[i+1] = state[i]
+			value ^= kuznyechikMultiply(state[i], kuznyechikL[i])
+		}
+
+		state[0] = value
+	}
+}
+
+// обратное линейное преобразование L^-1
+func kuznyechikLinearInverse(state *[KuznyechikBlockSize]byte) {
+	for round := 0; round < KuznyechikBlockSize; round++ {
+		value := state[0]
+
+		for i := 0; i < KuznyechikBlockSize-1; i++ {
+			state[i] = state[i+1]
+			value ^= kuznyechikMultiply(state[i], kuznyechikL[i])
+		}
+
+		state[KuznyechikBlockSize-1] = value
+	}
+}
+
+// прочитать блок как два числа big-endian
+func loadKuznyechikBlock(data []byte) [2]uint64 {
+	return [2]uint64{binary.BigEndian.Uint64(data), binary.BigEndian.Uint64(data[8:])}
+}
+
+// записать блок из двух чисел big-endian
+func storeKuznyechikBlock(data []byte, state [2]uint64) {
+	binary.BigEndian.PutUint64(data, state[0])
+	binary.BigEndian.PutUint64(data[8:], state[1])
+}
+
+// проверить размер входного и выходного блоков, как это делают блочные шифры crypto
+func checkBlockBuffers(dst, src []byte, blockSize int) {
+	if len(src) < blockSize {
+		panic("cryptography: input not full block")
+	}
+
+	if len(dst) < blockSize {
+		panic("cryptography: output not full block")
+	}
+}
+
+// получить блочный шифр Кузнечик, реализованный КриптоПро
+// каждый блок шифруется отдельным вызовом CryptEncrypt, поэтому шифр медленнее NewKuznyechik
+func CreateKuznyechikCSPCipher(cspType wrapper.CSPType, key []byte) (release func(), block cipher.Block, exception error) {
+	if len(key) != KuznyechikKeySize {
+		return nil, nil, errors.New("Неверная длина ключа Кузнечика")
+	}
+
+	return createCSPBlock(cspType, wrapper.GOST3412_2015_K, KuznyechikBlockSize, key)
+}
+
+/*
+Блочный шифр КриптоПро в режиме простой замены
+*/
+type cspBlock struct {
+	key       *wrapper.CryptoKey
+	blockSize int
+	// дескриптор ключа не допускает параллельных вызовов
+	mutex sync.Mutex
+}
+
+// импортировать ключ в провайдер и получить блочный шифр
+func createCSPBlock(cspType wrapper.CSPType, cipherType wrapper.CipherType, blockSize int, key []byte) (release func(), block cipher.Block, exception error) {
+	cryptoProvider, exception := wrapper.TakeCSP(cspType)
+
+	if exception != nil {
+		return nil, nil, exception
+	}
+
+	value := append([]byte{}, key...)
+	cryptoKey, exception := wrapper.ImportCipherKey(cryptoProvider, cipherType, &value)
+
+	if exception != nil {
+		wrapper.ReleaseCSP(cryptoProvider)
+		return nil, nil, exception
+	}
+
+	return func() {
+		wrapper.ReleaseKey(cryptoKey)
+		wrapper.ReleaseCSP(cryptoProvider)
+	}, &cspBlock{key: cryptoKey, blockSize: blockSize}, nil
+}
+
+func (block *cspBlock) BlockSize() int {
+	return block.blockSize
+}
+
+func (block *cspBlock) Encrypt(dst, src []byte) {
+	block.apply(dst, src, wrapper.EncryptData)
+}
+
+func (block *cspBlock) Decrypt(dst, src []byte) {
+	block.apply(dst, src, wrapper.DecryptData)
+}
+
+// выполнить операцию провайдера над одним блоком
+// интерфейс cipher.Block не возвращает ошибок, поэтому ошибка провайдера приводит к panic
+func (block *cspBlock) apply(dst, src []byte, operation func(*wrapper.CryptoKey, *[]byte) error) {
+	checkBlockBuffers(dst, src, block.blockSize)
+
+	buffer := append([]byte{}, src[:block.blockSize]...)
+
+	block.mutex.Lock()
+	exception := operation(block.key, &buffer)
+	block.mutex.Unlock()
+
+	if exception != nil {
+		panic(exception)
+	}
+
+	copy(dst, buffer)
+}
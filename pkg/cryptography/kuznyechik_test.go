@@ -0,0 +1,124 @@
+package cryptography
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/hex"
+	"testing"
+
+	"github.com/madpo/go-gost-crypto/pkg/wrapper"
+)
+
+// контрольный пример ГОСТ Р 34.12-2015, приложение А.1
+const (
+	testKuznyechikKey        = "8899aabbccddeeff0011223344556677fedcba98765432100123456789abcdef"
+	testKuznyechikPlaintext  = "1122334455667700ffeeddccbbaa9988"
+	testKuznyechikCiphertext = "7f679d90bebc24305a468d42b9d4edcd"
+)
+
+// проверить шифр на контрольном примере
+func checkTestKuznyechik(t *testing.T, block cipher.Block) {
+	plaintext, _ := hex.DecodeString(testKuznyechikPlaintext)
+	ciphertext := make([]byte, KuznyechikBlockSize)
+
+	block.Encrypt(ciphertext, plaintext)
+
+	if result := hex.EncodeToString(ciphertext); result != testKuznyechikCiphertext {
+		t.Errorf("Ожидался шифртекст %s. Получен %s", testKuznyechikCiphertext, result)
+	}
+
+	decrypted := make([]byte, KuznyechikBlockSize)
+	block.Decrypt(decrypted, ciphertext)
+
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("Ожидался открытый текст %s. Получен %x", testKuznyechikPlaintext, decrypted)
+	}
+}
+
+func Test_Kuznyechik_Success(t *testing.T) {
+	key, _ := hex.DecodeString(testKuznyechikKey)
+	block, error := NewKuznyechik(key)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	checkTestKuznyechik(t, block)
+
+	// итерационные ключи K1 и K10, приложение А.1.4
+	keys := block.(*kuznyechikCipher).keys
+	first := make([]byte, KuznyechikBlockSize)
+	last := make([]byte, KuznyechikBlockSize)
+
+	storeKuznyechikBlock(first, keys[0])
+	storeKuznyechikBlock(last, keys[9])
+
+	if result := hex.EncodeToString(first); result != testKuznyechikKey[:32] {
+		t.Errorf("Ожидался ключ K1 %s. Получен %s", testKuznyechikKey[:32], result)
+	}
+
+	if want, result := "72e9dd7416bcf45b755dbaa88e4a4043", hex.EncodeToString(last); result != want {
+		t.Errorf("Ожидался ключ K10 %s. Получен %s", want, result)
+	}
+
+	if _, error := NewKuznyechik(key[:16]); error == nil {
+		t.Error("Ожидалась ошибка для ключа длиной 16 байт")
+	}
+}
+
+func Test_KuznyechikTransformations_Success(t *testing.T) {
+	getKuznyechikTables()
+
+	// преобразование S, приложение А.1.1
+	state, _ := hex.DecodeString("ffeeddccbbaa99881122334455667700")
+
+	for i := range state {
+		state[i] = kuznyechikPi[state[i]]
+	}
+
+	if want, result := "b66cd8887d38e8d77765aeea0c9a7efc", hex.EncodeToString(state); result != want {
+		t.Errorf("Ожидался результат S %s. Получен %s", want, result)
+	}
+
+	// преобразование L, приложение А.1.3
+	var block [KuznyechikBlockSize]byte
+
+	value, _ := hex.DecodeString("64a59400000000000000000000000000")
+	copy(block[:], value)
+	kuznyechikLinear(&block)
+
+	if want, result := "d456584dd0e3e84cc3166e4b7fa2890d", hex.EncodeToString(block[:]); result != want {
+		t.Errorf("Ожидался результат L %s. Получен %s", want, result)
+	}
+
+	kuznyechikLinearInverse(&block)
+
+	if !bytes.Equal(block[:], value) {
+		t.Errorf("Ожидался результат L^-1 %x. Получен %x", value, block)
+	}
+}
+
+func Test_KuznyechikCSP_Success(t *testing.T) {
+	key, _ := hex.DecodeString(testKuznyechikKey)
+	release, block, error := CreateKuznyechikCSPCipher(wrapper.GOST2012_256, key)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer release()
+
+	checkTestKuznyechik(t, block)
+}
+
+func Benchmark_Kuznyechik(b *testing.B) {
+	key, _ := hex.DecodeString(testKuznyechikKey)
+	block, _ := NewKuznyechik(key)
+	data := make([]byte, KuznyechikBlockSize)
+
+	b.SetBytes(KuznyechikBlockSize)
+
+	for i := 0; i < b.N; i++ {
+		block.Encrypt(data, data)
+	}
+}
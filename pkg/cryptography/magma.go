@@ -0,0 +1,208 @@
+package cryptography
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"math/bits"
+)
+
+const (
+	// размер блока Магмы в байтах
+	MagmaBlockSize = 8
+	// размер ключа Магмы в байтах
+	MagmaKeySize = 32
+)
+
+/*
+Таблица замен Магмы и ГОСТ 28147-89
+строка i применяется к i-й четверке бит 32-битного слова, начиная с младшей
+*/
+type SBox [8][16]byte
+
+// таблица замен ГОСТ Р 34.12-2015, id-tc26-gost-28147-param-Z
+var SBoxTC26Z = &SBox{
+	{12, 4, 6, 2, 10, 5, 11, 9, 14, 8, 13, 7, 0, 3, 15, 1},
+	{6, 8, 2, 3, 9, 10, 5, 12, 1, 14, 4, 7, 11, 13, 0, 15},
+	{11, 3, 5, 8, 2, 15, 10, 13, 14, 1, 7, 4, 12, 9, 6, 0},
+	{12, 8, 2, 1, 13, 4, 15, 6, 7, 0, 10, 5, 3, 14, 9, 11},
+	{7, 15, 5, 10, 8, 1, 6, 13, 0, 9, 3, 14, 11, 4, 2, 12},
+	{5, 13, 15, 6, 9, 2, 12, 10, 11, 7, 8, 1, 4, 3, 14, 0},
+	{8, 14, 2, 5, 6, 9, 1, 12, 15, 4, 11, 0, 13, 10, 3, 7},
+	{1, 7, 14, 13, 0, 5, 8, 3, 4, 15, 10, 6, 9, 12, 11, 2},
+}
+
+// таблица замен КриптоПро, id-Gost28147-89-CryptoPro-A-ParamSet, RFC 4357
+var SBoxCryptoProA = &SBox{
+	{9, 6, 3, 2, 8, 11, 1, 7, 10, 4, 14, 15, 12, 0, 13, 5},
+	{3, 7, 14, 9, 8, 10, 15, 0, 5, 2, 6, 12, 11, 4, 13, 1},
+	{14, 4, 6, 2, 11, 3, 13, 8, 12, 15, 5, 10, 0, 7, 1, 9},
+	{14, 7, 10, 12, 13, 1, 3, 9, 0, 2, 11, 4, 15, 8, 5, 6},
+	{11, 5, 1, 9, 8, 13, 15, 0, 14, 4, 2, 3, 12, 7, 10, 6},
+	{3, 10, 13, 12, 1, 2, 0, 11, 7, 5, 9, 4, 8, 15, 14, 6},
+	{1, 13, 2, 9, 7, 10, 6, 0, 8, 12, 4, 5, 15, 3, 11, 14},
+	{11, 10, 15, 5, 0, 12, 14, 8, 6, 2, 3, 9, 1, 7, 13, 4},
+}
+
+// таблица замен КриптоПро, id-Gost28147-89-CryptoPro-B-ParamSet, RFC 4357
+var SBoxCryptoProB = &SBox{
+	{8, 4, 11, 1, 3, 5, 0, 9, 2, 14, 10, 12, 13, 6, 7, 15},
+	{0, 1, 2, 10, 4, 13, 5, 12, 9, 7, 3, 15, 11, 8, 6, 14},
+	{14, 12, 0, 10, 9, 2, 13, 11, 7, 5, 8, 15, 3, 6, 1, 4},
+	{7, 5, 0, 13, 11, 6, 1, 2, 3, 10, 12, 15, 4, 14, 9, 8},
+	{2, 7, 12, 15, 9, 5, 10, 11, 1, 4, 0, 13, 6, 8, 14, 3},
+	{8, 3, 2, 6, 4, 13, 14, 11, 12, 1, 7, 15, 10, 0, 9, 5},
+	{5, 2, 10, 11, 9, 1, 12, 3, 7, 4, 13, 0, 6, 15, 8, 14},
+	{0, 4, 11, 14, 8, 3, 7, 1, 10, 2, 9, 6, 15, 13, 5, 12},
+}
+
+// таблица замен КриптоПро, id-Gost28147-89-CryptoPro-C-ParamSet, RFC 4357
+var SBoxCryptoProC = &SBox{
+	{1, 11, 12, 2, 9, 13, 0, 15, 4, 5, 8, 14, 10, 7, 6, 3},
+	{0, 1, 7, 13, 11, 4, 5, 2, 8, 14, 15, 12, 9, 10, 6, 3},
+	{8, 2, 5, 0, 4, 9, 15, 10, 3, 7, 12, 13, 6, 14, 1, 11},
+	{3, 6, 0, 1, 5, 13, 10, 8, 11, 2, 9, 7, 14, 15, 12, 4},
+	{8, 13, 11, 0, 4, 5, 1, 2, 9, 3, 12, 14, 6, 15, 10, 7},
+	{12, 9, 11, 1, 8, 14, 2, 4, 7, 3, 6, 5, 10, 0, 15, 13},
+	{10, 9, 6, 8, 13, 14, 2, 0, 15, 3, 5, 11, 4, 1, 12, 7},
+	{7, 4, 0, 5, 10, 2, 15, 14, 12, 6, 1, 11, 13, 9, 3, 8},
+}
+
+// таблица замен КриптоПро, id-Gost28147-89-CryptoPro-D-ParamSet, RFC 4357
+var SBoxCryptoProD = &SBox{
+	{15, 12, 2, 10, 6, 4, 5, 0, 7, 9, 14, 13, 1, 11, 8, 3},
+	{11, 6, 3, 4, 12, 15, 14, 2, 7, 13, 8, 0, 5, 10, 9, 1},
+	{1, 12, 11, 0, 15, 14, 6, 5, 10, 13, 4, 8, 9, 3, 7, 2},
+	{1, 5, 14, 12, 10, 7, 0, 13, 6, 2, 11, 4, 9, 3, 15, 8},
+	{0, 12, 8, 9, 13, 2, 10, 11, 7, 3, 6, 5, 4, 14, 15, 1},
+	{8, 0, 15, 3, 2, 5, 14, 11, 1, 10, 4, 7, 12, 9, 13, 6},
+	{3, 0, 6, 15, 1, 14, 9, 2, 13, 8, 12, 4, 11, 10, 5, 7},
+	{1, 10, 6, 8, 15, 11, 0, 4, 12, 3, 5, 9, 7, 13, 2, 14},
+}
+
+/*
+Блочный шифр Магма, ГОСТ Р 34.12-2015
+*/
+type magmaCipher struct {
+	// итерационные ключи K1..K8
+	keys [8]uint32
+	// подстановка и циклический сдвиг на 11 бит для каждого байта слова
+	table *[4][256]uint32
+}
+
+// создать блочный шифр Магма с таблицей замен id-tc26-gost-28147-param-Z
+func NewMagma(key []byte) (cipher.Block, error) {
+	return NewMagmaWithSBox(key, SBoxTC26Z)
+}
+
+// создать блочный шифр Магма с заданной таблицей замен
+func NewMagmaWithSBox(key []byte, sbox *SBox) (cipher.Block, error) {
+	if len(key) != MagmaKeySize {
+		return nil, errors.New("Неверная длина ключа Магмы")
+	}
+
+	table, exception := createMagmaTable(sbox)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	block := &magmaCipher{table: table}
+
+	for i := range block.keys {
+		block.keys[i] = binary.BigEndian.Uint32(key[4*i:])
+	}
+
+	return block, nil
+}
+
+func (block *magmaCipher) BlockSize() int {
+	return MagmaBlockSize
+}
+
+func (block *magmaCipher) Encrypt(dst, src []byte) {
+	checkBlockBuffers(dst, src, MagmaBlockSize)
+
+	a1, a0 := block.encrypt(binary.BigEndian.Uint32(src), binary.BigEndian.Uint32(src[4:]))
+
+	binary.BigEndian.PutUint32(dst, a1)
+	binary.BigEndian.PutUint32(dst[4:], a0)
+}
+
+func (block *magmaCipher) Decrypt(dst, src []byte) {
+	checkBlockBuffers(dst, src, MagmaBlockSize)
+
+	a1, a0 := block.decrypt(binary.BigEndian.Uint32(src), binary.BigEndian.Uint32(src[4:]))
+
+	binary.BigEndian.PutUint32(dst, a1)
+	binary.BigEndian.PutUint32(dst[4:], a0)
+}
+
+// зашифровать блок a1||a0: ключи K1..K8 три раза, затем K8..K1
+func (block *magmaCipher) encrypt(a1, a0 uint32) (uint32, uint32) {
+	for i := 0; i < 24; i++ {
+		a1, a0 = a0, a1^block.round(a0, block.keys[i%8])
+	}
+
+	for i := 7; i >= 0; i-- {
+		a1, a0 = a0, a1^block.round(a0, block.keys[i])
+	}
+
+	// последнее преобразование G* не переставляет половины
+	return a0, a1
+}
+
+// расшифровать блок a1||a0: ключи K1..K8, затем K8..K1 три раза
+func (block *magmaCipher) decrypt(a1, a0 uint32) (uint32, uint32) {
+	for i := 0; i < 8; i++ {
+		a1, a0 = a0, a1^block.round(a0, block.keys[i])
+	}
+
+	for i := 23; i >= 0; i-- {
+		a1, a0 = a0, a1^block.round(a0, block.keys[i%8])
+	}
+
+	return a0, a1
+}
+
+// преобразование g[k](a) = t(a + k) <<< 11
+func (block *magmaCipher) round(value uint32, key uint32) uint32 {
+	value += key
+
+	return block.table[0][byte(value)] ^ block.table[1][byte(value>>8)] ^ block.table[2][byte(value>>16)] ^ block.table[3][byte(value>>24)]
+}
+
+// построить таблицы подстановки для пар строк таблицы замен
+func createMagmaTable(sbox *SBox) (*[4][256]uint32, error) {
+	if sbox == nil {
+		return nil, errors.New("Не задана таблица замен")
+	}
+
+	for _, row := range sbox {
+		var used uint16
+
+		for _, value := range row {
+			used |= 1 << (value & 0x0f)
+
+			if value > 0x0f {
+				used = 0
+				break
+			}
+		}
+
+		if used != 0xffff {
+			return nil, errors.New("Строка таблицы замен не является перестановкой")
+		}
+	}
+
+	table := &[4][256]uint32{}
+
+	for i := range table {
+		for value := 0; value < 256; value++ {
+			substituted := uint32(sbox[2*i+1][value>>4])<<4 | uint32(sbox[2*i][value&0x0f])
+			table[i][value] = bits.RotateLeft32(substituted<<(8*i), 11)
+		}
+	}
+
+	return table, nil
+}
@@ -0,0 +1,112 @@
+package cryptography
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// контрольный пример ГОСТ Р 34.12-2015, приложение А.2
+const (
+	testMagmaKey        = "ffeeddccbbaa99887766554433221100f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff"
+	testMagmaPlaintext  = "fedcba9876543210"
+	testMagmaCiphertext = "4ee901e5c2d8ca3d"
+)
+
+func Test_Magma_Success(t *testing.T) {
+	key, _ := hex.DecodeString(testMagmaKey)
+	block, error := NewMagma(key)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	plaintext, _ := hex.DecodeString(testMagmaPlaintext)
+	ciphertext := make([]byte, MagmaBlockSize)
+
+	block.Encrypt(ciphertext, plaintext)
+
+	if result := hex.EncodeToString(ciphertext); result != testMagmaCiphertext {
+		t.Errorf("Ожидался шифртекст %s. Получен %s", testMagmaCiphertext, result)
+	}
+
+	block.Decrypt(ciphertext, ciphertext)
+
+	if !bytes.Equal(ciphertext, plaintext) {
+		t.Errorf("Ожидался открытый текст %s. Получен %x", testMagmaPlaintext, ciphertext)
+	}
+
+	if _, error := NewMagma(key[:16]); error == nil {
+		t.Error("Ожидалась ошибка для ключа длиной 16 байт")
+	}
+}
+
+func Test_MagmaRound_Success(t *testing.T) {
+	key, _ := hex.DecodeString(testMagmaKey)
+	block, _ := NewMagma(key)
+
+	// преобразование g, приложение А.2.2
+	for _, example := range []struct{ key, value, want uint32 }{
+		{0x87654321, 0xfedcba98, 0xfdcbc20c},
+		{0xfdcbc20c, 0x87654321, 0x7e791a4b},
+		{0x7e791a4b, 0xfdcbc20c, 0xc76549ec},
+		{0xc76549ec, 0x7e791a4b, 0x9791c849},
+	} {
+		if result := block.(*magmaCipher).round(example.value, example.key); result != example.want {
+			t.Errorf("Ожидался результат g[%08x](%08x) %08x. Получен %08x", example.key, example.value, example.want, result)
+		}
+	}
+}
+
+func Test_MagmaSBox_Success(t *testing.T) {
+	key, _ := hex.DecodeString(testMagmaKey)
+	plaintext, _ := hex.DecodeString(testMagmaPlaintext)
+
+	block, error := NewMagmaWithSBox(key, SBoxCryptoProA)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	// ключ и открытый текст приложения А.2, таблица замен КриптоПро A по RFC 4357
+	want := "cd222ca34cb08341"
+	ciphertext := make([]byte, MagmaBlockSize)
+	block.Encrypt(ciphertext, plaintext)
+
+	if result := hex.EncodeToString(ciphertext); result != want {
+		t.Errorf("Ожидался шифртекст %s. Получен %s", want, result)
+	}
+
+	// ГОСТ 28147-89 с той же таблицей при обратном порядке байт слов ключа и блока
+	gostKey := make([]byte, GOST28147KeySize)
+
+	for i := 0; i < len(gostKey); i += 4 {
+		copy(gostKey[i:i+4], reverseTestBytes(key[i:i+4]))
+	}
+
+	gost, error := NewGOST28147(gostKey, SBoxCryptoProA)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	gostCiphertext := make([]byte, GOST28147BlockSize)
+	gost.Encrypt(gostCiphertext, reverseTestBytes(plaintext))
+
+	if result := hex.EncodeToString(reverseTestBytes(gostCiphertext)); result != want {
+		t.Errorf("Ожидался шифртекст ГОСТ 28147-89 %s. Получен %s", want, result)
+	}
+
+	block.Decrypt(ciphertext, ciphertext)
+
+	if !bytes.Equal(ciphertext, plaintext) {
+		t.Errorf("Ожидался открытый текст %s. Получен %x", testMagmaPlaintext, ciphertext)
+	}
+
+	broken := *SBoxTC26Z
+	broken[3][0] = broken[3][1]
+
+	if _, error := NewMagmaWithSBox(key, &broken); error == nil {
+		t.Error("Ожидалась ошибка для таблицы замен с повторяющимися значениями")
+	}
+}
@@ -0,0 +1,268 @@
+package cryptography
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+)
+
+// минимальная длина имитовставки MGM в байтах
+const MGMMinTagSize = 4
+
+/*
+Режим аутентифицированного шифрования MGM, RFC 9058
+*/
+type mgmMode struct {
+	block     cipher.Block
+	blockSize int
+	tagSize   int
+	// умножение в поле GF(2^n)
+	multiply func(x, y [2]uint64) [2]uint64
+}
+
+// получить режим MGM для Кузнечика или Магмы
+// tagSize - длина имитовставки от MGMMinTagSize до размера блока в байтах, при 0 используется размер блока
+func NewMGM(block cipher.Block, tagSize int) (cipher.AEAD, error) {
+	blockSize := block.BlockSize()
+	mode := &mgmMode{block: block, blockSize: blockSize, tagSize: tagSize}
+
+	switch blockSize {
+	case KuznyechikBlockSize:
+		mode.multiply = multiplyGF128
+	case MagmaBlockSize:
+		mode.multiply = multiplyGF64
+	default:
+		return nil, errors.New("Режим MGM определен для блоков 64 и 128 бит")
+	}
+
+	if tagSize == 0 {
+		mode.tagSize = blockSize
+	}
+
+	if mode.tagSize < MGMMinTagSize || mode.tagSize > blockSize {
+		return nil, errors.New("Неверная длина имитовставки MGM")
+	}
+
+	return mode, nil
+}
+
+// одноразовое значение ICN длины n с нулевым старшим битом
+func (mode *mgmMode) NonceSize() int {
+	return mode.blockSize
+}
+
+func (mode *mgmMode) Overhead() int {
+	return mode.tagSize
+}
+
+func (mode *mgmMode) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if exception := mode.checkInput(nonce, plaintext, additionalData); exception != nil {
+		panic("cryptography: " + exception.Error())
+	}
+
+	result, out := sliceForAppend(dst, len(plaintext)+mode.tagSize)
+	ciphertext := out[:len(plaintext)]
+
+	mode.crypt(ciphertext, plaintext, nonce)
+	copy(out[len(plaintext):], mode.tag(nonce, additionalData, ciphertext))
+
+	return result
+}
+
+func (mode *mgmMode) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < mode.tagSize {
+		return nil, errors.New("Длина шифртекста меньше длины имитовставки")
+	}
+
+	tag := ciphertext[len(ciphertext)-mode.tagSize:]
+	ciphertext = ciphertext[:len(ciphertext)-mode.tagSize]
+
+	if exception := mode.checkInput(nonce, ciphertext, additionalData); exception != nil {
+		return nil, exception
+	}
+
+	if subtle.ConstantTimeCompare(tag, mode.tag(nonce, additionalData, ciphertext)) != 1 {
+		return nil, errors.New("Неверная имитовставка MGM")
+	}
+
+	result, out := sliceForAppend(dst, len(ciphertext))
+	mode.crypt(out, ciphertext, nonce)
+
+	return result, nil
+}
+
+// проверить одноразовое значение и длины данных
+func (mode *mgmMode) checkInput(nonce, text, additionalData []byte) error {
+	if len(nonce) != mode.blockSize {
+		return errors.New("Неверная длина одноразового значения MGM")
+	}
+
+	if nonce[0]&0x80 != 0 {
+		return errors.New("Старший бит одноразового значения MGM должен быть равен нулю")
+	}
+
+	if len(text) == 0 && len(additionalData) == 0 {
+		return errors.New("Не заданы ни данные, ни дополнительные данные")
+	}
+
+	// длины в битах записываются в n/2 бит
+	if mode.blockSize == MagmaBlockSize && (uint64(len(text)) >= 1<<29 || uint64(len(additionalData)) >= 1<<29) {
+		return errors.New("Превышена допустимая длина данных MGM")
+	}
+
+	return nil
+}
+
+// зашифровать или расшифровать данные, Y_1 = E(0||ICN), счетчик увеличивается в правой половине
+func (mode *mgmMode) crypt(dst, src, nonce []byte) {
+	counter := make([]byte, mode.blockSize)
+	gamma := make([]byte, mode.blockSize)
+
+	copy(counter, nonce)
+	counter[0] &= 0x7f
+	mode.block.Encrypt(counter, counter)
+
+	for len(src) > 0 {
+		mode.block.Encrypt(gamma, counter)
+		incrementCounter(counter[mode.blockSize/2:])
+
+		count := len(src)
+
+		if count > mode.blockSize {
+			count = mode.blockSize
+		}
+
+		xorBytes(dst[:count], src[:count], gamma)
+
+		dst = dst[count:]
+		src = src[count:]
+	}
+}
+
+// вычислить имитовставку, Z_1 = E(1||ICN), счетчик увеличивается в левой половине
+func (mode *mgmMode) tag(nonce, additionalData, ciphertext []byte) []byte {
+	counter := make([]byte, mode.blockSize)
+	value := make([]byte, mode.blockSize)
+
+	copy(counter, nonce)
+	counter[0] |= 0x80
+	mode.block.Encrypt(counter, counter)
+
+	var sum [2]uint64
+
+	add := func(data []byte) {
+		mode.block.Encrypt(value, counter)
+		incrementCounter(counter[:mode.blockSize/2])
+
+		block := make([]byte, mode.blockSize)
+		copy(block, data)
+
+		product := mode.multiply(mode.load(value), mode.load(block))
+		sum[0] ^= product[0]
+		sum[1] ^= product[1]
+	}
+
+	for _, data := range [][]byte{additionalData, ciphertext} {
+		for offset := 0; offset < len(data); offset += mode.blockSize {
+			end := offset + mode.blockSize
+
+			if end > len(data) {
+				end = len(data)
+			}
+
+			add(data[offset:end])
+		}
+	}
+
+	// len(A) || len(C) в битах
+	lengths := make([]byte, mode.blockSize)
+	half := mode.blockSize / 2
+
+	if half == 8 {
+		binary.BigEndian.PutUint64(lengths, uint64(len(additionalData))*8)
+		binary.BigEndian.PutUint64(lengths[half:], uint64(len(ciphertext))*8)
+	} else {
+		binary.BigEndian.PutUint32(lengths, uint32(len(additionalData))*8)
+		binary.BigEndian.PutUint32(lengths[half:], uint32(len(ciphertext))*8)
+	}
+
+	add(lengths)
+
+	mode.store(value, sum)
+	mode.block.Encrypt(value, value)
+
+	return value[:mode.tagSize]
+}
+
+// прочитать блок как число big-endian
+func (mode *mgmMode) load(data []byte) [2]uint64 {
+	if mode.blockSize == MagmaBlockSize {
+		return [2]uint64{0, binary.BigEndian.Uint64(data)}
+	}
+
+	return [2]uint64{binary.BigEndian.Uint64(data), binary.BigEndian.Uint64(data[8:])}
+}
+
+// записать число big-endian в блок
+func (mode *mgmMode) store(data []byte, value [2]uint64) {
+	if mode.blockSize == MagmaBlockSize {
+		binary.BigEndian.PutUint64(data, value[1])
+		return
+	}
+
+	binary.BigEndian.PutUint64(data, value[0])
+	binary.BigEndian.PutUint64(data[8:], value[1])
+}
+
+// умножение в поле GF(2^128) по модулю x^128 + x^7 + x^2 + x + 1
+// выполняется за постоянное время: ветвления по битам заменены масками, как в GHASH из crypto/cipher
+func multiplyGF128(x, y [2]uint64) [2]uint64 {
+	var result [2]uint64
+
+	for i := 0; i < 128; i++ {
+		mask := -(y[1] & 1)
+		result[0] ^= x[0] & mask
+		result[1] ^= x[1] & mask
+
+		y[1] = y[1]>>1 | y[0]<<63
+		y[0] >>= 1
+
+		carry := -(x[0] >> 63)
+		x[0] = x[0]<<1 | x[1]>>63
+		x[1] = x[1]<<1 ^ 0x87&carry
+	}
+
+	return result
+}
+
+// умножение в поле GF(2^64) по модулю x^64 + x^4 + x^3 + x + 1
+// выполняется за постоянное время: всегда 64 итерации, ветвления по битам заменены масками
+func multiplyGF64(x, y [2]uint64) [2]uint64 {
+	var result uint64
+
+	a, b := x[1], y[1]
+
+	for i := 0; i < 64; i++ {
+		result ^= a & -(b & 1)
+		b >>= 1
+
+		a = a<<1 ^ 0x1b&-(a>>63)
+	}
+
+	return [2]uint64{0, result}
+}
+
+// расширить срез на n байт, как это делают режимы crypto/cipher
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+
+	tail = head[len(in):]
+
+	return head, tail
+}
@@ -0,0 +1,140 @@
+package cryptography
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// контрольный пример RFC 9058
+type testMGMExample struct {
+	name                  string
+	block                 cipher.Block
+	nonce, additional     string
+	plaintext, ciphertext string
+	tag                   string
+}
+
+func createTestMGMExamples(t *testing.T) []testMGMExample {
+	kuznyechikKey, _ := hex.DecodeString(testKuznyechikKey)
+	kuznyechik, error := NewKuznyechik(kuznyechikKey)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	magmaKey, _ := hex.DecodeString(testMagmaKey)
+	magma, error := NewMagma(magmaKey)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	return []testMGMExample{
+		{
+			name:       "Кузнечик",
+			block:      kuznyechik,
+			nonce:      "1122334455667700ffeeddccbbaa9988",
+			additional: "0202020202020202010101010101010104040404040404040303030303030303ea0505050505050505",
+			plaintext: "1122334455667700ffeeddccbbaa9988" + "00112233445566778899aabbcceeff0a" +
+				"112233445566778899aabbcceeff0a00" + "2233445566778899aabbcceeff0a0011" + "aabbcc",
+			ciphertext: "a9757b8147956e9055b8a33de89f42fc" + "8075d2212bf9fd5bd3f7069aadc16b39" +
+				"497ab15915a6ba85936b5d0ea9f6851c" + "c60c14d4d3f883d0ab94420695c76deb" + "2c7552",
+			tag: "cf5d656f40c34f5c46e8bb0e29fcdb4c",
+		},
+		{
+			name:       "Магма",
+			block:      magma,
+			nonce:      "12def06b3c130a59",
+			additional: "01010101010101010202020202020202030303030303030304040404040404040505050505050505ea",
+			plaintext: "ffeeddccbbaa998811223344556677008899aabbcceeff0a0011223344556677" +
+				"99aabbcceeff0a001122334455667788aabbcceeff0a00112233445566778899" + "aabbcc",
+			ciphertext: "c795066c5f9ea03b85113342459185ae1f2e00d6bf2b785d940470b8bb9c8e7d" +
+				"9a5dd3731f7ddc70ec27cb0ace6fa57670f65c646abb75d547aa37c3bcb5c34e" + "03bb9c",
+			tag: "a7928069aa10fd10",
+		},
+	}
+}
+
+func Test_MGM_Success(t *testing.T) {
+	for _, example := range createTestMGMExamples(t) {
+		aead, error := NewMGM(example.block, 0)
+
+		if error != nil {
+			t.Fatal(error)
+		}
+
+		nonce, _ := hex.DecodeString(example.nonce)
+		additional, _ := hex.DecodeString(example.additional)
+		plaintext, _ := hex.DecodeString(example.plaintext)
+
+		sealed := aead.Seal(nil, nonce, plaintext, additional)
+		want := example.ciphertext + example.tag
+
+		if result := hex.EncodeToString(sealed); result != want {
+			t.Errorf("%s: ожидался шифртекст %s. Получен %s", example.name, want, result)
+		}
+
+		opened, error := aead.Open(sealed[:0], nonce, sealed, additional)
+
+		if error != nil {
+			t.Fatalf("%s: %v", example.name, error)
+		}
+
+		if !bytes.Equal(opened, plaintext) {
+			t.Errorf("%s: ожидался открытый текст %s. Получен %x", example.name, example.plaintext, opened)
+		}
+	}
+}
+
+func Test_MGM_TagSize(t *testing.T) {
+	example := createTestMGMExamples(t)[0]
+	nonce, _ := hex.DecodeString(example.nonce)
+	additional, _ := hex.DecodeString(example.additional)
+	plaintext, _ := hex.DecodeString(example.plaintext)
+
+	aead, error := NewMGM(example.block, 8)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	sealed := aead.Seal(nil, nonce, plaintext, additional)
+
+	// укороченная имитовставка - старшие байты полной
+	if !strings.HasSuffix(hex.EncodeToString(sealed), example.tag[:16]) || len(sealed) != len(plaintext)+8 {
+		t.Errorf("Ожидалась имитовставка %s", example.tag[:16])
+	}
+
+	for _, size := range []int{3, 17} {
+		if _, error := NewMGM(example.block, size); error == nil {
+			t.Errorf("Ожидалась ошибка для имитовставки длины %d", size)
+		}
+	}
+}
+
+func Test_MGM_Failed(t *testing.T) {
+	example := createTestMGMExamples(t)[1]
+	nonce, _ := hex.DecodeString(example.nonce)
+	aead, _ := NewMGM(example.block, 0)
+
+	sealed := aead.Seal(nil, nonce, []byte("Hello world"), []byte("header"))
+
+	if _, error := aead.Open(nil, nonce, sealed, []byte("HEADER")); error == nil {
+		t.Error("Ожидалась ошибка для измененных дополнительных данных")
+	}
+
+	sealed[0] ^= 1
+
+	if _, error := aead.Open(nil, nonce, sealed, []byte("header")); error == nil {
+		t.Error("Ожидалась ошибка для измененного шифртекста")
+	}
+
+	nonce[0] |= 0x80
+
+	if _, error := aead.Open(nil, nonce, sealed, []byte("header")); error == nil {
+		t.Error("Ожидалась ошибка для одноразового значения с единичным старшим битом")
+	}
+}
@@ -0,0 +1,349 @@
+package cryptography
+
+import (
+	"crypto/cipher"
+	"errors"
+)
+
+// режимы работы блочных шифров ГОСТ Р 34.13-2015
+// размеры синхропосылки m и параметра s задаются в байтах
+
+// дополнить данные нулями до длины, кратной размеру блока, процедура 1
+// данные длины, кратной размеру блока, не дополняются, поэтому дополнение не может быть однозначно удалено
+func PadProcedure1(data []byte, blockSize int) []byte {
+	padded := append([]byte{}, data...)
+
+	if remainder := len(data) % blockSize; remainder != 0 {
+		padded = append(padded, make([]byte, blockSize-remainder)...)
+	}
+
+	return padded
+}
+
+// дополнить данные единичным битом и нулями, процедура 2
+// дополнение выполняется всегда, в том числе для данных длины, кратной размеру блока
+func PadProcedure2(data []byte, blockSize int) []byte {
+	padded := append(append([]byte{}, data...), 0x80)
+
+	if remainder := len(padded) % blockSize; remainder != 0 {
+		padded = append(padded, make([]byte, blockSize-remainder)...)
+	}
+
+	return padded
+}
+
+// дополнить данные по процедуре 2, если их длина не кратна размеру блока, процедура 3
+func PadProcedure3(data []byte, blockSize int) []byte {
+	if len(data)%blockSize == 0 {
+		return append([]byte{}, data...)
+	}
+
+	return PadProcedure2(data, blockSize)
+}
+
+// удалить дополнение процедуры 2
+func UnpadProcedure2(data []byte, blockSize int) ([]byte, error) {
+	if len(data) == 0 || len(data)%blockSize != 0 {
+		return nil, errors.New("Длина данных не кратна размеру блока")
+	}
+
+	for i := len(data) - 1; i >= len(data)-blockSize; i-- {
+		switch data[i] {
+		case 0x00:
+			continue
+		case 0x80:
+			return data[:i], nil
+		}
+
+		break
+	}
+
+	return nil, errors.New("Неверное дополнение данных")
+}
+
+/*
+Режим простой замены
+*/
+type ecbMode struct {
+	block   cipher.Block
+	decrypt bool
+}
+
+// получить режим простой замены для зашифрования
+func NewECBEncrypter(block cipher.Block) cipher.BlockMode {
+	return &ecbMode{block: block}
+}
+
+// получить режим простой замены для расшифрования
+func NewECBDecrypter(block cipher.Block) cipher.BlockMode {
+	return &ecbMode{block: block, decrypt: true}
+}
+
+func (mode *ecbMode) BlockSize() int {
+	return mode.block.BlockSize()
+}
+
+func (mode *ecbMode) CryptBlocks(dst, src []byte) {
+	blockSize := checkModeBuffers(dst, src, mode.block.BlockSize())
+
+	for i := 0; i < len(src); i += blockSize {
+		if mode.decrypt {
+			mode.block.Decrypt(dst[i:i+blockSize], src[i:i+blockSize])
+		} else {
+			mode.block.Encrypt(dst[i:i+blockSize], src[i:i+blockSize])
+		}
+	}
+}
+
+/*
+Режим простой замены с зацеплением
+регистр длины m = z*n содержит z последних блоков шифртекста
+*/
+type cbcMode struct {
+	block    cipher.Block
+	register []byte
+	decrypt  bool
+}
+
+// получить режим простой замены с зацеплением для зашифрования
+// длина синхропосылки кратна размеру блока
+func NewCBCEncrypter(block cipher.Block, iv []byte) (cipher.BlockMode, error) {
+	return newCBCMode(block, iv, false)
+}
+
+// получить режим простой замены с зацеплением для расшифрования
+func NewCBCDecrypter(block cipher.Block, iv []byte) (cipher.BlockMode, error) {
+	return newCBCMode(block, iv, true)
+}
+
+func newCBCMode(block cipher.Block, iv []byte, decrypt bool) (cipher.BlockMode, error) {
+	if len(iv) == 0 || len(iv)%block.BlockSize() != 0 {
+		return nil, errors.New("Длина синхропосылки не кратна размеру блока")
+	}
+
+	return &cbcMode{block: block, register: append([]byte{}, iv...), decrypt: decrypt}, nil
+}
+
+func (mode *cbcMode) BlockSize() int {
+	return mode.block.BlockSize()
+}
+
+func (mode *cbcMode) CryptBlocks(dst, src []byte) {
+	blockSize := checkModeBuffers(dst, src, mode.block.BlockSize())
+	buffer := make([]byte, blockSize)
+
+	for i := 0; i < len(src); i += blockSize {
+		if mode.decrypt {
+			// блок шифртекста сохраняется до записи результата, dst и src могут совпадать
+			ciphertext := append([]byte{}, src[i:i+blockSize]...)
+
+			mode.block.Decrypt(buffer, ciphertext)
+			xorBytes(dst[i:i+blockSize], buffer, mode.register)
+			shiftRegister(mode.register, ciphertext)
+		} else {
+			xorBytes(buffer, src[i:i+blockSize], mode.register)
+			mode.block.Encrypt(dst[i:i+blockSize], buffer)
+			shiftRegister(mode.register, dst[i:i+blockSize])
+		}
+	}
+}
+
+/*
+Режим гаммирования
+счетчик длины n инициализируется синхропосылкой длины n/2, дополненной нулями
+*/
+type ctrMode struct {
+	block   cipher.Block
+	counter []byte
+	gamma   []byte
+	segment int
+	offset  int
+}
+
+// получить режим гаммирования с параметром s
+func NewCTR(block cipher.Block, iv []byte, segment int) (cipher.Stream, error) {
+	blockSize := block.BlockSize()
+
+	if len(iv) != blockSize/2 {
+		return nil, errors.New("Длина синхропосылки должна быть равна половине размера блока")
+	}
+
+	if segment <= 0 || segment > blockSize {
+		return nil, errors.New("Неверный параметр s")
+	}
+
+	counter := make([]byte, blockSize)
+	copy(counter, iv)
+
+	return &ctrMode{block: block, counter: counter, gamma: make([]byte, blockSize), segment: segment}, nil
+}
+
+func (mode *ctrMode) XORKeyStream(dst, src []byte) {
+	checkStreamBuffers(dst, src)
+
+	for i := range src {
+		if mode.offset == 0 {
+			mode.block.Encrypt(mode.gamma, mode.counter)
+			incrementCounter(mode.counter)
+		}
+
+		dst[i] = src[i] ^ mode.gamma[mode.offset]
+		mode.offset = (mode.offset + 1) % mode.segment
+	}
+}
+
+/*
+Режим гаммирования с обратной связью по выходу
+регистр длины m = z*n сдвигается на n байт выхода шифра
+*/
+type ofbMode struct {
+	block    cipher.Block
+	register []byte
+	gamma    []byte
+	segment  int
+	offset   int
+}
+
+// получить режим гаммирования с обратной связью по выходу с параметром s
+// длина синхропосылки кратна размеру блока
+func NewOFB(block cipher.Block, iv []byte, segment int) (cipher.Stream, error) {
+	blockSize := block.BlockSize()
+
+	if len(iv) == 0 || len(iv)%blockSize != 0 {
+		return nil, errors.New("Длина синхропосылки не кратна размеру блока")
+	}
+
+	if segment <= 0 || segment > blockSize {
+		return nil, errors.New("Неверный параметр s")
+	}
+
+	return &ofbMode{block: block, register: append([]byte{}, iv...), gamma: make([]byte, blockSize), segment: segment}, nil
+}
+
+func (mode *ofbMode) XORKeyStream(dst, src []byte) {
+	checkStreamBuffers(dst, src)
+
+	for i := range src {
+		if mode.offset == 0 {
+			mode.block.Encrypt(mode.gamma, mode.register[:len(mode.gamma)])
+			shiftRegister(mode.register, mode.gamma)
+		}
+
+		dst[i] = src[i] ^ mode.gamma[mode.offset]
+		mode.offset = (mode.offset + 1) % mode.segment
+	}
+}
+
+/*
+Режим гаммирования с обратной связью по шифртексту
+регистр длины m >= n сдвигается на s байт шифртекста
+*/
+type cfbMode struct {
+	block      cipher.Block
+	register   []byte
+	gamma      []byte
+	ciphertext []byte
+	offset     int
+	decrypt    bool
+}
+
+// получить режим гаммирования с обратной связью по шифртексту для зашифрования с параметром s
+// длина синхропосылки не меньше размера блока
+func NewCFBEncrypter(block cipher.Block, iv []byte, segment int) (cipher.Stream, error) {
+	return newCFBMode(block, iv, segment, false)
+}
+
+// получить режим гаммирования с обратной связью по шифртексту для расшифрования с параметром s
+func NewCFBDecrypter(block cipher.Block, iv []byte, segment int) (cipher.Stream, error) {
+	return newCFBMode(block, iv, segment, true)
+}
+
+func newCFBMode(block cipher.Block, iv []byte, segment int, decrypt bool) (cipher.Stream, error) {
+	blockSize := block.BlockSize()
+
+	if len(iv) < blockSize {
+		return nil, errors.New("Длина синхропосылки меньше размера блока")
+	}
+
+	if segment <= 0 || segment > blockSize {
+		return nil, errors.New("Неверный параметр s")
+	}
+
+	return &cfbMode{
+		block:      block,
+		register:   append([]byte{}, iv...),
+		gamma:      make([]byte, blockSize),
+		ciphertext: make([]byte, segment),
+		decrypt:    decrypt,
+	}, nil
+}
+
+func (mode *cfbMode) XORKeyStream(dst, src []byte) {
+	checkStreamBuffers(dst, src)
+
+	for i := range src {
+		if mode.offset == 0 {
+			mode.block.Encrypt(mode.gamma, mode.register[:len(mode.gamma)])
+		}
+
+		value := src[i]
+		dst[i] = value ^ mode.gamma[mode.offset]
+
+		if !mode.decrypt {
+			value = dst[i]
+		}
+
+		mode.ciphertext[mode.offset] = value
+		mode.offset++
+
+		// регистр сдвигается только после получения всех s байт шифртекста
+		if mode.offset == len(mode.ciphertext) {
+			shiftRegister(mode.register, mode.ciphertext)
+			mode.offset = 0
+		}
+	}
+}
+
+// сдвинуть регистр влево на длину значения и записать значение в младшие байты
+func shiftRegister(register []byte, value []byte) {
+	copy(register, register[len(value):])
+	copy(register[len(register)-len(value):], value)
+}
+
+// увеличить счетчик big-endian на единицу по модулю 2^n
+func incrementCounter(counter []byte) {
+	for i := len(counter) - 1; i >= 0; i-- {
+		counter[i]++
+
+		if counter[i] != 0 {
+			return
+		}
+	}
+}
+
+// записать в dst побайтовое сложение a и b по модулю 2
+func xorBytes(dst, a, b []byte) {
+	for i := range dst {
+		dst[i] = a[i] ^ b[i]
+	}
+}
+
+// проверить буферы режима блочного шифрования и вернуть размер блока
+func checkModeBuffers(dst, src []byte, blockSize int) int {
+	if len(src)%blockSize != 0 {
+		panic("cryptography: input not full blocks")
+	}
+
+	if len(dst) < len(src) {
+		panic("cryptography: output smaller than input")
+	}
+
+	return blockSize
+}
+
+// проверить буферы режима гаммирования
+func checkStreamBuffers(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("cryptography: output smaller than input")
+	}
+}
@@ -0,0 +1,218 @@
+package cryptography
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+/*
+Контрольный пример режима ГОСТ Р 34.13-2015, приложение А
+*/
+type testModeExample struct {
+	name       string
+	iv         string
+	ciphertext []string
+	encrypt    func(cipher.Block, []byte) (func(dst, src []byte), error)
+	decrypt    func(cipher.Block, []byte) (func(dst, src []byte), error)
+}
+
+// открытый текст приложения А.1 для Кузнечика
+var testKuznyechikModePlaintext = []string{
+	"1122334455667700ffeeddccbbaa9988",
+	"00112233445566778899aabbcceeff0a",
+	"112233445566778899aabbcceeff0a00",
+	"2233445566778899aabbcceeff0a0011",
+}
+
+// открытый текст приложения А.2 для Магмы
+var testMagmaModePlaintext = []string{"92def06b3c130a59", "db54c704f8189d20", "4a98fb2e67a8024c", "8912409b17b57e41"}
+
+func testECB(decrypt bool) func(cipher.Block, []byte) (func(dst, src []byte), error) {
+	return func(block cipher.Block, _ []byte) (func(dst, src []byte), error) {
+		if decrypt {
+			return NewECBDecrypter(block).CryptBlocks, nil
+		}
+
+		return NewECBEncrypter(block).CryptBlocks, nil
+	}
+}
+
+func testCBC(decrypt bool) func(cipher.Block, []byte) (func(dst, src []byte), error) {
+	return func(block cipher.Block, iv []byte) (func(dst, src []byte), error) {
+		create := NewCBCEncrypter
+
+		if decrypt {
+			create = NewCBCDecrypter
+		}
+
+		mode, error := create(block, iv)
+
+		if error != nil {
+			return nil, error
+		}
+
+		return mode.CryptBlocks, nil
+	}
+}
+
+func testStream(create func(cipher.Block, []byte, int) (cipher.Stream, error)) func(cipher.Block, []byte) (func(dst, src []byte), error) {
+	return func(block cipher.Block, iv []byte) (func(dst, src []byte), error) {
+		stream, error := create(block, iv, block.BlockSize())
+
+		if error != nil {
+			return nil, error
+		}
+
+		return stream.XORKeyStream, nil
+	}
+}
+
+// проверить режимы на контрольных примерах
+func checkTestModes(t *testing.T, block cipher.Block, plaintext []string, examples []testModeExample) {
+	data, _ := hex.DecodeString(strings.Join(plaintext, ""))
+
+	for _, example := range examples {
+		iv, _ := hex.DecodeString(example.iv)
+		want := strings.Join(example.ciphertext, "")
+
+		encrypt, error := example.encrypt(block, iv)
+
+		if error != nil {
+			t.Fatalf("%s: %v", example.name, error)
+		}
+
+		result := make([]byte, len(data))
+		encrypt(result, data)
+
+		if hex.EncodeToString(result) != want {
+			t.Errorf("%s: ожидался шифртекст %s. Получен %x", example.name, want, result)
+		}
+
+		decrypt, error := example.decrypt(block, iv)
+
+		if error != nil {
+			t.Fatalf("%s: %v", example.name, error)
+		}
+
+		// расшифрование на месте частями, не кратными размеру блока
+		if strings.HasPrefix(example.name, "CTR") || strings.HasPrefix(example.name, "OFB") || strings.HasPrefix(example.name, "CFB") {
+			decrypt(result[:5], result[:5])
+			decrypt(result[5:], result[5:])
+		} else {
+			decrypt(result, result)
+		}
+
+		if !bytes.Equal(result, data) {
+			t.Errorf("%s: ожидался открытый текст %x. Получен %x", example.name, data, result)
+		}
+	}
+}
+
+func Test_KuznyechikModes_Success(t *testing.T) {
+	key, _ := hex.DecodeString(testKuznyechikKey)
+	block, _ := NewKuznyechik(key)
+	iv := "1234567890abcef0a1b2c3d4e5f0011223344556677889901213141516171819"
+
+	checkTestModes(t, block, testKuznyechikModePlaintext, []testModeExample{
+		{"ECB", "", []string{"7f679d90bebc24305a468d42b9d4edcd", "b429912c6e0032f9285452d76718d08b", "f0ca33549d247ceef3f5a5313bd4b157", "d0b09ccde830b9eb3a02c4c5aa8ada98"}, testECB(false), testECB(true)},
+		{"CTR", "1234567890abcef0", []string{"f195d8bec10ed1dbd57b5fa240bda1b8", "85eee733f6a13e5df33ce4b33c45dee4", "a5eae88be6356ed3d5e877f13564a3a5", "cb91fab1f20cbab6d1c6d15820bdba73"}, testStream(NewCTR), testStream(NewCTR)},
+		{"OFB", iv, []string{"81800a59b1842b24ff1f795e897abd95", "ed5b47a7048cfab48fb521369d9326bf", "66a257ac3ca0b8b1c80fe7fc10288a13", "203ebbc066138660a0292243f6903150"}, testStream(NewOFB), testStream(NewOFB)},
+		{"CBC", iv, []string{"689972d4a085fa4d90e52e3d6d7dcc27", "2826e661b478eca6af1e8e448d5ea5ac", "fe7babf1e91999e85640e8b0f49d90d0", "167688065a895c631a2d9a1560b63970"}, testCBC(false), testCBC(true)},
+		{"CFB", iv, []string{"81800a59b1842b24ff1f795e897abd95", "ed5b47a7048cfab48fb521369d9326bf", "79f2a8eb5cc68d38842d264e97a238b5", "4ffebecd4e922de6c75bd9dd44fbf4d1"}, testStream(NewCFBEncrypter), testStream(NewCFBDecrypter)},
+	})
+}
+
+func Test_MagmaModes_Success(t *testing.T) {
+	key, _ := hex.DecodeString(testMagmaKey)
+	block, _ := NewMagma(key)
+
+	checkTestModes(t, block, testMagmaModePlaintext, []testModeExample{
+		{"ECB", "", []string{"2b073f0494f372a0", "de70e715d3556e48", "11d8d9e9eacfbc1e", "7c68260996c67efb"}, testECB(false), testECB(true)},
+		{"CTR", "12345678", []string{"4e98110c97b7b93c", "3e250d93d6e85d69", "136d868807b2dbef", "568eb680ab52a12d"}, testStream(NewCTR), testStream(NewCTR)},
+		{"OFB", "1234567890abcdef234567890abcdef1", []string{"db37e0e266903c83", "0d46644c1f9a089c", "a0f83062430e327e", "c824efb8bd4fdb05"}, testStream(NewOFB), testStream(NewOFB)},
+		{"CBC", "1234567890abcdef234567890abcdef134567890abcdef12", []string{"96d1b05eea683919", "aff76129abb937b9", "5058b4a1c4bc0019", "20b78b1a7cd7e667"}, testCBC(false), testCBC(true)},
+		{"CFB", "1234567890abcdef234567890abcdef1", []string{"db37e0e266903c83", "0d46644c1f9a089c", "24bdd2035315d38b", "bcc0321421075505"}, testStream(NewCFBEncrypter), testStream(NewCFBDecrypter)},
+	})
+}
+
+func Test_ModesSegment_Success(t *testing.T) {
+	key, _ := hex.DecodeString(testKuznyechikKey)
+	block, _ := NewKuznyechik(key)
+	iv, _ := hex.DecodeString("1234567890abcef0a1b2c3d4e5f00112")
+	data := []byte("Сообщение, длина которого не кратна размеру блока")
+
+	// параметр s меньше размера блока
+	for _, segment := range []int{1, 7, KuznyechikBlockSize} {
+		encrypter, error := NewCFBEncrypter(block, iv, segment)
+
+		if error != nil {
+			t.Fatal(error)
+		}
+
+		decrypter, _ := NewCFBDecrypter(block, iv, segment)
+		ciphertext := make([]byte, len(data))
+		encrypter.XORKeyStream(ciphertext, data)
+
+		result := make([]byte, len(data))
+
+		for i := range ciphertext {
+			decrypter.XORKeyStream(result[i:i+1], ciphertext[i:i+1])
+		}
+
+		if !bytes.Equal(result, data) {
+			t.Errorf("Ожидалось совпадение данных для s = %d", segment)
+		}
+	}
+
+	if _, error := NewCTR(block, iv, KuznyechikBlockSize); error == nil {
+		t.Error("Ожидалась ошибка для синхропосылки CTR длины n")
+	}
+
+	if _, error := NewOFB(block, iv[:8], 17); error == nil {
+		t.Error("Ожидалась ошибка для синхропосылки OFB короче блока")
+	}
+
+	if _, error := NewCBCEncrypter(block, iv[:15]); error == nil {
+		t.Error("Ожидалась ошибка для синхропосылки CBC длины, не кратной блоку")
+	}
+}
+
+func Test_Padding_Success(t *testing.T) {
+	for _, example := range []struct {
+		data                 string
+		first, second, third string
+	}{
+		{"", "", "8000000000000000", ""},
+		{"0102", "0102000000000000", "0102800000000000", "0102800000000000"},
+		{"0102030405060708", "0102030405060708", "01020304050607088000000000000000", "0102030405060708"},
+	} {
+		data, _ := hex.DecodeString(example.data)
+
+		if result := hex.EncodeToString(PadProcedure1(data, MagmaBlockSize)); result != example.first {
+			t.Errorf("Ожидалось дополнение по процедуре 1 %s. Получено %s", example.first, result)
+		}
+
+		padded := PadProcedure2(data, MagmaBlockSize)
+
+		if result := hex.EncodeToString(padded); result != example.second {
+			t.Errorf("Ожидалось дополнение по процедуре 2 %s. Получено %s", example.second, result)
+		}
+
+		if result := hex.EncodeToString(PadProcedure3(data, MagmaBlockSize)); result != example.third {
+			t.Errorf("Ожидалось дополнение по процедуре 3 %s. Получено %s", example.third, result)
+		}
+
+		unpadded, error := UnpadProcedure2(padded, MagmaBlockSize)
+
+		if error != nil || !bytes.Equal(unpadded, data) {
+			t.Errorf("Ожидалось удаление дополнения %s. Получено %x: %v", example.data, unpadded, error)
+		}
+	}
+
+	if _, error := UnpadProcedure2(make([]byte, MagmaBlockSize), MagmaBlockSize); error == nil {
+		t.Error("Ожидалась ошибка для блока без единичного бита")
+	}
+}
@@ -0,0 +1,253 @@
+package cryptography
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/madpo/go-gost-crypto/pkg/wrapper"
+)
+
+// идентификаторы алгоритмов хэширования
+var (
+	OIDMD5               = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 5}
+	OIDSha256            = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	OIDSha384            = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	OIDSha512            = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+	OIDGOST3411          = asn1.ObjectIdentifier{1, 2, 643, 2, 2, 9}
+	OIDGOST3411_2012_256 = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 2, 2}
+	OIDGOST3411_2012_512 = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 2, 3}
+)
+
+// идентификаторы алгоритмов открытого ключа и подписи
+var (
+	OIDGOST3410_2001                   = asn1.ObjectIdentifier{1, 2, 643, 2, 2, 19}
+	OIDGOST3410_2012_256               = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 1, 1}
+	OIDGOST3410_2012_512               = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 1, 2}
+	OIDGOST3411_GOST3410_2001          = asn1.ObjectIdentifier{1, 2, 643, 2, 2, 3}
+	OIDGOST3411_2012_256_GOST3410_2012 = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 3, 2}
+	OIDGOST3411_2012_512_GOST3410_2012 = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 3, 3}
+	OIDRSA                             = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	OIDRSASha256                       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	OIDRSASha384                       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 12}
+	OIDRSASha512                       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 13}
+	OIDRSAPSS                          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 10}
+	OIDMGF1                            = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 8}
+)
+
+/*
+Алгоритм хэширования
+*/
+type HashAlgorithm struct {
+	Name string
+	OID  asn1.ObjectIdentifier
+	// размер значения хэша в байтах
+	Size int
+	// тип хэша КриптоПро, 0 для алгоритмов из стандартной библиотеки
+	HashType wrapper.HashType
+	// фабрика метода хэширования
+	Create func() (release func(), calculateHash func(io.Reader) (io.Reader, error), exception error)
+}
+
+/*
+Алгоритм подписи
+*/
+type SignatureAlgorithm struct {
+	Name string
+	// идентификатор алгоритма подписи
+	OID asn1.ObjectIdentifier
+	// идентификатор алгоритма открытого ключа
+	PublicKeyOID asn1.ObjectIdentifier
+	Hash         *HashAlgorithm
+}
+
+var (
+	HashMD5               = &HashAlgorithm{Name: "MD5", OID: OIDMD5, Size: 16, Create: CreateMD5HashMethod}
+	HashSha256            = &HashAlgorithm{Name: "SHA-256", OID: OIDSha256, Size: 32, Create: CreateSha256HashMethod}
+	HashSha384            = &HashAlgorithm{Name: "SHA-384", OID: OIDSha384, Size: 48, Create: CreateSha384HashMethod}
+	HashSha512            = &HashAlgorithm{Name: "SHA-512", OID: OIDSha512, Size: 64, Create: CreateSha512HashMethod}
+	HashGOST3411          = &HashAlgorithm{Name: "ГОСТ Р 34.11-94", OID: OIDGOST3411, Size: 32, HashType: wrapper.GOST3411, Create: CreateGOST3411HashMethod}
+	HashGOST3411_2012_256 = &HashAlgorithm{Name: "ГОСТ Р 34.11-2012-256", OID: OIDGOST3411_2012_256, Size: 32, HashType: wrapper.GOST3411_2012_256, Create: CreateGOST3411_2012_256HashMethod}
+	HashGOST3411_2012_512 = &HashAlgorithm{Name: "ГОСТ Р 34.11-2012-512", OID: OIDGOST3411_2012_512, Size: 64, HashType: wrapper.GOST3411_2012_512, Create: CreateGOST3411_2012_512HashMethod}
+)
+
+var (
+	SignatureGOST3410_2001 = &SignatureAlgorithm{
+		Name:         "ГОСТ Р 34.10-2001 с ГОСТ Р 34.11-94",
+		OID:          OIDGOST3411_GOST3410_2001,
+		PublicKeyOID: OIDGOST3410_2001,
+		Hash:         HashGOST3411,
+	}
+	SignatureGOST3410_2012_256 = &SignatureAlgorithm{
+		Name:         "ГОСТ Р 34.10-2012-256 с ГОСТ Р 34.11-2012-256",
+		OID:          OIDGOST3411_2012_256_GOST3410_2012,
+		PublicKeyOID: OIDGOST3410_2012_256,
+		Hash:         HashGOST3411_2012_256,
+	}
+	SignatureGOST3410_2012_512 = &SignatureAlgorithm{
+		Name:         "ГОСТ Р 34.10-2012-512 с ГОСТ Р 34.11-2012-512",
+		OID:          OIDGOST3411_2012_512_GOST3410_2012,
+		PublicKeyOID: OIDGOST3410_2012_512,
+		Hash:         HashGOST3411_2012_512,
+	}
+	SignatureRSASha256 = &SignatureAlgorithm{Name: "RSA-SHA256", OID: OIDRSASha256, PublicKeyOID: OIDRSA, Hash: HashSha256}
+	SignatureRSASha384 = &SignatureAlgorithm{Name: "RSA-SHA384", OID: OIDRSASha384, PublicKeyOID: OIDRSA, Hash: HashSha384}
+	SignatureRSASha512 = &SignatureAlgorithm{Name: "RSA-SHA512", OID: OIDRSASha512, PublicKeyOID: OIDRSA, Hash: HashSha512}
+	// RSASSA-PSS использует один OID, алгоритм хэширования задается в параметрах
+	SignatureRSAPSSSha256 = &SignatureAlgorithm{Name: "RSA-PSS-SHA256", OID: OIDRSAPSS, PublicKeyOID: OIDRSA, Hash: HashSha256}
+	SignatureRSAPSSSha384 = &SignatureAlgorithm{Name: "RSA-PSS-SHA384", OID: OIDRSAPSS, PublicKeyOID: OIDRSA, Hash: HashSha384}
+	SignatureRSAPSSSha512 = &SignatureAlgorithm{Name: "RSA-PSS-SHA512", OID: OIDRSAPSS, PublicKeyOID: OIDRSA, Hash: HashSha512}
+)
+
+var hashAlgorithms = []*HashAlgorithm{
+	HashMD5,
+	HashSha256,
+	HashSha384,
+	HashSha512,
+	HashGOST3411,
+	HashGOST3411_2012_256,
+	HashGOST3411_2012_512,
+}
+
+var signatureAlgorithms = []*SignatureAlgorithm{
+	SignatureGOST3410_2001,
+	SignatureGOST3410_2012_256,
+	SignatureGOST3410_2012_512,
+	SignatureRSASha256,
+	SignatureRSASha384,
+	SignatureRSASha512,
+	SignatureRSAPSSSha256,
+	SignatureRSAPSSSha384,
+	SignatureRSAPSSSha512,
+}
+
+// найти алгоритм хэширования по OID
+func FindHashAlgorithm(oid asn1.ObjectIdentifier) (*HashAlgorithm, error) {
+	for _, algorithm := range hashAlgorithms {
+		if algorithm.OID.Equal(oid) {
+			return algorithm, nil
+		}
+	}
+
+	return nil, errors.New("Не найден алгоритм хэширования " + oid.String())
+}
+
+// найти алгоритм хэширования по типу хэша КриптоПро
+func FindHashAlgorithmByType(hashType wrapper.HashType) (*HashAlgorithm, error) {
+	for _, algorithm := range hashAlgorithms {
+		if algorithm.HashType != 0 && algorithm.HashType == hashType {
+			return algorithm, nil
+		}
+	}
+
+	return nil, errors.New("Не найден тип хэширования")
+}
+
+// найти алгоритм подписи по OID подписи или по OID открытого ключа и алгоритму хэширования
+func FindSignatureAlgorithm(oid asn1.ObjectIdentifier, hash *HashAlgorithm) (*SignatureAlgorithm, error) {
+	// для RSASSA-PSS алгоритм определяется по OID вместе с алгоритмом хэширования
+	for _, algorithm := range signatureAlgorithms {
+		if algorithm.OID.Equal(oid) && algorithm.Hash == hash {
+			return algorithm, nil
+		}
+	}
+
+	for _, algorithm := range signatureAlgorithms {
+		if algorithm.OID.Equal(oid) {
+			return algorithm, nil
+		}
+	}
+
+	for _, algorithm := range signatureAlgorithms {
+		if algorithm.PublicKeyOID.Equal(oid) && algorithm.Hash == hash {
+			return algorithm, nil
+		}
+	}
+
+	return nil, errors.New("Не найден алгоритм подписи " + oid.String())
+}
+
+// найти алгоритм подписи по имени, например RSA-SHA256 или RSA-PSS-SHA512
+func FindSignatureAlgorithmByName(name string) (*SignatureAlgorithm, error) {
+	for _, algorithm := range signatureAlgorithms {
+		if strings.EqualFold(algorithm.Name, name) {
+			return algorithm, nil
+		}
+	}
+
+	return nil, errors.New("Не найден алгоритм подписи " + name)
+}
+
+// вычислить значение хэша последовательности байт
+func calculateDigest(algorithm *HashAlgorithm, reader io.Reader) ([]byte, error) {
+	release, calculateHash, exception := algorithm.Create()
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	defer release()
+
+	hash, exception := calculateHash(reader)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	return io.ReadAll(hash)
+}
+
+// вычислить значения хэша несколькими алгоритмами за одно чтение потока
+func calculateDigests(algorithms []*HashAlgorithm, reader io.Reader) ([][]byte, error) {
+	if len(algorithms) == 1 {
+		digest, exception := calculateDigest(algorithms[0], reader)
+
+		return [][]byte{digest}, exception
+	}
+
+	digests := make([][]byte, len(algorithms))
+	exceptions := make([]error, len(algorithms))
+	writers := make([]io.Writer, len(algorithms))
+	done := make(chan struct{})
+
+	for i, algorithm := range algorithms {
+		pipeReader, pipeWriter := io.Pipe()
+		writers[i] = pipeWriter
+
+		go func(i int, algorithm *HashAlgorithm) {
+			digests[i], exceptions[i] = calculateDigest(algorithm, pipeReader)
+			// дочитать поток, чтобы не заблокировать остальные алгоритмы
+			io.Copy(io.Discard, pipeReader)
+			done <- struct{}{}
+		}(i, algorithm)
+	}
+
+	_, exception := io.Copy(io.MultiWriter(writers...), reader)
+
+	for _, writer := range writers {
+		writer.(*io.PipeWriter).CloseWithError(exception)
+	}
+
+	for range algorithms {
+		<-done
+	}
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	for _, exception := range exceptions {
+		if exception != nil {
+			return nil, exception
+		}
+	}
+
+	return digests, nil
+}
+
+// вычислить значение хэша последовательности байт в памяти
+func calculateBytesDigest(algorithm *HashAlgorithm, data []byte) ([]byte, error) {
+	return calculateDigest(algorithm, bytes.NewReader(data))
+}
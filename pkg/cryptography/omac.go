@@ -0,0 +1,304 @@
+package cryptography
+
+import (
+	"crypto/cipher"
+	"errors"
+	"hash"
+
+	"github.com/madpo/go-gost-crypto/pkg/wrapper"
+)
+
+/*
+Имитовставка OMAC, ГОСТ Р 34.13-2015 п. 5.6
+*/
+type omac struct {
+	block   cipher.Block
+	tagSize int
+	// дополнительные ключи K1 и K2
+	first, second []byte
+	// состояние: C_{i-1} и необработанный последний блок
+	state  []byte
+	buffer []byte
+}
+
+// получить метод вычисления имитовставки OMAC для Кузнечика или Магмы
+// tagSize - длина имитовставки s в байтах, при 0 - размер блока
+func NewOMAC(block cipher.Block, tagSize int) (hash.Hash, error) {
+	blockSize := block.BlockSize()
+
+	if blockSize != KuznyechikBlockSize && blockSize != MagmaBlockSize {
+		return nil, errors.New("Имитовставка OMAC определена для блоков 64 и 128 бит")
+	}
+
+	if tagSize == 0 {
+		tagSize = blockSize
+	}
+
+	if tagSize < 0 || tagSize > blockSize {
+		return nil, errors.New("Неверная длина имитовставки")
+	}
+
+	// R = E(0^n), K1 = R << 1 (xor B), K2 = K1 << 1 (xor B)
+	value := make([]byte, blockSize)
+	block.Encrypt(value, value)
+
+	first := shiftSubkey(value)
+	mac := &omac{block: block, tagSize: tagSize, first: first, second: shiftSubkey(first)}
+
+	mac.Reset()
+
+	return mac, nil
+}
+
+func (mac *omac) Size() int {
+	return mac.tagSize
+}
+
+func (mac *omac) BlockSize() int {
+	return mac.block.BlockSize()
+}
+
+func (mac *omac) Reset() {
+	mac.state = make([]byte, mac.block.BlockSize())
+	mac.buffer = make([]byte, 0, mac.block.BlockSize())
+}
+
+func (mac *omac) Write(data []byte) (int, error) {
+	return writeMACBlocks(data, &mac.buffer, mac.block.BlockSize(), func(block []byte) {
+		xorBytes(mac.state, mac.state, block)
+		mac.block.Encrypt(mac.state, mac.state)
+	}), nil
+}
+
+func (mac *omac) Sum(in []byte) []byte {
+	// вычисление не изменяет состояние, запись может быть продолжена
+	last := make([]byte, mac.block.BlockSize())
+	copy(last, mac.buffer)
+
+	additional := mac.first
+
+	// неполный последний блок дополняется по процедуре 2
+	if len(mac.buffer) < len(last) {
+		last[len(mac.buffer)] = 0x80
+		additional = mac.second
+	}
+
+	xorBytes(last, last, mac.state)
+	xorBytes(last, last, additional)
+	mac.block.Encrypt(last, last)
+
+	return append(in, last[:mac.tagSize]...)
+}
+
+/*
+Имитовставка ГОСТ 28147-89
+*/
+type gost28147MAC struct {
+	block *gost28147Cipher
+	iv    []byte
+	// состояние, число обработанных блоков и необработанный последний блок
+	state  []byte
+	blocks int
+	buffer []byte
+}
+
+// получить метод вычисления имитовставки ГОСТ 28147-89 длиной 4 байта
+// при sbox = nil используется таблица замен КриптоПро A, при iv = nil - нулевая синхропосылка
+func NewGOST28147MAC(key []byte, sbox *SBox, iv []byte) (hash.Hash, error) {
+	if sbox == nil {
+		sbox = SBoxCryptoProA
+	}
+
+	block, exception := newGOST28147(key, sbox)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	if iv == nil {
+		iv = make([]byte, GOST28147BlockSize)
+	}
+
+	if len(iv) != GOST28147BlockSize {
+		return nil, errors.New("Неверная длина синхропосылки")
+	}
+
+	mac := &gost28147MAC{block: block, iv: append([]byte{}, iv...)}
+
+	mac.Reset()
+
+	return mac, nil
+}
+
+func (mac *gost28147MAC) Size() int {
+	return int(wrapper.SizeImit)
+}
+
+func (mac *gost28147MAC) BlockSize() int {
+	return GOST28147BlockSize
+}
+
+func (mac *gost28147MAC) Reset() {
+	mac.state = append([]byte{}, mac.iv...)
+	mac.blocks = 0
+	mac.buffer = make([]byte, 0, GOST28147BlockSize)
+}
+
+func (mac *gost28147MAC) Write(data []byte) (int, error) {
+	return writeMACBlocks(data, &mac.buffer, GOST28147BlockSize, func(block []byte) {
+		xorBytes(mac.state, mac.state, block)
+		mac.block.encryptMAC(mac.state, mac.state)
+		mac.blocks++
+	}), nil
+}
+
+func (mac *gost28147MAC) Sum(in []byte) []byte {
+	state := append([]byte{}, mac.state...)
+	blocks := mac.blocks
+
+	// последний блок дополняется нулями
+	if len(mac.buffer) > 0 {
+		last := make([]byte, GOST28147BlockSize)
+		copy(last, mac.buffer)
+
+		xorBytes(state, state, last)
+		mac.block.encryptMAC(state, state)
+		blocks++
+	}
+
+	// данные из одного блока дополняются нулевым блоком, как в КриптоПро
+	if blocks == 1 {
+		mac.block.encryptMAC(state, state)
+	}
+
+	return append(in, state[:wrapper.SizeImit]...)
+}
+
+// обработать полные блоки данных, последний блок остается в буфере до вычисления имитовставки
+func writeMACBlocks(data []byte, buffer *[]byte, blockSize int, process func(block []byte)) int {
+	count := len(data)
+
+	for len(data) > 0 {
+		if len(*buffer) == blockSize {
+			process(*buffer)
+			*buffer = (*buffer)[:0]
+		}
+
+		free := blockSize - len(*buffer)
+
+		if free > len(data) {
+			free = len(data)
+		}
+
+		*buffer = append(*buffer, data[:free]...)
+		data = data[free:]
+	}
+
+	return count
+}
+
+/*
+Имитовставка ГОСТ 28147-89, вычисляемая КриптоПро
+*/
+type cspGOST28147MAC struct {
+	provider   *wrapper.CryptoProvider
+	key        *wrapper.CryptoKey
+	hashMethod *wrapper.CryptoHash
+}
+
+// получить метод вычисления имитовставки ГОСТ 28147-89 средствами КриптоПро (CALG_G28147_IMIT)
+// используется таблица замен ключа провайдера по умолчанию и нулевая синхропосылка
+func CreateGOST28147CSPMACMethod(cspType wrapper.CSPType, key []byte) (release func(), mac hash.Hash, exception error) {
+	if len(key) != GOST28147KeySize {
+		return nil, nil, errors.New("Неверная длина ключа ГОСТ 28147-89")
+	}
+
+	cryptoProvider, exception := wrapper.TakeCSP(cspType)
+
+	if exception != nil {
+		return nil, nil, exception
+	}
+
+	value := append([]byte{}, key...)
+	cryptoKey, exception := wrapper.ImportCipherKey(cryptoProvider, wrapper.GOST28147, &value)
+
+	if exception != nil {
+		wrapper.ReleaseCSP(cryptoProvider)
+		return nil, nil, exception
+	}
+
+	iv := make([]byte, GOST28147BlockSize)
+
+	if exception := wrapper.SetCipherIV(cryptoKey, &iv); exception != nil {
+		wrapper.ReleaseKey(cryptoKey)
+		wrapper.ReleaseCSP(cryptoProvider)
+		return nil, nil, exception
+	}
+
+	hashMethod, exception := wrapper.TakeKeyedHashMethod(cryptoProvider, wrapper.GOST28147_IMIT, cryptoKey)
+
+	if exception != nil {
+		wrapper.ReleaseKey(cryptoKey)
+		wrapper.ReleaseCSP(cryptoProvider)
+		return nil, nil, exception
+	}
+
+	cspMAC := &cspGOST28147MAC{provider: cryptoProvider, key: cryptoKey, hashMethod: hashMethod}
+
+	return func() {
+		wrapper.ReleaseHashMethod(cspMAC.hashMethod)
+		wrapper.ReleaseKey(cryptoKey)
+		wrapper.ReleaseCSP(cryptoProvider)
+	}, cspMAC, nil
+}
+
+func (mac *cspGOST28147MAC) Size() int {
+	return int(wrapper.SizeImit)
+}
+
+func (mac *cspGOST28147MAC) BlockSize() int {
+	return GOST28147BlockSize
+}
+
+// интерфейс hash.Hash не возвращает ошибок из Reset и Sum, поэтому ошибка провайдера приводит к panic
+func (mac *cspGOST28147MAC) Reset() {
+	hashMethod, exception := wrapper.TakeKeyedHashMethod(mac.provider, wrapper.GOST28147_IMIT, mac.key)
+
+	if exception != nil {
+		panic(exception)
+	}
+
+	wrapper.ReleaseHashMethod(mac.hashMethod)
+	mac.hashMethod = hashMethod
+}
+
+func (mac *cspGOST28147MAC) Write(data []byte) (int, error) {
+	if len(data) == 0 {
+		return 0, nil
+	}
+
+	if exception := wrapper.ApplyHash(mac.hashMethod, &data); exception != nil {
+		return 0, exception
+	}
+
+	return len(data), nil
+}
+
+func (mac *cspGOST28147MAC) Sum(in []byte) []byte {
+	// значение получается из копии, чтобы запись могла быть продолжена
+	duplicate, exception := wrapper.DuplicateHashMethod(mac.hashMethod)
+
+	if exception != nil {
+		panic(exception)
+	}
+
+	defer wrapper.ReleaseHashMethod(duplicate)
+
+	value, exception := wrapper.CalculateHashValue(duplicate, wrapper.SizeImit)
+
+	if exception != nil {
+		panic(exception)
+	}
+
+	return append(in, *value...)
+}
@@ -0,0 +1,138 @@
+package cryptography
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/madpo/go-gost-crypto/pkg/wrapper"
+)
+
+func Test_OMAC_Success(t *testing.T) {
+	kuznyechikKey, _ := hex.DecodeString(testKuznyechikKey)
+	kuznyechik, _ := NewKuznyechik(kuznyechikKey)
+
+	magmaKey, _ := hex.DecodeString(testMagmaKey)
+	magma, _ := NewMagma(magmaKey)
+
+	// ГОСТ Р 34.13-2015, приложение А.1.6 и А.2.6
+	for _, example := range []struct {
+		name      string
+		block     cipher.Block
+		plaintext []string
+		tagSize   int
+		want      string
+	}{
+		{"Кузнечик", kuznyechik, testKuznyechikModePlaintext, 8, "336f4d296059fbe3"},
+		{"Магма", magma, testMagmaModePlaintext, 4, "154e7210"},
+	} {
+		mac, error := NewOMAC(example.block, example.tagSize)
+
+		if error != nil {
+			t.Fatal(error)
+		}
+
+		data, _ := hex.DecodeString(strings.Join(example.plaintext, ""))
+
+		if _, error := io.Copy(mac, bytes.NewReader(data)); error != nil {
+			t.Fatal(error)
+		}
+
+		if result := hex.EncodeToString(mac.Sum(nil)); result != example.want {
+			t.Errorf("%s: ожидалась имитовставка %s. Получена %s", example.name, example.want, result)
+		}
+
+		// неполный последний блок дополняется и использует ключ K2
+		mac.Write([]byte{0x01})
+		extended := mac.Sum(nil)
+
+		mac.Reset()
+		mac.Write(append(data, 0x01, 0x80))
+
+		if bytes.Equal(extended, mac.Sum(nil)) {
+			t.Errorf("%s: ожидались разные имитовставки для дополненных и полных данных", example.name)
+		}
+	}
+
+	if _, error := NewOMAC(kuznyechik, 17); error == nil {
+		t.Error("Ожидалась ошибка для имитовставки длиннее блока")
+	}
+}
+
+func Test_GOST28147MAC_Success(t *testing.T) {
+	key, _ := hex.DecodeString(testMagmaKey)
+
+	mac, error := NewGOST28147MAC(key, nil, nil)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	mac.Write([]byte("Hello world"))
+	value := mac.Sum(nil)
+
+	// значение вычислено независимой реализацией режима выработки имитовставки в соглашениях gost-engine (gost_imit)
+	if result, want := hex.EncodeToString(value), "6b259191"; result != want {
+		t.Errorf("Ожидалась имитовставка %s. Получена %s", want, result)
+	}
+
+	// Sum не изменяет состояние
+	if !bytes.Equal(value, mac.Sum(nil)) {
+		t.Error("Ожидалась та же имитовставка при повторном вызове Sum")
+	}
+
+	// один блок дополняется нулевым блоком
+	mac.Reset()
+	mac.Write([]byte("a"))
+	single := mac.Sum(nil)
+
+	mac.Reset()
+	mac.Write(append([]byte("a"), make([]byte, 15)...))
+
+	if !bytes.Equal(single, mac.Sum(nil)) {
+		t.Error("Ожидалось дополнение одного блока нулевым блоком")
+	}
+
+	if result, want := hex.EncodeToString(single), "bb007d16"; result != want {
+		t.Errorf("Ожидалась имитовставка одного блока %s. Получена %s", want, result)
+	}
+
+	other, _ := NewGOST28147MAC(key, SBoxTC26Z, []byte{1, 2, 3, 4, 5, 6, 7, 8})
+	other.Write([]byte("Hello world"))
+
+	if result, want := hex.EncodeToString(other.Sum(nil)), "ad89dc49"; result != want {
+		t.Errorf("Ожидалась имитовставка с таблицей замен TC26 Z и синхропосылкой %s. Получена %s", want, result)
+	}
+
+	if _, error := NewGOST28147MAC(key, nil, []byte{1}); error == nil {
+		t.Error("Ожидалась ошибка для синхропосылки неверной длины")
+	}
+}
+
+func Test_GOST28147MACCSP_Success(t *testing.T) {
+	key, _ := hex.DecodeString(testMagmaKey)
+	release, cspMAC, error := CreateGOST28147CSPMACMethod(wrapper.GOST2012_256, key)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer release()
+
+	mac, _ := NewGOST28147MAC(key, SBoxCryptoProA, nil)
+
+	for _, data := range []string{"Hello world", "Сообщение длиной больше одного блока"} {
+		cspMAC.Reset()
+		mac.Reset()
+
+		cspMAC.Write([]byte(data))
+		mac.Write([]byte(data))
+
+		if want, result := hex.EncodeToString(mac.Sum(nil)), hex.EncodeToString(cspMAC.Sum(nil)); result != want {
+			t.Errorf("Ожидалась имитовставка %s. Получена %s", want, result)
+		}
+	}
+}
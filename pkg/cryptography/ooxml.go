@@ -0,0 +1,1050 @@
+package cryptography
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/madpo/go-gost-crypto/pkg/c14n"
+)
+
+// пространства имен и идентификаторы Open Packaging Conventions (ECMA-376 часть 2)
+const (
+	opcRelationshipsNamespace    = "http://schemas.openxmlformats.org/package/2006/relationships"
+	opcDigitalSignatureNamespace = "http://schemas.openxmlformats.org/package/2006/digital-signature"
+	opcRelationshipTransform     = "http://schemas.openxmlformats.org/package/2006/RelationshipTransform"
+	opcOriginRelationship        = "http://schemas.openxmlformats.org/package/2006/relationships/digital-signature/origin"
+	opcSignatureRelationship     = "http://schemas.openxmlformats.org/package/2006/relationships/digital-signature/signature"
+	opcOriginContentType         = "application/vnd.openxmlformats-package.digital-signature-origin"
+	opcSignatureContentType      = "application/vnd.openxmlformats-package.digital-signature-xmlsignature+xml"
+	opcRelationshipsContentType  = "application/vnd.openxmlformats-package.relationships+xml"
+	opcContentTypesPart          = "/[Content_Types].xml"
+	opcSignaturesFolder          = "/_xmlsignatures/"
+	opcPackageObjectID           = "idPackageObject"
+	opcPackageSignatureID        = "idPackageSignature"
+)
+
+/*
+Результат проверки подписи пакета OOXML
+*/
+type OOXMLSignatureReport struct {
+	// имя части подписи
+	Part        string
+	Certificate *x509.Certificate
+	SigningTime time.Time
+	// подписанные части пакета
+	Parts []string
+	// подписаны все части пакета, кроме описания типов и частей подписей
+	CoversPackage bool
+	Exception     error
+}
+
+// подпись верна
+func (report *OOXMLSignatureReport) Valid() bool {
+	return report.Exception == nil
+}
+
+/*
+Связь части пакета
+*/
+type opcRelationship struct {
+	ID         string
+	Type       string
+	Target     string
+	TargetMode string
+}
+
+/*
+Подписываемая часть пакета
+*/
+type opcReference struct {
+	part string
+	// к части связей применяется преобразование RelationshipTransform
+	relationships bool
+	sourceIDs     []string
+	sourceTypes   []string
+}
+
+/*
+Пакет OPC: zip-архив частей с описанием типов
+*/
+type opcPackage struct {
+	files []*zip.File
+	// имя части в нижнем регистре - файл архива
+	parts     map[string]*zip.File
+	defaults  map[string]string
+	overrides map[string]string
+}
+
+// открыть пакет OPC
+func openOPCPackage(document io.ReadSeeker) (*opcPackage, error) {
+	size, exception := document.Seek(0, io.SeekEnd)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	reader, exception := zip.NewReader(readSeekerAt{document}, size)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	opc := &opcPackage{files: reader.File, parts: map[string]*zip.File{}, defaults: map[string]string{}, overrides: map[string]string{}}
+
+	for _, file := range reader.File {
+		if !strings.HasSuffix(file.Name, "/") {
+			opc.parts[strings.ToLower("/"+file.Name)] = file
+		}
+	}
+
+	data, exception := opc.read(opcContentTypesPart)
+
+	if exception != nil {
+		return nil, errors.New("Некорректный пакет OOXML: не найден [Content_Types].xml")
+	}
+
+	types, exception := c14n.Parse(bytes.NewReader(data))
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	for _, child := range types.Root().Children {
+		element, ok := child.(*c14n.Element)
+
+		if !ok {
+			continue
+		}
+
+		contentType, _ := element.Attribute("ContentType")
+
+		switch element.Local {
+		case "Default":
+			extension, _ := element.Attribute("Extension")
+			opc.defaults[strings.ToLower(extension)] = contentType
+		case "Override":
+			part, _ := element.Attribute("PartName")
+			opc.overrides[strings.ToLower(part)] = contentType
+		}
+	}
+
+	return opc, nil
+}
+
+// открыть часть пакета
+func (opc *opcPackage) open(part string) (io.ReadCloser, error) {
+	file, ok := opc.parts[strings.ToLower(part)]
+
+	if !ok {
+		return nil, errors.New("Не найдена часть пакета " + part)
+	}
+
+	return file.Open()
+}
+
+// прочитать часть пакета
+func (opc *opcPackage) read(part string) ([]byte, error) {
+	reader, exception := opc.open(part)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	defer reader.Close()
+
+	return io.ReadAll(reader)
+}
+
+// существует ли часть пакета
+func (opc *opcPackage) exists(part string) bool {
+	_, ok := opc.parts[strings.ToLower(part)]
+
+	return ok
+}
+
+// тип содержимого части
+func (opc *opcPackage) contentType(part string) string {
+	if contentType, ok := opc.overrides[strings.ToLower(part)]; ok {
+		return contentType
+	}
+
+	return opc.defaults[strings.ToLower(strings.TrimPrefix(path.Ext(part), "."))]
+}
+
+// имя части связей для части, "/" - связи пакета
+func opcRelationshipsPart(part string) string {
+	if part == "/" {
+		return "/_rels/.rels"
+	}
+
+	return path.Join(path.Dir(part), "_rels", path.Base(part)+".rels")
+}
+
+// имя части, на которую ссылается связь
+func opcTargetPart(source string, target string) string {
+	if strings.HasPrefix(target, "/") {
+		return path.Clean(target)
+	}
+
+	if source == "/" {
+		return path.Join("/", target)
+	}
+
+	return path.Join(path.Dir(source), target)
+}
+
+// прочитать связи части, nil если части связей нет
+func (opc *opcPackage) relationships(source string) ([]opcRelationship, error) {
+	part := opcRelationshipsPart(source)
+
+	if !opc.exists(part) {
+		return nil, nil
+	}
+
+	data, exception := opc.read(part)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	return parseOPCRelationships(data)
+}
+
+// разобрать часть связей
+func parseOPCRelationships(data []byte) ([]opcRelationship, error) {
+	document, exception := c14n.Parse(bytes.NewReader(data))
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	var relationships []opcRelationship
+
+	for _, child := range document.Root().Children {
+		element, ok := child.(*c14n.Element)
+
+		if !ok || element.Local != "Relationship" {
+			continue
+		}
+
+		relationship := opcRelationship{}
+		relationship.ID, _ = element.Attribute("Id")
+		relationship.Type, _ = element.Attribute("Type")
+		relationship.Target, _ = element.Attribute("Target")
+		relationship.TargetMode, _ = element.Attribute("TargetMode")
+		relationships = append(relationships, relationship)
+	}
+
+	return relationships, nil
+}
+
+// найти часть происхождения подписей
+func (opc *opcPackage) originPart() (string, error) {
+	relationships, exception := opc.relationships("/")
+
+	if exception != nil {
+		return "", exception
+	}
+
+	for _, relationship := range relationships {
+		if relationship.Type == opcOriginRelationship {
+			return opcTargetPart("/", relationship.Target), nil
+		}
+	}
+
+	return "", nil
+}
+
+// части пакета, подписываемые по умолчанию: все, кроме описания типов и частей подписей
+// из частей связей исключаются связи с происхождением подписей
+func (opc *opcPackage) signableReferences() ([]opcReference, error) {
+	var references []opcReference
+
+	for _, file := range opc.files {
+		if strings.HasSuffix(file.Name, "/") {
+			continue
+		}
+
+		part := "/" + file.Name
+		lower := strings.ToLower(part)
+
+		if lower == strings.ToLower(opcContentTypesPart) || strings.HasPrefix(lower, opcSignaturesFolder) {
+			continue
+		}
+
+		if opc.contentType(part) != opcRelationshipsContentType {
+			references = append(references, opcReference{part: part})
+			continue
+		}
+
+		data, exception := opc.read(part)
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		relationships, exception := parseOPCRelationships(data)
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		reference := opcReference{part: part, relationships: true}
+
+		for _, relationship := range relationships {
+			if relationship.Type != opcOriginRelationship {
+				reference.sourceIDs = append(reference.sourceIDs, relationship.ID)
+			}
+		}
+
+		if len(reference.sourceIDs) > 0 {
+			references = append(references, reference)
+		}
+	}
+
+	return references, nil
+}
+
+// вычислить хэш части пакета с учетом преобразования связей
+func (opc *opcPackage) digest(algorithm *HashAlgorithm, reference opcReference) ([]byte, error) {
+	data, exception := opc.read(reference.part)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	if !reference.relationships {
+		return calculateBytesDigest(algorithm, data)
+	}
+
+	var transformed bytes.Buffer
+
+	if exception := transformOPCRelationships(&transformed, data, reference.sourceIDs, reference.sourceTypes); exception != nil {
+		return nil, exception
+	}
+
+	return calculateBytesDigest(algorithm, transformed.Bytes())
+}
+
+// преобразование RelationshipTransform с последующей канонизацией:
+// выбираются связи с заданными Id или Type, сортируются по Id, TargetMode указывается явно
+func transformOPCRelationships(writer io.Writer, data []byte, sourceIDs []string, sourceTypes []string) error {
+	relationships, exception := parseOPCRelationships(data)
+
+	if exception != nil {
+		return exception
+	}
+
+	selected := map[string]bool{}
+
+	for _, value := range append(append([]string{}, sourceIDs...), sourceTypes...) {
+		selected[value] = true
+	}
+
+	root := &c14n.Element{Local: "Relationships", Namespaces: []c14n.Namespace{{URI: opcRelationshipsNamespace}}}
+	var result []opcRelationship
+
+	for _, relationship := range relationships {
+		if selected[relationship.ID] || selected[relationship.Type] {
+			result = append(result, relationship)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+
+	for _, relationship := range result {
+		if relationship.TargetMode == "" {
+			relationship.TargetMode = "Internal"
+		}
+
+		root.Children = append(root.Children, &c14n.Element{
+			Local: "Relationship",
+			Attributes: []c14n.Attribute{
+				{Local: "Id", Value: relationship.ID},
+				{Local: "Target", Value: relationship.Target},
+				{Local: "TargetMode", Value: relationship.TargetMode},
+				{Local: "Type", Value: relationship.Type},
+			},
+			Parent: root,
+		})
+	}
+
+	return c14n.Canonicalize(writer, root, false)
+}
+
+// получить метод подписи пакета OOXML (docx, xlsx, pptx)
+// подписываются все части пакета, подпись добавляется в часть /_xmlsignatures/sigN.xml
+func CreateOOXMLSignMethod(signer crypto.Signer, certificate *x509.Certificate) (release func(), sign func(document io.ReadSeeker, output io.Writer) error, exception error) {
+	algorithm, exception := FindCertificateSignatureAlgorithm(certificate)
+
+	if exception != nil {
+		return nil, nil, exception
+	}
+
+	method, ok := xmlSignatureMethods[algorithm.PublicKeyOID.String()]
+
+	if !ok {
+		return nil, nil, errors.New("Не поддерживается алгоритм подписи XMLDSig " + algorithm.Name)
+	}
+
+	return func() {},
+		func(document io.ReadSeeker, output io.Writer) error {
+			opc, exception := openOPCPackage(document)
+
+			if exception != nil {
+				return exception
+			}
+
+			references, exception := opc.signableReferences()
+
+			if exception != nil {
+				return exception
+			}
+
+			signature, exception := createOPCSignature(opc, references, signer, certificate, algorithm, method, time.Now())
+
+			if exception != nil {
+				return exception
+			}
+
+			updates, exception := opc.addSignaturePart(signature)
+
+			if exception != nil {
+				return exception
+			}
+
+			return opc.write(output, updates)
+		}, nil
+}
+
+// сформировать XMLDSig пакета: Manifest с хэшами частей и время подписи в Object, подписывается Object
+func createOPCSignature(opc *opcPackage, references []opcReference, signer crypto.Signer, certificate *x509.Certificate, algorithm *SignatureAlgorithm, method xmlSignatureMethod, signingTime time.Time) ([]byte, error) {
+	var builder strings.Builder
+
+	builder.WriteString(`<Signature xmlns="` + XMLDSigNamespace + `" Id="` + opcPackageSignatureID + `">`)
+	builder.WriteString(`<SignedInfo>`)
+	builder.WriteString(`<CanonicalizationMethod Algorithm="` + c14n.Canonical + `"/>`)
+	builder.WriteString(`<SignatureMethod Algorithm="` + method.signature + `"/>`)
+	builder.WriteString(`<Reference Type="` + XMLDSigNamespace + `Object" URI="#` + opcPackageObjectID + `">`)
+	builder.WriteString(`<DigestMethod Algorithm="` + method.digest + `"/><DigestValue></DigestValue>`)
+	builder.WriteString(`</Reference>`)
+	builder.WriteString(`</SignedInfo>`)
+	builder.WriteString(`<SignatureValue></SignatureValue>`)
+	builder.WriteString(`<KeyInfo><X509Data><X509Certificate>`)
+	builder.WriteString(base64.StdEncoding.EncodeToString(certificate.Raw))
+	builder.WriteString(`</X509Certificate></X509Data></KeyInfo>`)
+	builder.WriteString(`<Object Id="` + opcPackageObjectID + `"><Manifest>`)
+
+	for _, reference := range references {
+		digest, exception := opc.digest(algorithm.Hash, reference)
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		builder.WriteString(`<Reference URI="`)
+		xml.EscapeText(&builder, []byte(reference.part+"?ContentType="+opc.contentType(reference.part)))
+		builder.WriteString(`">`)
+
+		if reference.relationships {
+			builder.WriteString(`<Transforms><Transform Algorithm="` + opcRelationshipTransform + `">`)
+
+			for _, id := range reference.sourceIDs {
+				builder.WriteString(`<mdssi:RelationshipReference xmlns:mdssi="` + opcDigitalSignatureNamespace + `" SourceId="`)
+				xml.EscapeText(&builder, []byte(id))
+				builder.WriteString(`"/>`)
+			}
+
+			builder.WriteString(`</Transform><Transform Algorithm="` + c14n.Canonical + `"/></Transforms>`)
+		}
+
+		builder.WriteString(`<DigestMethod Algorithm="` + method.digest + `"/>`)
+		builder.WriteString(`<DigestValue>` + base64.StdEncoding.EncodeToString(digest) + `</DigestValue>`)
+		builder.WriteString(`</Reference>`)
+	}
+
+	builder.WriteString(`</Manifest>`)
+	builder.WriteString(`<SignatureProperties><SignatureProperty Id="idSignatureTime" Target="#` + opcPackageSignatureID + `">`)
+	builder.WriteString(`<mdssi:SignatureTime xmlns:mdssi="` + opcDigitalSignatureNamespace + `">`)
+	builder.WriteString(`<mdssi:Format>YYYY-MM-DDThh:mm:ssTZD</mdssi:Format>`)
+	builder.WriteString(`<mdssi:Value>` + signingTime.UTC().Format("2006-01-02T15:04:05Z") + `</mdssi:Value>`)
+	builder.WriteString(`</mdssi:SignatureTime></SignatureProperty></SignatureProperties>`)
+	builder.WriteString(`</Object>`)
+	builder.WriteString(`</Signature>`)
+
+	document, exception := c14n.Parse(strings.NewReader(builder.String()))
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	signature := document.Root()
+	var canonical bytes.Buffer
+
+	if exception := c14n.Canonicalize(&canonical, signature.FindByID(opcPackageObjectID), false); exception != nil {
+		return nil, exception
+	}
+
+	digest, exception := calculateBytesDigest(algorithm.Hash, canonical.Bytes())
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	digestValue := signature.FindByLocalName("SignedInfo").FindByLocalName("DigestValue")
+	digestValue.Children = []c14n.Node{c14n.Text(base64.StdEncoding.EncodeToString(digest))}
+
+	if exception := signXMLSignedInfo(signer, algorithm, signature, c14n.Canonical); exception != nil {
+		return nil, exception
+	}
+
+	document.Children = []c14n.Node{c14n.ProcInst{Target: "xml", Inst: `version="1.0" encoding="UTF-8"`}, signature}
+	var buffer bytes.Buffer
+
+	if exception := document.Write(&buffer); exception != nil {
+		return nil, exception
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// добавить часть подписи: обновить описание типов, связи пакета и происхождения подписей
+// возвращает новое содержимое частей
+func (opc *opcPackage) addSignaturePart(signature []byte) (map[string][]byte, error) {
+	updates := map[string][]byte{}
+
+	origin, exception := opc.originPart()
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	if origin == "" {
+		origin = opcSignaturesFolder + "origin.sigs"
+
+		rootRelationships, exception := opc.readOptional(opcRelationshipsPart("/"))
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		if updates[opcRelationshipsPart("/")], exception = addOPCRelationship(rootRelationships, opcOriginRelationship, strings.TrimPrefix(origin, "/")); exception != nil {
+			return nil, exception
+		}
+	}
+
+	if !opc.exists(origin) {
+		updates[origin] = []byte{}
+	}
+
+	part := ""
+
+	for index := 1; part == "" || opc.exists(part); index++ {
+		part = fmt.Sprintf("%ssig%d.xml", opcSignaturesFolder, index)
+	}
+
+	updates[part] = signature
+
+	originRelationships, exception := opc.readOptional(opcRelationshipsPart(origin))
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	if updates[opcRelationshipsPart(origin)], exception = addOPCRelationship(originRelationships, opcSignatureRelationship, path.Base(part)); exception != nil {
+		return nil, exception
+	}
+
+	data, exception := opc.read(opcContentTypesPart)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	types, exception := c14n.Parse(bytes.NewReader(data))
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	root := types.Root()
+	addType := func(local string, attributes ...c14n.Attribute) {
+		root.Children = append(root.Children, &c14n.Element{Prefix: root.Prefix, Local: local, Attributes: attributes, Parent: root})
+	}
+
+	extension := strings.TrimPrefix(path.Ext(origin), ".")
+
+	if opc.contentType(origin) != opcOriginContentType {
+		addType("Default", c14n.Attribute{Local: "Extension", Value: extension}, c14n.Attribute{Local: "ContentType", Value: opcOriginContentType})
+	}
+
+	if _, ok := opc.defaults["rels"]; !ok {
+		addType("Default", c14n.Attribute{Local: "Extension", Value: "rels"}, c14n.Attribute{Local: "ContentType", Value: opcRelationshipsContentType})
+	}
+
+	addType("Override", c14n.Attribute{Local: "PartName", Value: part}, c14n.Attribute{Local: "ContentType", Value: opcSignatureContentType})
+
+	var buffer bytes.Buffer
+
+	if exception := types.Write(&buffer); exception != nil {
+		return nil, exception
+	}
+
+	updates[opcContentTypesPart] = buffer.Bytes()
+
+	return updates, nil
+}
+
+// прочитать часть пакета, nil если части нет
+func (opc *opcPackage) readOptional(part string) ([]byte, error) {
+	if !opc.exists(part) {
+		return nil, nil
+	}
+
+	return opc.read(part)
+}
+
+// добавить связь в часть связей, при data = nil создается новая часть
+func addOPCRelationship(data []byte, relationshipType string, target string) ([]byte, error) {
+	if data == nil {
+		data = []byte(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" + `<Relationships xmlns="` + opcRelationshipsNamespace + `"></Relationships>`)
+	}
+
+	document, exception := c14n.Parse(bytes.NewReader(data))
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	root := document.Root()
+	used := map[string]bool{}
+
+	for _, child := range root.Children {
+		if element, ok := child.(*c14n.Element); ok {
+			id, _ := element.Attribute("Id")
+			used[id] = true
+		}
+	}
+
+	id := ""
+
+	for index := 1; id == "" || used[id]; index++ {
+		id = fmt.Sprintf("rId%d", index)
+	}
+
+	root.Children = append(root.Children, &c14n.Element{
+		Prefix: root.Prefix,
+		Local:  "Relationship",
+		Attributes: []c14n.Attribute{
+			{Local: "Id", Value: id},
+			{Local: "Type", Value: relationshipType},
+			{Local: "Target", Value: target},
+		},
+		Parent: root,
+	})
+
+	var buffer bytes.Buffer
+
+	if exception := document.Write(&buffer); exception != nil {
+		return nil, exception
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// записать пакет с измененными и новыми частями, остальные части копируются без перепаковки
+func (opc *opcPackage) write(output io.Writer, updates map[string][]byte) error {
+	writer := zip.NewWriter(output)
+	pending := map[string]string{}
+
+	for part := range updates {
+		pending[strings.ToLower(part)] = part
+	}
+
+	for _, file := range opc.files {
+		part, ok := pending[strings.ToLower("/"+file.Name)]
+
+		if !ok {
+			reader, exception := file.OpenRaw()
+
+			if exception != nil {
+				return exception
+			}
+
+			header := file.FileHeader
+			raw, exception := writer.CreateRaw(&header)
+
+			if exception != nil {
+				return exception
+			}
+
+			if _, exception := io.Copy(raw, reader); exception != nil {
+				return exception
+			}
+
+			continue
+		}
+
+		delete(pending, strings.ToLower(part))
+
+		if exception := writeZIPEntry(writer, file.Name, file.Modified, updates[part]); exception != nil {
+			return exception
+		}
+	}
+
+	parts := make([]string, 0, len(pending))
+
+	for _, part := range pending {
+		parts = append(parts, part)
+	}
+
+	sort.Strings(parts)
+
+	for _, part := range parts {
+		if exception := writeZIPEntry(writer, strings.TrimPrefix(part, "/"), time.Now(), updates[part]); exception != nil {
+			return exception
+		}
+	}
+
+	return writer.Close()
+}
+
+// записать сжатый файл в архив
+func writeZIPEntry(writer *zip.Writer, name string, modified time.Time, data []byte) error {
+	entry, exception := writer.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+
+	if exception != nil {
+		return exception
+	}
+
+	_, exception = entry.Write(data)
+
+	return exception
+}
+
+// получить метод проверки подписей пакета OOXML
+func CreateOOXMLVerifyMethod() (release func(), verify func(document io.ReadSeeker) ([]*OOXMLSignatureReport, error), exception error) {
+	return func() {},
+		func(document io.ReadSeeker) ([]*OOXMLSignatureReport, error) {
+			opc, exception := openOPCPackage(document)
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			origin, exception := opc.originPart()
+
+			if exception != nil || origin == "" {
+				return nil, exception
+			}
+
+			relationships, exception := opc.relationships(origin)
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			var reports []*OOXMLSignatureReport
+
+			for _, relationship := range relationships {
+				if relationship.Type == opcSignatureRelationship {
+					reports = append(reports, opc.verifySignature(opcTargetPart(origin, relationship.Target)))
+				}
+			}
+
+			return reports, nil
+		}, nil
+}
+
+// проверить часть подписи пакета
+func (opc *opcPackage) verifySignature(part string) *OOXMLSignatureReport {
+	report := &OOXMLSignatureReport{Part: part}
+	report.Exception = opc.verifySignatureReport(report)
+
+	return report
+}
+
+// заполнить результат проверки части подписи
+func (opc *opcPackage) verifySignatureReport(report *OOXMLSignatureReport) error {
+	data, exception := opc.read(report.Part)
+
+	if exception != nil {
+		return exception
+	}
+
+	document, exception := c14n.Parse(bytes.NewReader(data))
+
+	if exception != nil {
+		return exception
+	}
+
+	signature := document.Root()
+
+	if signature.Local != "Signature" || signature.NamespaceURI() != XMLDSigNamespace {
+		return errors.New("Часть подписи не содержит элемент XMLDSig Signature")
+	}
+
+	signedInfo := findXMLDSigChild(signature, "SignedInfo")
+	signatureValue := findXMLDSigChild(signature, "SignatureValue")
+	certificateElement := signature.FindByLocalName("X509Certificate")
+
+	if signedInfo == nil || signatureValue == nil || certificateElement == nil {
+		return errors.New("Некорректная подпись XMLDSig: нет SignedInfo, SignatureValue или сертификата")
+	}
+
+	canonicalizationMethod := findXMLDSigChild(signedInfo, "CanonicalizationMethod")
+	signatureMethod := findXMLDSigChild(signedInfo, "SignatureMethod")
+
+	if canonicalizationMethod == nil || signatureMethod == nil {
+		return errors.New("Некорректная подпись XMLDSig: нет CanonicalizationMethod или SignatureMethod")
+	}
+
+	rawCertificate, exception := decodeXMLBase64(certificateElement.Text())
+
+	if exception != nil {
+		return exception
+	}
+
+	if report.Certificate, exception = x509.ParseCertificate(rawCertificate); exception != nil {
+		return exception
+	}
+
+	algorithm, exception := FindCertificateSignatureAlgorithm(report.Certificate)
+
+	if exception != nil {
+		return exception
+	}
+
+	method, ok := xmlSignatureMethods[algorithm.PublicKeyOID.String()]
+
+	if !ok {
+		return errors.New("Не поддерживается алгоритм подписи XMLDSig " + algorithm.Name)
+	}
+
+	if value, _ := signatureMethod.Attribute("Algorithm"); value != method.signature {
+		return errors.New("Алгоритм подписи " + value + " не соответствует сертификату")
+	}
+
+	if timeElement := signature.FindByLocalName("SignatureTime"); timeElement != nil {
+		if value := timeElement.FindByLocalName("Value"); value != nil {
+			report.SigningTime, _ = time.Parse(time.RFC3339, strings.TrimSpace(value.Text()))
+		}
+	}
+
+	signed := map[string]bool{}
+
+	for _, child := range signedInfo.Children {
+		reference, ok := child.(*c14n.Element)
+
+		if !ok || reference.Local != "Reference" {
+			continue
+		}
+
+		uri, _ := reference.Attribute("URI")
+
+		if !strings.HasPrefix(uri, "#") {
+			return errors.New("Не поддерживается ссылка SignedInfo " + uri)
+		}
+
+		element := signature.FindByID(uri[1:])
+
+		if element == nil {
+			return errors.New("Не найден подписанный элемент " + uri)
+		}
+
+		var canonical bytes.Buffer
+
+		if exception := c14n.Canonicalize(&canonical, element, false); exception != nil {
+			return exception
+		}
+
+		digest, exception := calculateBytesDigest(algorithm.Hash, canonical.Bytes())
+
+		if exception != nil {
+			return exception
+		}
+
+		if exception := checkXMLDigest(reference, method, digest); exception != nil {
+			return exception
+		}
+
+		if manifest := element.FindByLocalName("Manifest"); manifest != nil {
+			if exception := opc.verifyManifest(manifest, algorithm, method, report, signed); exception != nil {
+				return exception
+			}
+		}
+	}
+
+	if len(report.Parts) == 0 {
+		return errors.New("Подпись не содержит подписанных частей пакета")
+	}
+
+	canonicalization, _ := canonicalizationMethod.Attribute("Algorithm")
+	var canonical bytes.Buffer
+
+	if exception := canonicalizeXMLElement(&canonical, signedInfo, canonicalization); exception != nil {
+		return exception
+	}
+
+	digest, exception := calculateBytesDigest(algorithm.Hash, canonical.Bytes())
+
+	if exception != nil {
+		return exception
+	}
+
+	value, exception := decodeXMLBase64(signatureValue.Text())
+
+	if exception != nil {
+		return exception
+	}
+
+	if exception := verifySignature(report.Certificate, algorithm, digest, value); exception != nil {
+		return exception
+	}
+
+	references, exception := opc.signableReferences()
+
+	if exception != nil {
+		return exception
+	}
+
+	report.CoversPackage = true
+
+	for _, reference := range references {
+		if !signed[strings.ToLower(reference.part)] {
+			report.CoversPackage = false
+		}
+	}
+
+	return nil
+}
+
+// проверить хэши частей пакета из Manifest
+func (opc *opcPackage) verifyManifest(manifest *c14n.Element, algorithm *SignatureAlgorithm, method xmlSignatureMethod, report *OOXMLSignatureReport, signed map[string]bool) error {
+	for _, child := range manifest.Children {
+		element, ok := child.(*c14n.Element)
+
+		if !ok || element.Local != "Reference" {
+			continue
+		}
+
+		uri, _ := element.Attribute("URI")
+		// тип содержимого не кодируется, "+" в нем не заменяется на пробел
+		uri, query, _ := strings.Cut(uri, "?")
+		part, exception := url.PathUnescape(uri)
+
+		if exception != nil {
+			return exception
+		}
+
+		reference := opcReference{part: part}
+
+		if contentType := strings.TrimPrefix(query, "ContentType="); contentType != "" && contentType != opc.contentType(reference.part) {
+			return errors.New("Тип содержимого части " + reference.part + " изменен")
+		}
+
+		if transforms := findXMLDSigChild(element, "Transforms"); transforms != nil {
+			for _, node := range transforms.Children {
+				transform, ok := node.(*c14n.Element)
+
+				if !ok {
+					continue
+				}
+
+				transformAlgorithm, _ := transform.Attribute("Algorithm")
+
+				switch {
+				case transformAlgorithm == opcRelationshipTransform:
+					reference.relationships = true
+
+					for _, node := range transform.Children {
+						if selector, ok := node.(*c14n.Element); ok {
+							if id, ok := selector.Attribute("SourceId"); ok {
+								reference.sourceIDs = append(reference.sourceIDs, id)
+							}
+
+							if sourceType, ok := selector.Attribute("SourceType"); ok {
+								reference.sourceTypes = append(reference.sourceTypes, sourceType)
+							}
+						}
+					}
+				// результат преобразования связей уже канонизирован
+				case transformAlgorithm == c14n.Canonical && reference.relationships:
+				default:
+					return errors.New("Не поддерживается преобразование " + transformAlgorithm)
+				}
+			}
+		}
+
+		digest, exception := opc.digest(algorithm.Hash, reference)
+
+		if exception != nil {
+			return exception
+		}
+
+		if exception := checkXMLDigest(element, method, digest); exception != nil {
+			return errors.New("Часть пакета " + reference.part + " изменена после подписи: " + exception.Error())
+		}
+
+		report.Parts = append(report.Parts, reference.part)
+		signed[strings.ToLower(reference.part)] = true
+	}
+
+	return nil
+}
+
+// сравнить хэш с DigestValue элемента Reference
+func checkXMLDigest(reference *c14n.Element, method xmlSignatureMethod, digest []byte) error {
+	digestMethod := findXMLDSigChild(reference, "DigestMethod")
+	digestValue := findXMLDSigChild(reference, "DigestValue")
+
+	if digestMethod == nil || digestValue == nil {
+		return errors.New("Некорректный элемент Reference")
+	}
+
+	if value, _ := digestMethod.Attribute("Algorithm"); value != method.digest {
+		return errors.New("Не поддерживается алгоритм хэширования " + value)
+	}
+
+	value, exception := decodeXMLBase64(digestValue.Text())
+
+	if exception != nil {
+		return exception
+	}
+
+	if !bytes.Equal(value, digest) {
+		return errors.New("Значение хэша не совпадает")
+	}
+
+	return nil
+}
+
+// найти дочерний элемент XMLDSig по локальному имени
+func findXMLDSigChild(element *c14n.Element, local string) *c14n.Element {
+	for _, child := range element.Children {
+		if child, ok := child.(*c14n.Element); ok && child.Local == local && child.NamespaceURI() == XMLDSigNamespace {
+			return child
+		}
+	}
+
+	return nil
+}
+
+// декодировать base64 с переносами строк
+func decodeXMLBase64(value string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(value), ""))
+}
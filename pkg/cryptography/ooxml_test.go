@@ -0,0 +1,254 @@
+package cryptography
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+)
+
+// части минимального документа docx
+var testOOXMLParts = []struct {
+	name    string
+	content string
+}{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>` +
+		`</Relationships>`},
+	{"word/document.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body><w:p><w:r><w:t>Hello world</w:t></w:r></w:p></w:body></w:document>`},
+	{"word/_rels/document.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="https://example.org" TargetMode="External"/>` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+		`</Relationships>`},
+	{"word/styles.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"/>`},
+}
+
+// сформировать пакет docx с заменой или добавлением частей
+func createTestOOXMLPackage(t *testing.T, replace map[string]string) []byte {
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+
+	for _, part := range testOOXMLParts {
+		content := part.content
+
+		if value, ok := replace[part.name]; ok {
+			content = value
+		}
+
+		entry, error := writer.Create(part.name)
+
+		if error != nil {
+			t.Fatal(error)
+		}
+
+		if _, error := entry.Write([]byte(content)); error != nil {
+			t.Fatal(error)
+		}
+	}
+
+	if error := writer.Close(); error != nil {
+		t.Fatal(error)
+	}
+
+	return buffer.Bytes()
+}
+
+// подписать пакет OOXML
+func signTestOOXML(t *testing.T, document []byte, commonName string) []byte {
+	privateKey, certificate := createTestGOSTCertificate(t, CurveTC26_512A, commonName)
+
+	release, sign, error := CreateOOXMLSignMethod(privateKey, certificate)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer release()
+
+	var output bytes.Buffer
+
+	if error := sign(bytes.NewReader(document), &output); error != nil {
+		t.Fatal(error)
+	}
+
+	return output.Bytes()
+}
+
+// проверить подписи пакета OOXML
+func verifyTestOOXML(t *testing.T, document []byte) []*OOXMLSignatureReport {
+	release, verify, error := CreateOOXMLVerifyMethod()
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer release()
+
+	reports, error := verify(bytes.NewReader(document))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	return reports
+}
+
+// заменить часть подписанного пакета, остальные части копируются без изменений
+func replaceTestOOXMLPart(t *testing.T, document []byte, name string, content string) []byte {
+	reader, error := zip.NewReader(bytes.NewReader(document), int64(len(document)))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+
+	for _, file := range reader.File {
+		entry, error := writer.Create(file.Name)
+
+		if error != nil {
+			t.Fatal(error)
+		}
+
+		source, error := file.Open()
+
+		if error != nil {
+			t.Fatal(error)
+		}
+
+		if file.Name == name {
+			_, error = entry.Write([]byte(content))
+		} else {
+			_, error = io.Copy(entry, source)
+		}
+
+		if error != nil {
+			t.Fatal(error)
+		}
+
+		source.Close()
+	}
+
+	if _, ok := reader.Open(name); ok != nil {
+		entry, error := writer.Create(name)
+
+		if error != nil {
+			t.Fatal(error)
+		}
+
+		if _, error := entry.Write([]byte(content)); error != nil {
+			t.Fatal(error)
+		}
+	}
+
+	if error := writer.Close(); error != nil {
+		t.Fatal(error)
+	}
+
+	return buffer.Bytes()
+}
+
+func Test_OOXMLSign_Success(t *testing.T) {
+	signed := signTestOOXML(t, createTestOOXMLPackage(t, nil), "OOXML")
+	reports := verifyTestOOXML(t, signed)
+
+	if len(reports) != 1 {
+		t.Fatalf("Ожидалась одна подпись. Получено %d", len(reports))
+	}
+
+	report := reports[0]
+
+	if !report.Valid() {
+		t.Fatalf("Ожидалась верная подпись. Получена ошибка %v", report.Exception)
+	}
+
+	if report.Part != "/_xmlsignatures/sig1.xml" {
+		t.Errorf("Ожидалась часть подписи /_xmlsignatures/sig1.xml. Получена %s", report.Part)
+	}
+
+	if !report.CoversPackage || len(report.Parts) != 4 {
+		t.Errorf("Ожидалось, что подписаны все 4 части пакета. Подписаны %v", report.Parts)
+	}
+
+	if report.Certificate.Subject.CommonName != "OOXML" || report.SigningTime.IsZero() {
+		t.Error("Ожидались сертификат и время подписи")
+	}
+}
+
+func Test_OOXMLSignTwice_Success(t *testing.T) {
+	signed := signTestOOXML(t, createTestOOXMLPackage(t, nil), "First")
+	signed = signTestOOXML(t, signed, "Second")
+	reports := verifyTestOOXML(t, signed)
+
+	if len(reports) != 2 {
+		t.Fatalf("Ожидалось две подписи. Получено %d", len(reports))
+	}
+
+	for _, report := range reports {
+		if !report.Valid() || !report.CoversPackage {
+			t.Errorf("Ожидалась верная подпись %s. Получена ошибка %v", report.Part, report.Exception)
+		}
+	}
+
+	if reports[1].Certificate.Subject.CommonName != "Second" {
+		t.Errorf("Ожидалась вторая подпись Second. Получена %s", reports[1].Certificate.Subject.CommonName)
+	}
+}
+
+func Test_OOXMLVerify_Modified(t *testing.T) {
+	signed := signTestOOXML(t, createTestOOXMLPackage(t, nil), "OOXML")
+	modified := replaceTestOOXMLPart(t, signed, "word/document.xml", testOOXMLParts[2].content+" ")
+	reports := verifyTestOOXML(t, modified)
+
+	if len(reports) != 1 || reports[0].Valid() {
+		t.Error("Ожидалась неверная подпись измененного пакета")
+	}
+}
+
+func Test_OOXMLVerify_AddedPart(t *testing.T) {
+	signed := signTestOOXML(t, createTestOOXMLPackage(t, nil), "OOXML")
+	modified := replaceTestOOXMLPart(t, signed, "word/media/image1.xml", "<image/>")
+	reports := verifyTestOOXML(t, modified)
+
+	if len(reports) != 1 || !reports[0].Valid() {
+		t.Fatal("Ожидалась верная подпись исходных частей пакета")
+	}
+
+	if reports[0].CoversPackage {
+		t.Error("Ожидалось, что добавленная часть не покрыта подписью")
+	}
+}
+
+func Test_OOXMLVerify_Unsigned(t *testing.T) {
+	if reports := verifyTestOOXML(t, createTestOOXMLPackage(t, nil)); len(reports) != 0 {
+		t.Errorf("Ожидалось отсутствие подписей. Получено %d", len(reports))
+	}
+}
+
+func Test_OOXMLRelationshipTransform_Success(t *testing.T) {
+	var buffer bytes.Buffer
+
+	if error := transformOPCRelationships(&buffer, []byte(testOOXMLParts[3].content), []string{"rId2", "rId1"}, nil); error != nil {
+		t.Fatal(error)
+	}
+
+	want := `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Target="styles.xml" TargetMode="Internal" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles"></Relationship>` +
+		`<Relationship Id="rId2" Target="https://example.org" TargetMode="External" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink"></Relationship>` +
+		`</Relationships>`
+
+	if buffer.String() != want {
+		t.Errorf("Ожидался результат преобразования %s. Получен %s", want, buffer.String())
+	}
+}
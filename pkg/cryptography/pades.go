@@ -0,0 +1,591 @@
+package cryptography
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// размер резерва под подпись CMS в словаре подписи, байт
+const pdfSignatureSize = 16384
+
+// длина поля числа в ByteRange
+const pdfByteRangeWidth = 10
+
+/*
+Результат проверки подписи PDF
+*/
+type PDFSignatureReport struct {
+	// имя поля подписи
+	Name      string
+	SubFilter string
+	ByteRange []int64
+	// время подписи из словаря подписи /M
+	SigningTime time.Time
+	// подпись покрывает весь документ, после нее нет изменений
+	CoversDocument bool
+	// результат проверки CMS, nil если подпись не удалось разобрать
+	Report    *VerifyReport
+	Exception error
+}
+
+// подпись верна
+func (report *PDFSignatureReport) Valid() bool {
+	return report.Exception == nil && report.Report != nil && report.Report.Valid()
+}
+
+// получить метод подписи PDF по PAdES
+// timestamp - метод получения штампа времени для PAdES-B-T, nil для PAdES-B-B
+// документ дополняется инкрементальным обновлением и записывается в output
+func CreatePAdESSignMethod(signer crypto.Signer, certificate *x509.Certificate, timestamp func(io.Reader) (io.Reader, error)) (release func(), sign func(document io.ReadSeeker, output io.Writer) error, exception error) {
+	algorithm, exception := FindCertificateSignatureAlgorithm(certificate)
+
+	if exception != nil {
+		return nil, nil, exception
+	}
+
+	parameters := &signParameters{
+		signer:             signer,
+		certificate:        certificate,
+		algorithm:          algorithm,
+		detached:           true,
+		attributes:         signingCertificateAttributes,
+		withoutSigningTime: true,
+	}
+
+	return func() {},
+		func(document io.ReadSeeker, output io.Writer) error {
+			pdf, exception := openPDF(document)
+
+			if exception != nil {
+				return exception
+			}
+
+			update, contentsStart, contentsEnd, exception := createPDFSignatureUpdate(pdf, time.Now())
+
+			if exception != nil {
+				return exception
+			}
+
+			original := io.NewSectionReader(pdf.reader, 0, pdf.size)
+			content := io.MultiReader(original, bytes.NewReader(update[:contentsStart]), bytes.NewReader(update[contentsEnd:]))
+
+			signedData, exception := signContent(content, parameters)
+
+			if exception != nil {
+				return exception
+			}
+
+			if timestamp != nil {
+				if exception := addSignatureTimestamp(&signedData.SignerInfos[0], timestamp); exception != nil {
+					return exception
+				}
+			}
+
+			signature, exception := marshalSignedData(signedData)
+
+			if exception != nil {
+				return exception
+			}
+
+			encoded, exception := io.ReadAll(signature)
+
+			if exception != nil {
+				return exception
+			}
+
+			if 2*len(encoded) > contentsEnd-contentsStart-2 {
+				return fmt.Errorf("Подпись размером %d байт не помещается в резерв %d байт", len(encoded), pdfSignatureSize)
+			}
+
+			hex.Encode(update[contentsStart+1:], encoded)
+
+			if _, exception := io.Copy(output, io.NewSectionReader(pdf.reader, 0, pdf.size)); exception != nil {
+				return exception
+			}
+
+			_, exception = output.Write(update)
+
+			return exception
+		}, nil
+}
+
+// сформировать инкрементальное обновление с полем подписи
+// возвращает обновление и границы строки /Contents в нем
+func createPDFSignatureUpdate(pdf *pdfDocument, signingTime time.Time) ([]byte, int, int, error) {
+	catalog, exception := pdf.dictionary(pdf.trailer["Root"])
+
+	if exception != nil {
+		return nil, 0, 0, exception
+	}
+
+	pageReference, page, exception := pdf.firstPage(catalog)
+
+	if exception != nil {
+		return nil, 0, 0, exception
+	}
+
+	size := int(pdfInt(pdf.trailer["Size"]))
+	signatureReference := pdfReference{number: size}
+	widgetReference := pdfReference{number: size + 1}
+	next := size + 2
+
+	objects := map[int]pdfObject{}
+	generations := map[int]int{}
+
+	// изменить массив прямо в объекте или в косвенном объекте, на который он ссылается
+	appendToArray := func(owner pdfDictionary, key pdfName, value pdfObject) (pdfDictionary, error) {
+		reference, isReference := owner[key].(pdfReference)
+		resolved, exception := pdf.resolve(owner[key])
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		array, _ := resolved.(pdfArray)
+		array = append(append(pdfArray{}, array...), value)
+
+		if isReference {
+			objects[reference.number] = array
+			generations[reference.number] = reference.generation
+
+			return owner, nil
+		}
+
+		owner = copyPDFDictionary(owner)
+		owner[key] = array
+
+		return owner, nil
+	}
+
+	fields := 0
+
+	// поле подписи совмещено с виджетом на первой странице
+	acroFormReference, acroFormIsReference := catalog["AcroForm"].(pdfReference)
+	acroForm := pdfDictionary{}
+
+	if _, ok := catalog["AcroForm"]; ok {
+		if acroForm, exception = pdf.dictionary(catalog["AcroForm"]); exception != nil {
+			return nil, 0, 0, exception
+		}
+
+		if resolved, exception := pdf.resolve(acroForm["Fields"]); exception == nil {
+			array, _ := resolved.(pdfArray)
+			fields = len(array)
+		}
+	}
+
+	acroForm, exception = appendToArray(acroForm, "Fields", widgetReference)
+
+	if exception != nil {
+		return nil, 0, 0, exception
+	}
+
+	acroForm = copyPDFDictionary(acroForm)
+	// SignaturesExist | AppendOnly
+	acroForm["SigFlags"] = newPDFNumber(3)
+
+	if acroFormIsReference {
+		objects[acroFormReference.number] = acroForm
+		generations[acroFormReference.number] = acroFormReference.generation
+	} else {
+		catalog = copyPDFDictionary(catalog)
+		catalog["AcroForm"] = acroForm
+		rootReference, _ := pdf.trailer["Root"].(pdfReference)
+		objects[rootReference.number] = catalog
+		generations[rootReference.number] = rootReference.generation
+	}
+
+	updatedPage, exception := appendToArray(page, "Annots", widgetReference)
+
+	if exception != nil {
+		return nil, 0, 0, exception
+	}
+
+	if _, ok := page["Annots"].(pdfReference); !ok {
+		objects[pageReference.number] = updatedPage
+		generations[pageReference.number] = pageReference.generation
+	}
+
+	objects[widgetReference.number] = pdfDictionary{
+		"Type":    pdfName("Annot"),
+		"Subtype": pdfName("Widget"),
+		"FT":      pdfName("Sig"),
+		"Rect":    pdfArray{newPDFNumber(0), newPDFNumber(0), newPDFNumber(0), newPDFNumber(0)},
+		// Print | Locked
+		"F": newPDFNumber(132),
+		"T": pdfString(fmt.Sprintf("Signature%d", fields+1)),
+		"V": signatureReference,
+		"P": pageReference,
+	}
+
+	var update bytes.Buffer
+	offsets := map[int]int64{}
+	update.WriteString("\n")
+
+	// словарь подписи записывается вручную, чтобы зарезервировать место под ByteRange и Contents
+	offsets[signatureReference.number] = pdf.size + int64(update.Len())
+	fmt.Fprintf(&update, "%d 0 obj\n<</Type /Sig /Filter /Adobe.PPKLite /SubFilter /ETSI.CAdES.detached /M ", signatureReference.number)
+	writePDFObject(&update, pdfString(formatPDFDate(signingTime)))
+	update.WriteString(" /ByteRange ")
+	byteRangeStart := update.Len()
+	update.WriteString("[" + strings.Repeat(" ", 3*(pdfByteRangeWidth+1)+2) + "]")
+	update.WriteString(" /Contents ")
+	contentsStart := update.Len()
+	update.WriteString("<" + strings.Repeat("0", 2*pdfSignatureSize) + ">")
+	contentsEnd := update.Len()
+	update.WriteString(">>\nendobj\n")
+
+	numbers := make([]int, 0, len(objects))
+
+	for number := range objects {
+		numbers = append(numbers, number)
+	}
+
+	sort.Ints(numbers)
+
+	for _, number := range numbers {
+		offsets[number] = pdf.size + int64(update.Len())
+		fmt.Fprintf(&update, "%d %d obj\n", number, generations[number])
+		writePDFObject(&update, objects[number])
+		update.WriteString("\nendobj\n")
+	}
+
+	trailer := pdfDictionary{
+		"Size": newPDFNumber(int64(next)),
+		"Root": pdf.trailer["Root"],
+		"Prev": newPDFNumber(pdf.startXRef),
+	}
+
+	for _, key := range []pdfName{"Info", "ID"} {
+		if value, ok := pdf.trailer[key]; ok {
+			trailer[key] = value
+		}
+	}
+
+	writePDFXRef(&update, pdf, offsets, generations, trailer, next)
+
+	// ByteRange: от начала файла до /Contents и от конца /Contents до конца файла
+	total := pdf.size + int64(update.Len())
+	first := pdf.size + int64(contentsStart)
+	second := pdf.size + int64(contentsEnd)
+	byteRange := fmt.Sprintf("[0 %d %d %d]", first, second, total-second)
+	byteRangeEnd := byteRangeStart + 3*(pdfByteRangeWidth+1) + 4
+
+	if len(byteRange) > byteRangeEnd-byteRangeStart {
+		return nil, 0, 0, errors.New("Документ PDF слишком большой для подписи")
+	}
+
+	data := update.Bytes()
+	copy(data[byteRangeStart:byteRangeEnd], byteRange+strings.Repeat(" ", byteRangeEnd-byteRangeStart-len(byteRange)))
+
+	return data, contentsStart, contentsEnd, nil
+}
+
+// записать таблицу перекрестных ссылок обновления в том же виде, что и в исходном документе
+func writePDFXRef(update *bytes.Buffer, pdf *pdfDocument, offsets map[int]int64, generations map[int]int, trailer pdfDictionary, next int) {
+	numbers := make([]int, 0, len(offsets)+1)
+
+	for number := range offsets {
+		numbers = append(numbers, number)
+	}
+
+	if !pdf.xrefStream {
+		sort.Ints(numbers)
+		xrefOffset := pdf.size + int64(update.Len())
+		update.WriteString("xref\n0 1\n0000000000 65535 f \n")
+
+		for _, number := range numbers {
+			fmt.Fprintf(update, "%d 1\n%010d %05d n \n", number, offsets[number], generations[number])
+		}
+
+		update.WriteString("trailer\n")
+		writePDFObject(update, trailer)
+		fmt.Fprintf(update, "\nstartxref\n%d\n%%%%EOF\n", xrefOffset)
+
+		return
+	}
+
+	// поток перекрестных ссылок без сжатия, включает запись о самом себе
+	xrefNumber := next
+	trailer["Size"] = newPDFNumber(int64(next + 1))
+	offsets[xrefNumber] = pdf.size + int64(update.Len())
+	numbers = append(numbers, xrefNumber)
+	sort.Ints(numbers)
+
+	var index pdfArray
+	var data []byte
+
+	for _, number := range numbers {
+		index = append(index, newPDFNumber(int64(number)), newPDFNumber(1))
+		offset := offsets[number]
+		data = append(data, 1, byte(offset>>24), byte(offset>>16), byte(offset>>8), byte(offset), byte(generations[number]>>8), byte(generations[number]))
+	}
+
+	trailer["Type"] = pdfName("XRef")
+	trailer["W"] = pdfArray{newPDFNumber(1), newPDFNumber(4), newPDFNumber(2)}
+	trailer["Index"] = index
+	trailer["Length"] = newPDFNumber(int64(len(data)))
+
+	fmt.Fprintf(update, "%d 0 obj\n", xrefNumber)
+	writePDFObject(update, trailer)
+	update.WriteString("\nstream\n")
+	update.Write(data)
+	fmt.Fprintf(update, "\nendstream\nendobj\nstartxref\n%d\n%%%%EOF\n", offsets[xrefNumber])
+}
+
+// найти первую страницу документа
+func (pdf *pdfDocument) firstPage(catalog pdfDictionary) (pdfReference, pdfDictionary, error) {
+	reference, _ := catalog["Pages"].(pdfReference)
+
+	for depth := 0; depth < 64; depth++ {
+		node, exception := pdf.dictionary(reference)
+
+		if exception != nil {
+			return pdfReference{}, nil, exception
+		}
+
+		if node["Type"] != pdfName("Pages") {
+			return reference, node, nil
+		}
+
+		kids, exception := pdf.resolve(node["Kids"])
+
+		if exception != nil {
+			return pdfReference{}, nil, exception
+		}
+
+		array, _ := kids.(pdfArray)
+
+		if len(array) == 0 {
+			break
+		}
+
+		if reference, _ = array[0].(pdfReference); reference.number == 0 {
+			break
+		}
+	}
+
+	return pdfReference{}, nil, errors.New("Некорректный PDF: не найдена страница")
+}
+
+// копия словаря PDF
+func copyPDFDictionary(dictionary pdfDictionary) pdfDictionary {
+	result := make(pdfDictionary, len(dictionary)+1)
+
+	for key, value := range dictionary {
+		result[key] = value
+	}
+
+	return result
+}
+
+// дата в формате PDF D:YYYYMMDDHHmmSSOHH'mm'
+func formatPDFDate(value time.Time) string {
+	_, offset := value.Zone()
+	sign := '+'
+
+	if offset < 0 {
+		sign = '-'
+		offset = -offset
+	}
+
+	return fmt.Sprintf("D:%s%c%02d'%02d'", value.Format("20060102150405"), sign, offset/3600, offset%3600/60)
+}
+
+// разобрать дату в формате PDF
+func parsePDFDate(value string) (time.Time, error) {
+	value = strings.TrimPrefix(value, "D:")
+	value = strings.ReplaceAll(value, "'", "")
+
+	if strings.HasSuffix(value, "Z") {
+		value = strings.TrimSuffix(value, "Z") + "+0000"
+	}
+
+	for _, layout := range []string{"20060102150405-0700", "20060102150405"} {
+		if parsed, exception := time.Parse(layout, value); exception == nil {
+			return parsed, nil
+		}
+	}
+
+	return time.Time{}, errors.New("Некорректная дата PDF " + value)
+}
+
+// получить метод проверки подписей PDF
+func CreatePAdESVerifyMethod() (release func(), verify func(document io.ReadSeeker) ([]*PDFSignatureReport, error), exception error) {
+	return func() {},
+		func(document io.ReadSeeker) ([]*PDFSignatureReport, error) {
+			pdf, exception := openPDF(document)
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			catalog, exception := pdf.dictionary(pdf.trailer["Root"])
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			if _, ok := catalog["AcroForm"]; !ok {
+				return nil, nil
+			}
+
+			acroForm, exception := pdf.dictionary(catalog["AcroForm"])
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			var reports []*PDFSignatureReport
+
+			exception = pdf.walkFields(acroForm["Fields"], "", "", func(name string, signature pdfDictionary) {
+				reports = append(reports, pdf.verifySignature(name, signature))
+			})
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			// порядок подписей - порядок ревизий документа
+			sort.SliceStable(reports, func(i, j int) bool {
+				return len(reports[i].ByteRange) == 4 && len(reports[j].ByteRange) == 4 && reports[i].ByteRange[3]+reports[i].ByteRange[2] < reports[j].ByteRange[3]+reports[j].ByteRange[2]
+			})
+
+			return reports, nil
+		}, nil
+}
+
+// обойти дерево полей формы и вызвать visit для заполненных полей подписи
+func (pdf *pdfDocument) walkFields(fields pdfObject, parentName string, parentType pdfName, visit func(name string, signature pdfDictionary)) error {
+	resolved, exception := pdf.resolve(fields)
+
+	if exception != nil {
+		return exception
+	}
+
+	array, _ := resolved.(pdfArray)
+
+	for _, field := range array {
+		dictionary, exception := pdf.dictionary(field)
+
+		if exception != nil {
+			return exception
+		}
+
+		name := parentName
+
+		if partial, ok := dictionary["T"].(pdfString); ok {
+			if name != "" {
+				name += "."
+			}
+
+			name += string(partial)
+		}
+
+		fieldType := parentType
+
+		if value, ok := dictionary["FT"].(pdfName); ok {
+			fieldType = value
+		}
+
+		if _, ok := dictionary["Kids"]; ok {
+			if exception := pdf.walkFields(dictionary["Kids"], name, fieldType, visit); exception != nil {
+				return exception
+			}
+
+			continue
+		}
+
+		if fieldType != "Sig" || dictionary["V"] == nil {
+			continue
+		}
+
+		signature, exception := pdf.dictionary(dictionary["V"])
+
+		if exception != nil {
+			return exception
+		}
+
+		visit(name, signature)
+	}
+
+	return nil
+}
+
+// проверить подпись из словаря подписи
+func (pdf *pdfDocument) verifySignature(name string, signature pdfDictionary) *PDFSignatureReport {
+	report := &PDFSignatureReport{Name: name}
+
+	if subFilter, ok := signature["SubFilter"].(pdfName); ok {
+		report.SubFilter = string(subFilter)
+	}
+
+	if value, ok := signature["M"].(pdfString); ok {
+		report.SigningTime, _ = parsePDFDate(string(value))
+	}
+
+	if report.SubFilter != "ETSI.CAdES.detached" && report.SubFilter != "adbe.pkcs7.detached" {
+		report.Exception = errors.New("Не поддерживается формат подписи PDF " + report.SubFilter)
+		return report
+	}
+
+	byteRange, _ := signature["ByteRange"].(pdfArray)
+
+	for _, value := range byteRange {
+		report.ByteRange = append(report.ByteRange, pdfInt(value))
+	}
+
+	ranges := report.ByteRange
+
+	if len(ranges) != 4 || ranges[0] != 0 || ranges[1] < 0 || ranges[2] <= ranges[1] || ranges[3] < 0 || ranges[2]+ranges[3] > pdf.size {
+		report.Exception = errors.New("Некорректный ByteRange подписи")
+		return report
+	}
+
+	// между диапазонами должна находиться только строка /Contents
+	delimiters := make([]byte, 2)
+
+	for i, offset := range []int64{ranges[1], ranges[2] - 1} {
+		if _, exception := pdf.reader.ReadAt(delimiters[i:i+1], offset); exception != nil {
+			report.Exception = exception
+			return report
+		}
+	}
+
+	if delimiters[0] != '<' || delimiters[1] != '>' {
+		report.Exception = errors.New("ByteRange подписи не исключает только значение /Contents")
+		return report
+	}
+
+	report.CoversDocument = ranges[2]+ranges[3] == pdf.size
+
+	contents, _ := signature["Contents"].(pdfString)
+
+	// значение дополнено нулями до размера резерва, подпись может быть закодирована в BER
+	der, exception := berToDER(contents)
+
+	if exception != nil {
+		report.Exception = exception
+		return report
+	}
+
+	signedData, exception := parseSignedData(der)
+
+	if exception != nil {
+		report.Exception = exception
+		return report
+	}
+
+	content := io.MultiReader(io.NewSectionReader(pdf.reader, ranges[0], ranges[1]), io.NewSectionReader(pdf.reader, ranges[2], ranges[3]))
+	report.Report, report.Exception = verifySignedData(signedData, content)
+
+	return report
+}
@@ -0,0 +1,408 @@
+package cryptography
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/asn1"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// объекты минимального документа PDF из одной страницы
+var testPDFObjects = []string{
+	"<< /Type /Catalog /Pages 2 0 R >>",
+	"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+	"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Contents 4 0 R >>",
+	"<< /Length 44 >>\nstream\nBT /F1 24 Tf 100 700 Td (Hello world) Tj ET\nendstream",
+}
+
+// сформировать документ PDF с классической таблицей перекрестных ссылок
+func createTestPDF() []byte {
+	var buffer bytes.Buffer
+	offsets := make([]int, len(testPDFObjects))
+	buffer.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+
+	for index, object := range testPDFObjects {
+		offsets[index] = buffer.Len()
+		fmt.Fprintf(&buffer, "%d 0 obj\n%s\nendobj\n", index+1, object)
+	}
+
+	xref := buffer.Len()
+	fmt.Fprintf(&buffer, "xref\n0 %d\n0000000000 65535 f \n", len(testPDFObjects)+1)
+
+	for _, offset := range offsets {
+		fmt.Fprintf(&buffer, "%010d 00000 n \n", offset)
+	}
+
+	fmt.Fprintf(&buffer, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(testPDFObjects)+1, xref)
+
+	return buffer.Bytes()
+}
+
+// сформировать документ PDF со сжатым потоком перекрестных ссылок и потоком объектов
+func createTestPDFWithXRefStream(t *testing.T) []byte {
+	return buildTestPDFWithXRefStream(t, nil, nil)
+}
+
+// сформировать документ PDF с потоком перекрестных ссылок
+// objectStream заменяет сжатые данные потока объектов 5, entry - запись объекта 5 в потоке перекрестных ссылок
+func buildTestPDFWithXRefStream(t *testing.T, objectStream []byte, entry []byte) []byte {
+	var buffer bytes.Buffer
+	buffer.WriteString("%PDF-1.7\n")
+
+	// каталог и дерево страниц помещаются в поток объектов 5
+	var header, body bytes.Buffer
+
+	for index, object := range testPDFObjects[:2] {
+		fmt.Fprintf(&header, "%d %d ", index+1, body.Len())
+		body.WriteString(object + "\n")
+	}
+
+	if objectStream == nil {
+		objectStream = compressTestData(t, append(header.Bytes(), body.Bytes()...))
+	}
+
+	offsets := map[int]int{}
+
+	for index, object := range testPDFObjects[2:] {
+		offsets[index+3] = buffer.Len()
+		fmt.Fprintf(&buffer, "%d 0 obj\n%s\nendobj\n", index+3, object)
+	}
+
+	offsets[5] = buffer.Len()
+	fmt.Fprintf(&buffer, "5 0 obj\n<< /Type /ObjStm /N 2 /First %d /Filter /FlateDecode /Length %d >>\nstream\n", header.Len(), len(objectStream))
+	buffer.Write(objectStream)
+	buffer.WriteString("\nendstream\nendobj\n")
+
+	xref := buffer.Len()
+
+	// записи по 4 байта с предсказателем PNG Up
+	rows := [][]byte{
+		{0, 0, 0, 0},
+		{2, 0, 5, 0},
+		{2, 0, 5, 1},
+		{1, byte(offsets[3] >> 8), byte(offsets[3]), 0},
+		{1, byte(offsets[4] >> 8), byte(offsets[4]), 0},
+		{1, byte(offsets[5] >> 8), byte(offsets[5]), 0},
+		{1, byte(xref >> 8), byte(xref), 0},
+	}
+
+	if entry != nil {
+		rows[5] = entry
+	}
+
+	var data []byte
+	previous := make([]byte, 4)
+
+	for _, row := range rows {
+		data = append(data, 2)
+
+		for index, value := range row {
+			data = append(data, value-previous[index])
+		}
+
+		previous = row
+	}
+
+	xrefStream := compressTestData(t, data)
+	fmt.Fprintf(&buffer, "6 0 obj\n<< /Type /XRef /Size 7 /Root 1 0 R /W [1 2 1] /Filter /FlateDecode "+
+		"/DecodeParms << /Predictor 12 /Columns 4 >> /Length %d >>\nstream\n", len(xrefStream))
+	buffer.Write(xrefStream)
+	fmt.Fprintf(&buffer, "\nendstream\nendobj\nstartxref\n%d\n%%%%EOF\n", xref)
+
+	return buffer.Bytes()
+}
+
+// сжать данные FlateDecode
+func compressTestData(t *testing.T, data []byte) []byte {
+	var buffer bytes.Buffer
+	writer := zlib.NewWriter(&buffer)
+
+	if _, error := writer.Write(data); error != nil {
+		t.Fatal(error)
+	}
+
+	if error := writer.Close(); error != nil {
+		t.Fatal(error)
+	}
+
+	return buffer.Bytes()
+}
+
+// подписать и проверить документ PDF
+func signAndVerifyTestPDF(t *testing.T, document []byte, signatures int) ([]byte, []*PDFSignatureReport) {
+	privateKey, certificate := createTestGOSTCertificate(t, CurveCryptoProA, "PAdES")
+
+	release, sign, error := CreatePAdESSignMethod(privateKey, certificate, nil)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer release()
+
+	for index := 0; index < signatures; index++ {
+		var output bytes.Buffer
+
+		if error := sign(bytes.NewReader(document), &output); error != nil {
+			t.Fatal(error)
+		}
+
+		if !bytes.HasPrefix(output.Bytes(), document) {
+			t.Fatal("Ожидалось инкрементальное обновление исходного документа")
+		}
+
+		document = output.Bytes()
+	}
+
+	releaseVerify, verify, error := CreatePAdESVerifyMethod()
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer releaseVerify()
+
+	reports, error := verify(bytes.NewReader(document))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if len(reports) != signatures {
+		t.Fatalf("Ожидалось подписей %d. Получено %d", signatures, len(reports))
+	}
+
+	return document, reports
+}
+
+func Test_PAdESSign_Success(t *testing.T) {
+	document, reports := signAndVerifyTestPDF(t, createTestPDF(), 1)
+	report := reports[0]
+
+	if !report.Valid() {
+		t.Fatalf("Ожидалась верная подпись. Получена ошибка %v", report.Exception)
+	}
+
+	if report.SubFilter != "ETSI.CAdES.detached" {
+		t.Errorf("Ожидался SubFilter ETSI.CAdES.detached. Получен %s", report.SubFilter)
+	}
+
+	if !report.CoversDocument {
+		t.Error("Ожидалось, что подпись покрывает весь документ")
+	}
+
+	if report.Name != "Signature1" {
+		t.Errorf("Ожидалось имя поля Signature1. Получено %s", report.Name)
+	}
+
+	if report.SigningTime.IsZero() {
+		t.Error("Ожидалось время подписи в словаре подписи")
+	}
+
+	if !strings.Contains(string(document), "/SigFlags 3") {
+		t.Error("Ожидался флаг SigFlags в AcroForm")
+	}
+}
+
+func Test_PAdESSignXRefStream_Success(t *testing.T) {
+	_, reports := signAndVerifyTestPDF(t, createTestPDFWithXRefStream(t), 1)
+
+	if !reports[0].Valid() {
+		t.Fatalf("Ожидалась верная подпись. Получена ошибка %v", reports[0].Exception)
+	}
+}
+
+func Test_PAdESSignTwice_Success(t *testing.T) {
+	_, reports := signAndVerifyTestPDF(t, createTestPDF(), 2)
+
+	for _, report := range reports {
+		if !report.Valid() {
+			t.Fatalf("Ожидалась верная подпись %s. Получена ошибка %v", report.Name, report.Exception)
+		}
+	}
+
+	if reports[0].CoversDocument || !reports[1].CoversDocument {
+		t.Error("Ожидалось, что весь документ покрывает только последняя подпись")
+	}
+
+	if reports[1].Name != "Signature2" {
+		t.Errorf("Ожидалось имя поля Signature2. Получено %s", reports[1].Name)
+	}
+}
+
+func Test_PAdESVerify_Modified(t *testing.T) {
+	document, _ := signAndVerifyTestPDF(t, createTestPDF(), 1)
+	modified := bytes.Replace(document, []byte("Hello world"), []byte("Hello WORLD"), 1)
+
+	release, verify, error := CreatePAdESVerifyMethod()
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer release()
+
+	reports, error := verify(bytes.NewReader(modified))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if len(reports) != 1 || reports[0].Valid() {
+		t.Error("Ожидалась неверная подпись измененного документа")
+	}
+}
+
+func Test_PAdESVerify_BERContents(t *testing.T) {
+	document, reports := signAndVerifyTestPDF(t, createTestPDF(), 1)
+	byteRange := reports[0].ByteRange
+	encoded := document[byteRange[1]+1 : byteRange[2]-1]
+
+	contents, error := hex.DecodeString(string(encoded))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if contents[0] != 0x30 || contents[1] != 0x82 {
+		t.Fatalf("Ожидался заголовок SEQUENCE с длиной в двух байтах. Получен %x", contents[:2])
+	}
+
+	// внешний SEQUENCE с неопределенной длиной занимает столько же байт, ByteRange не изменяется
+	length := int(contents[2])<<8 | int(contents[3])
+	ber := append([]byte{0x30, 0x80}, contents[4:4+length]...)
+	ber = append(ber, 0x00, 0x00)
+	ber = append(ber, contents[4+length:]...)
+
+	modified := append([]byte{}, document...)
+	copy(modified[byteRange[1]+1:], hex.EncodeToString(ber))
+
+	release, verify, error := CreatePAdESVerifyMethod()
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer release()
+
+	reports, error = verify(bytes.NewReader(modified))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if len(reports) != 1 || !reports[0].Valid() {
+		t.Fatalf("Ожидалась верная подпись в BER. Получена ошибка %v", reports[0].Exception)
+	}
+}
+
+func Test_PAdESVerify_MalformedPDF(t *testing.T) {
+	release, verify, error := CreatePAdESVerifyMethod()
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer release()
+
+	for name, document := range map[string][]byte{
+		// поток объектов 5 хранится в самом себе
+		"цикл": buildTestPDFWithXRefStream(t, nil, []byte{2, 0, 5, 2}),
+		// поток объектов распаковывается больше допустимого размера
+		"размер": buildTestPDFWithXRefStream(t, compressTestData(t, make([]byte, pdfMaxStreamSize+1)), nil),
+	} {
+		if _, error := verify(bytes.NewReader(document)); error == nil {
+			t.Errorf("%s: ожидалась ошибка некорректного PDF", name)
+		}
+	}
+}
+
+func Test_PAdEST_Success(t *testing.T) {
+	tsa := createTestTSA(t, tspGranted)
+	defer tsa.Close()
+
+	releaseTimestamp, timestamp, error := CreateTimestampMethod(tsa.URL, HashGOST3411_2012_256)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer releaseTimestamp()
+
+	privateKey, certificate := createTestGOSTCertificate(t, CurveCryptoProA, "PAdES-T")
+
+	release, sign, error := CreatePAdESSignMethod(privateKey, certificate, timestamp)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer release()
+
+	var output bytes.Buffer
+
+	if error := sign(bytes.NewReader(createTestPDF()), &output); error != nil {
+		t.Fatal(error)
+	}
+
+	releaseVerify, verify, error := CreatePAdESVerifyMethod()
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer releaseVerify()
+
+	reports, error := verify(bytes.NewReader(output.Bytes()))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if len(reports) != 1 || !reports[0].Valid() {
+		t.Fatal("Ожидалась верная подпись PAdES-T")
+	}
+
+	// значение /Contents находится между диапазонами ByteRange
+	byteRange := reports[0].ByteRange
+	contents, error := hex.DecodeString(string(output.Bytes()[byteRange[1]+1 : byteRange[2]-1]))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	var raw asn1.RawValue
+
+	if _, error := asn1.Unmarshal(contents, &raw); error != nil {
+		t.Fatal(error)
+	}
+
+	signedData, error := parseSignedData(raw.FullBytes)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	unsignedAttributes, error := parseAttributes(signedData.SignerInfos[0].UnsignedAttributes)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if _, ok := findAttribute(unsignedAttributes, OIDAttributeSignatureTimeStampToken); !ok {
+		t.Error("Ожидался атрибут signature-time-stamp")
+	}
+
+	signedAttributes, error := parseAttributes(signedData.SignerInfos[0].SignedAttributes)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if _, ok := findAttribute(signedAttributes, OIDAttributeSigningTime); ok {
+		t.Error("Атрибут signing-time не должен добавляться в PAdES")
+	}
+}
@@ -0,0 +1,1202 @@
+package cryptography
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+Объекты PDF: pdfName, pdfString, pdfNumber, pdfBoolean, nil, pdfArray, pdfDictionary, pdfReference, *pdfStream
+*/
+type pdfObject interface{}
+
+type pdfName string
+
+type pdfString []byte
+
+type pdfNumber string
+
+type pdfBoolean bool
+
+type pdfArray []pdfObject
+
+type pdfDictionary map[pdfName]pdfObject
+
+/*
+Ссылка на косвенный объект
+*/
+type pdfReference struct {
+	number     int
+	generation int
+}
+
+/*
+Поток PDF, данные читаются из файла по смещению
+*/
+type pdfStream struct {
+	dictionary pdfDictionary
+	offset     int64
+}
+
+// целое значение числа
+func (number pdfNumber) int() (int64, bool) {
+	value, exception := strconv.ParseInt(string(number), 10, 64)
+
+	return value, exception == nil
+}
+
+// целое значение прямого объекта, 0 если объект не является целым числом
+func pdfInt(object pdfObject) int64 {
+	number, _ := object.(pdfNumber)
+	value, _ := number.int()
+
+	return value
+}
+
+/*
+Лексема PDF
+*/
+type pdfToken struct {
+	kind  int
+	value string
+}
+
+const (
+	pdfTokenEOF = iota
+	pdfTokenKeyword
+	pdfTokenName
+	pdfTokenString
+	pdfTokenDictionaryStart
+	pdfTokenDictionaryEnd
+	pdfTokenArrayStart
+	pdfTokenArrayEnd
+)
+
+/*
+Лексический анализатор PDF
+*/
+type pdfLexer struct {
+	reader *bufio.Reader
+	// смещение следующего байта в файле
+	offset int64
+	pushed []pdfToken
+}
+
+func newPDFLexer(reader io.ReaderAt, offset int64, size int64) *pdfLexer {
+	return &pdfLexer{reader: bufio.NewReader(io.NewSectionReader(reader, offset, size-offset)), offset: offset}
+}
+
+func isPDFWhitespace(b byte) bool {
+	return b == 0 || b == '\t' || b == '\n' || b == '\f' || b == '\r' || b == ' '
+}
+
+func isPDFDelimiter(b byte) bool {
+	return strings.IndexByte("()<>[]{}/%", b) >= 0
+}
+
+func (lexer *pdfLexer) readByte() (byte, error) {
+	b, exception := lexer.reader.ReadByte()
+
+	if exception == nil {
+		lexer.offset++
+	}
+
+	return b, exception
+}
+
+func (lexer *pdfLexer) unreadByte() {
+	lexer.reader.UnreadByte()
+	lexer.offset--
+}
+
+// вернуть лексему для повторного чтения
+func (lexer *pdfLexer) push(token pdfToken) {
+	lexer.pushed = append(lexer.pushed, token)
+}
+
+// прочитать следующую лексему
+func (lexer *pdfLexer) next() (pdfToken, error) {
+	if len(lexer.pushed) != 0 {
+		token := lexer.pushed[len(lexer.pushed)-1]
+		lexer.pushed = lexer.pushed[:len(lexer.pushed)-1]
+
+		return token, nil
+	}
+
+	for {
+		b, exception := lexer.readByte()
+
+		if exception == io.EOF {
+			return pdfToken{kind: pdfTokenEOF}, nil
+		}
+
+		if exception != nil {
+			return pdfToken{}, exception
+		}
+
+		if isPDFWhitespace(b) {
+			continue
+		}
+
+		switch b {
+		case '%':
+			// комментарий до конца строки
+			for b != '\r' && b != '\n' {
+				if b, exception = lexer.readByte(); exception != nil {
+					return pdfToken{kind: pdfTokenEOF}, nil
+				}
+			}
+
+			continue
+		case '[':
+			return pdfToken{kind: pdfTokenArrayStart}, nil
+		case ']':
+			return pdfToken{kind: pdfTokenArrayEnd}, nil
+		case '/':
+			return lexer.readName()
+		case '(':
+			return lexer.readLiteralString()
+		case '<':
+			if b, exception = lexer.readByte(); exception == nil && b == '<' {
+				return pdfToken{kind: pdfTokenDictionaryStart}, nil
+			}
+
+			if exception == nil {
+				lexer.unreadByte()
+			}
+
+			return lexer.readHexString()
+		case '>':
+			if b, exception = lexer.readByte(); exception != nil || b != '>' {
+				return pdfToken{}, errors.New("Некорректный PDF: ожидался >>")
+			}
+
+			return pdfToken{kind: pdfTokenDictionaryEnd}, nil
+		}
+
+		var builder strings.Builder
+		builder.WriteByte(b)
+
+		for {
+			if b, exception = lexer.readByte(); exception != nil {
+				break
+			}
+
+			if isPDFWhitespace(b) || isPDFDelimiter(b) {
+				lexer.unreadByte()
+				break
+			}
+
+			builder.WriteByte(b)
+		}
+
+		return pdfToken{kind: pdfTokenKeyword, value: builder.String()}, nil
+	}
+}
+
+func (lexer *pdfLexer) readName() (pdfToken, error) {
+	var builder strings.Builder
+
+	for {
+		b, exception := lexer.readByte()
+
+		if exception != nil {
+			break
+		}
+
+		if isPDFWhitespace(b) || isPDFDelimiter(b) {
+			lexer.unreadByte()
+			break
+		}
+
+		// #xx - код символа
+		if b == '#' {
+			code := make([]byte, 2)
+
+			for i := range code {
+				if code[i], exception = lexer.readByte(); exception != nil {
+					return pdfToken{}, exception
+				}
+			}
+
+			decoded, exception := hex.DecodeString(string(code))
+
+			if exception != nil {
+				return pdfToken{}, exception
+			}
+
+			b = decoded[0]
+		}
+
+		builder.WriteByte(b)
+	}
+
+	return pdfToken{kind: pdfTokenName, value: builder.String()}, nil
+}
+
+func (lexer *pdfLexer) readLiteralString() (pdfToken, error) {
+	var buffer bytes.Buffer
+	depth := 1
+
+	for {
+		b, exception := lexer.readByte()
+
+		if exception != nil {
+			return pdfToken{}, errors.New("Некорректный PDF: не закрыта строка")
+		}
+
+		switch b {
+		case '(':
+			depth++
+		case ')':
+			depth--
+
+			if depth == 0 {
+				return pdfToken{kind: pdfTokenString, value: buffer.String()}, nil
+			}
+		case '\\':
+			if b, exception = lexer.readByte(); exception != nil {
+				return pdfToken{}, exception
+			}
+
+			switch b {
+			case 'n':
+				b = '\n'
+			case 'r':
+				b = '\r'
+			case 't':
+				b = '\t'
+			case 'b':
+				b = '\b'
+			case 'f':
+				b = '\f'
+			case '\r':
+				// перенос строки внутри строки
+				if b, exception = lexer.readByte(); exception == nil && b != '\n' {
+					lexer.unreadByte()
+				}
+
+				continue
+			case '\n':
+				continue
+			default:
+				if b >= '0' && b <= '7' {
+					code := int(b - '0')
+
+					for i := 0; i < 2; i++ {
+						if b, exception = lexer.readByte(); exception != nil {
+							break
+						}
+
+						if b < '0' || b > '7' {
+							lexer.unreadByte()
+							break
+						}
+
+						code = code*8 + int(b-'0')
+					}
+
+					b = byte(code)
+				}
+			}
+		}
+
+		buffer.WriteByte(b)
+	}
+}
+
+func (lexer *pdfLexer) readHexString() (pdfToken, error) {
+	var digits []byte
+
+	for {
+		b, exception := lexer.readByte()
+
+		if exception != nil {
+			return pdfToken{}, errors.New("Некорректный PDF: не закрыта шестнадцатеричная строка")
+		}
+
+		if b == '>' {
+			break
+		}
+
+		if !isPDFWhitespace(b) {
+			digits = append(digits, b)
+		}
+	}
+
+	if len(digits)%2 != 0 {
+		digits = append(digits, '0')
+	}
+
+	decoded, exception := hex.DecodeString(string(digits))
+
+	if exception != nil {
+		return pdfToken{}, exception
+	}
+
+	return pdfToken{kind: pdfTokenString, value: string(decoded)}, nil
+}
+
+// прочитать объект PDF
+func (lexer *pdfLexer) readObject() (pdfObject, error) {
+	token, exception := lexer.next()
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	switch token.kind {
+	case pdfTokenName:
+		return pdfName(token.value), nil
+	case pdfTokenString:
+		return pdfString(token.value), nil
+	case pdfTokenArrayStart:
+		array := pdfArray{}
+
+		for {
+			token, exception := lexer.next()
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			if token.kind == pdfTokenArrayEnd {
+				return array, nil
+			}
+
+			if token.kind == pdfTokenEOF {
+				return nil, errors.New("Некорректный PDF: не закрыт массив")
+			}
+
+			lexer.push(token)
+			value, exception := lexer.readObject()
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			array = append(array, value)
+		}
+	case pdfTokenDictionaryStart:
+		dictionary := pdfDictionary{}
+
+		for {
+			token, exception := lexer.next()
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			if token.kind == pdfTokenDictionaryEnd {
+				return dictionary, nil
+			}
+
+			if token.kind != pdfTokenName {
+				return nil, errors.New("Некорректный PDF: ожидалось имя ключа словаря")
+			}
+
+			value, exception := lexer.readObject()
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			dictionary[pdfName(token.value)] = value
+		}
+	case pdfTokenKeyword:
+		switch token.value {
+		case "true":
+			return pdfBoolean(true), nil
+		case "false":
+			return pdfBoolean(false), nil
+		case "null":
+			return nil, nil
+		}
+
+		number := pdfNumber(token.value)
+
+		if _, exception := strconv.ParseFloat(token.value, 64); exception != nil {
+			return nil, errors.New("Некорректный PDF: неожиданная лексема " + token.value)
+		}
+
+		// ссылка n g R
+		if value, ok := number.int(); ok {
+			generation, exception := lexer.next()
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			if generationValue, ok := pdfNumber(generation.value).int(); generation.kind == pdfTokenKeyword && ok {
+				keyword, exception := lexer.next()
+
+				if exception != nil {
+					return nil, exception
+				}
+
+				if keyword.kind == pdfTokenKeyword && keyword.value == "R" {
+					return pdfReference{number: int(value), generation: int(generationValue)}, nil
+				}
+
+				lexer.push(keyword)
+			}
+
+			lexer.push(generation)
+		}
+
+		return number, nil
+	}
+
+	return nil, errors.New("Некорректный PDF: неожиданный конец объекта")
+}
+
+// прочитать ожидаемое ключевое слово
+func (lexer *pdfLexer) expectKeyword(keyword string) error {
+	token, exception := lexer.next()
+
+	if exception != nil {
+		return exception
+	}
+
+	if token.kind != pdfTokenKeyword || token.value != keyword {
+		return errors.New("Некорректный PDF: ожидалось " + keyword)
+	}
+
+	return nil
+}
+
+// прочитать целое число
+func (lexer *pdfLexer) readInt() (int64, error) {
+	token, exception := lexer.next()
+
+	if exception != nil {
+		return 0, exception
+	}
+
+	value, ok := pdfNumber(token.value).int()
+
+	if token.kind != pdfTokenKeyword || !ok {
+		return 0, errors.New("Некорректный PDF: ожидалось целое число")
+	}
+
+	return value, nil
+}
+
+/*
+Запись таблицы перекрестных ссылок
+*/
+type pdfXRefEntry struct {
+	// 0 - свободный, 1 - по смещению, 2 - в потоке объектов
+	kind       int
+	offset     int64
+	stream     int
+	index      int
+	generation int
+}
+
+// максимальный размер декодированного потока PDF, байт
+const pdfMaxStreamSize = 64 << 20
+
+/*
+Документ PDF, объекты читаются по требованию
+*/
+type pdfDocument struct {
+	reader  io.ReaderAt
+	size    int64
+	xref    map[int]pdfXRefEntry
+	trailer pdfDictionary
+	// смещение последней таблицы перекрестных ссылок
+	startXRef int64
+	// последняя таблица записана потоком перекрестных ссылок
+	xrefStream    bool
+	objectStreams map[int]*pdfObjectStream
+	// объекты, чтение которых не завершено, для обнаружения циклических ссылок
+	loading map[int]bool
+}
+
+/*
+Декодированный поток объектов
+*/
+type pdfObjectStream struct {
+	data  []byte
+	first int64
+}
+
+/*
+Чтение по смещению из io.ReadSeeker
+*/
+type readSeekerAt struct {
+	reader io.ReadSeeker
+}
+
+func (reader readSeekerAt) ReadAt(buffer []byte, offset int64) (int, error) {
+	if _, exception := reader.reader.Seek(offset, io.SeekStart); exception != nil {
+		return 0, exception
+	}
+
+	return io.ReadFull(reader.reader, buffer)
+}
+
+// открыть документ PDF
+func openPDF(document io.ReadSeeker) (*pdfDocument, error) {
+	size, exception := document.Seek(0, io.SeekEnd)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	pdf := &pdfDocument{reader: readSeekerAt{document}, size: size, xref: map[int]pdfXRefEntry{}, objectStreams: map[int]*pdfObjectStream{}, loading: map[int]bool{}}
+
+	tailSize := int64(2048)
+
+	if tailSize > size {
+		tailSize = size
+	}
+
+	tail := make([]byte, tailSize)
+
+	if _, exception := pdf.reader.ReadAt(tail, size-tailSize); exception != nil {
+		return nil, exception
+	}
+
+	position := bytes.LastIndex(tail, []byte("startxref"))
+
+	if position < 0 {
+		return nil, errors.New("Некорректный PDF: не найден startxref")
+	}
+
+	lexer := newPDFLexer(pdf.reader, size-tailSize+int64(position)+int64(len("startxref")), size)
+
+	if pdf.startXRef, exception = lexer.readInt(); exception != nil {
+		return nil, exception
+	}
+
+	visited := map[int64]bool{}
+
+	for offset := pdf.startXRef; offset != 0; {
+		if visited[offset] || offset >= size {
+			return nil, errors.New("Некорректный PDF: неверная ссылка на таблицу перекрестных ссылок")
+		}
+
+		visited[offset] = true
+		trailer, stream, exception := pdf.readXRef(offset)
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		if pdf.trailer == nil {
+			pdf.trailer = trailer
+			pdf.xrefStream = stream
+		}
+
+		// гибридный файл: дополнительный поток перекрестных ссылок
+		if position := pdfInt(trailer["XRefStm"]); position != 0 && !stream {
+			if _, _, exception := pdf.readXRef(position); exception != nil {
+				return nil, exception
+			}
+		}
+
+		offset = pdfInt(trailer["Prev"])
+	}
+
+	if _, ok := pdf.trailer["Encrypt"]; ok {
+		return nil, errors.New("Зашифрованные PDF не поддерживаются")
+	}
+
+	return pdf, nil
+}
+
+// добавить запись, более новые ревизии имеют приоритет
+func (pdf *pdfDocument) addXRefEntry(number int, entry pdfXRefEntry) {
+	if _, ok := pdf.xref[number]; !ok {
+		pdf.xref[number] = entry
+	}
+}
+
+// прочитать таблицу или поток перекрестных ссылок, вернуть словарь трейлера
+func (pdf *pdfDocument) readXRef(offset int64) (pdfDictionary, bool, error) {
+	lexer := newPDFLexer(pdf.reader, offset, pdf.size)
+	token, exception := lexer.next()
+
+	if exception != nil {
+		return nil, false, exception
+	}
+
+	if token.kind != pdfTokenKeyword || token.value != "xref" {
+		trailer, exception := pdf.readXRefStream(offset)
+
+		return trailer, true, exception
+	}
+
+	for {
+		token, exception := lexer.next()
+
+		if exception != nil {
+			return nil, false, exception
+		}
+
+		if token.kind == pdfTokenKeyword && token.value == "trailer" {
+			break
+		}
+
+		lexer.push(token)
+		start, exception := lexer.readInt()
+
+		if exception != nil {
+			return nil, false, exception
+		}
+
+		count, exception := lexer.readInt()
+
+		if exception != nil {
+			return nil, false, exception
+		}
+
+		for i := int64(0); i < count; i++ {
+			entryOffset, exception := lexer.readInt()
+
+			if exception != nil {
+				return nil, false, exception
+			}
+
+			generation, exception := lexer.readInt()
+
+			if exception != nil {
+				return nil, false, exception
+			}
+
+			kind, exception := lexer.next()
+
+			if exception != nil {
+				return nil, false, exception
+			}
+
+			entry := pdfXRefEntry{offset: entryOffset, generation: int(generation)}
+
+			if kind.value == "n" {
+				entry.kind = 1
+			}
+
+			pdf.addXRefEntry(int(start+i), entry)
+		}
+	}
+
+	trailer, exception := lexer.readObject()
+
+	if exception != nil {
+		return nil, false, exception
+	}
+
+	dictionary, ok := trailer.(pdfDictionary)
+
+	if !ok {
+		return nil, false, errors.New("Некорректный PDF: неверный трейлер")
+	}
+
+	return dictionary, false, nil
+}
+
+// прочитать поток перекрестных ссылок
+func (pdf *pdfDocument) readXRefStream(offset int64) (pdfDictionary, error) {
+	_, object, exception := pdf.readIndirectObject(offset)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	stream, ok := object.(*pdfStream)
+
+	if !ok || stream.dictionary["Type"] != pdfName("XRef") {
+		return nil, errors.New("Некорректный PDF: неверный поток перекрестных ссылок")
+	}
+
+	data, exception := pdf.streamData(stream)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	widths, ok := stream.dictionary["W"].(pdfArray)
+
+	if !ok || len(widths) != 3 {
+		return nil, errors.New("Некорректный PDF: неверный параметр W потока перекрестных ссылок")
+	}
+
+	var width [3]int
+	rowSize := 0
+
+	for i := range width {
+		width[i] = int(pdfInt(widths[i]))
+		rowSize += width[i]
+	}
+
+	index := pdfArray{pdfNumber("0"), stream.dictionary["Size"]}
+
+	if value, ok := stream.dictionary["Index"].(pdfArray); ok {
+		index = value
+	}
+
+	field := func(row []byte) int64 {
+		var value int64
+
+		for _, b := range row {
+			value = value<<8 | int64(b)
+		}
+
+		return value
+	}
+
+	position := 0
+
+	for i := 0; i+1 < len(index); i += 2 {
+		start := pdfInt(index[i])
+		count := pdfInt(index[i+1])
+
+		for j := int64(0); j < count; j++ {
+			if position+rowSize > len(data) {
+				return nil, errors.New("Некорректный PDF: поток перекрестных ссылок короче ожидаемого")
+			}
+
+			row := data[position : position+rowSize]
+			position += rowSize
+
+			kind := int64(1)
+
+			if width[0] != 0 {
+				kind = field(row[:width[0]])
+			}
+
+			second := field(row[width[0] : width[0]+width[1]])
+			third := field(row[width[0]+width[1]:])
+
+			switch kind {
+			case 1:
+				pdf.addXRefEntry(int(start+j), pdfXRefEntry{kind: 1, offset: second, generation: int(third)})
+			case 2:
+				pdf.addXRefEntry(int(start+j), pdfXRefEntry{kind: 2, stream: int(second), index: int(third)})
+			default:
+				pdf.addXRefEntry(int(start+j), pdfXRefEntry{})
+			}
+		}
+	}
+
+	return stream.dictionary, nil
+}
+
+// прочитать косвенный объект по смещению
+func (pdf *pdfDocument) readIndirectObject(offset int64) (int, pdfObject, error) {
+	lexer := newPDFLexer(pdf.reader, offset, pdf.size)
+	number, exception := lexer.readInt()
+
+	if exception != nil {
+		return 0, nil, exception
+	}
+
+	if _, exception := lexer.readInt(); exception != nil {
+		return 0, nil, exception
+	}
+
+	if exception := lexer.expectKeyword("obj"); exception != nil {
+		return 0, nil, exception
+	}
+
+	object, exception := lexer.readObject()
+
+	if exception != nil {
+		return 0, nil, exception
+	}
+
+	dictionary, ok := object.(pdfDictionary)
+
+	if !ok {
+		return int(number), object, nil
+	}
+
+	token, exception := lexer.next()
+
+	if exception != nil {
+		return 0, nil, exception
+	}
+
+	if token.kind != pdfTokenKeyword || token.value != "stream" {
+		return int(number), object, nil
+	}
+
+	// после stream следует CRLF или LF
+	b, exception := lexer.readByte()
+
+	if exception == nil && b == '\r' {
+		if b, exception = lexer.readByte(); exception == nil && b != '\n' {
+			lexer.unreadByte()
+		}
+	}
+
+	return int(number), &pdfStream{dictionary: dictionary, offset: lexer.offset}, nil
+}
+
+// получить косвенный объект по номеру
+func (pdf *pdfDocument) object(number int) (pdfObject, error) {
+	entry, ok := pdf.xref[number]
+
+	if !ok || entry.kind == 0 {
+		return nil, nil
+	}
+
+	// поток объектов или косвенная длина потока могут ссылаться на читаемый объект
+	if pdf.loading[number] {
+		return nil, fmt.Errorf("Некорректный PDF: циклическая ссылка на объект %d", number)
+	}
+
+	pdf.loading[number] = true
+	defer delete(pdf.loading, number)
+
+	if entry.kind == 1 {
+		found, object, exception := pdf.readIndirectObject(entry.offset)
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		if found != number {
+			return nil, fmt.Errorf("Некорректный PDF: по смещению объекта %d найден объект %d", number, found)
+		}
+
+		return object, nil
+	}
+
+	objectStream, ok := pdf.objectStreams[entry.stream]
+
+	if !ok {
+		object, exception := pdf.object(entry.stream)
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		stream, ok := object.(*pdfStream)
+
+		if !ok {
+			return nil, errors.New("Некорректный PDF: не найден поток объектов")
+		}
+
+		data, exception := pdf.streamData(stream)
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		objectStream = &pdfObjectStream{data: data, first: pdfInt(stream.dictionary["First"])}
+		pdf.objectStreams[entry.stream] = objectStream
+	}
+
+	// заголовок потока объектов: пары номер-смещение, затем объекты начиная с First
+	reader := bytes.NewReader(objectStream.data)
+	size := int64(len(objectStream.data))
+	lexer := newPDFLexer(reader, 0, size)
+	var offset int64
+
+	for i := 0; i <= entry.index; i++ {
+		if _, exception := lexer.readInt(); exception != nil {
+			return nil, exception
+		}
+
+		value, exception := lexer.readInt()
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		offset = value
+	}
+
+	if objectStream.first+offset > size {
+		return nil, errors.New("Некорректный PDF: неверный поток объектов")
+	}
+
+	return newPDFLexer(reader, objectStream.first+offset, size).readObject()
+}
+
+// разрешить ссылку на косвенный объект
+func (pdf *pdfDocument) resolve(object pdfObject) (pdfObject, error) {
+	if reference, ok := object.(pdfReference); ok {
+		return pdf.object(reference.number)
+	}
+
+	return object, nil
+}
+
+// разрешить ссылку и получить словарь
+func (pdf *pdfDocument) dictionary(object pdfObject) (pdfDictionary, error) {
+	resolved, exception := pdf.resolve(object)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	if stream, ok := resolved.(*pdfStream); ok {
+		return stream.dictionary, nil
+	}
+
+	dictionary, ok := resolved.(pdfDictionary)
+
+	if !ok {
+		return nil, errors.New("Некорректный PDF: ожидался словарь")
+	}
+
+	return dictionary, nil
+}
+
+// разрешить ссылку и получить целое число
+func (pdf *pdfDocument) int(object pdfObject) (int64, error) {
+	resolved, exception := pdf.resolve(object)
+
+	if exception != nil {
+		return 0, exception
+	}
+
+	number, ok := resolved.(pdfNumber)
+
+	if !ok {
+		return 0, errors.New("Некорректный PDF: ожидалось число")
+	}
+
+	value, ok := number.int()
+
+	if !ok {
+		return 0, errors.New("Некорректный PDF: ожидалось целое число")
+	}
+
+	return value, nil
+}
+
+// прочитать и декодировать данные потока
+func (pdf *pdfDocument) streamData(stream *pdfStream) ([]byte, error) {
+	length, exception := pdf.int(stream.dictionary["Length"])
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	if length < 0 || stream.offset+length > pdf.size {
+		return nil, errors.New("Некорректный PDF: неверная длина потока")
+	}
+
+	data := make([]byte, length)
+
+	if _, exception := pdf.reader.ReadAt(data, stream.offset); exception != nil {
+		return nil, exception
+	}
+
+	filters, ok := stream.dictionary["Filter"].(pdfArray)
+
+	if name, isName := stream.dictionary["Filter"].(pdfName); isName {
+		filters, ok = pdfArray{name}, true
+	}
+
+	if !ok {
+		return data, nil
+	}
+
+	parameters, _ := stream.dictionary["DecodeParms"].(pdfArray)
+
+	if dictionary, isDictionary := stream.dictionary["DecodeParms"].(pdfDictionary); isDictionary {
+		parameters = pdfArray{dictionary}
+	}
+
+	for i, filter := range filters {
+		if filter != pdfName("FlateDecode") {
+			return nil, fmt.Errorf("Не поддерживается фильтр потока PDF %v", filter)
+		}
+
+		reader, exception := zlib.NewReader(bytes.NewReader(data))
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		if data, exception = io.ReadAll(io.LimitReader(reader, pdfMaxStreamSize+1)); exception != nil {
+			return nil, exception
+		}
+
+		if len(data) > pdfMaxStreamSize {
+			return nil, errors.New("Некорректный PDF: размер декодированного потока превышает допустимый")
+		}
+
+		if i < len(parameters) {
+			if dictionary, ok := parameters[i].(pdfDictionary); ok {
+				if data, exception = decodePNGPredictor(data, dictionary); exception != nil {
+					return nil, exception
+				}
+			}
+		}
+	}
+
+	return data, nil
+}
+
+// снять предсказатель PNG (Predictor >= 10) с данных потока
+func decodePNGPredictor(data []byte, parameters pdfDictionary) ([]byte, error) {
+	predictor := pdfInt(parameters["Predictor"])
+
+	if predictor < 10 {
+		if predictor > 1 {
+			return nil, errors.New("Не поддерживается предсказатель TIFF")
+		}
+
+		return data, nil
+	}
+
+	columns := int64(1)
+
+	if _, ok := parameters["Columns"]; ok {
+		columns = pdfInt(parameters["Columns"])
+	}
+
+	rowSize := int(columns) + 1
+
+	if columns <= 0 || len(data)%rowSize != 0 {
+		return nil, errors.New("Некорректный PDF: неверные параметры предсказателя")
+	}
+
+	result := make([]byte, 0, len(data)/rowSize*int(columns))
+	previous := make([]byte, columns)
+
+	for position := 0; position < len(data); position += rowSize {
+		kind := data[position]
+		row := append([]byte{}, data[position+1:position+rowSize]...)
+
+		for i := range row {
+			var left, upperLeft byte
+
+			if i > 0 {
+				left = row[i-1]
+				upperLeft = previous[i-1]
+			}
+
+			switch kind {
+			case 1:
+				row[i] += left
+			case 2:
+				row[i] += previous[i]
+			case 3:
+				row[i] += byte((int(left) + int(previous[i])) / 2)
+			case 4:
+				row[i] += paeth(left, previous[i], upperLeft)
+			}
+		}
+
+		result = append(result, row...)
+		previous = row
+	}
+
+	return result, nil
+}
+
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := p-int(a), p-int(b), p-int(c)
+
+	if pa < 0 {
+		pa = -pa
+	}
+
+	if pb < 0 {
+		pb = -pb
+	}
+
+	if pc < 0 {
+		pc = -pc
+	}
+
+	if pa <= pb && pa <= pc {
+		return a
+	}
+
+	if pb <= pc {
+		return b
+	}
+
+	return c
+}
+
+// записать объект PDF
+func writePDFObject(buffer *bytes.Buffer, object pdfObject) {
+	switch object := object.(type) {
+	case nil:
+		buffer.WriteString("null")
+	case pdfName:
+		buffer.WriteByte('/')
+
+		for _, b := range []byte(object) {
+			if b <= ' ' || b > '~' || b == '#' || isPDFDelimiter(b) {
+				fmt.Fprintf(buffer, "#%02X", b)
+			} else {
+				buffer.WriteByte(b)
+			}
+		}
+	case pdfString:
+		printable := true
+
+		for _, b := range object {
+			if b < ' ' || b > '~' || b == '(' || b == ')' || b == '\\' {
+				printable = false
+				break
+			}
+		}
+
+		if printable {
+			buffer.WriteString("(" + string(object) + ")")
+		} else {
+			buffer.WriteString("<" + hex.EncodeToString(object) + ">")
+		}
+	case pdfNumber:
+		buffer.WriteString(string(object))
+	case pdfBoolean:
+		buffer.WriteString(strconv.FormatBool(bool(object)))
+	case pdfReference:
+		fmt.Fprintf(buffer, "%d %d R", object.number, object.generation)
+	case pdfArray:
+		buffer.WriteByte('[')
+
+		for i, value := range object {
+			if i > 0 {
+				buffer.WriteByte(' ')
+			}
+
+			writePDFObject(buffer, value)
+		}
+
+		buffer.WriteByte(']')
+	case pdfDictionary:
+		keys := make([]string, 0, len(object))
+
+		for key := range object {
+			keys = append(keys, string(key))
+		}
+
+		sort.Strings(keys)
+		buffer.WriteString("<<")
+
+		for _, key := range keys {
+			writePDFObject(buffer, pdfName(key))
+			buffer.WriteByte(' ')
+			writePDFObject(buffer, object[pdfName(key)])
+		}
+
+		buffer.WriteString(">>")
+	}
+}
+
+// число PDF из целого
+func newPDFNumber(value int64) pdfNumber {
+	return pdfNumber(strconv.FormatInt(value, 10))
+}
@@ -0,0 +1,368 @@
+package cryptography
+
+import (
+	"crypto/rand"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+)
+
+// закрытые ключи ГОСТ Р 34.10-2012 в PKCS#8 (RFC 5958, RFC 9215) и шифрование PBES2 (RFC 8018, RFC 9337)
+
+var (
+	OIDPBES2                 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	OIDPBKDF2                = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	OIDHMACGOST3411_2012_256 = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 4, 1}
+	OIDHMACGOST3411_2012_512 = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 4, 2}
+	hmacHashes               = map[string]*HashAlgorithm{
+		OIDHMACGOST3411_2012_256.String(): HashGOST3411_2012_256,
+		OIDHMACGOST3411_2012_512.String(): HashGOST3411_2012_512,
+	}
+)
+
+const (
+	// число итераций PBKDF2 по умолчанию
+	PKCS8Iterations = 2000
+
+	pkcs8SaltSize = 32
+)
+
+/*
+Параметры шифрования закрытого ключа PBES2
+*/
+type PKCS8Options struct {
+	// алгоритм шифрования: OIDKuznyechikCTRACPKM (по умолчанию), OIDMagmaCTRACPKM или OIDGOST28147 (CFB, таблица замен TC26 Z)
+	// RFC 9337 не определяет идентификаторов режима CFB для Кузнечика и Магмы, CFB доступен только для ГОСТ 28147-89
+	Encryption asn1.ObjectIdentifier
+	// число итераций PBKDF2 с HMAC ГОСТ Р 34.11-2012 (512), по умолчанию PKCS8Iterations
+	Iterations int
+	// источник случайных чисел, по умолчанию crypto/rand
+	Random io.Reader
+}
+
+type privateKeyInfo struct {
+	Version    int
+	Algorithm  AlgorithmIdentifier
+	PrivateKey []byte
+	Attributes asn1.RawValue `asn1:"optional,tag:0"`
+	PublicKey  asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm     AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbes2Parameters struct {
+	KeyDerivationFunc AlgorithmIdentifier
+	EncryptionScheme  AlgorithmIdentifier
+}
+
+type pbkdf2Parameters struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                 `asn1:"optional"`
+	PRF            AlgorithmIdentifier `asn1:"optional"`
+}
+
+// закодировать закрытый ключ ГОСТ Р 34.10-2012 в PKCS#8 PrivateKeyInfo, значение ключа - OCTET STRING в little-endian
+func MarshalPKCS8PrivateKey(key *PrivateKey) ([]byte, error) {
+	algorithm, parameters := publicKeyParameters(key.Curve)
+
+	encodedParameters, exception := asn1.Marshal(parameters)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	encodedKey, exception := asn1.Marshal(bigToLittleEndian(key.D, key.Curve.PointSize))
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	return asn1.Marshal(privateKeyInfo{
+		Algorithm:  AlgorithmIdentifier{Algorithm: algorithm, Parameters: asn1.RawValue{FullBytes: encodedParameters}},
+		PrivateKey: encodedKey,
+	})
+}
+
+// разобрать закрытый ключ ГОСТ Р 34.10 из PKCS#8 PrivateKeyInfo
+// значение ключа принимается как OCTET STRING в little-endian, INTEGER или неупакованные байты в little-endian
+func ParsePKCS8PrivateKey(der []byte) (*PrivateKey, error) {
+	var info privateKeyInfo
+
+	if rest, exception := asn1.Unmarshal(der, &info); exception != nil {
+		return nil, exception
+	} else if len(rest) != 0 {
+		return nil, errors.New("Лишние данные после PKCS#8 PrivateKeyInfo")
+	}
+
+	algorithm := info.Algorithm.Algorithm
+
+	if !algorithm.Equal(OIDGOST3410_2001) && !algorithm.Equal(OIDGOST3410_2012_256) && !algorithm.Equal(OIDGOST3410_2012_512) {
+		return nil, errors.New("Закрытый ключ не является ключом ГОСТ Р 34.10: " + algorithm.String())
+	}
+
+	var parameters PublicKeyParameters
+
+	if _, exception := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &parameters); exception != nil {
+		return nil, exception
+	}
+
+	curve, exception := FindCurve(parameters.PublicKeyParamSet)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	var value asn1.RawValue
+
+	rest, exception := asn1.Unmarshal(info.PrivateKey, &value)
+	encoded := exception == nil && len(rest) == 0 && value.Class == asn1.ClassUniversal
+
+	switch {
+	case encoded && value.Tag == asn1.TagOctetString:
+		return NewPrivateKey(curve, littleEndianToBig(value.Bytes))
+	case encoded && value.Tag == asn1.TagInteger:
+		d := new(big.Int)
+
+		if _, exception := asn1.Unmarshal(info.PrivateKey, &d); exception != nil {
+			return nil, exception
+		}
+
+		return NewPrivateKey(curve, d)
+	case len(info.PrivateKey) == curve.PointSize:
+		return NewPrivateKey(curve, littleEndianToBig(info.PrivateKey))
+	}
+
+	return nil, errors.New("Неподдерживаемый формат значения закрытого ключа")
+}
+
+// зашифровать закрытый ключ на пароле и закодировать в PKCS#8 EncryptedPrivateKeyInfo
+func MarshalEncryptedPKCS8PrivateKey(key *PrivateKey, password []byte, options PKCS8Options) ([]byte, error) {
+	if options.Encryption == nil {
+		options.Encryption = OIDKuznyechikCTRACPKM
+	}
+
+	if options.Iterations == 0 {
+		options.Iterations = PKCS8Iterations
+	}
+
+	if options.Random == nil {
+		options.Random = rand.Reader
+	}
+
+	if options.Iterations < 0 || options.Iterations > pbkdf2MaxIterations {
+		return nil, errors.New("Неверное число итераций PBKDF2")
+	}
+
+	salt := make([]byte, pkcs8SaltSize)
+
+	if _, exception := io.ReadFull(options.Random, salt); exception != nil {
+		return nil, exception
+	}
+
+	encryption, exception := createPBES2Scheme(options.Encryption, options.Random)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	kdfParameters, exception := asn1.Marshal(pbkdf2Parameters{
+		Salt:           salt,
+		IterationCount: options.Iterations,
+		KeyLength:      KuznyechikKeySize,
+		PRF:            AlgorithmIdentifier{Algorithm: OIDHMACGOST3411_2012_512, Parameters: asn1Null},
+	})
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	parameters, exception := asn1.Marshal(pbes2Parameters{
+		KeyDerivationFunc: AlgorithmIdentifier{Algorithm: OIDPBKDF2, Parameters: asn1.RawValue{FullBytes: kdfParameters}},
+		EncryptionScheme:  encryption,
+	})
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	data, exception := MarshalPKCS8PrivateKey(key)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	encryptionKey, exception := PBKDF2(password, salt, options.Iterations, KuznyechikKeySize, HashGOST3411_2012_512)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	stream, exception := createContentStream(encryption, encryptionKey, false)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	stream.XORKeyStream(data, data)
+
+	return asn1.Marshal(encryptedPrivateKeyInfo{
+		Algorithm:     AlgorithmIdentifier{Algorithm: OIDPBES2, Parameters: asn1.RawValue{FullBytes: parameters}},
+		EncryptedData: data,
+	})
+}
+
+// расшифровать закрытый ключ из PKCS#8 EncryptedPrivateKeyInfo
+func ParseEncryptedPKCS8PrivateKey(der []byte, password []byte) (*PrivateKey, error) {
+	var info encryptedPrivateKeyInfo
+
+	if _, exception := asn1.Unmarshal(der, &info); exception != nil {
+		return nil, exception
+	}
+
+	if !info.Algorithm.Algorithm.Equal(OIDPBES2) {
+		return nil, errors.New("Неподдерживаемый алгоритм шифрования закрытого ключа " + info.Algorithm.Algorithm.String())
+	}
+
+	var parameters pbes2Parameters
+
+	if _, exception := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &parameters); exception != nil {
+		return nil, exception
+	}
+
+	if !parameters.KeyDerivationFunc.Algorithm.Equal(OIDPBKDF2) {
+		return nil, errors.New("Неподдерживаемая функция выработки ключа " + parameters.KeyDerivationFunc.Algorithm.String())
+	}
+
+	var kdfParameters pbkdf2Parameters
+
+	if _, exception := asn1.Unmarshal(parameters.KeyDerivationFunc.Parameters.FullBytes, &kdfParameters); exception != nil {
+		return nil, exception
+	}
+
+	hash, ok := hmacHashes[kdfParameters.PRF.Algorithm.String()]
+
+	if !ok {
+		return nil, errors.New("PBKDF2 поддерживается только с HMAC ГОСТ Р 34.11-2012")
+	}
+
+	if kdfParameters.IterationCount <= 0 || kdfParameters.IterationCount > pbkdf2MaxIterations {
+		return nil, errors.New("Неверное число итераций PBKDF2")
+	}
+
+	if kdfParameters.KeyLength != 0 && kdfParameters.KeyLength != KuznyechikKeySize {
+		return nil, errors.New("Неверная длина ключа PBKDF2")
+	}
+
+	encryptionKey, exception := PBKDF2(password, kdfParameters.Salt, kdfParameters.IterationCount, KuznyechikKeySize, hash)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	stream, exception := createContentStream(parameters.EncryptionScheme, encryptionKey, true)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	data := make([]byte, len(info.EncryptedData))
+	stream.XORKeyStream(data, info.EncryptedData)
+
+	key, exception := ParsePKCS8PrivateKey(data)
+
+	if exception != nil {
+		return nil, errors.New("Неверный пароль или поврежден закрытый ключ")
+	}
+
+	return key, nil
+}
+
+// сформировать алгоритм шифрования PBES2 со случайной синхропосылкой
+func createPBES2Scheme(encryption asn1.ObjectIdentifier, random io.Reader) (AlgorithmIdentifier, error) {
+	var parameters interface{}
+
+	if encryption.Equal(OIDGOST28147) {
+		iv := make([]byte, GOST28147BlockSize)
+
+		if _, exception := io.ReadFull(random, iv); exception != nil {
+			return AlgorithmIdentifier{}, exception
+		}
+
+		parameters = gost28147Parameters{IV: iv, EncryptionParamSet: OIDGOST28147TC26Z}
+	} else {
+		contentCipher, exception := FindContentCipher(encryption)
+
+		if exception != nil {
+			return AlgorithmIdentifier{}, exception
+		}
+
+		ukm := make([]byte, contentCipher.BlockSize/2+8)
+
+		if _, exception := io.ReadFull(random, ukm); exception != nil {
+			return AlgorithmIdentifier{}, exception
+		}
+
+		parameters = gost3412Parameters{UKM: ukm}
+	}
+
+	encoded, exception := asn1.Marshal(parameters)
+
+	if exception != nil {
+		return AlgorithmIdentifier{}, exception
+	}
+
+	return AlgorithmIdentifier{Algorithm: encryption, Parameters: asn1.RawValue{FullBytes: encoded}}, nil
+}
+
+// прочитать закрытый ключ ГОСТ из PEM или DER: PKCS#8 (PRIVATE KEY) или зашифрованный PKCS#8 (ENCRYPTED PRIVATE KEY)
+func ParseGOSTPrivateKey(key io.Reader, password []byte) (*PrivateKey, error) {
+	data, exception := io.ReadAll(key)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	if block, _ := pem.Decode(data); block != nil {
+		switch block.Type {
+		case "PRIVATE KEY":
+			return ParsePKCS8PrivateKey(block.Bytes)
+		case "ENCRYPTED PRIVATE KEY":
+			return ParseEncryptedPKCS8PrivateKey(block.Bytes, password)
+		default:
+			return nil, errors.New("Не поддерживается тип ключа PEM " + block.Type)
+		}
+	}
+
+	if privateKey, exception := ParsePKCS8PrivateKey(data); exception == nil {
+		return privateKey, nil
+	}
+
+	return ParseEncryptedPKCS8PrivateKey(data, password)
+}
+
+// закодировать закрытый ключ ГОСТ в PEM, при непустом пароле ключ шифруется PBES2
+func MarshalGOSTPrivateKeyPEM(key *PrivateKey, password []byte, options PKCS8Options) ([]byte, error) {
+	if len(password) == 0 {
+		data, exception := MarshalPKCS8PrivateKey(key)
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: data}), nil
+	}
+
+	data, exception := MarshalEncryptedPKCS8PrivateKey(key, password, options)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: data}), nil
+}
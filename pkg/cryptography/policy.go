@@ -0,0 +1,583 @@
+package cryptography
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/madpo/go-gost-crypto/pkg/wrapper"
+)
+
+// идентификаторы расширений квалифицированного сертификата (приказ ФСБ России № 795)
+var (
+	OIDSubjectSignTool = asn1.ObjectIdentifier{1, 2, 643, 100, 111}
+	OIDIssuerSignTool  = asn1.ObjectIdentifier{1, 2, 643, 100, 112}
+)
+
+// ГОСТ Р 34.10-2001 не допускается для формирования подписи с 1 января 2019 года
+var RestrictionGOST3410_2001 = AlgorithmRestriction{
+	OID:      OIDGOST3410_2001,
+	NotAfter: time.Date(2019, time.January, 1, 0, 0, 0, 0, time.FixedZone("MSK", 3*60*60)),
+}
+
+/*
+Правило политики проверки
+*/
+type PolicyRule string
+
+const (
+	// алгоритм хэширования входит в список допустимых
+	RuleHashAlgorithm PolicyRule = "hash-algorithm"
+	// алгоритм подписи входит в список допустимых
+	RuleSignatureAlgorithm PolicyRule = "signature-algorithm"
+	// подпись сформирована в период, когда алгоритм был допустим
+	RuleAlgorithmPeriod PolicyRule = "algorithm-period"
+	// сертификат действителен на время проверки
+	RuleCertificateValidity PolicyRule = "certificate-validity"
+	// цепочка сертификатов строится до доверенного корневого сертификата
+	RuleTrustedChain PolicyRule = "trusted-chain"
+	// сертификат содержит требуемые флаги использования ключа
+	RuleKeyUsage PolicyRule = "key-usage"
+	// сертификат содержит одну из требуемых политик
+	RuleCertificatePolicy PolicyRule = "certificate-policy"
+	// сертификат является квалифицированным
+	RuleQualifiedCertificate PolicyRule = "qualified-certificate"
+	// подпись содержит штамп времени
+	RuleTimestamp PolicyRule = "timestamp"
+)
+
+/*
+Ограничение срока использования алгоритма
+*/
+type AlgorithmRestriction struct {
+	// OID алгоритма хэширования, подписи или открытого ключа
+	OID asn1.ObjectIdentifier
+	// подписи, сформированные с этого момента, не принимаются
+	NotAfter time.Time
+}
+
+/*
+Политика проверки подписи
+пустые списки и нулевые значения отключают соответствующие правила
+*/
+type ValidationPolicy struct {
+	// допустимые алгоритмы хэширования по OID
+	HashAlgorithms []asn1.ObjectIdentifier
+	// допустимые алгоритмы хэширования по типу КриптоПро, объединяются с HashAlgorithms
+	HashTypes []wrapper.HashType
+	// допустимые алгоритмы подписи по OID подписи или открытого ключа
+	SignatureAlgorithms []asn1.ObjectIdentifier
+	// сроки использования алгоритмов, например RestrictionGOST3410_2001
+	Restrictions []AlgorithmRestriction
+	// доверенные корневые сертификаты
+	Roots []*x509.Certificate
+	// промежуточные сертификаты для построения цепочки, дополняются сертификатами из подписи
+	Intermediates []*x509.Certificate
+	// время проверки сертификатов, по умолчанию текущее
+	ValidationTime time.Time
+	// обязательные флаги использования ключа
+	KeyUsage x509.KeyUsage
+	// допустимые политики сертификата, достаточно одной из списка
+	CertificatePolicies []asn1.ObjectIdentifier
+	// требовать квалифицированный сертификат с расширениями subjectSignTool и issuerSignTool
+	Qualified bool
+	// требовать штамп времени на подпись
+	RequireTimestamp bool
+	// учитывать атрибут signing-time при проверке сроков алгоритмов, если нет доверенного штампа времени
+	// атрибут задается подписантом, поэтому по умолчанию используется время проверки
+	TrustSigningTime bool
+}
+
+/*
+Данные подписи для проверки политикой
+*/
+type PolicyInput struct {
+	Certificate *x509.Certificate
+	// сертификаты из подписи для построения цепочки
+	Certificates       []*x509.Certificate
+	DigestAlgorithm    asn1.ObjectIdentifier
+	SignatureAlgorithm asn1.ObjectIdentifier
+	// время подписи из подписанных атрибутов
+	SigningTime time.Time
+	// время штампа времени на подпись, нулевое если штампа нет
+	TimestampTime time.Time
+	// сертификат службы штампов времени, штамп учитывается только при расширении timeStamping
+	TimestampCertificate *x509.Certificate
+	// сертификаты из штампа времени для построения цепочки службы штампов
+	TimestampCertificates []*x509.Certificate
+}
+
+/*
+Результат проверки одного правила
+*/
+type RuleResult struct {
+	Rule   PolicyRule
+	Passed bool
+	// причина невыполнения правила
+	Exception error
+}
+
+/*
+Результат проверки подписи политикой
+*/
+type PolicyReport struct {
+	// время, на которое проверялись сертификаты
+	ValidationTime time.Time
+	// цепочка от сертификата подписанта до доверенного корневого, если она строилась
+	Chain []*x509.Certificate
+	Rules []RuleResult
+}
+
+// выполнены ли все правила
+func (report *PolicyReport) Valid() bool {
+	for _, rule := range report.Rules {
+		if !rule.Passed {
+			return false
+		}
+	}
+
+	return true
+}
+
+// невыполненные правила
+func (report *PolicyReport) Failed() []RuleResult {
+	var failed []RuleResult
+
+	for _, rule := range report.Rules {
+		if !rule.Passed {
+			failed = append(failed, rule)
+		}
+	}
+
+	return failed
+}
+
+// добавить результат правила
+func (report *PolicyReport) add(rule PolicyRule, exception error) {
+	report.Rules = append(report.Rules, RuleResult{Rule: rule, Passed: exception == nil, Exception: exception})
+}
+
+// получить метод проверки подписи политикой
+// метод подходит для результатов любых API проверки: CMS, PAdES, XMLDSig и OOXML
+func CreatePolicyCheckMethod(policy ValidationPolicy) (release func(), check func(input PolicyInput) *PolicyReport, exception error) {
+	for _, hashType := range policy.HashTypes {
+		if _, exception := FindHashAlgorithmByType(hashType); exception != nil {
+			return nil, nil, exception
+		}
+	}
+
+	return func() {},
+		func(input PolicyInput) *PolicyReport {
+			return checkPolicy(&policy, &input)
+		}, nil
+}
+
+// получить метод проверки CMS подписи с применением политики
+// результат политики записывается в поле Policy каждого подписанта и учитывается в Valid()
+func CreateCMSPolicyVerifyMethod(policy ValidationPolicy) (release func(), verify func(signature io.Reader, content io.Reader) (*VerifyReport, error), exception error) {
+	release, check, exception := CreatePolicyCheckMethod(policy)
+
+	if exception != nil {
+		return nil, nil, exception
+	}
+
+	return release,
+		func(signature io.Reader, content io.Reader) (*VerifyReport, error) {
+			signedData, exception := readSignedData(signature)
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			report, exception := verifySignedData(signedData, content)
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			applySignedDataPolicy(check, signedData, report)
+
+			return report, nil
+		}, nil
+}
+
+// применить политику ко всем подписантам SignedData
+func applySignedDataPolicy(check func(PolicyInput) *PolicyReport, signedData *SignedData, report *VerifyReport) {
+	for i := range report.Signers {
+		signer := &report.Signers[i]
+
+		input := PolicyInput{
+			Certificate:        signer.Certificate,
+			Certificates:       report.Certificates,
+			DigestAlgorithm:    signer.DigestAlgorithm,
+			SignatureAlgorithm: signer.SignatureAlgorithm,
+			SigningTime:        signer.SigningTime,
+		}
+
+		// штамп времени учитывается, только если он верен и выдан на значение подписи
+		if info, timestamp, exception := signatureTimestamp(&signedData.SignerInfos[i]); exception == nil && info != nil {
+			input.TimestampTime = info.GenTime
+			input.TimestampCertificate = timestamp.Signers[0].Certificate
+			input.TimestampCertificates = timestamp.Certificates
+		}
+
+		signer.Policy = check(input)
+	}
+}
+
+// разобрать и проверить штамп времени на значение подписи, nil если штампа нет
+func signatureTimestamp(signerInfo *SignerInfo) (*TSTInfo, *VerifyReport, error) {
+	attributes, exception := parseAttributes(signerInfo.UnsignedAttributes)
+
+	if exception != nil {
+		return nil, nil, exception
+	}
+
+	attribute, ok := findAttribute(attributes, OIDAttributeSignatureTimeStampToken)
+
+	if !ok || len(attribute.Values) == 0 {
+		return nil, nil, nil
+	}
+
+	info, report, exception := parseTimestampToken(bytes.NewReader(attribute.Values[0].FullBytes))
+
+	if exception != nil {
+		return nil, nil, exception
+	}
+
+	algorithm, exception := FindHashAlgorithm(info.MessageImprint.HashAlgorithm.Algorithm)
+
+	if exception != nil {
+		return nil, nil, exception
+	}
+
+	digest, exception := calculateBytesDigest(algorithm, signerInfo.Signature)
+
+	if exception != nil {
+		return nil, nil, exception
+	}
+
+	if !bytes.Equal(digest, info.MessageImprint.HashedMessage) {
+		return nil, nil, errors.New("Штамп времени выдан не на значение подписи")
+	}
+
+	return info, report, nil
+}
+
+// проверить данные подписи всеми правилами политики
+func checkPolicy(policy *ValidationPolicy, input *PolicyInput) *PolicyReport {
+	report := &PolicyReport{ValidationTime: policy.ValidationTime}
+
+	if report.ValidationTime.IsZero() {
+		report.ValidationTime = time.Now()
+	}
+
+	hash, _ := FindHashAlgorithm(input.DigestAlgorithm)
+	timestampTime, timestampException := checkTimestampAuthority(policy, input)
+
+	if len(policy.HashAlgorithms) > 0 || len(policy.HashTypes) > 0 {
+		report.add(RuleHashAlgorithm, checkPolicyHash(policy, input.DigestAlgorithm, hash))
+	}
+
+	if len(policy.SignatureAlgorithms) > 0 {
+		report.add(RuleSignatureAlgorithm, checkPolicySignature(policy, input.SignatureAlgorithm, hash))
+	}
+
+	if len(policy.Restrictions) > 0 {
+		report.add(RuleAlgorithmPeriod, checkPolicyRestrictions(policy, input, hash, timestampTime, report.ValidationTime))
+	}
+
+	if input.Certificate == nil {
+		report.add(RuleCertificateValidity, errors.New("Не найден сертификат подписанта"))
+
+		return report
+	}
+
+	report.add(RuleCertificateValidity, checkCertificateValidity(input.Certificate, report.ValidationTime))
+
+	if len(policy.Roots) > 0 {
+		intermediates := append(append([]*x509.Certificate{}, policy.Intermediates...), input.Certificates...)
+		chain, exception := buildCertificateChain(input.Certificate, intermediates, policy.Roots, report.ValidationTime)
+
+		report.Chain = chain
+		report.add(RuleTrustedChain, exception)
+	}
+
+	if policy.KeyUsage != 0 {
+		var exception error
+
+		if input.Certificate.KeyUsage&policy.KeyUsage != policy.KeyUsage {
+			exception = fmt.Errorf("Сертификат не содержит требуемые флаги использования ключа %#x", int(policy.KeyUsage))
+		}
+
+		report.add(RuleKeyUsage, exception)
+	}
+
+	if len(policy.CertificatePolicies) > 0 {
+		exception := errors.New("Сертификат не содержит требуемую политику")
+
+		for _, oid := range input.Certificate.PolicyIdentifiers {
+			if containsOID(policy.CertificatePolicies, oid) {
+				exception = nil
+				break
+			}
+		}
+
+		report.add(RuleCertificatePolicy, exception)
+	}
+
+	if policy.Qualified {
+		report.add(RuleQualifiedCertificate, checkQualifiedCertificate(input.Certificate))
+	}
+
+	if policy.RequireTimestamp {
+		report.add(RuleTimestamp, timestampException)
+	}
+
+	return report
+}
+
+// проверить алгоритм хэширования по спискам OID и типов КриптоПро
+func checkPolicyHash(policy *ValidationPolicy, oid asn1.ObjectIdentifier, hash *HashAlgorithm) error {
+	if containsOID(policy.HashAlgorithms, oid) {
+		return nil
+	}
+
+	if hash != nil && hash.HashType != 0 {
+		for _, hashType := range policy.HashTypes {
+			if hash.HashType == hashType {
+				return nil
+			}
+		}
+	}
+
+	return errors.New("Алгоритм хэширования " + oid.String() + " не допускается политикой")
+}
+
+// проверить алгоритм подписи по OID подписи или открытого ключа
+func checkPolicySignature(policy *ValidationPolicy, oid asn1.ObjectIdentifier, hash *HashAlgorithm) error {
+	if containsOID(policy.SignatureAlgorithms, oid) {
+		return nil
+	}
+
+	if algorithm, exception := FindSignatureAlgorithm(oid, hash); exception == nil {
+		if containsOID(policy.SignatureAlgorithms, algorithm.OID) || containsOID(policy.SignatureAlgorithms, algorithm.PublicKeyOID) {
+			return nil
+		}
+	}
+
+	return errors.New("Алгоритм подписи " + oid.String() + " не допускается политикой")
+}
+
+// проверить сроки использования алгоритмов
+// время подписи берется из доверенного штампа времени, затем из атрибута signing-time при TrustSigningTime, иначе используется время проверки
+func checkPolicyRestrictions(policy *ValidationPolicy, input *PolicyInput, hash *HashAlgorithm, timestampTime time.Time, validationTime time.Time) error {
+	signingTime := timestampTime
+
+	if signingTime.IsZero() && policy.TrustSigningTime {
+		signingTime = input.SigningTime
+	}
+
+	if signingTime.IsZero() {
+		signingTime = validationTime
+	}
+
+	oids := []asn1.ObjectIdentifier{input.DigestAlgorithm, input.SignatureAlgorithm}
+
+	if algorithm, exception := FindSignatureAlgorithm(input.SignatureAlgorithm, hash); exception == nil {
+		oids = append(oids, algorithm.OID, algorithm.PublicKeyOID)
+	}
+
+	for _, restriction := range policy.Restrictions {
+		if containsOID(oids, restriction.OID) && !signingTime.Before(restriction.NotAfter) {
+			return fmt.Errorf("Алгоритм %s не допускается для подписей после %s", restriction.OID, restriction.NotAfter.Format("02.01.2006"))
+		}
+	}
+
+	return nil
+}
+
+// проверить службу штампов времени и вернуть время штампа
+// сертификат службы должен содержать расширение timeStamping и строиться до доверенного корневого сертификата, если он задан
+func checkTimestampAuthority(policy *ValidationPolicy, input *PolicyInput) (time.Time, error) {
+	if input.TimestampTime.IsZero() {
+		return time.Time{}, errors.New("Подпись не содержит верный штамп времени")
+	}
+
+	if input.TimestampCertificate == nil {
+		return time.Time{}, errors.New("Не найден сертификат службы штампов времени")
+	}
+
+	timeStamping := false
+
+	for _, usage := range input.TimestampCertificate.ExtKeyUsage {
+		timeStamping = timeStamping || usage == x509.ExtKeyUsageTimeStamping
+	}
+
+	if !timeStamping {
+		return time.Time{}, errors.New("Сертификат " + input.TimestampCertificate.Subject.CommonName + " не предназначен для штампов времени")
+	}
+
+	if len(policy.Roots) > 0 {
+		intermediates := append(append([]*x509.Certificate{}, policy.Intermediates...), input.TimestampCertificates...)
+
+		if _, exception := buildCertificateChain(input.TimestampCertificate, intermediates, policy.Roots, input.TimestampTime); exception != nil {
+			return time.Time{}, exception
+		}
+	}
+
+	return input.TimestampTime, nil
+}
+
+// проверить срок действия сертификата
+func checkCertificateValidity(certificate *x509.Certificate, at time.Time) error {
+	if at.Before(certificate.NotBefore) || at.After(certificate.NotAfter) {
+		return fmt.Errorf("Сертификат %s не действителен на %s", certificate.Subject.CommonName, at.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// проверить расширения квалифицированного сертификата
+func checkQualifiedCertificate(certificate *x509.Certificate) error {
+	for _, oid := range []asn1.ObjectIdentifier{OIDSubjectSignTool, OIDIssuerSignTool} {
+		found := false
+
+		for _, extension := range certificate.Extensions {
+			if extension.Id.Equal(oid) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return errors.New("Сертификат не является квалифицированным: нет расширения " + oid.String())
+		}
+	}
+
+	return nil
+}
+
+// построить цепочку от сертификата до доверенного корневого с проверкой подписей и сроков действия
+func buildCertificateChain(certificate *x509.Certificate, intermediates []*x509.Certificate, roots []*x509.Certificate, at time.Time) ([]*x509.Certificate, error) {
+	chain := []*x509.Certificate{certificate}
+	current := certificate
+
+	// ограничение длины защищает от циклов в промежуточных сертификатах
+	for len(chain) <= 10 {
+		for _, root := range roots {
+			if bytes.Equal(root.Raw, current.Raw) {
+				return chain, nil
+			}
+		}
+
+		var issuer *x509.Certificate
+		var constraint error
+
+		for _, candidates := range [][]*x509.Certificate{roots, intermediates} {
+			for _, candidate := range candidates {
+				if issuer != nil || bytes.Equal(candidate.Raw, current.Raw) || !bytes.Equal(candidate.RawSubject, current.RawIssuer) || checkCertificateSignature(current, candidate) != nil {
+					continue
+				}
+
+				// промежуточные сертификаты передает подписант, поэтому издатель обязан быть УЦ
+				if exception := checkIssuerConstraints(candidate, len(chain)-1); exception != nil {
+					constraint = exception
+					continue
+				}
+
+				issuer = candidate
+			}
+		}
+
+		if issuer == nil && constraint != nil {
+			return chain, constraint
+		}
+
+		if issuer == nil {
+			return chain, errors.New("Не удалось построить цепочку до доверенного корневого сертификата для " + current.Subject.CommonName)
+		}
+
+		if exception := checkCertificateValidity(issuer, at); exception != nil {
+			return chain, exception
+		}
+
+		chain = append(chain, issuer)
+		current = issuer
+	}
+
+	return chain, errors.New("Слишком длинная цепочка сертификатов")
+}
+
+// проверить, что сертификат может издавать сертификаты: basicConstraints УЦ, keyCertSign и длина пути по RFC 5280 п. 6.1.4
+func checkIssuerConstraints(issuer *x509.Certificate, intermediates int) error {
+	if !issuer.BasicConstraintsValid || !issuer.IsCA {
+		return errors.New("Сертификат издателя " + issuer.Subject.CommonName + " не является сертификатом УЦ")
+	}
+
+	if issuer.KeyUsage != 0 && issuer.KeyUsage&x509.KeyUsageCertSign == 0 {
+		return errors.New("Сертификат издателя " + issuer.Subject.CommonName + " не допускает подпись сертификатов")
+	}
+
+	if (issuer.MaxPathLen > 0 || issuer.MaxPathLenZero) && intermediates > issuer.MaxPathLen {
+		return errors.New("Превышена длина пути сертификатов издателя " + issuer.Subject.CommonName)
+	}
+
+	return nil
+}
+
+/*
+Сертификат с необработанными полями для проверки подписи
+*/
+type rawCertificate struct {
+	TBSCertificate     asn1.RawValue
+	SignatureAlgorithm AlgorithmIdentifier
+	Signature          asn1.BitString
+}
+
+// проверить подпись сертификата ключом издателя, включая алгоритмы ГОСТ Р 34.10
+func checkCertificateSignature(certificate *x509.Certificate, issuer *x509.Certificate) error {
+	if certificate.SignatureAlgorithm != x509.UnknownSignatureAlgorithm {
+		return certificate.CheckSignatureFrom(issuer)
+	}
+
+	var raw rawCertificate
+
+	if _, exception := asn1.Unmarshal(certificate.Raw, &raw); exception != nil {
+		return exception
+	}
+
+	algorithm, exception := FindSignatureAlgorithm(raw.SignatureAlgorithm.Algorithm, nil)
+
+	if exception != nil {
+		return exception
+	}
+
+	digest, exception := calculateBytesDigest(algorithm.Hash, raw.TBSCertificate.FullBytes)
+
+	if exception != nil {
+		return exception
+	}
+
+	publicKey, exception := CertificatePublicKey(issuer)
+
+	if exception != nil {
+		return exception
+	}
+
+	return verifyDigest(publicKey, algorithm, digest, raw.Signature.RightAlign())
+}
+
+func containsOID(oids []asn1.ObjectIdentifier, oid asn1.ObjectIdentifier) bool {
+	for _, value := range oids {
+		if value.Equal(oid) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,399 @@
+package cryptography
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"io"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/madpo/go-gost-crypto/pkg/wrapper"
+)
+
+// политика сертификата класса КС1
+var testPolicyKC1 = asn1.ObjectIdentifier{1, 2, 643, 100, 113, 1}
+
+type testExtendedTBSCertificate struct {
+	Version            int `asn1:"explicit,tag:0"`
+	SerialNumber       *big.Int
+	SignatureAlgorithm AlgorithmIdentifier
+	Issuer             asn1.RawValue
+	Validity           testValidity
+	Subject            asn1.RawValue
+	PublicKey          asn1.RawValue
+	Extensions         []pkix.Extension `asn1:"optional,explicit,tag:3"`
+}
+
+// сформировать сертификат ГОСТ с расширениями, выпущенный issuer, при issuer = nil - самоподписанный
+func createTestIssuedCertificate(t *testing.T, curve *Curve, commonName string, issuerKey *PrivateKey, issuer *x509.Certificate, extensions []pkix.Extension) (*PrivateKey, *x509.Certificate) {
+	privateKey, error := GeneratePrivateKey(curve, nil)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	publicKey, error := MarshalPublicKey(&privateKey.PublicKey)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	subject, error := asn1.Marshal(pkix.Name{CommonName: commonName}.ToRDNSequence())
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	issuerName := subject
+
+	if issuer == nil {
+		issuerKey = privateKey
+	} else {
+		issuerName = issuer.RawSubject
+	}
+
+	algorithm := SignatureGOST3410_2012_256
+
+	if issuerKey.Curve.PointSize == 64 {
+		algorithm = SignatureGOST3410_2012_512
+	}
+
+	tbs, error := asn1.Marshal(testExtendedTBSCertificate{
+		Version:            2,
+		SerialNumber:       big.NewInt(time.Now().UnixNano()),
+		SignatureAlgorithm: AlgorithmIdentifier{Algorithm: algorithm.OID},
+		Issuer:             asn1.RawValue{FullBytes: issuerName},
+		Validity:           testValidity{NotBefore: time.Now().Add(-time.Hour).UTC(), NotAfter: time.Now().Add(time.Hour).UTC()},
+		Subject:            asn1.RawValue{FullBytes: subject},
+		PublicKey:          asn1.RawValue{FullBytes: publicKey},
+		Extensions:         extensions,
+	})
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	digest, error := calculateBytesDigest(algorithm.Hash, tbs)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	signature, error := issuerKey.SignDigest(digest, nil)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	raw, error := asn1.Marshal(testCertificate{
+		TBSCertificate:     asn1.RawValue{FullBytes: tbs},
+		SignatureAlgorithm: AlgorithmIdentifier{Algorithm: algorithm.OID},
+		Signature:          asn1.BitString{Bytes: signature, BitLength: 8 * len(signature)},
+	})
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	certificate, error := x509.ParseCertificate(raw)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	return privateKey, certificate
+}
+
+// сформировать расширение сертификата
+func newTestExtension(t *testing.T, oid asn1.ObjectIdentifier, value interface{}) pkix.Extension {
+	encoded, error := asn1.Marshal(value)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	return pkix.Extension{Id: oid, Value: encoded}
+}
+
+// сформировать корневой сертификат и квалифицированный сертификат подписанта
+func createTestQualifiedChain(t *testing.T) (*x509.Certificate, *PrivateKey, *x509.Certificate) {
+	rootKey, root := createTestIssuedCertificate(t, CurveTC26_512A, "Test Root", nil, nil, []pkix.Extension{
+		newTestExtension(t, asn1.ObjectIdentifier{2, 5, 29, 19}, struct{ IsCA bool }{true}),
+		// keyCertSign
+		newTestExtension(t, asn1.ObjectIdentifier{2, 5, 29, 15}, asn1.BitString{Bytes: []byte{0x04}, BitLength: 6}),
+	})
+
+	privateKey, certificate := createTestIssuedCertificate(t, CurveTC26_256A, "Qualified", rootKey, root, []pkix.Extension{
+		// digitalSignature и nonRepudiation
+		newTestExtension(t, asn1.ObjectIdentifier{2, 5, 29, 15}, asn1.BitString{Bytes: []byte{0xc0}, BitLength: 2}),
+		newTestExtension(t, asn1.ObjectIdentifier{2, 5, 29, 32}, []struct{ Policy asn1.ObjectIdentifier }{{testPolicyKC1}}),
+		newTestExtension(t, OIDSubjectSignTool, "Средство ЭП"),
+		newTestExtension(t, OIDIssuerSignTool, []string{"Средство ЭП", "Средство УЦ", "Сертификат СКЗИ", "Сертификат УЦ"}),
+	})
+
+	return root, privateKey, certificate
+}
+
+// найти результат правила
+func findTestRule(report *PolicyReport, rule PolicyRule) *RuleResult {
+	for i := range report.Rules {
+		if report.Rules[i].Rule == rule {
+			return &report.Rules[i]
+		}
+	}
+
+	return nil
+}
+
+// проверить CMS подпись с политикой
+func verifyTestPolicy(t *testing.T, policy ValidationPolicy, data []byte, content io.Reader) *VerifyReport {
+	release, verify, error := CreateCMSPolicyVerifyMethod(policy)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer release()
+
+	report, error := verify(bytes.NewReader(data), content)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	return report
+}
+
+func Test_CMSPolicyVerify_Success(t *testing.T) {
+	root, privateKey, certificate := createTestQualifiedChain(t)
+
+	data := signTestCMS(t, privateKey, certificate, true)
+
+	report := verifyTestPolicy(t, ValidationPolicy{
+		HashTypes:           []wrapper.HashType{wrapper.GOST3411_2012_256, wrapper.GOST3411_2012_512},
+		SignatureAlgorithms: []asn1.ObjectIdentifier{OIDGOST3410_2012_256, OIDGOST3410_2012_512},
+		Restrictions:        []AlgorithmRestriction{RestrictionGOST3410_2001},
+		Roots:               []*x509.Certificate{root},
+		KeyUsage:            x509.KeyUsageDigitalSignature | x509.KeyUsageContentCommitment,
+		CertificatePolicies: []asn1.ObjectIdentifier{testPolicyKC1},
+		Qualified:           true,
+	}, data, strings.NewReader("Hello world"))
+
+	policy := report.Signers[0].Policy
+
+	if policy == nil {
+		t.Fatal("Ожидался результат проверки политикой")
+	}
+
+	if !report.Valid() {
+		t.Fatalf("Ожидалось выполнение политики. Не выполнены правила %v", policy.Failed())
+	}
+
+	if len(policy.Rules) != 8 {
+		t.Errorf("Ожидалось 8 правил. Получено %d", len(policy.Rules))
+	}
+
+	if len(policy.Chain) != 2 || !policy.Chain[1].Equal(root) {
+		t.Error("Ожидалась цепочка до корневого сертификата")
+	}
+}
+
+func Test_CMSPolicyVerify_Failed(t *testing.T) {
+	otherRoot, _, _ := createTestQualifiedChain(t)
+	privateKey, certificate := createTestGOSTCertificate(t, CurveTC26_256A, "Unqualified")
+
+	data := signTestCMS(t, privateKey, certificate, true)
+
+	report := verifyTestPolicy(t, ValidationPolicy{
+		HashAlgorithms:      []asn1.ObjectIdentifier{OIDSha512},
+		SignatureAlgorithms: []asn1.ObjectIdentifier{OIDRSA},
+		Roots:               []*x509.Certificate{otherRoot},
+		ValidationTime:      time.Now().Add(24 * time.Hour),
+		KeyUsage:            x509.KeyUsageDigitalSignature,
+		CertificatePolicies: []asn1.ObjectIdentifier{testPolicyKC1},
+		Qualified:           true,
+		RequireTimestamp:    true,
+	}, data, strings.NewReader("Hello world"))
+
+	if report.Valid() {
+		t.Fatal("Ожидалось невыполнение политики")
+	}
+
+	signer := report.Signers[0]
+
+	if signer.Status != SignerValid {
+		t.Errorf("Ожидалась криптографически верная подпись. Получен статус %s", signer.Status)
+	}
+
+	for _, rule := range []PolicyRule{RuleHashAlgorithm, RuleSignatureAlgorithm, RuleCertificateValidity, RuleTrustedChain, RuleKeyUsage, RuleCertificatePolicy, RuleQualifiedCertificate, RuleTimestamp} {
+		result := findTestRule(signer.Policy, rule)
+
+		if result == nil || result.Passed || result.Exception == nil {
+			t.Errorf("Ожидалось невыполнение правила %s", rule)
+		}
+	}
+}
+
+func Test_PolicyChainConstraints_Rejected(t *testing.T) {
+	root, leafKey, leaf := createTestQualifiedChain(t)
+
+	// сертификат, выпущенный конечным сертификатом без признака УЦ
+	_, issuedByLeaf := createTestIssuedCertificate(t, CurveTC26_256A, "Issued by leaf", leafKey, leaf, nil)
+
+	release, check, error := CreatePolicyCheckMethod(ValidationPolicy{Roots: []*x509.Certificate{root}})
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer release()
+
+	report := check(PolicyInput{Certificate: issuedByLeaf, Certificates: []*x509.Certificate{leaf}})
+
+	if result := findTestRule(report, RuleTrustedChain); result == nil || result.Passed || result.Exception == nil {
+		t.Error("Ожидалась ошибка цепочки для сертификата, выпущенного не УЦ")
+	}
+
+	// корневой УЦ с pathLenConstraint 0 не может выпускать промежуточные УЦ
+	rootKey, limitedRoot := createTestIssuedCertificate(t, CurveTC26_512A, "Limited Root", nil, nil, []pkix.Extension{
+		newTestExtension(t, asn1.ObjectIdentifier{2, 5, 29, 19}, struct {
+			IsCA       bool
+			MaxPathLen int
+		}{true, 0}),
+	})
+	intermediateKey, intermediate := createTestIssuedCertificate(t, CurveTC26_512A, "Intermediate", rootKey, limitedRoot, []pkix.Extension{
+		newTestExtension(t, asn1.ObjectIdentifier{2, 5, 29, 19}, struct{ IsCA bool }{true}),
+	})
+	_, certificate := createTestIssuedCertificate(t, CurveTC26_256A, "Leaf", intermediateKey, intermediate, nil)
+
+	release, check, error = CreatePolicyCheckMethod(ValidationPolicy{Roots: []*x509.Certificate{limitedRoot}})
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer release()
+
+	if result := findTestRule(check(PolicyInput{Certificate: intermediate}), RuleTrustedChain); result == nil || !result.Passed {
+		t.Error("Ожидалась цепочка промежуточного УЦ до корневого")
+	}
+
+	if result := findTestRule(check(PolicyInput{Certificate: certificate, Certificates: []*x509.Certificate{intermediate}}), RuleTrustedChain); result == nil || result.Passed {
+		t.Error("Ожидалась ошибка превышения длины пути")
+	}
+}
+
+func Test_PolicyAlgorithmPeriod_Success(t *testing.T) {
+	release, check, error := CreatePolicyCheckMethod(ValidationPolicy{Restrictions: []AlgorithmRestriction{RestrictionGOST3410_2001}})
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer release()
+
+	_, certificate := createTestGOSTCertificate(t, CurveCryptoProA, "GOST 2001")
+
+	input := PolicyInput{
+		Certificate:        certificate,
+		DigestAlgorithm:    OIDGOST3411,
+		SignatureAlgorithm: OIDGOST3410_2001,
+		SigningTime:        time.Date(2018, time.June, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	// атрибут signing-time задается подписантом и по умолчанию не учитывается
+	if result := findTestRule(check(input), RuleAlgorithmPeriod); result == nil || result.Passed {
+		t.Error("Ожидалось, что время подписи без штампа берется из времени проверки")
+	}
+
+	releaseTrusted, checkTrusted, error := CreatePolicyCheckMethod(ValidationPolicy{Restrictions: []AlgorithmRestriction{RestrictionGOST3410_2001}, TrustSigningTime: true})
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer releaseTrusted()
+
+	if result := findTestRule(checkTrusted(input), RuleAlgorithmPeriod); result == nil || !result.Passed {
+		t.Error("Ожидалось, что подпись ГОСТ Р 34.10-2001 2018 года допускается")
+	}
+
+	input.SigningTime = time.Date(2020, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+	if result := findTestRule(checkTrusted(input), RuleAlgorithmPeriod); result == nil || result.Passed {
+		t.Error("Ожидалось, что подпись ГОСТ Р 34.10-2001 2020 года не допускается")
+	}
+
+	// время штампа имеет приоритет над атрибутом signing-time
+	_, tsaCertificate := createTestRSACertificate(t, "Test TSA")
+	input.TimestampTime = time.Date(2018, time.December, 31, 0, 0, 0, 0, time.UTC)
+	input.TimestampCertificate = tsaCertificate
+
+	if result := findTestRule(checkTrusted(input), RuleAlgorithmPeriod); result == nil || !result.Passed {
+		t.Error("Ожидалось, что время подписи берется из штампа времени")
+	}
+
+	// штамп службы без расширения timeStamping не учитывается
+	input.TimestampCertificate = certificate
+
+	if result := findTestRule(checkTrusted(input), RuleAlgorithmPeriod); result == nil || result.Passed {
+		t.Error("Ожидалось, что штамп службы без расширения timeStamping не учитывается")
+	}
+
+	if _, _, error := CreatePolicyCheckMethod(ValidationPolicy{HashTypes: []wrapper.HashType{1}}); error == nil {
+		t.Error("Ожидалась ошибка для неизвестного типа хэша")
+	}
+}
+
+func Test_CMSPolicyVerify_Timestamp(t *testing.T) {
+	tsaKey, tsaCertificate := createTestRSACertificate(t, "Test TSA")
+	tsa := startTestTSA(t, tspGranted, tsaKey, tsaCertificate)
+	defer tsa.Close()
+
+	releaseTimestamp, timestamp, error := CreateTimestampMethod(tsa.URL, HashGOST3411_2012_256)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer releaseTimestamp()
+
+	privateKey, certificate := createTestGOSTCertificate(t, CurveTC26_256A, "CAdES-T")
+
+	release, sign, error := CreateCAdESTSignMethod(privateKey, certificate, false, timestamp, SignOptions{})
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer release()
+
+	signature, error := sign(strings.NewReader("Hello world"))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	data, error := io.ReadAll(signature)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	report := verifyTestPolicy(t, ValidationPolicy{Roots: []*x509.Certificate{tsaCertificate, certificate}, RequireTimestamp: true}, data, nil)
+
+	if !report.Valid() {
+		t.Fatalf("Ожидалось выполнение политики. Не выполнены правила %v", report.Signers[0].Policy.Failed())
+	}
+
+	// служба штампов времени не входит в доверенные
+	report = verifyTestPolicy(t, ValidationPolicy{Roots: []*x509.Certificate{certificate}, RequireTimestamp: true}, data, nil)
+
+	if result := findTestRule(report.Signers[0].Policy, RuleTimestamp); result == nil || result.Passed {
+		t.Error("Ожидалось, что штамп недоверенной службы не учитывается")
+	}
+}
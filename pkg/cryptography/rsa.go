@@ -0,0 +1,156 @@
+package cryptography
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"io"
+)
+
+/*
+Параметры RSASSA-PSS (RFC 4055), алгоритмы хэширования SHA-1 по умолчанию не поддерживаются
+*/
+type rsaPSSParameters struct {
+	Hash         AlgorithmIdentifier `asn1:"explicit,tag:0"`
+	MGF          AlgorithmIdentifier `asn1:"explicit,tag:1"`
+	SaltLength   int                 `asn1:"optional,explicit,tag:2,default:20"`
+	TrailerField int                 `asn1:"optional,explicit,tag:3,default:1"`
+}
+
+// закодировать параметры RSASSA-PSS: MGF1 с тем же алгоритмом хэширования, длина соли равна размеру хэша
+func marshalRSAPSSParameters(hash *HashAlgorithm) ([]byte, error) {
+	hashAlgorithm := AlgorithmIdentifier{Algorithm: hash.OID, Parameters: asn1Null}
+	encodedHash, exception := asn1.Marshal(hashAlgorithm)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	return asn1.Marshal(rsaPSSParameters{
+		Hash:         hashAlgorithm,
+		MGF:          AlgorithmIdentifier{Algorithm: OIDMGF1, Parameters: asn1.RawValue{FullBytes: encodedHash}},
+		SaltLength:   hash.Size,
+		TrailerField: 1,
+	})
+}
+
+// получить алгоритм хэширования из параметров RSASSA-PSS
+func parseRSAPSSHash(parameters []byte) (*HashAlgorithm, error) {
+	var value rsaPSSParameters
+
+	if _, exception := asn1.Unmarshal(parameters, &value); exception != nil {
+		return nil, exception
+	}
+
+	return FindHashAlgorithm(value.Hash.Algorithm)
+}
+
+// проверить параметры RSASSA-PSS подписи с алгоритмом хэширования hash и получить длину соли
+// хэш сообщения и MGF1 должны использовать hash, trailerField только 1
+func parseRSAPSSParameters(parameters []byte, hash *HashAlgorithm) (int, error) {
+	var value rsaPSSParameters
+
+	if rest, exception := asn1.Unmarshal(parameters, &value); exception != nil {
+		return 0, exception
+	} else if len(rest) > 0 {
+		return 0, errors.New("Лишние данные после параметров RSASSA-PSS")
+	}
+
+	if !value.Hash.Algorithm.Equal(hash.OID) {
+		return 0, errors.New("Алгоритм хэширования RSASSA-PSS " + value.Hash.Algorithm.String() + " не совпадает с алгоритмом хэширования подписи")
+	}
+
+	if !value.MGF.Algorithm.Equal(OIDMGF1) {
+		return 0, errors.New("Не поддерживается функция генерации маски " + value.MGF.Algorithm.String())
+	}
+
+	var mgfHash AlgorithmIdentifier
+
+	if _, exception := asn1.Unmarshal(value.MGF.Parameters.FullBytes, &mgfHash); exception != nil {
+		return 0, exception
+	}
+
+	if !mgfHash.Algorithm.Equal(hash.OID) {
+		return 0, errors.New("Алгоритм хэширования MGF1 " + mgfHash.Algorithm.String() + " не совпадает с алгоритмом хэширования подписи")
+	}
+
+	if value.TrailerField != 1 {
+		return 0, errors.New("Не поддерживается trailerField RSASSA-PSS")
+	}
+
+	// нулевая длина соли в rsa.PSSOptions означает автоопределение
+	if value.SaltLength <= 0 {
+		return 0, errors.New("Не поддерживается длина соли RSASSA-PSS")
+	}
+
+	return value.SaltLength, nil
+}
+
+// прочитать закрытый ключ RSA из PEM или DER: PKCS#1 (RSA PRIVATE KEY) или PKCS#8 (PRIVATE KEY)
+func ParseRSAPrivateKey(key io.Reader) (*rsa.PrivateKey, error) {
+	data, exception := io.ReadAll(key)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	if block, _ := pem.Decode(data); block != nil {
+		switch block.Type {
+		case "RSA PRIVATE KEY":
+			return x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "PRIVATE KEY":
+			data = block.Bytes
+		default:
+			return nil, errors.New("Не поддерживается тип ключа PEM " + block.Type)
+		}
+	} else if privateKey, exception := x509.ParsePKCS1PrivateKey(data); exception == nil {
+		return privateKey, nil
+	}
+
+	privateKey, exception := x509.ParsePKCS8PrivateKey(data)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	rsaPrivateKey, ok := privateKey.(*rsa.PrivateKey)
+
+	if !ok {
+		return nil, errors.New("Ключ PKCS#8 не является ключом RSA")
+	}
+
+	return rsaPrivateKey, nil
+}
+
+// прочитать открытый ключ RSA из PEM или DER: SubjectPublicKeyInfo (PUBLIC KEY) или PKCS#1 (RSA PUBLIC KEY)
+func ParseRSAPublicKey(key io.Reader) (*rsa.PublicKey, error) {
+	data, exception := io.ReadAll(key)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	if block, _ := pem.Decode(data); block != nil {
+		if block.Type == "RSA PUBLIC KEY" {
+			return x509.ParsePKCS1PublicKey(block.Bytes)
+		}
+
+		data = block.Bytes
+	}
+
+	publicKey, exception := x509.ParsePKIXPublicKey(data)
+
+	if exception != nil {
+		return x509.ParsePKCS1PublicKey(data)
+	}
+
+	rsaPublicKey, ok := publicKey.(*rsa.PublicKey)
+
+	if !ok {
+		return nil, errors.New("Открытый ключ не является ключом RSA")
+	}
+
+	return rsaPublicKey, nil
+}
@@ -0,0 +1,224 @@
+package cryptography
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"strings"
+	"testing"
+)
+
+func Test_ParseRSAPrivateKey_Success(t *testing.T) {
+	privateKey, _ := createTestRSACertificate(t, "RSA")
+
+	pkcs8, error := x509.MarshalPKCS8PrivateKey(privateKey)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	pkcs1 := x509.MarshalPKCS1PrivateKey(privateKey)
+
+	keys := map[string][]byte{
+		"PKCS#1 PEM": pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: pkcs1}),
+		"PKCS#8 PEM": pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}),
+		"PKCS#1 DER": pkcs1,
+		"PKCS#8 DER": pkcs8,
+	}
+
+	for name, data := range keys {
+		parsed, error := ParseRSAPrivateKey(bytes.NewReader(data))
+
+		if error != nil {
+			t.Fatalf("%s: %v", name, error)
+		}
+
+		if !parsed.Equal(privateKey) {
+			t.Errorf("%s: ожидался исходный ключ", name)
+		}
+	}
+
+	if _, error := ParseRSAPrivateKey(strings.NewReader("-----BEGIN CERTIFICATE-----\nAAAA\n-----END CERTIFICATE-----\n")); error == nil {
+		t.Error("Ожидалась ошибка для сертификата вместо ключа")
+	}
+}
+
+func Test_ParseRSAPublicKey_Success(t *testing.T) {
+	privateKey, _ := createTestRSACertificate(t, "RSA")
+
+	pkix, error := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	for _, block := range []*pem.Block{
+		{Type: "PUBLIC KEY", Bytes: pkix},
+		{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&privateKey.PublicKey)},
+	} {
+		parsed, error := ParseRSAPublicKey(bytes.NewReader(pem.EncodeToMemory(block)))
+
+		if error != nil {
+			t.Fatalf("%s: %v", block.Type, error)
+		}
+
+		if !parsed.Equal(&privateKey.PublicKey) {
+			t.Errorf("%s: ожидался исходный ключ", block.Type)
+		}
+	}
+}
+
+func Test_RSAPSSCMS_Success(t *testing.T) {
+	privateKey, certificate := createTestRSACertificate(t, "RSA-PSS")
+
+	signedData, error := signContent(strings.NewReader("Hello world"), &signParameters{
+		signer:      privateKey,
+		certificate: certificate,
+		algorithm:   SignatureRSAPSSSha512,
+		detached:    true,
+	})
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	var parameters rsaPSSParameters
+
+	if _, error := asn1.Unmarshal(signedData.SignerInfos[0].SignatureAlgorithm.Parameters.FullBytes, &parameters); error != nil {
+		t.Fatal(error)
+	}
+
+	if !parameters.Hash.Algorithm.Equal(OIDSha512) || parameters.SaltLength != 64 {
+		t.Errorf("Неверные параметры RSASSA-PSS: %v, соль %d", parameters.Hash.Algorithm, parameters.SaltLength)
+	}
+
+	report, error := verifySignedData(signedData, strings.NewReader("Hello world"))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if !report.Valid() {
+		t.Fatalf("Ожидалась верная подпись. Получен статус %s: %v", report.Signers[0].Status, report.Signers[0].Exception)
+	}
+}
+
+func Test_CAdESSignOptions_Success(t *testing.T) {
+	privateKey, certificate := createTestRSACertificate(t, "RSA-PSS")
+
+	release, sign, error := CreateCAdESBESSignMethod(privateKey, certificate, true, SignOptions{Algorithm: SignatureRSAPSSSha384})
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer release()
+
+	signature, error := sign(strings.NewReader("Hello world"))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	signedData, error := readSignedData(signature)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	report, error := verifySignedData(signedData, strings.NewReader("Hello world"))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if !report.Valid() {
+		t.Fatalf("Ожидалась верная подпись. Получен статус %s: %v", report.Signers[0].Status, report.Signers[0].Exception)
+	}
+
+	if !report.Signers[0].SignatureAlgorithm.Equal(OIDRSAPSS) || !report.Signers[0].DigestAlgorithm.Equal(OIDSha384) {
+		t.Errorf("Ожидался алгоритм RSA-PSS-SHA384. Получен %v, %v", report.Signers[0].SignatureAlgorithm, report.Signers[0].DigestAlgorithm)
+	}
+}
+
+func Test_CAdESSignOptions_Rejected(t *testing.T) {
+	privateKey, certificate := createTestGOSTCertificate(t, CurveTC26_256A, "Options")
+
+	if _, _, error := CreateCAdESBESSignMethod(privateKey, certificate, true, SignOptions{Algorithm: SignatureRSAPSSSha384}); error == nil {
+		t.Error("Ожидалась ошибка для алгоритма RSA с ключом ГОСТ")
+	}
+
+	if _, _, error := CreateCMSCosignMethod(privateKey, certificate, SignOptions{Algorithm: SignatureGOST3410_2012_512}); error == nil {
+		t.Error("Ожидалась ошибка для алгоритма ГОСТ 512 бит с ключом 256 бит")
+	}
+}
+
+func Test_RSAPSSCMSParameters_Rejected(t *testing.T) {
+	privateKey, certificate := createTestRSACertificate(t, "RSA-PSS")
+
+	encodedHash := func(hash *HashAlgorithm) []byte {
+		encoded, error := asn1.Marshal(AlgorithmIdentifier{Algorithm: hash.OID, Parameters: asn1Null})
+
+		if error != nil {
+			t.Fatal(error)
+		}
+
+		return encoded
+	}
+
+	cases := map[string]struct {
+		parameters rsaPSSParameters
+		status     SignerStatus
+	}{
+		"хэш": {rsaPSSParameters{
+			Hash:         AlgorithmIdentifier{Algorithm: OIDSha256, Parameters: asn1Null},
+			MGF:          AlgorithmIdentifier{Algorithm: OIDMGF1, Parameters: asn1.RawValue{FullBytes: encodedHash(HashSha512)}},
+			SaltLength:   64,
+			TrailerField: 1,
+		}, SignerMalformed},
+		"MGF1": {rsaPSSParameters{
+			Hash:         AlgorithmIdentifier{Algorithm: OIDSha512, Parameters: asn1Null},
+			MGF:          AlgorithmIdentifier{Algorithm: OIDMGF1, Parameters: asn1.RawValue{FullBytes: encodedHash(HashSha256)}},
+			SaltLength:   64,
+			TrailerField: 1,
+		}, SignerMalformed},
+		"соль": {rsaPSSParameters{
+			Hash:         AlgorithmIdentifier{Algorithm: OIDSha512, Parameters: asn1Null},
+			MGF:          AlgorithmIdentifier{Algorithm: OIDMGF1, Parameters: asn1.RawValue{FullBytes: encodedHash(HashSha512)}},
+			SaltLength:   32,
+			TrailerField: 1,
+		}, SignerSignatureInvalid},
+	}
+
+	for name, value := range cases {
+		signedData, error := signContent(strings.NewReader("Hello world"), &signParameters{
+			signer:      privateKey,
+			certificate: certificate,
+			algorithm:   SignatureRSAPSSSha512,
+			detached:    true,
+		})
+
+		if error != nil {
+			t.Fatal(error)
+		}
+
+		parameters, error := asn1.Marshal(value.parameters)
+
+		if error != nil {
+			t.Fatal(error)
+		}
+
+		signedData.SignerInfos[0].SignatureAlgorithm.Parameters = asn1.RawValue{FullBytes: parameters}
+
+		report, error := verifySignedData(signedData, strings.NewReader("Hello world"))
+
+		if error != nil {
+			t.Fatal(error)
+		}
+
+		if report.Signers[0].Status != value.status {
+			t.Errorf("%s: ожидался статус %s. Получен %s: %v", name, value.status, report.Signers[0].Status, report.Signers[0].Exception)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package cryptography
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"io"
+	"time"
+)
+
+// NULL параметры алгоритма
+var asn1Null = asn1.RawValue{FullBytes: []byte{asn1.TagNull, 0}}
+
+/*
+Параметры формирования подписи CMS
+*/
+type signParameters struct {
+	signer      crypto.Signer
+	certificate *x509.Certificate
+	algorithm   *SignatureAlgorithm
+	// тип подписываемого содержимого, по умолчанию id-data
+	contentType asn1.ObjectIdentifier
+	detached    bool
+	// дополнительные подписанные атрибуты
+	attributes func(algorithm *SignatureAlgorithm, certificate *x509.Certificate) ([]Attribute, error)
+	// время подписи, по умолчанию текущее
+	signingTime time.Time
+	// не добавлять атрибут signing-time, PAdES требует указывать время в словаре подписи
+	withoutSigningTime bool
+	// не добавлять атрибут content-type, он запрещен в заверяющей подписи
+	withoutContentType bool
+}
+
+/*
+Параметры формирования CMS подписи
+*/
+type SignOptions struct {
+	// алгоритм подписи, по умолчанию определяется по сертификату
+	Algorithm *SignatureAlgorithm
+}
+
+// получить алгоритм подписи из параметров, заданный алгоритм должен подходить для ключа сертификата
+func findSignOptionsAlgorithm(certificate *x509.Certificate, options SignOptions) (*SignatureAlgorithm, error) {
+	if options.Algorithm == nil {
+		return FindCertificateSignatureAlgorithm(certificate)
+	}
+
+	publicKey, exception := CertificatePublicKey(certificate)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	if exception := checkSignatureKey(publicKey, options.Algorithm); exception != nil {
+		return nil, exception
+	}
+
+	return options.Algorithm, nil
+}
+
+// получить метод формирования CMS подписи
+func CreateCMSSignMethod(signer crypto.Signer, certificate *x509.Certificate, detached bool, options SignOptions) (release func(), sign func(io.Reader) (io.Reader, error), exception error) {
+	algorithm, exception := findSignOptionsAlgorithm(certificate, options)
+
+	if exception != nil {
+		return nil, nil, exception
+	}
+
+	parameters := &signParameters{
+		signer:      signer,
+		certificate: certificate,
+		algorithm:   algorithm,
+		detached:    detached,
+	}
+
+	return func() {},
+		func(content io.Reader) (io.Reader, error) {
+			signedData, exception := signContent(content, parameters)
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			return marshalSignedData(signedData)
+		}, nil
+}
+
+// сформировать SignedData с одним подписантом
+func signContent(content io.Reader, parameters *signParameters) (*SignedData, error) {
+	contentType := parameters.contentType
+
+	if contentType == nil {
+		contentType = OIDData
+	}
+
+	// для присоединенной подписи содержимое сохраняется при вычислении хэша
+	var buffer bytes.Buffer
+
+	if !parameters.detached {
+		content = io.TeeReader(content, &buffer)
+	}
+
+	digest, exception := calculateDigest(parameters.algorithm.Hash, content)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	signerInfo, exception := createSignerInfo(digest, contentType, parameters)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	signedData := &SignedData{
+		Version:          1,
+		DigestAlgorithms: []AlgorithmIdentifier{digestAlgorithmIdentifier(parameters.algorithm.Hash)},
+		EncapContentInfo: EncapsulatedContentInfo{EContentType: contentType},
+		SignerInfos:      []SignerInfo{*signerInfo},
+	}
+
+	if !parameters.detached {
+		signedData.EncapContentInfo.EContent = buffer.Bytes()
+
+		// пустое содержимое должно присутствовать в присоединенной подписи
+		if signedData.EncapContentInfo.EContent == nil {
+			signedData.EncapContentInfo.EContent = []byte{}
+		}
+	}
+
+	if !contentType.Equal(OIDData) {
+		signedData.Version = 3
+	}
+
+	signedData.Certificates = marshalCertificates([]*x509.Certificate{parameters.certificate})
+
+	return signedData, nil
+}
+
+// сформировать SignerInfo по хэшу содержимого
+func createSignerInfo(digest []byte, contentType asn1.ObjectIdentifier, parameters *signParameters) (*SignerInfo, error) {
+	algorithm := parameters.algorithm
+	signingTime := parameters.signingTime
+
+	if signingTime.IsZero() {
+		signingTime = time.Now()
+	}
+
+	attributes := []Attribute{}
+
+	values := []struct {
+		oid   asn1.ObjectIdentifier
+		value interface{}
+	}{
+		{OIDAttributeContentType, contentType},
+		{OIDAttributeSigningTime, signingTime.UTC()},
+		{OIDAttributeMessageDigest, digest},
+	}
+
+	for _, value := range values {
+		if value.oid.Equal(OIDAttributeContentType) && parameters.withoutContentType ||
+			value.oid.Equal(OIDAttributeSigningTime) && parameters.withoutSigningTime {
+			continue
+		}
+
+		attribute, exception := newAttribute(value.oid, value.value)
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		attributes = append(attributes, attribute)
+	}
+
+	if parameters.attributes != nil {
+		extra, exception := parameters.attributes(algorithm, parameters.certificate)
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		attributes = append(attributes, extra...)
+	}
+
+	encodedAttributes, exception := asn1.MarshalWithParams(attributes, "set")
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	attributesDigest, exception := calculateBytesDigest(algorithm.Hash, encodedAttributes)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	signature, exception := signDigest(parameters.signer, algorithm, attributesDigest)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	sid, exception := marshalIssuerAndSerialNumber(parameters.certificate)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	signatureAlgorithm, exception := signatureAlgorithmIdentifier(algorithm)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	// в SignerInfo атрибуты кодируются как [0] IMPLICIT SET OF
+	encodedAttributes[0] = 0xa0
+
+	return &SignerInfo{
+		Version:            1,
+		SID:                asn1.RawValue{FullBytes: sid},
+		DigestAlgorithm:    digestAlgorithmIdentifier(algorithm.Hash),
+		SignedAttributes:   asn1.RawValue{FullBytes: encodedAttributes},
+		SignatureAlgorithm: signatureAlgorithm,
+		Signature:          signature,
+	}, nil
+}
+
+// создать атрибут с одним значением
+func newAttribute(oid asn1.ObjectIdentifier, value interface{}) (Attribute, error) {
+	encoded, exception := asn1.Marshal(value)
+
+	if exception != nil {
+		return Attribute{}, exception
+	}
+
+	return Attribute{Type: oid, Values: []asn1.RawValue{{FullBytes: encoded}}}, nil
+}
+
+// идентификатор алгоритма хэширования для CMS
+func digestAlgorithmIdentifier(algorithm *HashAlgorithm) AlgorithmIdentifier {
+	return AlgorithmIdentifier{Algorithm: algorithm.OID}
+}
+
+// идентификатор алгоритма подписи для SignerInfo
+func signatureAlgorithmIdentifier(algorithm *SignatureAlgorithm) (AlgorithmIdentifier, error) {
+	if algorithm.OID.Equal(OIDRSAPSS) {
+		parameters, exception := marshalRSAPSSParameters(algorithm.Hash)
+
+		if exception != nil {
+			return AlgorithmIdentifier{}, exception
+		}
+
+		return AlgorithmIdentifier{Algorithm: OIDRSAPSS, Parameters: asn1.RawValue{FullBytes: parameters}}, nil
+	}
+
+	signatureAlgorithm := AlgorithmIdentifier{Algorithm: algorithm.PublicKeyOID}
+
+	if algorithm.PublicKeyOID.Equal(OIDRSA) {
+		signatureAlgorithm.Parameters = asn1Null
+	}
+
+	return signatureAlgorithm, nil
+}
+
+// сформировать IssuerAndSerialNumber сертификата
+func marshalIssuerAndSerialNumber(certificate *x509.Certificate) ([]byte, error) {
+	return asn1.Marshal(IssuerAndSerialNumber{
+		Issuer:       asn1.RawValue{FullBytes: certificate.RawIssuer},
+		SerialNumber: certificate.SerialNumber,
+	})
+}
+
+// сформировать набор сертификатов [0] IMPLICIT CertificateSet
+func marshalCertificates(certificates []*x509.Certificate) asn1.RawValue {
+	var content []byte
+
+	for _, certificate := range certificates {
+		content = append(content, certificate.Raw...)
+	}
+
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: content}
+}
+
+// закодировать SignedData в ContentInfo
+func marshalSignedData(signedData *SignedData) (io.Reader, error) {
+	if len(signedData.SignerInfos) == 0 {
+		return nil, errors.New("В подписи нет подписантов")
+	}
+
+	content, exception := asn1.Marshal(*signedData)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	data, exception := asn1.Marshal(ContentInfo{
+		ContentType: OIDSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: content},
+	})
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	return bytes.NewReader(data), nil
+}
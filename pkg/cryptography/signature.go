@@ -0,0 +1,79 @@
+package cryptography
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"errors"
+	"io"
+)
+
+// получить метод простой подписи данных без CMS
+// хэш вычисляется фабрикой алгоритма хэширования, подпись ГОСТ возвращается как s||r, RSA - по PKCS#1 v1.5 или PSS
+func CreateSignMethod(signer crypto.Signer, algorithm *SignatureAlgorithm) (release func(), sign func(io.Reader) (io.Reader, error), exception error) {
+	if exception := checkSignatureKey(signer.Public(), algorithm); exception != nil {
+		return nil, nil, exception
+	}
+
+	return func() {},
+		func(content io.Reader) (io.Reader, error) {
+			digest, exception := calculateDigest(algorithm.Hash, content)
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			signature, exception := signDigest(signer, algorithm, digest)
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			return bytes.NewReader(signature), nil
+		}, nil
+}
+
+// получить метод проверки простой подписи данных
+// publicKey - *PublicKey для ГОСТ Р 34.10 или *rsa.PublicKey, из сертификата ключ получается функцией CertificatePublicKey
+func CreateVerifyMethod(publicKey crypto.PublicKey, algorithm *SignatureAlgorithm) (release func(), verify func(signature io.Reader, content io.Reader) error, exception error) {
+	if exception := checkSignatureKey(publicKey, algorithm); exception != nil {
+		return nil, nil, exception
+	}
+
+	return func() {},
+		func(signature io.Reader, content io.Reader) error {
+			value, exception := io.ReadAll(signature)
+
+			if exception != nil {
+				return exception
+			}
+
+			digest, exception := calculateDigest(algorithm.Hash, content)
+
+			if exception != nil {
+				return exception
+			}
+
+			return verifyDigest(publicKey, algorithm, digest, value)
+		}, nil
+}
+
+// проверить, что ключ подходит для алгоритма подписи
+func checkSignatureKey(publicKey crypto.PublicKey, algorithm *SignatureAlgorithm) error {
+	if algorithm == nil {
+		return errors.New("Не задан алгоритм подписи")
+	}
+
+	switch publicKey := publicKey.(type) {
+	case *PublicKey:
+		if !algorithm.PublicKeyOID.Equal(OIDRSA) && algorithm.Hash.Size == publicKey.Curve.PointSize {
+			return nil
+		}
+	case *rsa.PublicKey:
+		if algorithm.PublicKeyOID.Equal(OIDRSA) {
+			return nil
+		}
+	}
+
+	return errors.New("Ключ не подходит для алгоритма подписи " + algorithm.Name)
+}
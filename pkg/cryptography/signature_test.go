@@ -0,0 +1,126 @@
+package cryptography
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+)
+
+// подписать и проверить данные простой подписью
+func signAndVerifyTestContent(t *testing.T, signer crypto.Signer, publicKey crypto.PublicKey, algorithm *SignatureAlgorithm) {
+	release, sign, error := CreateSignMethod(signer, algorithm)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer release()
+
+	signature, error := sign(strings.NewReader("Hello world"))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	value := new(bytes.Buffer)
+
+	if _, error := value.ReadFrom(signature); error != nil {
+		t.Fatal(error)
+	}
+
+	releaseVerify, verify, error := CreateVerifyMethod(publicKey, algorithm)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer releaseVerify()
+
+	if error := verify(bytes.NewReader(value.Bytes()), strings.NewReader("Hello world")); error != nil {
+		t.Errorf("Ожидалась верная подпись %s. Получена ошибка %v", algorithm.Name, error)
+	}
+
+	if error := verify(bytes.NewReader(value.Bytes()), strings.NewReader("Hello WORLD")); error == nil {
+		t.Errorf("Ожидалась неверная подпись %s измененных данных", algorithm.Name)
+	}
+}
+
+func Test_RSASignMethod_Success(t *testing.T) {
+	privateKey, error := rsa.GenerateKey(rand.Reader, 2048)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	for _, name := range []string{"RSA-SHA256", "RSA-SHA384", "RSA-SHA512", "RSA-PSS-SHA256", "RSA-PSS-SHA384", "RSA-PSS-SHA512"} {
+		algorithm, error := FindSignatureAlgorithmByName(name)
+
+		if error != nil {
+			t.Fatal(error)
+		}
+
+		signAndVerifyTestContent(t, privateKey, &privateKey.PublicKey, algorithm)
+	}
+}
+
+func Test_GOSTSignMethod_Success(t *testing.T) {
+	privateKey, certificate := createTestGOSTCertificate(t, CurveTC26_512A, "GOST")
+
+	publicKey, error := CertificatePublicKey(certificate)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	signAndVerifyTestContent(t, privateKey, publicKey, SignatureGOST3410_2012_512)
+}
+
+func Test_SignMethod_KeyMismatch(t *testing.T) {
+	privateKey, _ := createTestGOSTCertificate(t, CurveCryptoProA, "GOST")
+
+	if _, _, error := CreateSignMethod(privateKey, SignatureRSASha256); error == nil {
+		t.Error("Ожидалась ошибка для ключа ГОСТ и алгоритма RSA")
+	}
+
+	if _, _, error := CreateSignMethod(privateKey, SignatureGOST3410_2012_512); error == nil {
+		t.Error("Ожидалась ошибка для ключа 256 бит и алгоритма ГОСТ Р 34.10-2012-512")
+	}
+}
+
+func Test_ContainerSignerSign_Rejected(t *testing.T) {
+	// проверки выполняются до обращения к провайдеру
+	signer := &ContainerSigner{hash: HashGOST3411_2012_256}
+
+	for name, example := range map[string]struct {
+		digest  []byte
+		options crypto.SignerOpts
+	}{
+		"длина":           {make([]byte, 64), nil},
+		"пустой хэш":      {nil, nil},
+		"алгоритм ГОСТ":   {make([]byte, 32), &SignerOptions{Hash: HashGOST3411_2012_512}},
+		"алгоритм crypto": {make([]byte, 32), crypto.SHA256},
+	} {
+		if _, error := signer.Sign(rand.Reader, example.digest, example.options); error == nil {
+			t.Errorf("%s: ожидалась ошибка подписи хэша другого алгоритма", name)
+		}
+	}
+}
+
+func Test_FindSignatureAlgorithm_RSAPSS(t *testing.T) {
+	algorithm, error := FindSignatureAlgorithm(OIDRSAPSS, HashSha384)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if algorithm != SignatureRSAPSSSha384 {
+		t.Errorf("Ожидался алгоритм RSA-PSS-SHA384. Получен %s", algorithm.Name)
+	}
+
+	if algorithm, _ := FindSignatureAlgorithm(OIDRSA, HashSha512); algorithm != SignatureRSASha512 {
+		t.Error("Ожидался алгоритм RSA-SHA512 для ключа RSA и SHA-512")
+	}
+}
@@ -0,0 +1,193 @@
+package cryptography
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"io"
+
+	"github.com/madpo/go-gost-crypto/pkg/wrapper"
+)
+
+/*
+Подписант на ключе из контейнера КриптоПро
+*/
+type ContainerSigner struct {
+	provider    *wrapper.CryptoProvider
+	key         *wrapper.CryptoKey
+	keySpec     wrapper.KeySpec
+	certificate *x509.Certificate
+	publicKey   *PublicKey
+	hash        *HashAlgorithm
+}
+
+// открыть ключевой контейнер для подписи
+func CreateContainerSigner(cspType wrapper.CSPType, container string, pin string) (release func(), signer *ContainerSigner, exception error) {
+	cryptoProvider, exception := wrapper.TakeContainer(cspType, container)
+
+	if exception != nil {
+		return nil, nil, exception
+	}
+
+	signer = &ContainerSigner{provider: cryptoProvider, keySpec: wrapper.KeyExchange}
+
+	release = func() {
+		wrapper.ReleaseKey(signer.key)
+		wrapper.ReleaseCSP(cryptoProvider)
+	}
+
+	signer.key, exception = wrapper.TakeUserKey(cryptoProvider, signer.keySpec)
+
+	if exception != nil {
+		signer.keySpec = wrapper.Signature
+		signer.key, exception = wrapper.TakeUserKey(cryptoProvider, signer.keySpec)
+	}
+
+	if exception != nil {
+		release()
+		return nil, nil, exception
+	}
+
+	if pin != "" {
+		if exception := wrapper.SetContainerPin(cryptoProvider, signer.keySpec, pin); exception != nil {
+			release()
+			return nil, nil, exception
+		}
+	}
+
+	rawCertificate, exception := wrapper.GetKeyCertificate(signer.key)
+
+	if exception != nil {
+		release()
+		return nil, nil, exception
+	}
+
+	signer.certificate, exception = x509.ParseCertificate(*rawCertificate)
+
+	if exception != nil {
+		release()
+		return nil, nil, exception
+	}
+
+	signer.publicKey, exception = ParsePublicKey(signer.certificate.RawSubjectPublicKeyInfo)
+
+	if exception != nil {
+		release()
+		return nil, nil, exception
+	}
+
+	algorithm, exception := FindCertificateSignatureAlgorithm(signer.certificate)
+
+	if exception != nil {
+		release()
+		return nil, nil, exception
+	}
+
+	signer.hash = algorithm.Hash
+
+	return release, signer, nil
+}
+
+// сертификат ключа из контейнера
+func (signer *ContainerSigner) Certificate() *x509.Certificate {
+	return signer.certificate
+}
+
+// открытый ключ, реализация crypto.Signer
+func (signer *ContainerSigner) Public() crypto.PublicKey {
+	return signer.publicKey
+}
+
+// подписать хэш, реализация crypto.Signer
+// хэш передается в порядке байт КриптоПро, подпись возвращается как s||r (big-endian)
+func (signer *ContainerSigner) Sign(random io.Reader, digest []byte, options crypto.SignerOpts) ([]byte, error) {
+	if len(digest) != signer.hash.Size {
+		return nil, errors.New("Размер хэша не соответствует алгоритму хэширования ключа " + signer.hash.Name)
+	}
+
+	if gostOptions, ok := options.(*SignerOptions); ok && gostOptions.Hash != nil && gostOptions.Hash != signer.hash {
+		return nil, errors.New("Алгоритм хэширования " + gostOptions.Hash.Name + " не соответствует ключу контейнера")
+	}
+
+	if options != nil && options.HashFunc() != 0 {
+		return nil, errors.New("Ключ контейнера подписывает только хэш " + signer.hash.Name)
+	}
+
+	hashMethod, exception := wrapper.TakeHashMethod(signer.provider, signer.hash.HashType)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	defer wrapper.ReleaseHashMethod(hashMethod)
+
+	if exception := wrapper.SetHashValue(hashMethod, &digest); exception != nil {
+		return nil, exception
+	}
+
+	signature, exception := wrapper.SignHash(hashMethod, signer.keySpec)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	return reverseBytes(*signature), nil
+}
+
+// определить алгоритм подписи по открытому ключу сертификата
+func FindCertificateSignatureAlgorithm(certificate *x509.Certificate) (*SignatureAlgorithm, error) {
+	var info subjectPublicKeyInfo
+
+	if _, exception := asn1.Unmarshal(certificate.RawSubjectPublicKeyInfo, &info); exception != nil {
+		return nil, exception
+	}
+
+	switch {
+	case info.Algorithm.Algorithm.Equal(OIDGOST3410_2001):
+		return SignatureGOST3410_2001, nil
+	case info.Algorithm.Algorithm.Equal(OIDGOST3410_2012_256):
+		return SignatureGOST3410_2012_256, nil
+	case info.Algorithm.Algorithm.Equal(OIDGOST3410_2012_512):
+		return SignatureGOST3410_2012_512, nil
+	case info.Algorithm.Algorithm.Equal(OIDRSA):
+		return SignatureRSASha256, nil
+	}
+
+	return nil, errors.New("Не поддерживается алгоритм открытого ключа " + info.Algorithm.Algorithm.String())
+}
+
+// подписать хэш подписантом в формате, принятом для алгоритма
+func signDigest(signer crypto.Signer, algorithm *SignatureAlgorithm, digest []byte) ([]byte, error) {
+	if algorithm.PublicKeyOID.Equal(OIDRSA) {
+		if _, ok := signer.Public().(*rsa.PublicKey); !ok {
+			return nil, errors.New("Ключ подписанта не является ключом RSA")
+		}
+
+		if algorithm.OID.Equal(OIDRSAPSS) {
+			return signer.Sign(rand.Reader, digest, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: cryptoHash(algorithm.Hash)})
+		}
+
+		return signer.Sign(rand.Reader, digest, cryptoHash(algorithm.Hash))
+	}
+
+	return signer.Sign(rand.Reader, digest, &SignerOptions{Hash: algorithm.Hash})
+}
+
+// хэш стандартной библиотеки для алгоритма из реестра
+func cryptoHash(algorithm *HashAlgorithm) crypto.Hash {
+	switch algorithm {
+	case HashMD5:
+		return crypto.MD5
+	case HashSha256:
+		return crypto.SHA256
+	case HashSha384:
+		return crypto.SHA384
+	case HashSha512:
+		return crypto.SHA512
+	}
+
+	return 0
+}
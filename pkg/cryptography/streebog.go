@@ -0,0 +1,244 @@
+package cryptography
+
+import (
+	"encoding/binary"
+	"hash"
+	"math/bits"
+	"sync"
+)
+
+// матрица линейного преобразования l ГОСТ Р 34.11-2012, строка A[i] соответствует биту 63-i
+var streebogA = [64]uint64{
+	0x8e20faa72ba0b470, 0x47107ddd9b505a38, 0xad08b0e0c3282d1c, 0xd8045870ef14980e,
+	0x6c022c38f90a4c07, 0x3601161cf205268d, 0x1b8e0b0e798c13c8, 0x83478b07b2468764,
+	0xa011d380818e8f40, 0x5086e740ce47c920, 0x2843fd2067adea10, 0x14aff010bdd87508,
+	0x0ad97808d06cb404, 0x05e23c0468365a02, 0x8c711e02341b2d01, 0x46b60f011a83988e,
+	0x90dab52a387ae76f, 0x486dd4151c3dfdb9, 0x24b86a840e90f0d2, 0x125c354207487869,
+	0x092e94218d243cba, 0x8a174a9ec8121e5d, 0x4585254f64090fa0, 0xaccc9ca9328a8950,
+	0x9d4df05d5f661451, 0xc0a878a0a1330aa6, 0x60543c50de970553, 0x302a1e286fc58ca7,
+	0x18150f14b9ec46dd, 0x0c84890ad27623e0, 0x0642ca05693b9f70, 0x0321658cba93c138,
+	0x86275df09ce8aaa8, 0x439da0784e745554, 0xafc0503c273aa42a, 0xd960281e9d1d5215,
+	0xe230140fc0802984, 0x71180a8960409a42, 0xb60c05ca30204d21, 0x5b068c651810a89e,
+	0x456c34887a3805b9, 0xac361a443d1c8cd2, 0x561b0d22900e4669, 0x2b838811480723ba,
+	0x9bcf4486248d9f5d, 0xc3e9224312c8c1a0, 0xeffa11af0964ee50, 0xf97d86d98a327728,
+	0xe4fa2054a80b329c, 0x727d102a548b194e, 0x39b008152acb8227, 0x9258048415eb419d,
+	0x492c024284fbaec0, 0xaa16012142f35760, 0x550b8e9e21f7a530, 0xa48b474f9ef5dc18,
+	0x70a6a56e2440598e, 0x3853dc371220a247, 0x1ca76e95091051ad, 0x0edd37c48a08a6d8,
+	0x07e095624504536c, 0x8d70c431ac02a736, 0xc83862965601dd1b, 0x641c314b2b8ee083,
+}
+
+// итерационные константы C1..C12 функции сжатия, слова в порядке little-endian
+var streebogC = [12][8]uint64{
+	{0xdd806559f2a64507, 0x05767436cc744d23, 0xa2422a08a460d315, 0x4b7ce09192676901,
+		0x714eb88d7585c4fc, 0x2f6a76432e45d016, 0xebcb2f81c0657c1f, 0xb1085bda1ecadae9},
+	{0xe679047021b19bb7, 0x55dda21bd7cbcd56, 0x5cb561c2db0aa7ca, 0x9ab5176b12d69958,
+		0x61d55e0f16b50131, 0xf3feea720a232b98, 0x4fe39d460f70b5d7, 0x6fa3b58aa99d2f1a},
+	{0x991e96f50aba0ab2, 0xc2b6f443867adb31, 0xc1c93a376062db09, 0xd3e20fe490359eb1,
+		0xf2ea7514b1297b7b, 0x06f15e5f529c1f8b, 0x0a39fc286a3d8435, 0xf574dcac2bce2fc7},
+	{0x220cbebc84e3d12e, 0x3453eaa193e837f1, 0xd8b71333935203be, 0xa9d72c82ed03d675,
+		0x9d721cad685e353f, 0x488e857e335c3c7d, 0xf948e1a05d71e4dd, 0xef1fdfb3e81566d2},
+	{0x601758fd7c6cfe57, 0x7a56a27ea9ea63f5, 0xdfff00b723271a16, 0xbfcd1747253af5a3,
+		0x359e35d7800fffbd, 0x7f151c1f1686104a, 0x9a3f410c6ca92363, 0x4bea6bacad474799},
+	{0xfa68407a46647d6e, 0xbf71c57236904f35, 0x0af21f66c2bec6b6, 0xcffaa6b71c9ab7b4,
+		0x187f9ab49af08ec6, 0x2d66c4f95142a46c, 0x6fa4c33b7a3039c0, 0xae4faeae1d3ad3d9},
+	{0x8886564d3a14d493, 0x3517454ca23c4af3, 0x06476983284a0504, 0x0992abc52d822c37,
+		0xd3473e33197a93c9, 0x399ec6c7e6bf87c9, 0x51ac86febf240954, 0xf4c70e16eeaac5ec},
+	{0xa47f0dd4bf02e71e, 0x36acc2355951a8d9, 0x69d18d2bd1a5c42f, 0xf4892bcb929b0690,
+		0x89b4443b4ddbc49a, 0x4eb7f8719c36de1e, 0x03e7aa020c6e4141, 0x9b1f5b424d93c9a7},
+	{0x7261445183235adb, 0x0e38dc92cb1f2a60, 0x7b2b8a9aa6079c54, 0x800a440bdbb2ceb1,
+		0x3cd955b7e00d0984, 0x3a7d3a1b25894224, 0x944c9ad8ec165fde, 0x378f5a541631229b},
+	{0x74b4c7fb98459ced, 0x3698fad1153bb6c3, 0x7a1e6c303b7652f4, 0x9fe76702af69334b,
+		0x1fffe18a1b336103, 0x8941e71cff8a78db, 0x382ae548b2e4f3f3, 0xabbedea680056f52},
+	{0x6bcaa4cd81f32d1b, 0xdea2594ac06fd85d, 0xefbacd1d7d476e98, 0x8a1d71efea48b9ca,
+		0x2001802114846679, 0xd8fa6bbbebab0761, 0x3002c6cd635afe94, 0x7bcd9ed0efc889fb},
+	{0x48bc924af11bd720, 0xfaf417d5d9b21b99, 0xe71da4aa88e12852, 0x5d80ef9d1891cc86,
+		0xf82012d430219f9b, 0xcda43c32bcdf1d77, 0xd21380b00449b17a, 0x378ee767f11631ba},
+}
+
+var (
+	streebogOnce  sync.Once
+	streebogTable *[8][256]uint64
+)
+
+/*
+Функция хэширования Стрибог, ГОСТ Р 34.11-2012, без обращения к криптопровайдеру
+*/
+type streebog struct {
+	// длина хэш-кода в байтах: 32 или 64
+	size int
+	// промежуточное значение хэш-функции h, счетчик длины N и контрольная сумма Σ
+	state, length, sum [8]uint64
+	// необработанный остаток сообщения
+	buffer [gost3411BlockSize]byte
+	filled int
+}
+
+// создать функцию хэширования ГОСТ Р 34.11-2012 с длиной хэш-кода size байт
+func newStreebog(size int) hash.Hash {
+	getStreebogTable()
+
+	digest := &streebog{size: size}
+	digest.Reset()
+
+	return digest
+}
+
+func (digest *streebog) Size() int {
+	return digest.size
+}
+
+func (digest *streebog) BlockSize() int {
+	return gost3411BlockSize
+}
+
+func (digest *streebog) Reset() {
+	// IV 512 бит - нулевой вектор, IV 256 бит - вектор из байтов 0x01
+	initial := uint64(0)
+
+	if digest.size == 32 {
+		initial = 0x0101010101010101
+	}
+
+	for i := range digest.state {
+		digest.state[i] = initial
+		digest.length[i] = 0
+		digest.sum[i] = 0
+	}
+
+	digest.filled = 0
+}
+
+func (digest *streebog) Write(data []byte) (int, error) {
+	written := len(data)
+
+	for len(data) > 0 {
+		count := copy(digest.buffer[digest.filled:], data)
+		digest.filled += count
+		data = data[count:]
+
+		if digest.filled == gost3411BlockSize {
+			digest.block(digest.buffer[:], 8*gost3411BlockSize)
+			digest.filled = 0
+		}
+	}
+
+	return written, nil
+}
+
+func (digest *streebog) Sum(data []byte) []byte {
+	final := *digest
+
+	// дополнение: m || 1 || 0...0
+	padding := final.buffer
+	padding[final.filled] = 0x01
+
+	for i := final.filled + 1; i < gost3411BlockSize; i++ {
+		padding[i] = 0
+	}
+
+	final.block(padding[:], uint64(8*final.filled))
+
+	var zero [8]uint64
+
+	streebogCompress(&final.state, &zero, &final.length)
+	streebogCompress(&final.state, &zero, &final.sum)
+
+	var result [gost3411BlockSize]byte
+
+	for i, value := range final.state {
+		binary.LittleEndian.PutUint64(result[8*i:], value)
+	}
+
+	// хэш-код длины 256 бит - старшая половина h
+	return append(data, result[gost3411BlockSize-digest.size:]...)
+}
+
+// обработать блок сообщения длиной 512 бит, содержащий bits значащих бит
+func (digest *streebog) block(data []byte, bits uint64) {
+	var message [8]uint64
+
+	for i := range message {
+		message[i] = binary.LittleEndian.Uint64(data[8*i:])
+	}
+
+	streebogCompress(&digest.state, &digest.length, &message)
+	streebogAdd(&digest.length, &[8]uint64{bits})
+	streebogAdd(&digest.sum, &message)
+}
+
+// сложение по модулю 2^512
+func streebogAdd(result *[8]uint64, value *[8]uint64) {
+	var carry uint64
+
+	for i := range result {
+		result[i], carry = bits.Add64(result[i], value[i], carry)
+	}
+}
+
+// функция сжатия g_N(h, m) = E(LPS(h ^ N), m) ^ h ^ m
+func streebogCompress(state *[8]uint64, length *[8]uint64, message *[8]uint64) {
+	var key, value [8]uint64
+
+	for i := range key {
+		key[i] = state[i] ^ length[i]
+	}
+
+	streebogLPS(&key)
+	value = *message
+
+	for i := range streebogC {
+		for j := range value {
+			value[j] ^= key[j]
+			key[j] ^= streebogC[i][j]
+		}
+
+		streebogLPS(&value)
+		streebogLPS(&key)
+	}
+
+	for i := range state {
+		state[i] ^= value[i] ^ key[i] ^ message[i]
+	}
+}
+
+// преобразование LPS по таблицам: байт i слова j после перестановки P становится байтом j слова i
+func streebogLPS(state *[8]uint64) {
+	table := streebogTable
+	input := *state
+
+	for i := range state {
+		shift := 8 * uint(i)
+
+		state[i] = table[0][byte(input[0]>>shift)] ^ table[1][byte(input[1]>>shift)] ^
+			table[2][byte(input[2]>>shift)] ^ table[3][byte(input[3]>>shift)] ^
+			table[4][byte(input[4]>>shift)] ^ table[5][byte(input[5]>>shift)] ^
+			table[6][byte(input[6]>>shift)] ^ table[7][byte(input[7]>>shift)]
+	}
+}
+
+// получить таблицы преобразования LS для каждой позиции байта в слове, таблицы вычисляются один раз
+func getStreebogTable() *[8][256]uint64 {
+	streebogOnce.Do(func() {
+		table := &[8][256]uint64{}
+
+		for position := range table {
+			for value := range table[position] {
+				input := uint64(kuznyechikPi[value]) << (8 * uint(position))
+
+				var result uint64
+
+				for bit := 0; bit < 64; bit++ {
+					if input>>(63-uint(bit))&1 == 1 {
+						result ^= streebogA[bit]
+					}
+				}
+
+				table[position][value] = result
+			}
+		}
+
+		streebogTable = table
+	})
+
+	return streebogTable
+}
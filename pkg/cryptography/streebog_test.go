@@ -0,0 +1,66 @@
+package cryptography
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// примеры ГОСТ Р 34.11-2012, приложение А (RFC 6986, раздел 10)
+func Test_Streebog_Success(t *testing.T) {
+	first := []byte("012345678901234567890123456789012345678901234567890123456789012")
+	second, _ := hex.DecodeString("d1e520e2e5f2f0e82c20d1f2f0e8e1eee6e820e2edf3f6e82c20e2e5fef2fa20f120eceef0ff20f1f2f0e5ebe0ece820ede020f5f0e0e1f0fbff20efebfaeafb20c8e3eef0e5e2fb")
+
+	for _, example := range []struct {
+		message []byte
+		size    int
+		want    string
+	}{
+		{first, 64, "1b54d01a4af5b9d5cc3d86d68d285462b19abc2475222f35c085122be4ba1ffa00ad30f8767b3a82384c6574f024c311e2a481332b08ef7f41797891c1646f48"},
+		{first, 32, "9d151eefd8590b89daa6ba6cb74af9275dd051026bb149a452fd84e5e57b5500"},
+		{second, 64, "1e88e62226bfca6f9994f1f2d51569e0daf8475a3b0fe61a5300eee46d961376035fe83549ada2b8620fcd7c496ce5b33f0cb9dddc2b6460143b03dabac9fb28"},
+		{second, 32, "9dd2fe4e90409e5da87f53976d7405b0c0cac628fc669a741d50063c557e8f50"},
+	} {
+		digest := newStreebog(example.size)
+		digest.Write(example.message)
+
+		if result := hex.EncodeToString(digest.Sum(nil)); result != example.want {
+			t.Errorf("Ожидался хэш %s. Получен %s", example.want, result)
+		}
+	}
+}
+
+func Test_StreebogWrite_Success(t *testing.T) {
+	message := bytes.Repeat([]byte{0x5a, 0x01, 0xff}, 100)
+
+	whole := newStreebog(64)
+	whole.Write(message)
+	want := whole.Sum(nil)
+
+	// запись частями, пересекающими границы блоков, и повторный вызов Sum
+	parts := newStreebog(64)
+
+	for data := message; len(data) > 0; {
+		count := 7
+
+		if count > len(data) {
+			count = len(data)
+		}
+
+		parts.Write(data[:count])
+		data = data[count:]
+	}
+
+	parts.Sum(nil)
+
+	if result := parts.Sum(nil); !bytes.Equal(result, want) {
+		t.Errorf("Ожидался хэш %x. Получен %x", want, result)
+	}
+
+	parts.Reset()
+	parts.Write(message)
+
+	if result := parts.Sum(nil); !bytes.Equal(result, want) {
+		t.Errorf("Ожидался хэш после Reset %x. Получен %x", want, result)
+	}
+}
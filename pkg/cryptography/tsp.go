@@ -0,0 +1,236 @@
+package cryptography
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// идентификатор содержимого штампа времени
+var OIDTSTInfo = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+
+/*
+Хэш данных для штампа времени
+*/
+type MessageImprint struct {
+	HashAlgorithm AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+/*
+Запрос штампа времени RFC 3161
+*/
+type TimeStampRequest struct {
+	Version        int
+	MessageImprint MessageImprint
+	ReqPolicy      asn1.ObjectIdentifier `asn1:"optional"`
+	Nonce          *big.Int              `asn1:"optional"`
+	CertReq        bool                  `asn1:"optional,default:false"`
+	Extensions     asn1.RawValue         `asn1:"optional,tag:0"`
+}
+
+/*
+Статус ответа службы штампов времени
+*/
+type PKIStatusInfo struct {
+	Status       int
+	StatusString []string       `asn1:"optional"`
+	FailInfo     asn1.BitString `asn1:"optional"`
+}
+
+/*
+Ответ службы штампов времени RFC 3161
+*/
+type TimeStampResponse struct {
+	Status         PKIStatusInfo
+	TimeStampToken asn1.RawValue `asn1:"optional"`
+}
+
+/*
+Точность времени штампа
+*/
+type Accuracy struct {
+	Seconds int `asn1:"optional"`
+	Millis  int `asn1:"optional,tag:0"`
+	Micros  int `asn1:"optional,tag:1"`
+}
+
+/*
+Содержимое штампа времени
+*/
+type TSTInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint MessageImprint
+	SerialNumber   *big.Int
+	GenTime        time.Time     `asn1:"generalized"`
+	Accuracy       Accuracy      `asn1:"optional"`
+	Ordering       bool          `asn1:"optional,default:false"`
+	Nonce          *big.Int      `asn1:"optional"`
+	TSA            asn1.RawValue `asn1:"optional,tag:0"`
+	Extensions     asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+// статусы ответа, при которых штамп времени выдан
+const (
+	tspGranted         = 0
+	tspGrantedWithMods = 1
+)
+
+const timestampQueryMimeType = "application/timestamp-query"
+
+// получить метод получения штампа времени по RFC 3161
+// штамп запрашивается на хэш данных, вычисленный алгоритмом algorithm (по умолчанию ГОСТ Р 34.11-2012-256)
+func CreateTimestampMethod(url string, algorithm *HashAlgorithm) (release func(), timestamp func(io.Reader) (io.Reader, error), exception error) {
+	if url == "" {
+		return nil, nil, errors.New("Не задан адрес службы штампов времени")
+	}
+
+	if algorithm == nil {
+		algorithm = HashGOST3411_2012_256
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	return func() {
+			client.CloseIdleConnections()
+		},
+		func(data io.Reader) (io.Reader, error) {
+			digest, exception := calculateDigest(algorithm, data)
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			nonce, exception := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			request, exception := asn1.Marshal(TimeStampRequest{
+				Version:        1,
+				MessageImprint: MessageImprint{HashAlgorithm: digestAlgorithmIdentifier(algorithm), HashedMessage: digest},
+				Nonce:          nonce,
+				CertReq:        true,
+			})
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			response, exception := client.Post(url, timestampQueryMimeType, bytes.NewReader(request))
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			defer response.Body.Close()
+
+			if response.StatusCode != http.StatusOK {
+				return nil, fmt.Errorf("Служба штампов времени вернула HTTP статус %d", response.StatusCode)
+			}
+
+			body, exception := io.ReadAll(response.Body)
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			token, exception := parseTimeStampResponse(body)
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			info, exception := ParseTimestampToken(bytes.NewReader(token))
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			if !info.MessageImprint.HashAlgorithm.Algorithm.Equal(algorithm.OID) || !bytes.Equal(info.MessageImprint.HashedMessage, digest) {
+				return nil, errors.New("Хэш в штампе времени не совпадает с запрошенным")
+			}
+
+			if info.Nonce == nil || info.Nonce.Cmp(nonce) != 0 {
+				return nil, errors.New("Nonce в штампе времени не совпадает с запрошенным")
+			}
+
+			return bytes.NewReader(token), nil
+		}, nil
+}
+
+// разобрать ответ службы штампов времени, вернуть штамп времени
+func parseTimeStampResponse(data []byte) ([]byte, error) {
+	var response TimeStampResponse
+
+	if _, exception := asn1.Unmarshal(data, &response); exception != nil {
+		return nil, exception
+	}
+
+	if response.Status.Status != tspGranted && response.Status.Status != tspGrantedWithMods {
+		return nil, fmt.Errorf("Служба штампов времени отклонила запрос, статус %d: %s", response.Status.Status, strings.Join(response.Status.StatusString, "; "))
+	}
+
+	if len(response.TimeStampToken.FullBytes) == 0 {
+		return nil, errors.New("В ответе службы штампов времени нет штампа")
+	}
+
+	return response.TimeStampToken.FullBytes, nil
+}
+
+// разобрать штамп времени и проверить его подпись
+func ParseTimestampToken(token io.Reader) (*TSTInfo, error) {
+	info, _, exception := parseTimestampToken(token)
+
+	return info, exception
+}
+
+// разобрать штамп времени и вернуть результат проверки его подписи с сертификатом службы штампов
+func parseTimestampToken(token io.Reader) (*TSTInfo, *VerifyReport, error) {
+	data, exception := readCMS(token)
+
+	if exception != nil {
+		return nil, nil, exception
+	}
+
+	signedData, exception := parseSignedData(data)
+
+	if exception != nil {
+		return nil, nil, exception
+	}
+
+	if !signedData.EncapContentInfo.EContentType.Equal(OIDTSTInfo) {
+		return nil, nil, errors.New("Штамп времени не содержит TSTInfo")
+	}
+
+	report, exception := verifySignedData(signedData, nil)
+
+	if exception != nil {
+		return nil, nil, exception
+	}
+
+	if len(report.Signers) == 0 {
+		return nil, nil, errors.New("Штамп времени не подписан")
+	}
+
+	if !report.Valid() {
+		return nil, nil, fmt.Errorf("Подпись штампа времени не верна: %v", report.Signers[0].Exception)
+	}
+
+	var info TSTInfo
+
+	if _, exception := asn1.Unmarshal(signedData.EncapContentInfo.EContent, &info); exception != nil {
+		return nil, nil, exception
+	}
+
+	return &info, report, nil
+}
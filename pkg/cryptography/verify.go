@@ -0,0 +1,500 @@
+package cryptography
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"io"
+	"time"
+)
+
+/*
+Статус проверки подписанта
+*/
+type SignerStatus int
+
+const (
+	// подпись верна
+	SignerValid SignerStatus = iota
+	// не найден сертификат подписанта
+	SignerCertificateNotFound
+	// не поддерживается алгоритм хэширования или подписи
+	SignerUnsupportedAlgorithm
+	// значение хэша не совпадает с атрибутом messageDigest
+	SignerDigestMismatch
+	// значение подписи не верно
+	SignerSignatureInvalid
+	// некорректная структура SignerInfo
+	SignerMalformed
+)
+
+func (status SignerStatus) String() string {
+	switch status {
+	case SignerValid:
+		return "Подпись верна"
+	case SignerCertificateNotFound:
+		return "Не найден сертификат подписанта"
+	case SignerUnsupportedAlgorithm:
+		return "Алгоритм не поддерживается"
+	case SignerDigestMismatch:
+		return "Значение хэша не совпадает"
+	case SignerSignatureInvalid:
+		return "Подпись не верна"
+	case SignerMalformed:
+		return "Некорректная структура подписи"
+	}
+
+	return "Неизвестный статус"
+}
+
+/*
+Результат проверки подписанта
+*/
+type SignerReport struct {
+	// сертификат подписанта, nil если не найден
+	Certificate *x509.Certificate
+	// время подписи из атрибута signingTime, нулевое если атрибута нет
+	SigningTime        time.Time
+	DigestAlgorithm    asn1.ObjectIdentifier
+	SignatureAlgorithm asn1.ObjectIdentifier
+	Status             SignerStatus
+	// причина ошибки проверки
+	Exception error
+	// результаты проверки заверяющих подписей countersignature
+	Countersignatures []SignerReport
+	// результат проверки политикой, nil если политика не применялась
+	Policy *PolicyReport
+}
+
+// верна ли подпись, выполнена ли политика и верны ли все заверяющие подписи
+func (report *SignerReport) Valid() bool {
+	if report.Status != SignerValid || report.Policy != nil && !report.Policy.Valid() {
+		return false
+	}
+
+	for i := range report.Countersignatures {
+		if !report.Countersignatures[i].Valid() {
+			return false
+		}
+	}
+
+	return true
+}
+
+/*
+Результат проверки CMS подписи
+*/
+type VerifyReport struct {
+	ContentType asn1.ObjectIdentifier
+	// открепленная подпись
+	Detached bool
+	// подписанное содержимое присоединенной подписи
+	Content      []byte
+	Certificates []*x509.Certificate
+	Signers      []SignerReport
+}
+
+// все ли подписи верны
+func (report *VerifyReport) Valid() bool {
+	if len(report.Signers) == 0 {
+		return false
+	}
+
+	for i := range report.Signers {
+		if !report.Signers[i].Valid() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// получить метод проверки CMS подписи
+// для открепленной подписи content - подписанные данные, для присоединенной - nil
+func CreateCMSVerifyMethod() (release func(), verify func(signature io.Reader, content io.Reader) (*VerifyReport, error), exception error) {
+	return func() {},
+		func(signature io.Reader, content io.Reader) (*VerifyReport, error) {
+			data, exception := readCMS(signature)
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			signedData, exception := parseSignedData(data)
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			return verifySignedData(signedData, content)
+		}, nil
+}
+
+// проверить все подписи SignedData
+func verifySignedData(signedData *SignedData, content io.Reader) (*VerifyReport, error) {
+	report := &VerifyReport{
+		ContentType: signedData.EncapContentInfo.EContentType,
+		Detached:    signedData.EncapContentInfo.EContent == nil,
+		Content:     signedData.EncapContentInfo.EContent,
+	}
+
+	certificates, exception := parseCertificates(signedData.Certificates)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	report.Certificates = certificates
+
+	if report.Detached {
+		if content == nil {
+			return nil, errors.New("Для открепленной подписи не переданы подписанные данные")
+		}
+	} else {
+		content = bytes.NewReader(report.Content)
+	}
+
+	// хэши содержимого вычисляются за одно чтение для всех алгоритмов подписантов
+	var algorithms []*HashAlgorithm
+
+	for _, signerInfo := range signedData.SignerInfos {
+		algorithm, exception := FindHashAlgorithm(signerInfo.DigestAlgorithm.Algorithm)
+
+		if exception != nil || containsHashAlgorithm(algorithms, algorithm) {
+			continue
+		}
+
+		algorithms = append(algorithms, algorithm)
+	}
+
+	digests := map[*HashAlgorithm][]byte{}
+
+	if len(algorithms) > 0 {
+		values, exception := calculateDigests(algorithms, content)
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		for i, algorithm := range algorithms {
+			digests[algorithm] = values[i]
+		}
+	}
+
+	for _, signerInfo := range signedData.SignerInfos {
+		signer := verifySignerInfo(&signerInfo, signedData.EncapContentInfo.EContentType, certificates, digests)
+		signer.Countersignatures = verifyCountersignatures(&signerInfo, certificates)
+
+		report.Signers = append(report.Signers, signer)
+	}
+
+	return report, nil
+}
+
+func containsHashAlgorithm(algorithms []*HashAlgorithm, algorithm *HashAlgorithm) bool {
+	for _, value := range algorithms {
+		if value == algorithm {
+			return true
+		}
+	}
+
+	return false
+}
+
+// разобрать сертификаты [0] IMPLICIT CertificateSet
+func parseCertificates(raw asn1.RawValue) ([]*x509.Certificate, error) {
+	var certificates []*x509.Certificate
+	rest := raw.Bytes
+
+	for len(rest) > 0 {
+		var element asn1.RawValue
+		var exception error
+
+		rest, exception = asn1.Unmarshal(rest, &element)
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		// пропускаем сертификаты в других форматах (атрибутные и т.п.)
+		if element.Class != asn1.ClassUniversal || element.Tag != asn1.TagSequence {
+			continue
+		}
+
+		certificate, exception := x509.ParseCertificate(element.FullBytes)
+
+		if exception != nil {
+			return nil, exception
+		}
+
+		certificates = append(certificates, certificate)
+	}
+
+	return certificates, nil
+}
+
+// найти сертификат подписанта по SignerIdentifier
+func findSignerCertificate(sid asn1.RawValue, certificates []*x509.Certificate) *x509.Certificate {
+	// [0] SubjectKeyIdentifier
+	if sid.Class == asn1.ClassContextSpecific && sid.Tag == 0 {
+		for _, certificate := range certificates {
+			if bytes.Equal(certificate.SubjectKeyId, sid.Bytes) {
+				return certificate
+			}
+		}
+
+		return nil
+	}
+
+	var issuerAndSerialNumber IssuerAndSerialNumber
+
+	if _, exception := asn1.Unmarshal(sid.FullBytes, &issuerAndSerialNumber); exception != nil {
+		return nil
+	}
+
+	for _, certificate := range certificates {
+		if bytes.Equal(certificate.RawIssuer, issuerAndSerialNumber.Issuer.FullBytes) &&
+			certificate.SerialNumber.Cmp(issuerAndSerialNumber.SerialNumber) == 0 {
+			return certificate
+		}
+	}
+
+	return nil
+}
+
+// проверить подпись одного подписанта
+// для заверяющей подписи contentType равен nil, атрибут content-type в ней запрещен
+func verifySignerInfo(signerInfo *SignerInfo, contentType asn1.ObjectIdentifier, certificates []*x509.Certificate, digests map[*HashAlgorithm][]byte) SignerReport {
+	report := SignerReport{
+		DigestAlgorithm:    signerInfo.DigestAlgorithm.Algorithm,
+		SignatureAlgorithm: signerInfo.SignatureAlgorithm.Algorithm,
+	}
+
+	fail := func(status SignerStatus, exception error) SignerReport {
+		report.Status = status
+		report.Exception = exception
+
+		return report
+	}
+
+	report.Certificate = findSignerCertificate(signerInfo.SID, certificates)
+
+	if report.Certificate == nil {
+		return fail(SignerCertificateNotFound, errors.New("Сертификат подписанта не найден в подписи"))
+	}
+
+	hashAlgorithm, exception := FindHashAlgorithm(signerInfo.DigestAlgorithm.Algorithm)
+
+	if exception != nil {
+		return fail(SignerUnsupportedAlgorithm, exception)
+	}
+
+	signatureAlgorithm, exception := FindSignatureAlgorithm(signerInfo.SignatureAlgorithm.Algorithm, hashAlgorithm)
+
+	if exception != nil {
+		return fail(SignerUnsupportedAlgorithm, exception)
+	}
+
+	var saltLength int
+
+	if signatureAlgorithm.OID.Equal(OIDRSAPSS) {
+		saltLength, exception = parseRSAPSSParameters(signerInfo.SignatureAlgorithm.Parameters.FullBytes, hashAlgorithm)
+
+		if exception != nil {
+			return fail(SignerMalformed, exception)
+		}
+	}
+
+	digest := digests[hashAlgorithm]
+
+	attributes, exception := parseAttributes(signerInfo.SignedAttributes)
+
+	if exception != nil {
+		return fail(SignerMalformed, exception)
+	}
+
+	if len(attributes) > 0 {
+		if contentType == nil {
+			if _, ok := findAttribute(attributes, OIDAttributeContentType); ok {
+				return fail(SignerMalformed, errors.New("Заверяющая подпись не должна содержать атрибут contentType"))
+			}
+		} else if attribute, ok := findAttribute(attributes, OIDAttributeContentType); ok && len(attribute.Values) == 1 {
+			var value asn1.ObjectIdentifier
+
+			if _, exception := asn1.Unmarshal(attribute.Values[0].FullBytes, &value); exception != nil || !value.Equal(contentType) {
+				return fail(SignerMalformed, errors.New("Атрибут contentType не совпадает с типом содержимого"))
+			}
+		} else {
+			return fail(SignerMalformed, errors.New("Не найден атрибут contentType"))
+		}
+
+		if attribute, ok := findAttribute(attributes, OIDAttributeSigningTime); ok && len(attribute.Values) == 1 {
+			report.SigningTime, exception = parseSigningTime(attribute.Values[0])
+
+			if exception != nil {
+				return fail(SignerMalformed, exception)
+			}
+		}
+
+		attribute, ok := findAttribute(attributes, OIDAttributeMessageDigest)
+
+		if !ok || len(attribute.Values) != 1 {
+			return fail(SignerMalformed, errors.New("Не найден атрибут messageDigest"))
+		}
+
+		var messageDigest []byte
+
+		if _, exception := asn1.Unmarshal(attribute.Values[0].FullBytes, &messageDigest); exception != nil {
+			return fail(SignerMalformed, exception)
+		}
+
+		if !bytes.Equal(messageDigest, digest) {
+			return fail(SignerDigestMismatch, errors.New("Значение хэша данных не совпадает с атрибутом messageDigest"))
+		}
+
+		if exception := verifySigningCertificate(attributes, report.Certificate); exception != nil {
+			return fail(SignerMalformed, exception)
+		}
+
+		// подписываются атрибуты в кодировке SET OF
+		signedAttributes := append([]byte{0x31}, signerInfo.SignedAttributes.FullBytes[1:]...)
+
+		digest, exception = calculateBytesDigest(hashAlgorithm, signedAttributes)
+
+		if exception != nil {
+			return fail(SignerUnsupportedAlgorithm, exception)
+		}
+	}
+
+	if signatureAlgorithm.OID.Equal(OIDRSAPSS) {
+		exception = verifyRSAPSSSignature(report.Certificate, signatureAlgorithm, saltLength, digest, signerInfo.Signature)
+	} else {
+		exception = verifySignature(report.Certificate, signatureAlgorithm, digest, signerInfo.Signature)
+	}
+
+	if exception != nil {
+		return fail(SignerSignatureInvalid, exception)
+	}
+
+	report.Status = SignerValid
+
+	return report
+}
+
+// проверить заверяющие подписи из неподписанных атрибутов SignerInfo, включая вложенные
+func verifyCountersignatures(signerInfo *SignerInfo, certificates []*x509.Certificate) []SignerReport {
+	var reports []SignerReport
+
+	attributes, exception := parseAttributes(signerInfo.UnsignedAttributes)
+
+	if exception != nil {
+		return []SignerReport{{Status: SignerMalformed, Exception: exception}}
+	}
+
+	for _, attribute := range attributes {
+		if !attribute.Type.Equal(OIDAttributeCounterSignature) {
+			continue
+		}
+
+		for _, value := range attribute.Values {
+			var countersignature SignerInfo
+
+			if _, exception := asn1.Unmarshal(value.FullBytes, &countersignature); exception != nil {
+				reports = append(reports, SignerReport{Status: SignerMalformed, Exception: exception})
+
+				continue
+			}
+
+			// заверяется значение подписи, а не содержимое
+			digests := map[*HashAlgorithm][]byte{}
+
+			if algorithm, exception := FindHashAlgorithm(countersignature.DigestAlgorithm.Algorithm); exception == nil {
+				digest, exception := calculateBytesDigest(algorithm, signerInfo.Signature)
+
+				if exception != nil {
+					reports = append(reports, SignerReport{Status: SignerUnsupportedAlgorithm, Exception: exception})
+
+					continue
+				}
+
+				digests[algorithm] = digest
+			}
+
+			report := verifySignerInfo(&countersignature, nil, certificates, digests)
+			report.Countersignatures = verifyCountersignatures(&countersignature, certificates)
+
+			reports = append(reports, report)
+		}
+	}
+
+	return reports
+}
+
+// проверить значение подписи над хэшем открытым ключом сертификата
+func verifySignature(certificate *x509.Certificate, algorithm *SignatureAlgorithm, digest []byte, signature []byte) error {
+	publicKey, exception := CertificatePublicKey(certificate)
+
+	if exception != nil {
+		return exception
+	}
+
+	return verifyDigest(publicKey, algorithm, digest, signature)
+}
+
+// проверить подпись RSASSA-PSS с длиной соли, заданной в параметрах алгоритма
+func verifyRSAPSSSignature(certificate *x509.Certificate, algorithm *SignatureAlgorithm, saltLength int, digest []byte, signature []byte) error {
+	publicKey, exception := CertificatePublicKey(certificate)
+
+	if exception != nil {
+		return exception
+	}
+
+	rsaPublicKey, ok := publicKey.(*rsa.PublicKey)
+
+	if !ok {
+		return errors.New("Открытый ключ не является ключом RSA")
+	}
+
+	return rsa.VerifyPSS(rsaPublicKey, cryptoHash(algorithm.Hash), digest, signature, &rsa.PSSOptions{SaltLength: saltLength, Hash: cryptoHash(algorithm.Hash)})
+}
+
+// проверить значение подписи над хэшем открытым ключом
+func verifyDigest(publicKey crypto.PublicKey, algorithm *SignatureAlgorithm, digest []byte, signature []byte) error {
+	if algorithm.PublicKeyOID.Equal(OIDRSA) {
+		rsaPublicKey, ok := publicKey.(*rsa.PublicKey)
+
+		if !ok {
+			return errors.New("Открытый ключ не является ключом RSA")
+		}
+
+		if algorithm.OID.Equal(OIDRSAPSS) {
+			return rsa.VerifyPSS(rsaPublicKey, cryptoHash(algorithm.Hash), digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto})
+		}
+
+		return rsa.VerifyPKCS1v15(rsaPublicKey, cryptoHash(algorithm.Hash), digest, signature)
+	}
+
+	gostPublicKey, ok := publicKey.(*PublicKey)
+
+	if !ok {
+		return errors.New("Открытый ключ не является ключом ГОСТ Р 34.10")
+	}
+
+	if !gostPublicKey.VerifyDigest(digest, signature) {
+		return errors.New("Значение подписи ГОСТ Р 34.10 не верно")
+	}
+
+	return nil
+}
+
+// открытый ключ сертификата: *PublicKey для ГОСТ Р 34.10, ключ стандартной библиотеки для остальных алгоритмов
+func CertificatePublicKey(certificate *x509.Certificate) (crypto.PublicKey, error) {
+	if certificate.PublicKey != nil {
+		return certificate.PublicKey, nil
+	}
+
+	return ParsePublicKey(certificate.RawSubjectPublicKeyInfo)
+}
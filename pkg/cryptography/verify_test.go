@@ -0,0 +1,187 @@
+package cryptography
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_VerifyDetachedCMS_Success(t *testing.T) {
+	release, verify, error := CreateCMSVerifyMethod()
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer release()
+
+	signature, error := os.Open("../../test/HashTest.xml.sig")
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer signature.Close()
+
+	content, error := os.Open("../../test/HashTest.xml")
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer content.Close()
+
+	report, error := verify(signature, content)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if !report.Detached {
+		t.Error("Ожидалась открепленная подпись")
+	}
+
+	if !report.Valid() {
+		t.Fatalf("Ожидалась верная подпись. Получен статус %s: %v", report.Signers[0].Status, report.Signers[0].Exception)
+	}
+
+	signer := report.Signers[0]
+
+	if signer.Certificate.Subject.CommonName != "go-gost-crypto test" {
+		t.Errorf("Ожидался сертификат go-gost-crypto test. Получен %s", signer.Certificate.Subject.CommonName)
+	}
+
+	if signer.SigningTime.IsZero() {
+		t.Error("Ожидалось время подписи")
+	}
+
+	if !signer.DigestAlgorithm.Equal(OIDSha256) {
+		t.Errorf("Ожидался алгоритм хэширования %s. Получен %s", OIDSha256, signer.DigestAlgorithm)
+	}
+}
+
+func Test_VerifyAttachedCMS_Success(t *testing.T) {
+	release, verify, error := CreateCMSVerifyMethod()
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer release()
+
+	signature, error := os.Open("../../test/HashTest.xml.p7s")
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer signature.Close()
+
+	report, error := verify(signature, nil)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if report.Detached {
+		t.Error("Ожидалась присоединенная подпись")
+	}
+
+	if !report.Valid() {
+		t.Fatalf("Ожидалась верная подпись. Получен статус %s: %v", report.Signers[0].Status, report.Signers[0].Exception)
+	}
+
+	want, error := os.ReadFile("../../test/HashTest.xml")
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if string(report.Content) != string(want) {
+		t.Error("Подписанное содержимое не совпадает с исходным файлом")
+	}
+}
+
+func Test_VerifyDetachedCMS_DigestMismatch(t *testing.T) {
+	release, verify, error := CreateCMSVerifyMethod()
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer release()
+
+	signature, error := os.Open("../../test/HashTest.xml.sig")
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer signature.Close()
+
+	report, error := verify(signature, strings.NewReader("Hello world"))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if report.Valid() {
+		t.Error("Ожидалась неверная подпись")
+	}
+
+	if report.Signers[0].Status != SignerDigestMismatch {
+		t.Errorf("Ожидался статус %s. Получен %s", SignerDigestMismatch, report.Signers[0].Status)
+	}
+}
+
+func Test_BERToDER_Success(t *testing.T) {
+	// ContentInfo с неопределенными длинами и составной OCTET STRING
+	ber := []byte{
+		0x30, 0x80,
+		0x06, 0x09, 0x2a, 0x86, 0x48, 0x86, 0xf7, 0x0d, 0x01, 0x07, 0x01,
+		0xa0, 0x80,
+		0x24, 0x80, 0x04, 0x02, 'H', 'e', 0x04, 0x03, 'l', 'l', 'o', 0x00, 0x00,
+		0x00, 0x00,
+		0x00, 0x00,
+	}
+
+	der, error := berToDER(ber)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	want := []byte{
+		0x30, 0x14,
+		0x06, 0x09, 0x2a, 0x86, 0x48, 0x86, 0xf7, 0x0d, 0x01, 0x07, 0x01,
+		0xa0, 0x07,
+		0x04, 0x05, 'H', 'e', 'l', 'l', 'o',
+	}
+
+	if string(der) != string(want) {
+		t.Errorf("Ожидалось DER представление %x. Получено %x", want, der)
+	}
+}
+
+func Test_ReadCMS_TrailingSpaceByte(t *testing.T) {
+	// DER, оканчивающийся байтом 0x09, не должен обрезаться
+	data := []byte{0x30, 0x03, 0x04, 0x01, 0x09}
+
+	result, error := readCMS(bytes.NewReader(data))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if !bytes.Equal(result, data) {
+		t.Errorf("Ожидалось %x. Получено %x", data, result)
+	}
+
+	result, error = readCMS(strings.NewReader("\n" + base64.StdEncoding.EncodeToString(data) + "\n"))
+
+	if error != nil || !bytes.Equal(result, data) {
+		t.Errorf("Ожидалось %x из base64. Получено %x, %v", data, result, error)
+	}
+}
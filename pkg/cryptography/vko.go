@@ -0,0 +1,129 @@
+package cryptography
+
+import (
+	"encoding/asn1"
+	"errors"
+	"io"
+
+	"github.com/madpo/go-gost-crypto/pkg/wrapper"
+)
+
+// размер UKM ключа согласования КриптоПро в байтах
+const cspUKMSize = 8
+
+// сгенерировать эфемерный ключ для выработки ключа согласования, аналог ecdh.Curve.GenerateKey
+func (curve *Curve) GenerateKey(random io.Reader) (*PrivateKey, error) {
+	return GeneratePrivateKey(curve, random)
+}
+
+// проверить открытый ключ другой стороны: точка лежит на кривой и принадлежит подгруппе порядка q
+func (key *PublicKey) Validate() error {
+	if key == nil || key.Curve == nil || key.X == nil || key.Y == nil {
+		return errors.New("Открытый ключ не задан")
+	}
+
+	if !key.Curve.IsOnCurve(key.X, key.Y) {
+		return errors.New("Точка открытого ключа не принадлежит кривой")
+	}
+
+	if x, _ := key.Curve.scalarMultVartime(key.X, key.Y, key.Curve.Q); x != nil {
+		return errors.New("Точка открытого ключа не принадлежит подгруппе порядка q")
+	}
+
+	return nil
+}
+
+// выработать ключ согласования VKO, RFC 7836 п. 4.3
+// hash - ГОСТ Р 34.11-2012 256 или 512 бит (VKO_GOSTR3410_2012_256 и VKO_GOSTR3410_2012_512)
+// либо ГОСТ Р 34.11-94 для ключей ГОСТ Р 34.10-2001 (VKO_GOSTR3410_2001, RFC 4357)
+func (key *PrivateKey) VKO(remote *PublicKey, ukm []byte, hash *HashAlgorithm) ([]byte, error) {
+	if hash != HashGOST3411_2012_256 && hash != HashGOST3411_2012_512 && hash != HashGOST3411 {
+		return nil, errors.New("Ключ согласования VKO вычисляется только алгоритмами хэширования ГОСТ")
+	}
+
+	point, exception := key.agreementPoint(remote, ukm)
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	return calculateBytesDigest(hash, point)
+}
+
+// вычислить точку K = (m/q * UKM * d mod q) * Q в little-endian представлении X||Y
+func (key *PrivateKey) agreementPoint(remote *PublicKey, ukm []byte) ([]byte, error) {
+	if exception := remote.Validate(); exception != nil {
+		return nil, exception
+	}
+
+	curve := key.Curve
+
+	if remote.Curve != curve {
+		return nil, errors.New("Открытый ключ другой стороны использует другую кривую")
+	}
+
+	if len(ukm) == 0 {
+		return nil, errors.New("Не задано значение UKM")
+	}
+
+	// UKM - число в порядке little-endian, нулевое значение заменяется единицей
+	scalar := littleEndianToBig(ukm)
+
+	if scalar.Sign() == 0 {
+		scalar.SetInt64(1)
+	}
+
+	scalar.Mul(scalar, curve.Cofactor)
+	scalar.Mul(scalar, key.D)
+	scalar.Mod(scalar, curve.Q)
+
+	x, y := curve.ScalarMult(remote.X, remote.Y, scalar)
+
+	if x == nil {
+		return nil, errors.New("Ключ согласования равен бесконечно удаленной точке")
+	}
+
+	return append(bigToLittleEndian(x, curve.PointSize), bigToLittleEndian(y, curve.PointSize)...), nil
+}
+
+// выработать ключ согласования VKO ключом обмена контейнера средствами КриптоПро
+// ключ согласования не извлекается из провайдера и используется для экспорта и импорта ключей
+func (signer *ContainerSigner) AgreeKey(remote *PublicKey, ukm []byte) (release func(), key *wrapper.CryptoKey, exception error) {
+	if signer.keySpec != wrapper.KeyExchange {
+		return nil, nil, errors.New("Ключ контейнера не предназначен для обмена ключами")
+	}
+
+	if exception := remote.Validate(); exception != nil {
+		return nil, nil, exception
+	}
+
+	if len(ukm) != cspUKMSize {
+		return nil, nil, errors.New("Неверная длина UKM")
+	}
+
+	agreementType := wrapper.GOST3410_2012_256_DH
+
+	if remote.Curve.PointSize == 64 {
+		agreementType = wrapper.GOST3410_2012_512_DH
+	}
+
+	_, parameters := publicKeyParameters(remote.Curve)
+	encodedParameters, exception := asn1.Marshal(parameters)
+
+	if exception != nil {
+		return nil, nil, exception
+	}
+
+	publicKey := remote.Raw()
+	value := append([]byte{}, ukm...)
+
+	key, exception = wrapper.ImportAgreementKey(signer.provider, signer.key, agreementType, &encodedParameters, &publicKey, &value)
+
+	if exception != nil {
+		return nil, nil, exception
+	}
+
+	return func() {
+		wrapper.ReleaseKey(key)
+	}, key, nil
+}
@@ -0,0 +1,186 @@
+package cryptography
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+func Test_VKO_Success(t *testing.T) {
+	ukm, _ := hex.DecodeString("1d80603c8544c727")
+
+	for _, curve := range []*Curve{CurveCryptoProA, CurveTC26_256A, CurveTC26_512A, CurveTC26_512C} {
+		first, error := curve.GenerateKey(nil)
+
+		if error != nil {
+			t.Fatal(error)
+		}
+
+		second, _ := curve.GenerateKey(nil)
+
+		for _, hash := range []*HashAlgorithm{HashGOST3411_2012_256, HashGOST3411_2012_512} {
+			firstKEK, error := first.VKO(&second.PublicKey, ukm, hash)
+
+			if error != nil {
+				t.Fatal(error)
+			}
+
+			secondKEK, error := second.VKO(&first.PublicKey, ukm, hash)
+
+			if error != nil {
+				t.Fatal(error)
+			}
+
+			if len(firstKEK) != hash.Size || !bytes.Equal(firstKEK, secondKEK) {
+				t.Errorf("%s, %s: ожидалось совпадение ключей согласования сторон", curve.Name, hash.Name)
+			}
+
+			otherKEK, _ := first.VKO(&second.PublicKey, []byte{1, 2, 3, 4, 5, 6, 7, 8}, hash)
+
+			if bytes.Equal(firstKEK, otherKEK) {
+				t.Errorf("%s, %s: ожидались разные ключи согласования для разных UKM", curve.Name, hash.Name)
+			}
+		}
+	}
+}
+
+func Test_VKORFC7836_Success(t *testing.T) {
+	// RFC 7836 приложение A.2, кривая id-tc26-gost-3410-12-512-paramSetA, значения в порядке little-endian
+	firstRaw, _ := hex.DecodeString("c990ecd972fce84ec4db022778f50fcac726f46708384b8d458304962d7147f8c2db41cef22c90b102f2968404f9b9be6d47c79692d81826b32b8daca43cb667")
+	firstPublic, _ := hex.DecodeString("aab0eda4abff21208d18799fb9a8556654ba783070eba10cb9abb253ec56dcf5d3ccba6192e464e6e5bcb6dea137792f2431f6c897eb1b3c0cc14327b1adc0a7" +
+		"914613a3074e363aedb204d38d3563971bd8758e878c9db11403721b48002d38461f92472d40ea92f9958c0ffa4c93756401b97f89fdbe0b5e46e4a4631cdb5a")
+	secondRaw, _ := hex.DecodeString("48c859f7b6f11585887cc05ec6ef1390cfea739b1a18c0d4662293ef63b79e3b8014070b44918590b4b996acfea4edfbbbcccc8c06edd8bf5bda92a51392d0db")
+	secondPublic, _ := hex.DecodeString("192fe183b9713a077253c72c8735de2ea42a3dbc66ea317838b65fa32523cd5efca974eda7c863f4954d1147f1f2b25c395fce1c129175e876d132e94ed5a651" +
+		"04883b414c9b592ec4dc84826f07d0b6d9006dda176ce48c391e3f97d102e03bb598bf132a228a45f7201aba08fc524a2d77e43a362ab022ad4028f75bde3b79")
+	ukm, _ := hex.DecodeString("1d80603c8544c727")
+
+	first, error := NewPrivateKey(CurveTC26_512A, littleEndianToBig(firstRaw))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	second, error := NewPrivateKey(CurveTC26_512A, littleEndianToBig(secondRaw))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if !bytes.Equal(first.PublicKey.Raw(), firstPublic) || !bytes.Equal(second.PublicKey.Raw(), secondPublic) {
+		t.Fatal("Ожидались открытые ключи из RFC 7836")
+	}
+
+	for _, test := range []struct {
+		hash *HashAlgorithm
+		kek  string
+	}{
+		{HashGOST3411_2012_256, "c9a9a77320e2cc559ed72dce6f47e2192ccea95fa648670582c054c0ef36c221"},
+		{HashGOST3411_2012_512, "79f002a96940ce7bde3259a52e015297adaad84597a0d205b50e3e1719f97bfa7ee1d2661fa9979a5aa235b558a7e6d9f88f982dd63fc35a8ec0dd5e242d3bdf"},
+	} {
+		want, _ := hex.DecodeString(test.kek)
+
+		for _, pair := range [][2]*PrivateKey{{first, second}, {second, first}} {
+			kek, error := pair[0].VKO(&pair[1].PublicKey, ukm, test.hash)
+
+			if error != nil {
+				t.Fatal(error)
+			}
+
+			if !bytes.Equal(kek, want) {
+				t.Errorf("%s: ожидался ключ согласования %x. Получен %x", test.hash.Name, want, kek)
+			}
+		}
+	}
+}
+
+func Test_VKOPoint_Success(t *testing.T) {
+	// на кривой с кофактором 4 точка K = (4 * UKM * d mod q) * Q
+	curve := CurveTC26_256A
+	first, _ := NewPrivateKey(curve, big.NewInt(12345))
+	second, _ := NewPrivateKey(curve, big.NewInt(67890))
+
+	point, error := first.agreementPoint(&second.PublicKey, []byte{0x02})
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	x, y := curve.ScalarBaseMult(big.NewInt(4 * 2 * 12345 * 67890))
+
+	if want := (&PublicKey{Curve: curve, X: x, Y: y}).Raw(); !bytes.Equal(point, want) {
+		t.Errorf("Ожидалась точка %x. Получена %x", want, point)
+	}
+
+	// нулевое значение UKM заменяется единицей
+	zero, _ := first.agreementPoint(&second.PublicKey, make([]byte, 8))
+	one, _ := first.agreementPoint(&second.PublicKey, []byte{0x01})
+
+	if !bytes.Equal(zero, one) {
+		t.Error("Ожидалась замена нулевого UKM единицей")
+	}
+
+	if _, error := first.agreementPoint(&second.PublicKey, nil); error == nil {
+		t.Error("Ожидалась ошибка для пустого UKM")
+	}
+
+	other, _ := CurveCryptoProA.GenerateKey(nil)
+
+	if _, error := first.agreementPoint(&other.PublicKey, []byte{0x01}); error == nil {
+		t.Error("Ожидалась ошибка для ключа на другой кривой")
+	}
+
+	if _, error := first.VKO(&second.PublicKey, []byte{0x01}, HashSha256); error == nil {
+		t.Error("Ожидалась ошибка для алгоритма хэширования не ГОСТ")
+	}
+}
+
+func Test_PublicKeyValidate_Success(t *testing.T) {
+	curve := CurveTC26_256A
+	key, _ := curve.GenerateKey(nil)
+
+	if error := key.PublicKey.Validate(); error != nil {
+		t.Fatal(error)
+	}
+
+	outside := &PublicKey{Curve: curve, X: key.X, Y: new(big.Int).Add(key.Y, big.NewInt(1))}
+
+	if error := outside.Validate(); error == nil {
+		t.Error("Ожидалась ошибка для точки вне кривой")
+	}
+
+	// точка кривой с кофактором 4 вне подгруппы порядка q
+	for value := int64(1); ; value++ {
+		x := big.NewInt(value)
+
+		right := new(big.Int).Mul(x, x)
+		right.Add(right, curve.A)
+		right.Mul(right, x)
+		right.Add(right, curve.B)
+		right.Mod(right, curve.P)
+
+		y := new(big.Int).ModSqrt(right, curve.P)
+
+		if y == nil {
+			continue
+		}
+
+		if qx, _ := curve.ScalarMult(x, y, curve.Q); qx == nil {
+			continue
+		}
+
+		if error := (&PublicKey{Curve: curve, X: x, Y: y}).Validate(); error == nil {
+			t.Error("Ожидалась ошибка для точки вне подгруппы порядка q")
+		}
+
+		if _, error := key.agreementPoint(&PublicKey{Curve: curve, X: x, Y: y}, []byte{0x01}); error == nil {
+			t.Error("Ожидалась ошибка согласования с точкой вне подгруппы")
+		}
+
+		break
+	}
+
+	if error := (*PublicKey)(nil).Validate(); error == nil {
+		t.Error("Ожидалась ошибка для пустого ключа")
+	}
+}
@@ -0,0 +1,211 @@
+package cryptography
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/madpo/go-gost-crypto/pkg/c14n"
+)
+
+// пространство имен XMLDSig
+const XMLDSigNamespace = "http://www.w3.org/2000/09/xmldsig#"
+
+/*
+Идентификаторы алгоритмов подписи и хэширования XMLDSig
+*/
+type xmlSignatureMethod struct {
+	signature string
+	digest    string
+}
+
+// идентификаторы XMLDSig для алгоритмов ГОСТ
+var xmlSignatureMethods = map[string]xmlSignatureMethod{
+	OIDGOST3410_2001.String(): {
+		signature: "http://www.w3.org/2001/04/xmldsig-more#gostr34102001-gostr3411",
+		digest:    "http://www.w3.org/2001/04/xmldsig-more#gostr3411",
+	},
+	OIDGOST3410_2012_256.String(): {
+		signature: "urn:ietf:params:xml:ns:cpxmlsec:algorithms:gostr34102012-gostr34112012-256",
+		digest:    "urn:ietf:params:xml:ns:cpxmlsec:algorithms:gostr34112012-256",
+	},
+	OIDGOST3410_2012_512.String(): {
+		signature: "urn:ietf:params:xml:ns:cpxmlsec:algorithms:gostr34102012-gostr34112012-512",
+		digest:    "urn:ietf:params:xml:ns:cpxmlsec:algorithms:gostr34112012-512",
+	},
+}
+
+// элементы СМЭВ 3 по умолчанию
+const (
+	smevReferenceID      = "SIGNED_BY_CONSUMER"
+	smevSignatureElement = "CallerInformationSystemSignature"
+)
+
+// получить метод подписи XMLDSig сообщения СМЭВ 3
+// подписывается элемент с атрибутом Id = referenceID (по умолчанию SIGNED_BY_CONSUMER),
+// ds:Signature помещается в элемент с локальным именем signatureElement (по умолчанию CallerInformationSystemSignature)
+func CreateSMEVSignMethod(signer crypto.Signer, certificate *x509.Certificate, referenceID string, signatureElement string) (release func(), sign func(io.Reader) (io.Reader, error), exception error) {
+	algorithm, exception := FindCertificateSignatureAlgorithm(certificate)
+
+	if exception != nil {
+		return nil, nil, exception
+	}
+
+	method, ok := xmlSignatureMethods[algorithm.PublicKeyOID.String()]
+
+	if !ok {
+		return nil, nil, errors.New("Не поддерживается алгоритм подписи XMLDSig " + algorithm.Name)
+	}
+
+	if referenceID == "" {
+		referenceID = smevReferenceID
+	}
+
+	if signatureElement == "" {
+		signatureElement = smevSignatureElement
+	}
+
+	return func() {},
+		func(document io.Reader) (io.Reader, error) {
+			parsed, exception := c14n.Parse(document)
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			reference := parsed.Root().FindByID(referenceID)
+
+			if reference == nil {
+				return nil, errors.New("Не найден подписываемый элемент с Id " + referenceID)
+			}
+
+			container := parsed.Root().FindByLocalName(signatureElement)
+
+			if container == nil {
+				return nil, errors.New("Не найден элемент для подписи " + signatureElement)
+			}
+
+			digest, exception := calculateSMEVDigest(algorithm.Hash, reference)
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			signature, exception := createXMLSignature(signer, certificate, algorithm, method, referenceID, digest)
+
+			if exception != nil {
+				return nil, exception
+			}
+
+			signature.Parent = container
+			container.Children = []c14n.Node{signature}
+
+			var buffer bytes.Buffer
+
+			if exception := parsed.Write(&buffer); exception != nil {
+				return nil, exception
+			}
+
+			return &buffer, nil
+		}, nil
+}
+
+// вычислить хэш элемента после исключающей канонизации и преобразования СМЭВ
+func calculateSMEVDigest(algorithm *HashAlgorithm, element *c14n.Element) ([]byte, error) {
+	canonical := c14n.NewReader(func(writer io.Writer) error {
+		return c14n.CanonicalizeExclusive(writer, element, nil, false)
+	})
+
+	transformed := c14n.NewReader(func(writer io.Writer) error {
+		return c14n.TransformSMEV(writer, canonical)
+	})
+
+	digest, exception := calculateDigest(algorithm, transformed)
+	// дочитать поток, чтобы завершить горутины канонизации
+	io.Copy(io.Discard, transformed)
+
+	return digest, exception
+}
+
+// сформировать элемент ds:Signature
+func createXMLSignature(signer crypto.Signer, certificate *x509.Certificate, algorithm *SignatureAlgorithm, method xmlSignatureMethod, referenceID string, digest []byte) (*c14n.Element, error) {
+	var builder strings.Builder
+
+	builder.WriteString(`<ds:Signature xmlns:ds="` + XMLDSigNamespace + `">`)
+	builder.WriteString(`<ds:SignedInfo>`)
+	builder.WriteString(`<ds:CanonicalizationMethod Algorithm="` + c14n.Exclusive + `"/>`)
+	builder.WriteString(`<ds:SignatureMethod Algorithm="` + method.signature + `"/>`)
+	builder.WriteString(`<ds:Reference URI="#`)
+	xml.EscapeText(&builder, []byte(referenceID))
+	builder.WriteString(`">`)
+	builder.WriteString(`<ds:Transforms>`)
+	builder.WriteString(`<ds:Transform Algorithm="` + c14n.Exclusive + `"/>`)
+	builder.WriteString(`<ds:Transform Algorithm="` + c14n.SMEVTransform + `"/>`)
+	builder.WriteString(`</ds:Transforms>`)
+	builder.WriteString(`<ds:DigestMethod Algorithm="` + method.digest + `"/>`)
+	builder.WriteString(`<ds:DigestValue>` + base64.StdEncoding.EncodeToString(digest) + `</ds:DigestValue>`)
+	builder.WriteString(`</ds:Reference>`)
+	builder.WriteString(`</ds:SignedInfo>`)
+	builder.WriteString(`<ds:SignatureValue></ds:SignatureValue>`)
+	builder.WriteString(`<ds:KeyInfo><ds:X509Data><ds:X509Certificate>`)
+	builder.WriteString(base64.StdEncoding.EncodeToString(certificate.Raw))
+	builder.WriteString(`</ds:X509Certificate></ds:X509Data></ds:KeyInfo>`)
+	builder.WriteString(`</ds:Signature>`)
+
+	document, exception := c14n.Parse(strings.NewReader(builder.String()))
+
+	if exception != nil {
+		return nil, exception
+	}
+
+	signature := document.Root()
+
+	if exception := signXMLSignedInfo(signer, algorithm, signature, c14n.Exclusive); exception != nil {
+		return nil, exception
+	}
+
+	return signature, nil
+}
+
+// канонизировать элемент методом XMLDSig
+func canonicalizeXMLElement(writer io.Writer, element *c14n.Element, algorithm string) error {
+	switch algorithm {
+	case c14n.Canonical:
+		return c14n.Canonicalize(writer, element, false)
+	case c14n.Exclusive:
+		return c14n.CanonicalizeExclusive(writer, element, nil, false)
+	}
+
+	return errors.New("Не поддерживается метод канонизации " + algorithm)
+}
+
+// подписать канонизированный SignedInfo и записать значение в SignatureValue
+func signXMLSignedInfo(signer crypto.Signer, algorithm *SignatureAlgorithm, signature *c14n.Element, canonicalization string) error {
+	var canonical bytes.Buffer
+
+	if exception := canonicalizeXMLElement(&canonical, signature.FindByLocalName("SignedInfo"), canonicalization); exception != nil {
+		return exception
+	}
+
+	digest, exception := calculateBytesDigest(algorithm.Hash, canonical.Bytes())
+
+	if exception != nil {
+		return exception
+	}
+
+	value, exception := signDigest(signer, algorithm, digest)
+
+	if exception != nil {
+		return exception
+	}
+
+	signatureValue := signature.FindByLocalName("SignatureValue")
+	signatureValue.Children = []c14n.Node{c14n.Text(base64.StdEncoding.EncodeToString(value))}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package cryptography
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/madpo/go-gost-crypto/pkg/c14n"
+)
+
+func Test_SMEVSign_Success(t *testing.T) {
+	privateKey, certificate := createTestGOSTCertificate(t, CurveCryptoProA, "SMEV")
+
+	release, sign, error := CreateSMEVSignMethod(privateKey, certificate, "", "")
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer release()
+
+	file, error := os.Open("../../test/HashTest.xml")
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	defer file.Close()
+
+	signed, error := sign(file)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	document, error := c14n.Parse(signed)
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	container := document.Root().FindByLocalName("CallerInformationSystemSignature")
+	signature := container.FindByLocalName("Signature")
+
+	if signature == nil {
+		t.Fatal("Ожидался элемент ds:Signature в CallerInformationSystemSignature")
+	}
+
+	if uri, _ := signature.LookupNamespace(signature.Prefix); uri != XMLDSigNamespace {
+		t.Errorf("Ожидалось пространство имен %s. Получено %s", XMLDSigNamespace, uri)
+	}
+
+	method := signature.FindByLocalName("SignatureMethod")
+
+	if method.Attributes[0].Value != "urn:ietf:params:xml:ns:cpxmlsec:algorithms:gostr34102012-gostr34112012-256" {
+		t.Errorf("Неверный алгоритм подписи %s", method.Attributes[0].Value)
+	}
+
+	// элемент SIGNED_BY_CONSUMER после исключающей канонизации и преобразования СМЭВ
+	transformed := `<ns1:MessageTypeSelector xmlns:ns1="urn://x-artefacts-smev-gov-ru/services/message-exchange/types/basic/1.3" Id="SIGNED_BY_CONSUMER">` +
+		`<ns1:NamespaceURI>urn://x-artefacts-zags-rogdzp/root/112-23/4.0.1</ns1:NamespaceURI>` +
+		`<ns1:RootElementLocalName>Request</ns1:RootElementLocalName>` +
+		`<ns1:Timestamp>2020-08-19T11:31:55.7639468+03:00</ns1:Timestamp>` +
+		`</ns1:MessageTypeSelector>`
+
+	digest, error := calculateBytesDigest(HashGOST3411_2012_256, []byte(transformed))
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	digestValue := signature.FindByLocalName("DigestValue").Text()
+
+	if digestValue != base64.StdEncoding.EncodeToString(digest) {
+		t.Errorf("Ожидался DigestValue %s. Получен %s", base64.StdEncoding.EncodeToString(digest), digestValue)
+	}
+
+	if want := "EE/0hWkRcNPgHKNZR8U1i6BshdRIzk9KQA6Kq3CaH5U="; digestValue != want {
+		t.Errorf("Ожидался DigestValue %s. Получен %s", want, digestValue)
+	}
+
+	var canonical bytes.Buffer
+
+	if error := c14n.CanonicalizeExclusive(&canonical, signature.FindByLocalName("SignedInfo"), nil, false); error != nil {
+		t.Fatal(error)
+	}
+
+	if !strings.HasPrefix(canonical.String(), `<ds:SignedInfo xmlns:ds="`+XMLDSigNamespace+`">`) {
+		t.Errorf("Неверная канонизация SignedInfo %s", canonical.String())
+	}
+
+	signedInfoDigest, error := calculateBytesDigest(HashGOST3411_2012_256, canonical.Bytes())
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	value, error := base64.StdEncoding.DecodeString(signature.FindByLocalName("SignatureValue").Text())
+
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	if !privateKey.PublicKey.VerifyDigest(signedInfoDigest, value) {
+		t.Error("Ожидалась верная подпись SignedInfo")
+	}
+
+	rawCertificate := signature.FindByLocalName("X509Certificate").Text()
+
+	if rawCertificate != base64.StdEncoding.EncodeToString(certificate.Raw) {
+		t.Error("Ожидался сертификат подписанта в KeyInfo")
+	}
+}
@@ -17,9 +17,12 @@ package wrapper
 #include <winerror.h>
 #include <prsht.h>
 #include <ades-core.h>
+#include <stdlib.h>
 */
 import "C"
 
+import "unsafe"
+
 /*
 Тип CSP
 */
@@ -40,6 +43,16 @@ type CryptoProvider C.HCRYPTPROV
 */
 type CryptoHash C.HCRYPTHASH
 
+/*
+Ключ
+*/
+type CryptoKey C.HCRYPTKEY
+
+/*
+Назначение ключа в контейнере
+*/
+type KeySpec uint
+
 // Исключение работы с csp
 type CSPException struct {
 	code int64
@@ -60,6 +73,31 @@ type GetHashException struct {
 	code int64
 }
 
+// исключение установки параметра хэша
+type SetHashException struct {
+	code int64
+}
+
+// исключение установки параметра криптопровайдера
+type ProviderParamException struct {
+	code int64
+}
+
+// исключение работы с ключом
+type KeyException struct {
+	code int64
+}
+
+// исключение подписания хэша
+type SignHashException struct {
+	code int64
+}
+
+// исключение шифрования
+type CipherException struct {
+	code int64
+}
+
 func (exception *CSPException) Error() string {
 	switch exception.code {
 	case C.ERROR_BUSY:
@@ -188,6 +226,147 @@ func (exception *GetHashException) Error() string {
 	return "Undefined GetHashParam Error"
 }
 
+func (exception *SetHashException) Error() string {
+	switch exception.code {
+	case C.ERROR_BUSY:
+		return "ERROR_BUSY. The CSP context is currently being used by another process."
+	case C.ERROR_INVALID_HANDLE:
+		return "ERROR_INVALID_HANDLE. One of the parameters specifies a handle that is not valid."
+	case C.ERROR_INVALID_PARAMETER:
+		return "ERROR_INVALID_PARAMETER. One of the parameters contains a value that is not valid. This is most often a pointer that is not valid."
+	case C.NTE_BAD_FLAGS:
+		return "NTE_BAD_FLAGS. The dwFlags parameter is nonzero or the pbData buffer contains a value that is not valid."
+	case C.NTE_BAD_HASH:
+		return "NTE_BAD_HASH. The hash object specified by the hHash parameter is not valid."
+	case C.NTE_BAD_TYPE:
+		return "NTE_BAD_TYPE. The dwParam parameter specifies an unknown parameter."
+	case C.NTE_BAD_UID:
+		return "NTE_BAD_UID. The CSP context that was specified when the hKey key was created cannot be found."
+	case C.NTE_FAIL:
+		return "NTE_FAIL. The function failed in some unexpected way."
+	}
+
+	return "Undefined SetHashParam Error"
+}
+
+func (exception *ProviderParamException) Error() string {
+	switch exception.code {
+	case C.ERROR_BUSY:
+		return "ERROR_BUSY. The CSP context is currently being used by another process."
+	case C.ERROR_FILE_NOT_FOUND:
+		return "ERROR_FILE_NOT_FOUND. The key container could not be found."
+	case C.ERROR_INVALID_HANDLE:
+		return "ERROR_INVALID_HANDLE. One of the parameters specifies a handle that is not valid."
+	case C.ERROR_INVALID_PARAMETER:
+		return "ERROR_INVALID_PARAMETER. One of the parameters contains a value that is not valid. This is most often a pointer that is not valid."
+	case C.NTE_BAD_FLAGS:
+		return "NTE_BAD_FLAGS. The dwFlags parameter is nonzero or the pbData buffer contains a value that is not valid."
+	case C.NTE_BAD_TYPE:
+		return "NTE_BAD_TYPE. The dwParam parameter specifies an unknown parameter."
+	case C.NTE_BAD_UID:
+		return "NTE_BAD_UID. The CSP context specified by hProv is not valid."
+	case C.NTE_FAIL:
+		return "NTE_FAIL. The function failed in some unexpected way."
+	case C.SCARD_W_WRONG_CHV:
+		return "SCARD_W_WRONG_CHV. The PIN code of the key container is wrong."
+	}
+
+	return "Undefined SetProvParam Error"
+}
+
+func (exception *KeyException) Error() string {
+	switch exception.code {
+	case C.ERROR_BUSY:
+		return "ERROR_BUSY. The key object specified by hKey is currently being used by another process."
+	case C.ERROR_INVALID_HANDLE:
+		return "ERROR_INVALID_HANDLE. One of the parameters specifies a handle that is not valid."
+	case C.ERROR_INVALID_PARAMETER:
+		return "ERROR_INVALID_PARAMETER. One of the parameters contains a value that is not valid. This is most often a pointer that is not valid."
+	case C.ERROR_MORE_DATA:
+		return "ERROR_MORE_DATA. The buffer specified by the pbData parameter is not large enough to hold the returned data."
+	case C.NTE_BAD_FLAGS:
+		return "NTE_BAD_FLAGS. The dwFlags parameter is nonzero."
+	case C.NTE_BAD_KEY:
+		return "NTE_BAD_KEY. The key specified by the hKey parameter is not valid."
+	case C.NTE_BAD_KEYSET:
+		return "NTE_BAD_KEYSET. The key container could not be opened."
+	case C.NTE_BAD_TYPE:
+		return "NTE_BAD_TYPE. The dwParam parameter specifies an unknown value number."
+	case C.NTE_BAD_UID:
+		return "NTE_BAD_UID. The CSP context that was specified when the key was created cannot be found."
+	case C.NTE_NO_KEY:
+		return "NTE_NO_KEY. The key requested by the dwKeySpec parameter does not exist."
+	case C.NTE_NOT_FOUND:
+		return "NTE_NOT_FOUND. The requested key parameter, for example the certificate, is not set."
+	}
+
+	return "Undefined Key Error"
+}
+
+func (exception *SignHashException) Error() string {
+	switch exception.code {
+	case C.ERROR_INVALID_HANDLE:
+		return "ERROR_INVALID_HANDLE. One of the parameters specifies a handle that is not valid."
+	case C.ERROR_INVALID_PARAMETER:
+		return "ERROR_INVALID_PARAMETER. One of the parameters contains a value that is not valid. This is most often a pointer that is not valid."
+	case C.ERROR_MORE_DATA:
+		return "ERROR_MORE_DATA. The buffer specified by the pbSignature parameter is not large enough to hold the returned data."
+	case C.NTE_BAD_ALGID:
+		return "NTE_BAD_ALGID. The hHash handle specifies an algorithm that this CSP does not support, or the dwKeySpec parameter has an incorrect value."
+	case C.NTE_BAD_FLAGS:
+		return "NTE_BAD_FLAGS. The dwFlags parameter is nonzero."
+	case C.NTE_BAD_HASH:
+		return "NTE_BAD_HASH. The hash object specified by the hHash parameter is not valid."
+	case C.NTE_BAD_UID:
+		return "NTE_BAD_UID. The CSP context that was specified when the hash object was created cannot be found."
+	case C.NTE_NO_KEY:
+		return "NTE_NO_KEY. The private key specified by dwKeySpec does not exist."
+	case C.NTE_NO_MEMORY:
+		return "NTE_NO_MEMORY. The CSP ran out of memory during the operation."
+	case C.SCARD_W_WRONG_CHV:
+		return "SCARD_W_WRONG_CHV. The PIN code of the key container is wrong."
+	}
+
+	return "Undefined SignHash Error"
+}
+
+func (exception *CipherException) Error() string {
+	switch exception.code {
+	case C.ERROR_INVALID_HANDLE:
+		return "ERROR_INVALID_HANDLE. One of the parameters specifies a handle that is not valid."
+	case C.ERROR_INVALID_PARAMETER:
+		return "ERROR_INVALID_PARAMETER. One of the parameters contains a value that is not valid. This is most often a pointer that is not valid."
+	case C.ERROR_MORE_DATA:
+		return "ERROR_MORE_DATA. The size of the output data exceeds the size of the buffer."
+	case C.NTE_BAD_ALGID:
+		return "NTE_BAD_ALGID. The key algorithm is not supported by this CSP."
+	case C.NTE_BAD_DATA:
+		return "NTE_BAD_DATA. The data or the key blob is not valid."
+	case C.NTE_BAD_FLAGS:
+		return "NTE_BAD_FLAGS. The dwFlags parameter is nonzero."
+	case C.NTE_BAD_KEY:
+		return "NTE_BAD_KEY. The key specified by the hKey parameter is not valid."
+	case C.NTE_BAD_LEN:
+		return "NTE_BAD_LEN. The size of the data is not a multiple of the block size."
+	case C.NTE_BAD_TYPE:
+		return "NTE_BAD_TYPE. The key blob type or the key parameter is not supported."
+	case C.NTE_BAD_UID:
+		return "NTE_BAD_UID. The CSP context that was specified when the key was created cannot be found."
+	case C.NTE_BAD_VER:
+		return "NTE_BAD_VER. The version number of the key blob does not match the CSP version."
+	case C.NTE_DOUBLE_ENCRYPT:
+		return "NTE_DOUBLE_ENCRYPT. The application attempted to encrypt the same data twice."
+	case C.NTE_FAIL:
+		return "NTE_FAIL. The function failed in some unexpected way."
+	case C.NTE_NO_MEMORY:
+		return "NTE_NO_MEMORY. The CSP ran out of memory during the operation."
+	case C.NTE_PERM:
+		return "NTE_PERM. The CSP does not allow import of the key in plain text."
+	}
+
+	return "Undefined Cipher Error"
+}
+
 /*
 Размер хэша
 */
@@ -196,6 +375,8 @@ type HSize C.ulong
 var (
 	Size256 HSize = 32
 	Size512 HSize = 64
+	// имитовставка ГОСТ 28147-89
+	SizeImit HSize = 4
 )
 
 const (
@@ -213,6 +394,44 @@ const (
 	GOST3411          HashType = C.CALG_GR3411
 	GOST3411_2012_256 HashType = C.CALG_GR3411_2012_256
 	GOST3411_2012_512 HashType = C.CALG_GR3411_2012_512
+	// имитовставка ГОСТ 28147-89, требует ключа
+	GOST28147_IMIT HashType = C.CALG_G28147_IMIT
+)
+
+/*
+Алгоритм блочного шифрования
+*/
+type CipherType uint
+
+const (
+	GOST28147       CipherType = C.CALG_G28147
+	GOST3412_2015_M CipherType = C.CALG_GR3412_2015_M
+	GOST3412_2015_K CipherType = C.CALG_GR3412_2015_K
+)
+
+/*
+Режим шифрования ключа
+*/
+type CipherMode uint
+
+const (
+	CipherModeECB CipherMode = C.CRYPT_MODE_ECB
+	CipherModeCFB CipherMode = C.CRYPT_MODE_CFB
+)
+
+/*
+Алгоритм открытого ключа стороны при выработке ключа согласования
+*/
+type AgreementType uint
+
+const (
+	GOST3410_2012_256_DH AgreementType = C.CALG_DH_GR3410_12_256_SF
+	GOST3410_2012_512_DH AgreementType = C.CALG_DH_GR3410_12_512_SF
+)
+
+const (
+	KeyExchange KeySpec = C.AT_KEYEXCHANGE
+	Signature   KeySpec = C.AT_SIGNATURE
 )
 
 // получить экземпляр крипто провайдера
@@ -271,6 +490,46 @@ func TakeHashMethod(cryptoProvider *CryptoProvider, hashType HashType) (*CryptoH
 	return &hashMethod, nil
 }
 
+// получить метод хэширования на ключе, например имитовставки
+func TakeKeyedHashMethod(cryptoProvider *CryptoProvider, hashType HashType, key *CryptoKey) (*CryptoHash, error) {
+	var hashMethod_CType C.HCRYPTHASH
+	var hashMethod CryptoHash
+
+	cryptoProvider_CType := (*C.HCRYPTPROV)(cryptoProvider)
+	key_CType := (*C.HCRYPTKEY)(key)
+	hashType_CType := C.uint(hashType)
+
+	result := C.CryptCreateHash(*cryptoProvider_CType, hashType_CType, *key_CType, 0, &hashMethod_CType)
+
+	if result == Failure {
+		errorCode := C.GetLastError()
+		return nil, &HashMethodException{code: (int64)(errorCode)}
+	}
+
+	hashMethod = (CryptoHash)(hashMethod_CType)
+
+	return &hashMethod, nil
+}
+
+// скопировать метод хэширования вместе с состоянием
+func DuplicateHashMethod(hashMethod *CryptoHash) (*CryptoHash, error) {
+	var duplicate_CType C.HCRYPTHASH
+	var duplicate CryptoHash
+
+	hashMethod_CType := (*C.HCRYPTHASH)(hashMethod)
+
+	result := C.CryptDuplicateHash(*hashMethod_CType, nil, 0, &duplicate_CType)
+
+	if result == Failure {
+		errorCode := C.GetLastError()
+		return nil, &HashMethodException{code: (int64)(errorCode)}
+	}
+
+	duplicate = (CryptoHash)(duplicate_CType)
+
+	return &duplicate, nil
+}
+
 // освободить метод хэширования
 func ReleaseHashMethod(hashMethod *CryptoHash) {
 	if hashMethod == nil {
@@ -316,3 +575,370 @@ func CalculateHashValue(hashObject *CryptoHash, size HSize) (*[]byte, error) {
 
 	return &hashBuffer, nil
 }
+
+// получить экземпляр крипто провайдера с доступом к ключевому контейнеру
+func TakeContainer(cspType CSPType, container string) (*CryptoProvider, error) {
+	var cryptoProvider_CType C.HCRYPTPROV
+	cspType_CType := C.ulong(cspType)
+
+	container_CType := C.CString(container)
+	defer C.free(unsafe.Pointer(container_CType))
+
+	result := C.CryptAcquireContext(&cryptoProvider_CType, container_CType, nil, cspType_CType, C.CRYPT_SILENT)
+
+	if result == Failure {
+		errorCode := C.GetLastError()
+		return nil, &CSPException{code: (int64)(errorCode)}
+	}
+
+	cryptoProvider := (CryptoProvider)(cryptoProvider_CType)
+	return &cryptoProvider, nil
+}
+
+// установить пин-код ключевого контейнера
+func SetContainerPin(cryptoProvider *CryptoProvider, keySpec KeySpec, pin string) error {
+	cryptoProvider_CType := (*C.HCRYPTPROV)(cryptoProvider)
+
+	param_CType := C.ulong(C.PP_KEYEXCHANGE_PIN)
+
+	if keySpec == Signature {
+		param_CType = C.ulong(C.PP_SIGNATURE_PIN)
+	}
+
+	pin_CType := C.CString(pin)
+	defer C.free(unsafe.Pointer(pin_CType))
+
+	result := C.CryptSetProvParam(*cryptoProvider_CType, param_CType, (*C.uchar)(unsafe.Pointer(pin_CType)), 0)
+
+	if result == Failure {
+		errorCode := C.GetLastError()
+		return &ProviderParamException{code: (int64)(errorCode)}
+	}
+
+	return nil
+}
+
+// получить ключ из контейнера
+func TakeUserKey(cryptoProvider *CryptoProvider, keySpec KeySpec) (*CryptoKey, error) {
+	var key_CType C.HCRYPTKEY
+
+	cryptoProvider_CType := (*C.HCRYPTPROV)(cryptoProvider)
+
+	result := C.CryptGetUserKey(*cryptoProvider_CType, C.ulong(keySpec), &key_CType)
+
+	if result == Failure {
+		errorCode := C.GetLastError()
+		return nil, &KeyException{code: (int64)(errorCode)}
+	}
+
+	key := (CryptoKey)(key_CType)
+	return &key, nil
+}
+
+// освободить ключ
+func ReleaseKey(key *CryptoKey) {
+	if key == nil {
+		return
+	}
+
+	key_CType := (*C.HCRYPTKEY)(key)
+
+	result := C.CryptDestroyKey(*key_CType)
+
+	if result == Failure {
+		errorCode := C.GetLastError()
+		panic(&KeyException{code: (int64)(errorCode)})
+	}
+}
+
+// получить сертификат ключа в DER
+func GetKeyCertificate(key *CryptoKey) (*[]byte, error) {
+	key_CType := (*C.HCRYPTKEY)(key)
+	var size C.ulong
+
+	result := C.CryptGetKeyParam(*key_CType, C.KP_CERTIFICATE, nil, &size, 0)
+
+	if result == Failure {
+		errorCode := C.GetLastError()
+		return nil, &KeyException{code: (int64)(errorCode)}
+	}
+
+	certificate := make([]byte, size)
+
+	result = C.CryptGetKeyParam(*key_CType, C.KP_CERTIFICATE, (*C.uchar)(&certificate[0]), &size, 0)
+
+	if result == Failure {
+		errorCode := C.GetLastError()
+		return nil, &KeyException{code: (int64)(errorCode)}
+	}
+
+	certificate = certificate[:size]
+
+	return &certificate, nil
+}
+
+// установить значение хэша
+func SetHashValue(hashObject *CryptoHash, data *[]byte) error {
+	hashObject_CType := (*C.HCRYPTHASH)(hashObject)
+
+	value := *data
+
+	if len(value) == 0 {
+		return &SetHashException{code: C.NTE_BAD_LEN}
+	}
+
+	result := C.CryptSetHashParam(*hashObject_CType, C.HP_HASHVAL, (*C.uchar)(&value[0]), 0)
+
+	if result == Failure {
+		errorCode := C.GetLastError()
+		return &SetHashException{code: (int64)(errorCode)}
+	}
+
+	return nil
+}
+
+// подписать хэш ключом контейнера, подпись возвращается в порядке байт КриптоПро
+func SignHash(hashObject *CryptoHash, keySpec KeySpec) (*[]byte, error) {
+	hashObject_CType := (*C.HCRYPTHASH)(hashObject)
+	var size C.ulong
+
+	result := C.CryptSignHash(*hashObject_CType, C.ulong(keySpec), nil, 0, nil, &size)
+
+	if result == Failure {
+		errorCode := C.GetLastError()
+		return nil, &SignHashException{code: (int64)(errorCode)}
+	}
+
+	signature := make([]byte, size)
+
+	result = C.CryptSignHash(*hashObject_CType, C.ulong(keySpec), nil, 0, (*C.uchar)(&signature[0]), &size)
+
+	if result == Failure {
+		errorCode := C.GetLastError()
+		return nil, &SignHashException{code: (int64)(errorCode)}
+	}
+
+	signature = signature[:size]
+
+	return &signature, nil
+}
+
+// импортировать открытый симметричный ключ и установить режим простой замены
+// провайдер должен разрешать импорт ключей в формате PLAINTEXTKEYBLOB
+func ImportCipherKey(cryptoProvider *CryptoProvider, cipherType CipherType, key *[]byte) (*CryptoKey, error) {
+	var key_CType C.HCRYPTKEY
+
+	cryptoProvider_CType := (*C.HCRYPTPROV)(cryptoProvider)
+	value := *key
+
+	// BLOBHEADER: тип, версия, резерв и алгоритм, затем длина ключа и сам ключ
+	blob := make([]byte, 12+len(value))
+	blob[0] = C.PLAINTEXTKEYBLOB
+	blob[1] = C.CUR_BLOB_VERSION
+	putUint32(blob[4:], uint32(cipherType))
+	putUint32(blob[8:], uint32(len(value)))
+	copy(blob[12:], value)
+
+	result := C.CryptImportKey(*cryptoProvider_CType, (*C.uchar)(&blob[0]), C.ulong(len(blob)), 0, 0, &key_CType)
+
+	if result == Failure {
+		errorCode := C.GetLastError()
+		return nil, &CipherException{code: (int64)(errorCode)}
+	}
+
+	mode := C.ulong(C.CRYPT_MODE_ECB)
+	result = C.CryptSetKeyParam(key_CType, C.KP_MODE, (*C.uchar)(unsafe.Pointer(&mode)), 0)
+
+	if result == Failure {
+		errorCode := C.GetLastError()
+		C.CryptDestroyKey(key_CType)
+		return nil, &CipherException{code: (int64)(errorCode)}
+	}
+
+	cryptoKey := (CryptoKey)(key_CType)
+	return &cryptoKey, nil
+}
+
+// установить синхропосылку ключа
+func SetCipherIV(key *CryptoKey, iv *[]byte) error {
+	key_CType := (*C.HCRYPTKEY)(key)
+	value := *iv
+
+	if len(value) == 0 {
+		return &CipherException{code: C.NTE_BAD_LEN}
+	}
+
+	result := C.CryptSetKeyParam(*key_CType, C.KP_IV, (*C.uchar)(&value[0]), 0)
+
+	if result == Failure {
+		errorCode := C.GetLastError()
+		return &CipherException{code: (int64)(errorCode)}
+	}
+
+	return nil
+}
+
+// выработать ключ согласования VKO по ключу контейнера и открытому ключу другой стороны
+// parameters - DER параметров открытого ключа, publicKey - X||Y в порядке little-endian, ukm - 8 байт
+// ключ согласования не извлекается из провайдера и используется для экспорта и импорта ключей
+func ImportAgreementKey(cryptoProvider *CryptoProvider, userKey *CryptoKey, agreementType AgreementType, parameters *[]byte, publicKey *[]byte, ukm *[]byte) (*CryptoKey, error) {
+	var key_CType C.HCRYPTKEY
+
+	cryptoProvider_CType := (*C.HCRYPTPROV)(cryptoProvider)
+	userKey_CType := (*C.HCRYPTKEY)(userKey)
+	encodedParameters := *parameters
+	value := *publicKey
+
+	// BLOBHEADER, CRYPT_PUBKEYPARAM (магическое число и длина открытого ключа в битах), параметры и сам ключ
+	blob := make([]byte, 16+len(encodedParameters)+len(value))
+	blob[0] = C.PUBLICKEYBLOB
+	blob[1] = C.BLOB_VERSION
+	putUint32(blob[4:], uint32(agreementType))
+	putUint32(blob[8:], C.GR3410_1_MAGIC)
+	putUint32(blob[12:], uint32(8*len(value)))
+	copy(blob[16:], encodedParameters)
+	copy(blob[16+len(encodedParameters):], value)
+
+	result := C.CryptImportKey(*cryptoProvider_CType, (*C.uchar)(&blob[0]), C.ulong(len(blob)), *userKey_CType, 0, &key_CType)
+
+	if result == Failure {
+		errorCode := C.GetLastError()
+		return nil, &KeyException{code: (int64)(errorCode)}
+	}
+
+	algorithm := C.ulong(C.CALG_PRO12_EXPORT)
+	result = C.CryptSetKeyParam(key_CType, C.KP_ALGID, (*C.uchar)(unsafe.Pointer(&algorithm)), 0)
+
+	if result == Failure {
+		errorCode := C.GetLastError()
+		C.CryptDestroyKey(key_CType)
+		return nil, &KeyException{code: (int64)(errorCode)}
+	}
+
+	// UKM задается синхропосылкой ключа согласования
+	agreementUKM := *ukm
+	result = C.CryptSetKeyParam(key_CType, C.KP_IV, (*C.uchar)(&agreementUKM[0]), 0)
+
+	if result == Failure {
+		errorCode := C.GetLastError()
+		C.CryptDestroyKey(key_CType)
+		return nil, &KeyException{code: (int64)(errorCode)}
+	}
+
+	cryptoKey := (CryptoKey)(key_CType)
+	return &cryptoKey, nil
+}
+
+// импортировать ключ ГОСТ 28147-89, экспортированный по алгоритму КриптоПро, на ключе согласования
+// wrapped - UKM || зашифрованный ключ || имитовставка (8, 32 и 4 байта), parameters - DER идентификатора параметров шифрования
+func ImportWrappedKey(cryptoProvider *CryptoProvider, agreementKey *CryptoKey, wrapped *[]byte, parameters *[]byte) (*CryptoKey, error) {
+	var key_CType C.HCRYPTKEY
+
+	cryptoProvider_CType := (*C.HCRYPTPROV)(cryptoProvider)
+	agreementKey_CType := (*C.HCRYPTKEY)(agreementKey)
+	value := *wrapped
+	encodedParameters := *parameters
+
+	if len(value) != 44 {
+		return nil, &CipherException{code: C.NTE_BAD_LEN}
+	}
+
+	// SIMPLEBLOB: BLOBHEADER, магическое число и алгоритм экспорта, затем UKM, ключ, имитовставка и параметры
+	blob := make([]byte, 16+len(value)+len(encodedParameters))
+	blob[0] = C.SIMPLEBLOB
+	blob[1] = C.BLOB_VERSION
+	putUint32(blob[4:], C.CALG_G28147)
+	putUint32(blob[8:], C.G28147_MAGIC)
+	putUint32(blob[12:], C.CALG_G28147)
+	copy(blob[16:], value)
+	copy(blob[16+len(value):], encodedParameters)
+
+	result := C.CryptImportKey(*cryptoProvider_CType, (*C.uchar)(&blob[0]), C.ulong(len(blob)), *agreementKey_CType, 0, &key_CType)
+
+	if result == Failure {
+		errorCode := C.GetLastError()
+		return nil, &CipherException{code: (int64)(errorCode)}
+	}
+
+	cryptoKey := (CryptoKey)(key_CType)
+	return &cryptoKey, nil
+}
+
+// установить режим шифрования ключа
+func SetCipherMode(key *CryptoKey, mode CipherMode) error {
+	key_CType := (*C.HCRYPTKEY)(key)
+	value := C.ulong(mode)
+
+	result := C.CryptSetKeyParam(*key_CType, C.KP_MODE, (*C.uchar)(unsafe.Pointer(&value)), 0)
+
+	if result == Failure {
+		errorCode := C.GetLastError()
+		return &CipherException{code: (int64)(errorCode)}
+	}
+
+	return nil
+}
+
+// зашифровать данные на месте, длина данных кратна размеру блока
+func EncryptData(key *CryptoKey, data *[]byte) error {
+	key_CType := (*C.HCRYPTKEY)(key)
+
+	value := *data
+	size := C.ulong(len(value))
+
+	result := C.CryptEncrypt(*key_CType, 0, Failure, 0, (*C.uchar)(&value[0]), &size, C.ulong(len(value)))
+
+	if result == Failure {
+		errorCode := C.GetLastError()
+		return &CipherException{code: (int64)(errorCode)}
+	}
+
+	return nil
+}
+
+// расшифровать данные на месте, длина данных кратна размеру блока
+func DecryptData(key *CryptoKey, data *[]byte) error {
+	key_CType := (*C.HCRYPTKEY)(key)
+
+	value := *data
+	size := C.ulong(len(value))
+
+	result := C.CryptDecrypt(*key_CType, 0, Failure, 0, (*C.uchar)(&value[0]), &size)
+
+	if result == Failure {
+		errorCode := C.GetLastError()
+		return &CipherException{code: (int64)(errorCode)}
+	}
+
+	return nil
+}
+
+// расшифровать последний фрагмент данных на месте, длина данных может быть не кратна размеру блока
+func DecryptFinalData(key *CryptoKey, data *[]byte) error {
+	key_CType := (*C.HCRYPTKEY)(key)
+
+	value := *data
+	size := C.ulong(len(value))
+	var pointer *C.uchar
+
+	if len(value) > 0 {
+		pointer = (*C.uchar)(&value[0])
+	}
+
+	result := C.CryptDecrypt(*key_CType, 0, Success, 0, pointer, &size)
+
+	if result == Failure {
+		errorCode := C.GetLastError()
+		return &CipherException{code: (int64)(errorCode)}
+	}
+
+	return nil
+}
+
+// записать число в порядке little-endian, как в структурах CryptoAPI
+func putUint32(buffer []byte, value uint32) {
+	buffer[0] = byte(value)
+	buffer[1] = byte(value >> 8)
+	buffer[2] = byte(value >> 16)
+	buffer[3] = byte(value >> 24)
+}